@@ -14,7 +14,9 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"github.com/svenskhalsovard/api/internal/config"
+	"github.com/svenskhalsovard/api/internal/encryption"
 	"github.com/svenskhalsovard/api/internal/handlers"
+	"github.com/svenskhalsovard/api/internal/mailer"
 	"github.com/svenskhalsovard/api/internal/middleware"
 	"github.com/svenskhalsovard/api/internal/repository"
 	"github.com/svenskhalsovard/api/internal/service"
@@ -53,19 +55,76 @@ func main() {
 		}
 	}
 
+	// Load the keys for encryption of personal data
+	keyring, err := encryption.LoadKeyring(cfg.Encryption.KeyFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load encryption keys")
+	}
+
 	// Initialize repositories
 	serviceRepo := repository.NewServiceRepository(db)
-	bookingRepo := repository.NewBookingRepository(db)
-	paymentRepo := repository.NewPaymentRepository(db)
+	categoryRepo := repository.NewCategoryRepository(db)
+	campaignRepo := repository.NewCampaignRepository(db)
+	bookingRepo := repository.NewBookingRepository(db, keyring)
+	eventRepo := repository.NewEventRepository(db)
+	idempotencyRepo := repository.NewIdempotencyRepository(db)
+	paymentRepo := repository.NewPaymentRepository(db, keyring)
+	promoCodeRepo := repository.NewPromoCodeRepository(db, keyring)
+	giftCardRepo := repository.NewGiftCardRepository(db)
+	cartRepo := repository.NewCartRepository(db)
+	quoteRepo := repository.NewQuoteRepository(db)
+	recoveryRepo := repository.NewCheckoutRecoveryRepository(db)
+	outboxRepo := repository.NewOutboxRepository(db)
+	contactRepo := repository.NewContactRepository(db)
+	customerRepo := repository.NewCustomerRepository(db, keyring)
+	authRepo := repository.NewAuthRepository(db)
+	dataExportRepo := repository.NewDataExportRepository(db, keyring)
+	erasureRepo := repository.NewErasureRepository(db, keyring)
+	consentRepo := repository.NewConsentRepository(db)
 
 	// Initialize Svea Ekonomi client
 	sveaClient := svea.NewClient(cfg.Svea)
 
+	// Initialize mail client
+	mailClient := mailer.NewClient(cfg.Mail)
+
 	// Initialize services
-	serviceService := service.NewServiceService(serviceRepo)
-	paymentService := service.NewPaymentService(paymentRepo, sveaClient)
-	bookingService := service.NewBookingService(bookingRepo, paymentRepo)
-	checkoutService := service.NewCheckoutService(paymentService, bookingService, serviceService)
+	pricingService := service.NewPricingService(campaignRepo)
+	serviceService := service.NewServiceService(serviceRepo, categoryRepo, pricingService, cfg.Catalog.CacheTTL)
+	categoryService := service.NewCategoryService(categoryRepo)
+	campaignService := service.NewCampaignService(campaignRepo, serviceService)
+	paymentService := service.NewPaymentService(paymentRepo, giftCardRepo, promoCodeRepo, eventRepo, sveaClient, keyring)
+	bookingService := service.NewBookingService(bookingRepo, paymentRepo, eventRepo)
+	promoCodeService := service.NewPromoCodeService(promoCodeRepo)
+	giftCardService := service.NewGiftCardService(giftCardRepo)
+	cartService := service.NewCartService(cartRepo, serviceService, pricingService, cfg.Cart.TTL)
+	customerService := service.NewCustomerService(customerRepo, bookingRepo, keyring)
+	authService := service.NewAuthService(authRepo, customerRepo, cfg.Auth.LoginCodeTTL, cfg.Auth.SessionTTL, cfg.Auth.LoginCodesPerHour)
+	consentService := service.NewConsentService(consentRepo, cfg.Consent.TermsVersion, cfg.Consent.PrivacyPolicyVersion)
+	checkoutService := service.NewCheckoutService(paymentService, bookingService, serviceService, pricingService, promoCodeService, giftCardService, cartService, customerService, consentService, quoteRepo, cfg.Checkout.QuoteTTL)
+	recoveryService := service.NewCheckoutRecoveryService(
+		recoveryRepo,
+		paymentService,
+		consentService,
+		mailClient,
+		cfg.Recovery.ResumeURL,
+		cfg.Recovery.OptOutURL,
+		cfg.Recovery.MinAge,
+		cfg.Recovery.MaxAge,
+	)
+	reconciliationService := service.NewPaymentReconciliationService(checkoutService, cfg.Reconciliation.MinAge, cfg.Reconciliation.MaxAge)
+	timelineService := service.NewTimelineService(eventRepo, paymentRepo, bookingRepo)
+	idempotencyService := service.NewIdempotencyService(idempotencyRepo, cfg.Idempotency.TTL, cfg.Idempotency.ClaimTimeout)
+	outboxService := service.NewOutboxService(outboxRepo, mailClient)
+	dataExportService := service.NewDataExportService(dataExportRepo, customerRepo, keyring, cfg.DataExport.DownloadURL, cfg.DataExport.TTL)
+	erasureService := service.NewErasureService(erasureRepo, customerRepo, cfg.Retention.Years)
+	contactService := service.NewContactService(
+		contactRepo,
+		cfg.Contact.StaffEmail,
+		cfg.Contact.FormSecret,
+		cfg.Contact.MinSubmitTime,
+		cfg.Contact.MaxPerIPPerHour,
+	)
 
 	// Initialize router
 	router := handlers.NewRouter(cfg)
@@ -77,6 +136,7 @@ func main() {
 	// Register API routes
 	apiRouter := router.Group("/api")
 	apiRouter.Use(middleware.RequestID)
+	apiRouter.Use(middleware.AuditActor)
 	apiRouter.Use(middleware.Logging)
 	apiRouter.Use(middleware.Recovery)
 	apiRouter.Use(middleware.CORS(cfg.CORS))
@@ -87,15 +147,90 @@ func main() {
 	apiRouter.Get("/services", serviceHandler.GetServices)
 	apiRouter.Get("/services/{id}", serviceHandler.GetServiceByID)
 
+	// Register category handlers
+	categoryHandler := handlers.NewCategoryHandler(categoryService, serviceService)
+	apiRouter.Get("/categories", categoryHandler.GetCategories)
+	apiRouter.Get("/categories/{slug}/services", categoryHandler.GetCategoryServices)
+
+	// Register cart handlers
+	cartHandler := handlers.NewCartHandler(cartService)
+	apiRouter.Post("/carts/{token}", cartHandler.ReplaceCart)
+	apiRouter.Get("/carts/{token}", cartHandler.GetCart)
+	apiRouter.Patch("/carts/{token}", cartHandler.UpdateCart)
+
 	// Register checkout handlers
 	checkoutHandler := handlers.NewCheckoutHandler(checkoutService)
-	apiRouter.Post("/checkout/initiate", checkoutHandler.InitiateCheckout)
-	apiRouter.Post("/checkout/payment", checkoutHandler.ProcessPayment)
+	apiRouter.Post("/checkout/quote", checkoutHandler.CreateQuote)
+	idempotentRouter := apiRouter.With(middleware.Idempotency(idempotencyService))
+	idempotentRouter.With(middleware.OptionalCustomerAuth(authService)).Post("/checkout/initiate", checkoutHandler.InitiateCheckout)
+	idempotentRouter.Post("/checkout/payment", checkoutHandler.ProcessPayment)
 	apiRouter.Get("/checkout/verify/{paymentId}", checkoutHandler.VerifyPayment)
 
+	// Register checkout recovery handlers
+	recoveryHandler := handlers.NewCheckoutRecoveryHandler(recoveryService)
+	apiRouter.Get("/checkout/recovery/{token}", recoveryHandler.ResumeCheckout)
+	apiRouter.Post("/checkout/recovery/{token}/opt-out", recoveryHandler.OptOut)
+
+	// Register gift card handlers
+	giftCardHandler := handlers.NewGiftCardHandler(giftCardService)
+	apiRouter.Get("/gift-cards/{code}", giftCardHandler.GetGiftCard)
+
 	// Register booking handlers
 	bookingHandler := handlers.NewBookingHandler(bookingService)
-	apiRouter.Post("/bookings", bookingHandler.CreateBooking)
+	idempotentRouter.Post("/bookings", bookingHandler.CreateBooking)
+
+	// Register staff booking handlers
+	staffRouter := apiRouter.With(middleware.StaffAuth(cfg.Staff.APIKeys))
+	staffRouter.Post("/staff/bookings/{id}/check-in", bookingHandler.CheckIn)
+	staffRouter.Post("/staff/bookings/{id}/no-show", bookingHandler.MarkNoShow)
+	staffRouter.Post("/staff/bookings/{id}/complete", bookingHandler.CompleteBooking)
+
+	// Register staff service handlers
+	staffRouter.Post("/staff/services", serviceHandler.CreateService)
+	staffRouter.Put("/staff/services/{id}", serviceHandler.UpdateService)
+
+	// Register staff campaign handlers
+	campaignHandler := handlers.NewCampaignHandler(campaignService)
+	staffRouter.Post("/staff/campaigns", campaignHandler.CreateCampaign)
+	staffRouter.Post("/staff/campaigns/{id}/end", campaignHandler.EndCampaign)
+
+	// Register staff timeline handlers
+	timelineHandler := handlers.NewTimelineHandler(timelineService)
+	staffRouter.Get("/staff/payments/{id}/timeline", timelineHandler.GetPaymentTimeline)
+	staffRouter.Get("/staff/bookings/{id}/timeline", timelineHandler.GetBookingTimeline)
+
+	// Register contact handlers
+	contactHandler := handlers.NewContactHandler(contactService)
+	apiRouter.Get("/contact/token", contactHandler.GetFormToken)
+	apiRouter.Post("/contact", contactHandler.SubmitMessage)
+
+	// Register customer login handlers
+	authHandler := handlers.NewAuthHandler(authService)
+	apiRouter.Post("/auth/login-code", authHandler.RequestLoginCode)
+	apiRouter.Post("/auth/verify", authHandler.VerifyLoginCode)
+	apiRouter.Post("/auth/logout", authHandler.Logout)
+
+	// Register customer account handlers
+	customerHandler := handlers.NewCustomerHandler(customerService)
+	meRouter := apiRouter.With(middleware.CustomerAuth(authService))
+	meRouter.Get("/me", customerHandler.GetProfile)
+	meRouter.Put("/me", customerHandler.UpdateProfile)
+	meRouter.Get("/me/bookings", customerHandler.GetBookings)
+
+	// Register GDPR data export handlers
+	dataExportHandler := handlers.NewDataExportHandler(dataExportService)
+	meRouter.Post("/me/data-export", dataExportHandler.CreateExport)
+	apiRouter.Get("/data-exports/{token}", dataExportHandler.DownloadExport)
+
+	// Register GDPR erasure handlers
+	erasureHandler := handlers.NewErasureHandler(erasureService)
+	meRouter.Delete("/me", erasureHandler.EraseAccount)
+
+	// Register consent handlers
+	consentHandler := handlers.NewConsentHandler(consentService)
+	apiRouter.Get("/consents/policy", consentHandler.GetPolicy)
+	meRouter.Get("/me/consents", consentHandler.GetConsents)
+	meRouter.Delete("/me/consents/{type}", consentHandler.WithdrawConsent)
 
 	// Start server with graceful shutdown
 	server := &http.Server{
@@ -114,12 +249,28 @@ func main() {
 		}
 	}()
 
+	// Start background jobs
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	go outboxService.Run(jobCtx, cfg.Outbox.Interval)
+	go dataExportService.Run(jobCtx, time.Hour)
+	go idempotencyService.Run(jobCtx, time.Hour)
+	go erasureService.Run(jobCtx, cfg.Retention.Interval)
+	go reconciliationService.Run(jobCtx, cfg.Reconciliation.Interval)
+
+	if cfg.Recovery.Enabled {
+		go recoveryService.Run(jobCtx, cfg.Recovery.Interval)
+		log.Info().Dur("interval", cfg.Recovery.Interval).Msg("Checkout recovery job started")
+	}
+
 	// Setup graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
 	log.Info().Msg("Shutting down server...")
+	stopJobs()
 
 	// Create context with timeout for shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownTimeoutSeconds)*time.Second)
@@ -0,0 +1,108 @@
+// Command audit-log prints the audit log of a payment, booking or customer:
+// every recorded change with the actor and request that made it.
+//
+// Usage:
+//
+//	audit-log -entity payment -id 42
+//	audit-log -entity booking -id 7 -json
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/svenskhalsovard/api/internal/config"
+	"github.com/svenskhalsovard/api/internal/repository"
+	"github.com/svenskhalsovard/api/internal/service"
+)
+
+func main() {
+	entityType := flag.String("entity", "", "entity type: payment, booking or customer")
+	entityID := flag.Int64("id", 0, "ID of the entity")
+	asJSON := flag.Bool("json", false, "print the entries as JSON")
+	flag.Parse()
+
+	if *entityType == "" || *entityID == 0 {
+		fmt.Fprintln(os.Stderr, "Specify -entity and -id")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Load environment variables
+	if err := godotenv.Load(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: .env file not found or cannot be read: %v\n", err)
+	}
+
+	setupLogger()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load configuration")
+	}
+
+	db, err := repository.NewDatabase(cfg.Database)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to database")
+	}
+	defer db.Close()
+
+	auditService := service.NewAuditService(repository.NewAuditRepository(db))
+
+	entries, err := auditService.GetHistory(context.Background(), *entityType, *entityID)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to get audit log")
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(entries); err != nil {
+			log.Fatal().Err(err).Msg("Failed to write audit log")
+		}
+		return
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "TIME\tACTION\tOLD\tNEW\tACTOR\tREQUEST")
+	for _, entry := range entries {
+		actor := entry.ActorType
+		if entry.ActorID != "" {
+			actor += ":" + entry.ActorID
+		}
+
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			entry.CreatedAt.Format(time.RFC3339),
+			entry.Action,
+			valuesOrDash(entry.OldValues),
+			valuesOrDash(entry.NewValues),
+			actor,
+			entry.RequestID,
+		)
+	}
+	writer.Flush()
+}
+
+// valuesOrDash returns audited values for printing, or a dash when there are none
+func valuesOrDash(values json.RawMessage) string {
+	if len(values) == 0 {
+		return "-"
+	}
+	return string(values)
+}
+
+func setupLogger() {
+	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+	// Log to stderr so the audit log is the only output on stdout
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339})
+}
@@ -0,0 +1,114 @@
+// Command gdpr-erase erases a customer on request under GDPR Article 17. The
+// customer record is pseudonymized at once; payments, bookings and other rows
+// that must be retained are pseudonymized when their retention ends, see the
+// RETENTION_YEARS_* settings.
+//
+// Usage:
+//
+//	gdpr-erase -email anna@example.com -confirm
+//	gdpr-erase -customer-id 42 -confirm
+//	gdpr-erase -pseudonymize-expired
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/svenskhalsovard/api/internal/audit"
+	"github.com/svenskhalsovard/api/internal/config"
+	"github.com/svenskhalsovard/api/internal/encryption"
+	"github.com/svenskhalsovard/api/internal/repository"
+	"github.com/svenskhalsovard/api/internal/service"
+)
+
+func main() {
+	email := flag.String("email", "", "email address of the customer")
+	customerID := flag.Int64("customer-id", 0, "ID of the customer")
+	confirm := flag.Bool("confirm", false, "confirm the erasure, which cannot be undone")
+	pseudonymizeExpired := flag.Bool("pseudonymize-expired", false, "pseudonymize retained rows whose retention has ended")
+	flag.Parse()
+
+	if !*pseudonymizeExpired && (*email == "") == (*customerID == 0) {
+		fmt.Fprintln(os.Stderr, "Specify either -email or -customer-id, or -pseudonymize-expired")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if !*pseudonymizeExpired && !*confirm {
+		fmt.Fprintln(os.Stderr, "Erasure cannot be undone; add -confirm to proceed")
+		os.Exit(2)
+	}
+
+	// Load environment variables
+	if err := godotenv.Load(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: .env file not found or cannot be read: %v\n", err)
+	}
+
+	setupLogger()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load configuration")
+	}
+
+	keyring, err := encryption.LoadKeyring(cfg.Encryption.KeyFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load encryption keys")
+	}
+
+	db, err := repository.NewDatabase(cfg.Database)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to database")
+	}
+	defer db.Close()
+
+	erasureService := service.NewErasureService(
+		repository.NewErasureRepository(db, keyring),
+		repository.NewCustomerRepository(db, keyring),
+		cfg.Retention.Years,
+	)
+
+	// Changes are made by the admin running the command
+	ctx := audit.WithActor(context.Background(), audit.CurrentAdmin())
+
+	if *pseudonymizeExpired {
+		pseudonymized, err := erasureService.PseudonymizeExpired(ctx)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to pseudonymize retained records")
+		}
+		fmt.Printf("Pseudonymized %d retained records\n", pseudonymized)
+		return
+	}
+
+	var result *service.ErasureResult
+	if *email != "" {
+		result, err = erasureService.EraseCustomerByEmail(ctx, *email)
+	} else {
+		result, err = erasureService.EraseCustomer(ctx, *customerID)
+	}
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to erase customer")
+	}
+
+	fmt.Printf("Erased customer %d\n", result.CustomerID)
+	fmt.Printf("Pseudonymized records: %d\n", result.Erased)
+	fmt.Printf("Retained records:      %d\n", result.Retained)
+	if result.Retained > 0 {
+		fmt.Printf("Retained until:        %s\n", result.RetainedUntil.Format("2006-01-02"))
+	}
+}
+
+func setupLogger() {
+	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+	// Log to stderr so the summary is the only output on stdout
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339})
+}
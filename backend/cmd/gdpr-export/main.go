@@ -0,0 +1,93 @@
+// Command gdpr-export creates an export of the personal data held about a
+// customer, for answering requests under GDPR Article 15. It prints a
+// time-limited download link for the archive, to be sent to the customer.
+//
+// Usage:
+//
+//	gdpr-export -email anna@example.com
+//	gdpr-export -customer-id 42
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/svenskhalsovard/api/internal/config"
+	"github.com/svenskhalsovard/api/internal/encryption"
+	"github.com/svenskhalsovard/api/internal/entity"
+	"github.com/svenskhalsovard/api/internal/repository"
+	"github.com/svenskhalsovard/api/internal/service"
+)
+
+func main() {
+	email := flag.String("email", "", "email address of the customer")
+	customerID := flag.Int64("customer-id", 0, "ID of the customer")
+	flag.Parse()
+
+	if (*email == "") == (*customerID == 0) {
+		fmt.Fprintln(os.Stderr, "Specify either -email or -customer-id")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Load environment variables
+	if err := godotenv.Load(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: .env file not found or cannot be read: %v\n", err)
+	}
+
+	setupLogger()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load configuration")
+	}
+
+	keyring, err := encryption.LoadKeyring(cfg.Encryption.KeyFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load encryption keys")
+	}
+
+	db, err := repository.NewDatabase(cfg.Database)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to database")
+	}
+	defer db.Close()
+
+	dataExportService := service.NewDataExportService(
+		repository.NewDataExportRepository(db, keyring),
+		repository.NewCustomerRepository(db, keyring),
+		keyring,
+		cfg.DataExport.DownloadURL,
+		cfg.DataExport.TTL,
+	)
+
+	ctx := context.Background()
+
+	var link *service.DataExportLink
+	if *email != "" {
+		link, err = dataExportService.CreateExportByEmail(ctx, *email, entity.DataExportRequestedByStaff)
+	} else {
+		link, err = dataExportService.CreateExport(ctx, *customerID, entity.DataExportRequestedByStaff)
+	}
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to create data export")
+	}
+
+	fmt.Printf("Download link: %s\n", link.URL)
+	fmt.Printf("Expires at:    %s\n", link.ExpiresAt.Local().Format(time.RFC1123))
+}
+
+func setupLogger() {
+	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+	// Log to stderr so the link is the only output on stdout
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339})
+}
@@ -0,0 +1,24 @@
+// Command keygen creates a key file with new random keys for encrypting
+// customer personal data, see keys/README.md. It refuses to overwrite an
+// existing key file.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/svenskhalsovard/api/internal/encryption"
+)
+
+func main() {
+	out := flag.String("out", "keys/development.json", "path of the key file to create")
+	flag.Parse()
+
+	if err := encryption.GenerateKeyFile(*out); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create key file: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Created key file %s\n", *out)
+}
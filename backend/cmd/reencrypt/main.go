@@ -0,0 +1,102 @@
+// Command reencrypt encrypts customer personal data stored before encryption
+// was enabled, and re-encrypts stored data under the active master key after
+// a key rotation. It is safe to run repeatedly and while the API is running.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/svenskhalsovard/api/internal/config"
+	"github.com/svenskhalsovard/api/internal/encryption"
+	"github.com/svenskhalsovard/api/internal/repository"
+)
+
+func main() {
+	batchSize := flag.Int("batch-size", 500, "number of rows to process per batch")
+	flag.Parse()
+
+	// Load environment variables
+	if err := godotenv.Load(); err != nil {
+		fmt.Printf("Warning: .env file not found or cannot be read: %v\n", err)
+	}
+
+	setupLogger()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load configuration")
+	}
+
+	keyring, err := encryption.LoadKeyring(cfg.Encryption.KeyFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load encryption keys")
+	}
+
+	db, err := repository.NewDatabase(cfg.Database)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to database")
+	}
+	defer db.Close()
+
+	repo := repository.NewReencryptionRepository(db, keyring)
+	ctx := context.Background()
+
+	log.Info().Int("activeKeyVersion", keyring.ActiveVersion()).Msg("Re-encrypting personal data")
+
+	customers, err := reencryptAll(func(afterID int64) (int64, int, error) {
+		return repo.ReencryptCustomers(ctx, afterID, *batchSize)
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to re-encrypt customers")
+	}
+	log.Info().Int("updated", customers).Msg("Customers re-encrypted")
+
+	for _, table := range []string{"payments", "bookings"} {
+		updated, err := reencryptAll(func(afterID int64) (int64, int, error) {
+			return repo.ReencryptSnapshots(ctx, table, afterID, *batchSize)
+		})
+		if err != nil {
+			log.Fatal().Err(err).Str("table", table).Msg("Failed to re-encrypt customer snapshots")
+		}
+		log.Info().Int("updated", updated).Str("table", table).Msg("Customer snapshots re-encrypted")
+	}
+
+	log.Info().Msg("Re-encryption completed")
+}
+
+// reencryptAll runs batch until it reports there are no more rows and returns
+// the total number of rows updated
+func reencryptAll(batch func(afterID int64) (int64, int, error)) (int, error) {
+	total := 0
+	afterID := int64(0)
+
+	for {
+		lastID, updated, err := batch(afterID)
+		if err != nil {
+			return total, err
+		}
+		if lastID == 0 {
+			return total, nil
+		}
+
+		total += updated
+		afterID = lastID
+	}
+}
+
+func setupLogger() {
+	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+	if os.Getenv("APP_ENV") != "production" {
+		log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: time.RFC3339})
+	}
+}
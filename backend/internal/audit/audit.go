@@ -0,0 +1,71 @@
+package audit
+
+import (
+	"context"
+	"os/user"
+	"strconv"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Actor identifies who or what made a change. ID is the customer ID or the name
+// of the admin user, and is empty for the system and anonymous customers.
+type Actor struct {
+	Type string
+	ID   string
+}
+
+// ActorType represents the kinds of actors that make changes
+const (
+	ActorSystem   = "system"   // background jobs
+	ActorWebhook  = "webhook"  // callbacks from the payment provider
+	ActorAdmin    = "admin"    // staff using the command line tools or staff endpoints
+	ActorCustomer = "customer" // requests from the shop
+)
+
+// actorContextKey is the context key of the actor making a change
+type actorContextKey struct{}
+
+// WithActor returns a copy of ctx carrying the actor making changes
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorContextKey{}, actor)
+}
+
+// ActorFromContext returns the actor making changes. Changes made outside a
+// request, such as by background jobs, are made by the system.
+func ActorFromContext(ctx context.Context) Actor {
+	if actor, ok := ctx.Value(actorContextKey{}).(Actor); ok {
+		return actor
+	}
+	return Actor{Type: ActorSystem}
+}
+
+// Customer returns the actor for a customer. An ID of 0 is an anonymous
+// customer.
+func Customer(customerID int64) Actor {
+	actor := Actor{Type: ActorCustomer}
+	if customerID > 0 {
+		actor.ID = strconv.FormatInt(customerID, 10)
+	}
+	return actor
+}
+
+// Admin returns the actor for a named member of staff
+func Admin(name string) Actor {
+	return Actor{Type: ActorAdmin, ID: name}
+}
+
+// CurrentAdmin returns the actor for the operating system user running a
+// command line tool
+func CurrentAdmin() Actor {
+	if current, err := user.Current(); err == nil {
+		return Admin(current.Username)
+	}
+	return Admin("")
+}
+
+// RequestID returns the ID of the request making changes, or an empty string
+// outside a request
+func RequestID(ctx context.Context) string {
+	return middleware.GetReqID(ctx)
+}
@@ -3,11 +3,15 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 )
 
+// developmentKeyFile is the name of the key file used for local development
+const developmentKeyFile = "development.json"
+
 // Config holds the application configuration
 type Config struct {
 	Env      string
@@ -16,6 +20,21 @@ type Config struct {
 	CORS     CORSConfig
 	Svea     SveaConfig
 	RateLimit RateLimitConfig
+	Catalog   CatalogConfig
+	Cart      CartConfig
+	Checkout  CheckoutConfig
+	Mail      MailConfig
+	Recovery  RecoveryConfig
+	Contact   ContactConfig
+	Outbox    OutboxConfig
+	Auth      AuthConfig
+	Encryption EncryptionConfig
+	DataExport DataExportConfig
+	Retention  RetentionConfig
+	Consent    ConsentConfig
+	Staff      StaffConfig
+	Idempotency IdempotencyConfig
+	Reconciliation ReconciliationConfig
 }
 
 // ServerConfig holds the HTTP server configuration
@@ -62,6 +81,115 @@ type RateLimitConfig struct {
 	Enabled           bool
 }
 
+// CatalogConfig holds service catalog configuration
+type CatalogConfig struct {
+	CacheTTL time.Duration
+}
+
+// CartConfig holds server-side cart configuration
+type CartConfig struct {
+	TTL time.Duration
+}
+
+// CheckoutConfig holds checkout configuration
+type CheckoutConfig struct {
+	QuoteTTL time.Duration
+}
+
+// MailConfig holds outgoing email (SMTP) configuration
+type MailConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	FromName string
+}
+
+// RecoveryConfig holds abandoned checkout recovery configuration. Checkouts are
+// reminded about once they are MinAge old, unless they are older than MaxAge.
+type RecoveryConfig struct {
+	Enabled   bool
+	Interval  time.Duration
+	MinAge    time.Duration
+	MaxAge    time.Duration
+	ResumeURL string
+	OptOutURL string
+}
+
+// ContactConfig holds contact form configuration
+type ContactConfig struct {
+	StaffEmail      string
+	FormSecret      string
+	MinSubmitTime   time.Duration
+	MaxPerIPPerHour int
+}
+
+// OutboxConfig holds email outbox delivery configuration
+type OutboxConfig struct {
+	Interval time.Duration
+}
+
+// StaffConfig holds the configuration of the staff endpoints. APIKeys maps each
+// API key to the name of the member of staff using it.
+type StaffConfig struct {
+	APIKeys map[string]string
+}
+
+// IdempotencyConfig holds the configuration of idempotent requests. Responses
+// are replayed for retries for TTL after the first request. A key whose
+// request has not completed within ClaimTimeout, such as after a crash, can be
+// claimed by a retry.
+type IdempotencyConfig struct {
+	TTL          time.Duration
+	ClaimTimeout time.Duration
+}
+
+// ReconciliationConfig holds the configuration of payment reconciliation.
+// Payments are reconciled once they have not changed for MinAge, unless they
+// last changed more than MaxAge ago, in which case unpaid payments are cancelled.
+type ReconciliationConfig struct {
+	Interval time.Duration
+	MinAge   time.Duration
+	MaxAge   time.Duration
+}
+
+// EncryptionConfig holds the configuration for encrypting personal data at rest.
+// KeyFile is the path to the JSON file with the versioned master keys.
+type EncryptionConfig struct {
+	KeyFile string
+}
+
+// DataExportConfig holds GDPR data export configuration. Download links are
+// DownloadURL followed by a token and are valid for TTL.
+type DataExportConfig struct {
+	DownloadURL string
+	TTL         time.Duration
+}
+
+// RetentionConfig holds the retention rules applied when a customer is erased.
+// Years maps a table to how many years its rows are kept after the end of the
+// calendar year they were created, for example for bookkeeping; rows past that
+// are pseudonymized at once. Retained rows are checked every Interval.
+type RetentionConfig struct {
+	Years    map[string]int
+	Interval time.Duration
+}
+
+// ConsentConfig holds the versions of the terms customers accept at checkout
+// and of the privacy policy other consents are given under
+type ConsentConfig struct {
+	TermsVersion         string
+	PrivacyPolicyVersion string
+}
+
+// AuthConfig holds customer login configuration
+type AuthConfig struct {
+	LoginCodeTTL      time.Duration
+	SessionTTL        time.Duration
+	LoginCodesPerHour int
+}
+
 // Load reads configuration from environment variables
 func Load() (*Config, error) {
 	config := &Config{
@@ -84,8 +212,8 @@ func Load() (*Config, error) {
 		},
 		CORS: CORSConfig{
 			AllowedOrigins: getEnvAsSlice("CORS_ALLOWED_ORIGINS", []string{"*"}),
-			AllowedMethods: getEnvAsSlice("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
-			AllowedHeaders: getEnvAsSlice("CORS_ALLOWED_HEADERS", []string{"Origin", "Content-Type", "Accept", "Authorization"}),
+			AllowedMethods: getEnvAsSlice("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}),
+			AllowedHeaders: getEnvAsSlice("CORS_ALLOWED_HEADERS", []string{"Origin", "Content-Type", "Accept", "Authorization", "Idempotency-Key"}),
 			MaxAge:         getEnvAsInt("CORS_MAX_AGE", 86400),
 		},
 		Svea: SveaConfig{
@@ -100,6 +228,79 @@ func Load() (*Config, error) {
 			BurstSize:         getEnvAsInt("RATE_LIMIT_BURST_SIZE", 10),
 			Enabled:           getEnvAsBool("RATE_LIMIT_ENABLED", true),
 		},
+		Catalog: CatalogConfig{
+			CacheTTL: time.Duration(getEnvAsInt("CATALOG_CACHE_TTL", 300)) * time.Second,
+		},
+		Cart: CartConfig{
+			TTL: time.Duration(getEnvAsInt("CART_TTL_DAYS", 30)) * 24 * time.Hour,
+		},
+		Checkout: CheckoutConfig{
+			QuoteTTL: time.Duration(getEnvAsInt("CHECKOUT_QUOTE_TTL_MINUTES", 30)) * time.Minute,
+		},
+		Mail: MailConfig{
+			Host:     getEnv("SMTP_HOST", "localhost"),
+			Port:     getEnvAsInt("SMTP_PORT", 587),
+			Username: getEnv("SMTP_USERNAME", ""),
+			Password: getEnv("SMTP_PASSWORD", ""),
+			From:     getEnv("MAIL_FROM", "noreply@svenskhalsovard.se"),
+			FromName: getEnv("MAIL_FROM_NAME", "Svensk Hälsovård"),
+		},
+		Recovery: RecoveryConfig{
+			Enabled:   getEnvAsBool("CHECKOUT_RECOVERY_ENABLED", false),
+			Interval:  time.Duration(getEnvAsInt("CHECKOUT_RECOVERY_INTERVAL_MINUTES", 15)) * time.Minute,
+			MinAge:    time.Duration(getEnvAsInt("CHECKOUT_RECOVERY_MIN_AGE_MINUTES", 60)) * time.Minute,
+			MaxAge:    time.Duration(getEnvAsInt("CHECKOUT_RECOVERY_MAX_AGE_HOURS", 72)) * time.Hour,
+			ResumeURL: getEnv("CHECKOUT_RECOVERY_RESUME_URL", "http://localhost:3000/checkout/resume"),
+			OptOutURL: getEnv("CHECKOUT_RECOVERY_OPT_OUT_URL", "http://localhost:3000/checkout/opt-out"),
+		},
+		Contact: ContactConfig{
+			StaffEmail:      getEnv("CONTACT_STAFF_EMAIL", "info@svenskhalsovard.se"),
+			FormSecret:      getEnv("CONTACT_FORM_SECRET", ""),
+			MinSubmitTime:   time.Duration(getEnvAsInt("CONTACT_MIN_SUBMIT_SECONDS", 3)) * time.Second,
+			MaxPerIPPerHour: getEnvAsInt("CONTACT_MAX_PER_IP_PER_HOUR", 5),
+		},
+		Outbox: OutboxConfig{
+			Interval: time.Duration(getEnvAsInt("OUTBOX_INTERVAL_SECONDS", 30)) * time.Second,
+		},
+		Auth: AuthConfig{
+			LoginCodeTTL:      time.Duration(getEnvAsInt("LOGIN_CODE_TTL_MINUTES", 10)) * time.Minute,
+			SessionTTL:        time.Duration(getEnvAsInt("SESSION_TTL_DAYS", 30)) * 24 * time.Hour,
+			LoginCodesPerHour: getEnvAsInt("LOGIN_CODES_PER_HOUR", 5),
+		},
+		Encryption: EncryptionConfig{
+			KeyFile: getEnv("PII_KEY_FILE", ""),
+		},
+		DataExport: DataExportConfig{
+			DownloadURL: getEnv("DATA_EXPORT_DOWNLOAD_URL", "http://localhost:8080/api/data-exports"),
+			TTL:         time.Duration(getEnvAsInt("DATA_EXPORT_TTL_HOURS", 72)) * time.Hour,
+		},
+		Retention: RetentionConfig{
+			Years: map[string]int{
+				"payments":            getEnvAsInt("RETENTION_YEARS_PAYMENTS", 7),
+				"bookings":            getEnvAsInt("RETENTION_YEARS_BOOKINGS", 7),
+				"contact_messages":    getEnvAsInt("RETENTION_YEARS_CONTACT_MESSAGES", 0),
+				"email_outbox":        getEnvAsInt("RETENTION_YEARS_EMAIL_OUTBOX", 0),
+				"checkout_recoveries": getEnvAsInt("RETENTION_YEARS_CHECKOUT_RECOVERIES", 0),
+				"customer_consents":   getEnvAsInt("RETENTION_YEARS_CUSTOMER_CONSENTS", 0),
+			},
+			Interval: time.Duration(getEnvAsInt("RETENTION_INTERVAL_HOURS", 24)) * time.Hour,
+		},
+		Consent: ConsentConfig{
+			TermsVersion:         getEnv("CONSENT_TERMS_VERSION", "2024-01-01"),
+			PrivacyPolicyVersion: getEnv("CONSENT_PRIVACY_POLICY_VERSION", "2024-01-01"),
+		},
+		Staff: StaffConfig{
+			APIKeys: parseStaffAPIKeys(getEnvAsSlice("STAFF_API_KEYS", nil)),
+		},
+		Idempotency: IdempotencyConfig{
+			TTL:          time.Duration(getEnvAsInt("IDEMPOTENCY_KEY_TTL_HOURS", 24)) * time.Hour,
+			ClaimTimeout: time.Duration(getEnvAsInt("IDEMPOTENCY_CLAIM_TIMEOUT_SECONDS", 120)) * time.Second,
+		},
+		Reconciliation: ReconciliationConfig{
+			Interval: time.Duration(getEnvAsInt("PAYMENT_RECONCILIATION_INTERVAL_MINUTES", 5)) * time.Minute,
+			MinAge:   time.Duration(getEnvAsInt("PAYMENT_RECONCILIATION_MIN_AGE_MINUTES", 10)) * time.Minute,
+			MaxAge:   time.Duration(getEnvAsInt("PAYMENT_RECONCILIATION_MAX_AGE_HOURS", 72)) * time.Hour,
+		},
 	}
 
 	// Validate required configuration
@@ -111,6 +312,16 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("SVEA_SECRET is required")
 	}
 
+	if config.Encryption.KeyFile == "" {
+		return nil, fmt.Errorf("PII_KEY_FILE is required")
+	}
+
+	// The development key file is generated for local use and must never
+	// protect production data
+	if config.Env == "production" && filepath.Base(config.Encryption.KeyFile) == developmentKeyFile {
+		return nil, fmt.Errorf("PII_KEY_FILE must not be %s in production", developmentKeyFile)
+	}
+
 	return config, nil
 }
 
@@ -176,4 +387,18 @@ func getEnvAsSlice(key string, defaultValue []string) []string {
 		return strings.Split(valueStr, ",")
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+// parseStaffAPIKeys parses name:key pairs into a map from key to name. Pairs
+// without a name or key are ignored.
+func parseStaffAPIKeys(pairs []string) map[string]string {
+	keys := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		name, key, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || name == "" || key == "" {
+			continue
+		}
+		keys[key] = name
+	}
+	return keys
+}
@@ -0,0 +1,22 @@
+package dto
+
+import "time"
+
+// LoginCodeRequest represents a request for a one-time login code
+type LoginCodeRequest struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+// VerifyLoginCodeRequest represents a login with a one-time code
+type VerifyLoginCodeRequest struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+	Code  string `json:"code" validate:"required,numeric,len=6"`
+}
+
+// LoginResponse represents a successful login. Token is sent as a bearer token
+// in the Authorization header of later requests.
+type LoginResponse struct {
+	Token     string           `json:"token"`
+	ExpiresAt time.Time        `json:"expiresAt"`
+	Customer  CustomerResponse `json:"customer"`
+}
@@ -0,0 +1,63 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// CreateCampaignRequest represents a staff request to start a price campaign.
+// The campaign applies to every service when AppliesToAll is set, otherwise to
+// the services in ServiceIDs.
+type CreateCampaignRequest struct {
+	Name          string     `json:"name" validate:"required,max=255"`
+	DiscountType  string     `json:"discountType" validate:"required,oneof=percentage fixed_amount"`
+	DiscountValue float64    `json:"discountValue" validate:"gt=0"`
+	StartsAt      time.Time  `json:"startsAt" validate:"required"`
+	EndsAt        *time.Time `json:"endsAt,omitempty"`
+	AppliesToAll  bool       `json:"appliesToAll"`
+	ServiceIDs    []int64    `json:"serviceIds,omitempty"`
+}
+
+// CampaignResponse represents a price campaign in the API response
+type CampaignResponse struct {
+	ID            int64      `json:"id"`
+	Name          string     `json:"name"`
+	DiscountType  string     `json:"discountType"`
+	DiscountValue float64    `json:"discountValue"`
+	StartsAt      time.Time  `json:"startsAt"`
+	EndsAt        *time.Time `json:"endsAt,omitempty"`
+	AppliesToAll  bool       `json:"appliesToAll"`
+	ServiceIDs    []int64    `json:"serviceIds,omitempty"`
+}
+
+// MapCreateCampaignRequestToEntity maps a CreateCampaignRequest to an entity.PriceCampaign
+func MapCreateCampaignRequestToEntity(req CreateCampaignRequest) entity.PriceCampaign {
+	campaign := entity.PriceCampaign{
+		Name:          req.Name,
+		DiscountType:  req.DiscountType,
+		DiscountValue: req.DiscountValue,
+		StartsAt:      req.StartsAt.UTC(),
+		AppliesToAll:  req.AppliesToAll,
+		ServiceIDs:    req.ServiceIDs,
+	}
+	if req.EndsAt != nil {
+		endsAt := req.EndsAt.UTC()
+		campaign.EndsAt = &endsAt
+	}
+	return campaign
+}
+
+// MapCampaignToResponse maps an entity.PriceCampaign to a CampaignResponse
+func MapCampaignToResponse(campaign entity.PriceCampaign) CampaignResponse {
+	return CampaignResponse{
+		ID:            campaign.ID,
+		Name:          campaign.Name,
+		DiscountType:  campaign.DiscountType,
+		DiscountValue: campaign.DiscountValue,
+		StartsAt:      campaign.StartsAt,
+		EndsAt:        campaign.EndsAt,
+		AppliesToAll:  campaign.AppliesToAll,
+		ServiceIDs:    campaign.ServiceIDs,
+	}
+}
@@ -0,0 +1,79 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// CartItemRequest represents an item in a cart request. In an update a quantity
+// of zero removes the item.
+type CartItemRequest struct {
+	ServiceID    int64  `json:"serviceId" validate:"required,min=1"`
+	Quantity     int    `json:"quantity" validate:"min=0,max=10"`
+	PurchaseType string `json:"purchaseType" validate:"required,oneof=one-time subscription"`
+}
+
+// CartRequest represents a request to set or update the items of a cart
+type CartRequest struct {
+	Items []CartItemRequest `json:"items" validate:"dive"`
+}
+
+// CartItemResponse represents an item in a cart in the API response
+type CartItemResponse struct {
+	ServiceID    int64   `json:"serviceId"`
+	ServiceName  string  `json:"serviceName"`
+	Quantity     int     `json:"quantity"`
+	PurchaseType string  `json:"purchaseType"`
+	ListPrice    float64 `json:"listPrice"`
+	UnitPrice    float64 `json:"unitPrice"`
+	TotalPrice   float64 `json:"totalPrice"`
+}
+
+// CartResponse represents a cart in the API response
+type CartResponse struct {
+	Token       string             `json:"token"`
+	Status      string             `json:"status"`
+	Items       []CartItemResponse `json:"items"`
+	TotalAmount float64            `json:"totalAmount"`
+	ExpiresAt   time.Time          `json:"expiresAt"`
+	UpdatedAt   time.Time          `json:"updatedAt"`
+}
+
+// MapCartRequestToItems maps a CartRequest to cart items
+func MapCartRequestToItems(req CartRequest) []entity.CartItem {
+	items := make([]entity.CartItem, 0, len(req.Items))
+	for _, item := range req.Items {
+		items = append(items, entity.CartItem{
+			ServiceID:    item.ServiceID,
+			Quantity:     item.Quantity,
+			PurchaseType: item.PurchaseType,
+		})
+	}
+	return items
+}
+
+// MapCartToResponse maps an entity.CartWithItems to a CartResponse
+func MapCartToResponse(cart entity.CartWithItems) CartResponse {
+	items := make([]CartItemResponse, 0, len(cart.Items))
+	for _, item := range cart.Items {
+		items = append(items, CartItemResponse{
+			ServiceID:    item.ServiceID,
+			ServiceName:  item.ServiceName,
+			Quantity:     item.Quantity,
+			PurchaseType: item.PurchaseType,
+			ListPrice:    item.ListPrice,
+			UnitPrice:    item.UnitPrice,
+			TotalPrice:   item.TotalPrice,
+		})
+	}
+
+	return CartResponse{
+		Token:       cart.Cart.Token,
+		Status:      cart.Cart.Status,
+		Items:       items,
+		TotalAmount: cart.TotalAmount,
+		ExpiresAt:   cart.Cart.ExpiresAt,
+		UpdatedAt:   cart.Cart.UpdatedAt,
+	}
+}
@@ -0,0 +1,68 @@
+package dto
+
+import "github.com/svenskhalsovard/api/internal/entity"
+
+// CategoryResponse represents a service category in the API response
+type CategoryResponse struct {
+	ID          int64              `json:"id"`
+	ParentID    *int64             `json:"parentId,omitempty"`
+	Slug        string             `json:"slug"`
+	Name        string             `json:"name"`
+	Description string             `json:"description,omitempty"`
+	Children    []CategoryResponse `json:"children,omitempty"`
+}
+
+// CategoryTreeResponse represents the category tree in the API response
+type CategoryTreeResponse struct {
+	Categories []CategoryResponse `json:"categories"`
+}
+
+// CategoryServicesResponse represents a category with a page of its services in the API response
+type CategoryServicesResponse struct {
+	Category   CategoryResponse    `json:"category"`
+	Services   []ServiceResponse   `json:"services"`
+	Count      int                 `json:"count"`
+	Pagination *PaginationResponse `json:"pagination,omitempty"`
+}
+
+// MapCategoryToResponse maps an entity.ServiceCategory to a CategoryResponse
+func MapCategoryToResponse(category entity.ServiceCategory) CategoryResponse {
+	return CategoryResponse{
+		ID:          category.ID,
+		ParentID:    category.ParentID,
+		Slug:        category.Slug,
+		Name:        category.Name,
+		Description: category.Description,
+	}
+}
+
+// MapCategoryTreeToResponse maps a category tree to a CategoryTreeResponse
+func MapCategoryTreeToResponse(nodes []entity.ServiceCategoryNode) CategoryTreeResponse {
+	return CategoryTreeResponse{
+		Categories: mapCategoryNodes(nodes),
+	}
+}
+
+// MapCategoryServicesToResponse maps a category and a page of its services to a CategoryServicesResponse
+func MapCategoryServicesToResponse(category entity.ServiceCategory, page entity.ServicePage) CategoryServicesResponse {
+	services := MapServicePageToResponse(page)
+	return CategoryServicesResponse{
+		Category:   MapCategoryToResponse(category),
+		Services:   services.Services,
+		Count:      services.Count,
+		Pagination: services.Pagination,
+	}
+}
+
+// mapCategoryNodes recursively maps category tree nodes to responses
+func mapCategoryNodes(nodes []entity.ServiceCategoryNode) []CategoryResponse {
+	responses := make([]CategoryResponse, 0, len(nodes))
+	for _, node := range nodes {
+		response := MapCategoryToResponse(node.Category)
+		if len(node.Children) > 0 {
+			response.Children = mapCategoryNodes(node.Children)
+		}
+		responses = append(responses, response)
+	}
+	return responses
+}
@@ -5,7 +5,8 @@ import (
 	"github.com/svenskhalsovard/api/internal/svea"
 )
 
-// CustomerRequest represents a customer in the API request
+// CustomerRequest represents a customer in the API request. NationalID is the
+// personnummer or samordningsnummer, which invoice payments require.
 type CustomerRequest struct {
 	FirstName      string `json:"firstName" validate:"required"`
 	LastName       string `json:"lastName" validate:"required"`
@@ -15,21 +16,42 @@ type CustomerRequest struct {
 	PostalCode     string `json:"postalCode" validate:"required"`
 	City           string `json:"city" validate:"required"`
 	AdditionalInfo string `json:"additionalInfo"`
+	NationalID     string `json:"nationalId" validate:"omitempty,max=13"`
 }
 
-// CheckoutItemRequest represents an item in a checkout request
+// CheckoutItemRequest represents an item in a quote request. Prices are not
+// accepted from the client.
 type CheckoutItemRequest struct {
-	ServiceID    int64   `json:"serviceId" validate:"required,min=1"`
-	Quantity     int     `json:"quantity" validate:"required,min=1,max=10"`
-	PurchaseType string  `json:"purchaseType" validate:"required,oneof=one-time subscription"`
-	Price        float64 `json:"price" validate:"required,min=0"`
+	ServiceID    int64  `json:"serviceId" validate:"required,min=1"`
+	Quantity     int    `json:"quantity" validate:"required,min=1,max=10"`
+	PurchaseType string `json:"purchaseType" validate:"required,oneof=one-time subscription"`
 }
 
-// CheckoutRequest represents a checkout request
+// QuoteRequest represents a request to price an order. Items may be left out
+// when pricing a server-side cart by CartToken.
+type QuoteRequest struct {
+	Items        []CheckoutItemRequest `json:"items" validate:"required_without=CartToken,dive"`
+	PromoCode    string                `json:"promoCode" validate:"omitempty,max=50"`
+	GiftCardCode string                `json:"giftCardCode" validate:"omitempty,max=50"`
+	CartToken    string                `json:"cartToken" validate:"omitempty,uuid"`
+}
+
+// CheckoutConsentsRequest represents the consents given at checkout.
+// TermsVersion is the version of the terms the customer accepted; the other
+// options are opt-ins the customer ticked.
+type CheckoutConsentsRequest struct {
+	TermsVersion   string `json:"termsVersion" validate:"required,max=50"`
+	MarketingEmail bool   `json:"marketingEmail"`
+	SMSReminders   bool   `json:"smsReminders"`
+}
+
+// CheckoutRequest represents a checkout request confirming a quote.
+// RecoveryConsent opts in to a reminder email if the checkout is not finished.
 type CheckoutRequest struct {
-	Customer    CustomerRequest      `json:"customer" validate:"required"`
-	Items       []CheckoutItemRequest `json:"items" validate:"required,min=1,dive"`
-	TotalAmount float64              `json:"totalAmount" validate:"required,min=0"`
+	Customer        CustomerRequest         `json:"customer" validate:"required"`
+	QuoteID         string                  `json:"quoteId" validate:"required,uuid"`
+	RecoveryConsent bool                    `json:"recoveryConsent"`
+	Consents        CheckoutConsentsRequest `json:"consents"`
 }
 
 // PaymentRequest represents a payment request
@@ -50,6 +72,40 @@ type CheckoutResponse struct {
 	CheckoutUI     CheckoutUIResponse `json:"checkoutUI"`
 	Status         string             `json:"status"`
 	OrderReference string             `json:"orderReference"`
+	DiscountAmount float64            `json:"discountAmount"`
+	GiftCardAmount float64            `json:"giftCardAmount"`
+	TotalAmount    float64            `json:"totalAmount"`
+	Booking        *ProcessPaymentResponse `json:"booking,omitempty"`
+}
+
+// QuoteResponse represents a server-priced order. TotalAmount is the amount left
+// to pay after the discount and gift card; all prices include VAT.
+type QuoteResponse struct {
+	QuoteID        string              `json:"quoteId"`
+	Items          []QuoteItemResponse `json:"items"`
+	PromoCode      *string             `json:"promoCode,omitempty"`
+	GiftCardCode   *string             `json:"giftCardCode,omitempty"`
+	Subtotal       float64             `json:"subtotal"`
+	DiscountAmount float64             `json:"discountAmount"`
+	GiftCardAmount float64             `json:"giftCardAmount"`
+	VatAmount      float64             `json:"vatAmount"`
+	TotalAmount    float64             `json:"totalAmount"`
+	Currency       string              `json:"currency"`
+	ExpiresAt      string              `json:"expiresAt"`
+}
+
+// QuoteItemResponse represents a priced line of a quote
+type QuoteItemResponse struct {
+	ServiceID      int64   `json:"serviceId"`
+	ServiceName    string  `json:"serviceName"`
+	Quantity       int     `json:"quantity"`
+	PurchaseType   string  `json:"purchaseType"`
+	ListPrice      float64 `json:"listPrice"`
+	UnitPrice      float64 `json:"unitPrice"`
+	TotalPrice     float64 `json:"totalPrice"`
+	DiscountAmount float64 `json:"discountAmount"`
+	VatPercent     int     `json:"vatPercent"`
+	VatAmount      float64 `json:"vatAmount"`
 }
 
 // CheckoutUIResponse represents the UI data for checkout
@@ -70,6 +126,16 @@ type PaymentResponse struct {
 	CreatedAt      string  `json:"createdAt"`
 }
 
+// ProcessPaymentResponse represents the result of a processed payment. The
+// status is "booking_pending" when the payment was taken but its booking is
+// still to be created. GiftCards holds the gift cards bought with the payment.
+type ProcessPaymentResponse struct {
+	Status        string             `json:"status"`
+	BookingID     int64              `json:"bookingId,omitempty"`
+	BookingNumber string             `json:"bookingNumber,omitempty"`
+	GiftCards     []GiftCardResponse `json:"giftCards,omitempty"`
+}
+
 // MapCustomerRequestToEntity maps a CustomerRequest to an entity.Customer
 func MapCustomerRequestToEntity(req CustomerRequest) entity.Customer {
 	return entity.Customer{
@@ -84,8 +150,8 @@ func MapCustomerRequestToEntity(req CustomerRequest) entity.Customer {
 	}
 }
 
-// MapCheckoutRequestToServiceItems converts a checkout request to service items for validation
-func MapCheckoutRequestToServiceItems(req CheckoutRequest) []struct {
+// MapQuoteRequestToServiceItems converts a quote request to service items for validation
+func MapQuoteRequestToServiceItems(req QuoteRequest) []struct {
 	ServiceID int64
 	Quantity  int
 } {
@@ -107,6 +173,39 @@ func MapCheckoutRequestToServiceItems(req CheckoutRequest) []struct {
 	return items
 }
 
+// MapQuoteToResponse maps an entity.CheckoutQuote to a QuoteResponse
+func MapQuoteToResponse(quote entity.CheckoutQuote) QuoteResponse {
+	items := make([]QuoteItemResponse, 0, len(quote.Items))
+	for _, item := range quote.Items {
+		items = append(items, QuoteItemResponse{
+			ServiceID:      item.ServiceID,
+			ServiceName:    item.ServiceName,
+			Quantity:       item.Quantity,
+			PurchaseType:   item.PurchaseType,
+			ListPrice:      item.ListPrice,
+			UnitPrice:      item.UnitPrice,
+			TotalPrice:     item.TotalPrice,
+			DiscountAmount: item.DiscountAmount,
+			VatPercent:     item.VatPercent,
+			VatAmount:      item.VatAmount,
+		})
+	}
+
+	return QuoteResponse{
+		QuoteID:        quote.ID,
+		Items:          items,
+		PromoCode:      quote.PromoCode,
+		GiftCardCode:   quote.GiftCardCode,
+		Subtotal:       quote.Subtotal,
+		DiscountAmount: quote.DiscountAmount,
+		GiftCardAmount: quote.GiftCardAmount,
+		VatAmount:      quote.VatAmount,
+		TotalAmount:    quote.TotalAmount,
+		Currency:       quote.Currency,
+		ExpiresAt:      quote.ExpiresAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
 // MapSveaCheckoutToResponse maps a Svea checkout response to a CheckoutResponse
 func MapSveaCheckoutToResponse(paymentID int64, orderRef string, sveaResponse svea.OrderResponse) CheckoutResponse {
 	return CheckoutResponse{
@@ -133,4 +232,20 @@ func MapPaymentToResponse(payment entity.Payment) PaymentResponse {
 		OrderReference: payment.OrderReference,
 		CreatedAt:      payment.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
 	}
+}
+
+// MapPaymentResultToResponse maps the status of a processed payment, its
+// booking, if created, and the gift cards it bought to a ProcessPaymentResponse
+func MapPaymentResultToResponse(status string, booking *entity.Booking, giftCards []entity.GiftCard) ProcessPaymentResponse {
+	response := ProcessPaymentResponse{
+		Status: status,
+	}
+	if booking != nil {
+		response.BookingID = booking.ID
+		response.BookingNumber = booking.BookingNumber
+	}
+	for _, giftCard := range giftCards {
+		response.GiftCards = append(response.GiftCards, MapGiftCardToResponse(giftCard))
+	}
+	return response
 }
\ No newline at end of file
@@ -0,0 +1,38 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// ConsentResponse represents the current state of a customer's consent
+type ConsentResponse struct {
+	Type          string    `json:"type"`
+	Granted       bool      `json:"granted"`
+	PolicyVersion string    `json:"policyVersion"`
+	Source        string    `json:"source"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// ConsentPolicyResponse represents the versions of the terms and privacy policy
+// consents are currently given under
+type ConsentPolicyResponse struct {
+	TermsVersion         string `json:"termsVersion"`
+	PrivacyPolicyVersion string `json:"privacyPolicyVersion"`
+}
+
+// MapConsentsToResponse maps consents to ConsentResponses
+func MapConsentsToResponse(consents []entity.CustomerConsent) []ConsentResponse {
+	response := make([]ConsentResponse, 0, len(consents))
+	for _, consent := range consents {
+		response = append(response, ConsentResponse{
+			Type:          consent.ConsentType,
+			Granted:       consent.Granted,
+			PolicyVersion: consent.PolicyVersion,
+			Source:        consent.Source,
+			UpdatedAt:     consent.CreatedAt,
+		})
+	}
+	return response
+}
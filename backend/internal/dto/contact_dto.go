@@ -0,0 +1,18 @@
+package dto
+
+// ContactRequest represents a contact form submission. Website is a honeypot
+// field hidden from people; FormToken is issued by GET /api/contact/token.
+type ContactRequest struct {
+	Name      string `json:"name" validate:"required,max=255"`
+	Email     string `json:"email" validate:"required,email,max=255"`
+	Subject   string `json:"subject" validate:"required,max=255"`
+	Message   string `json:"message" validate:"required,max=5000"`
+	Consent   bool   `json:"consent" validate:"required"`
+	Website   string `json:"website"`
+	FormToken string `json:"formToken" validate:"required"`
+}
+
+// ContactFormTokenResponse represents the token to send with a contact form
+type ContactFormTokenResponse struct {
+	FormToken string `json:"formToken"`
+}
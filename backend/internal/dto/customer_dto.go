@@ -0,0 +1,129 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// CustomerResponse represents a customer profile in the API response.
+// NationalID is masked.
+type CustomerResponse struct {
+	ID              int64      `json:"id"`
+	FirstName       string     `json:"firstName"`
+	LastName        string     `json:"lastName"`
+	Email           string     `json:"email"`
+	Phone           string     `json:"phone"`
+	StreetAddress   string     `json:"streetAddress"`
+	PostalCode      string     `json:"postalCode"`
+	City            string     `json:"city"`
+	AdditionalInfo  string     `json:"additionalInfo"`
+	NationalID      string     `json:"nationalId,omitempty"`
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt,omitempty"`
+}
+
+// UpdateProfileRequest represents a change to a customer's own profile. The
+// email address is the login and cannot be changed. A stored NationalID is kept
+// when NationalID is left empty.
+type UpdateProfileRequest struct {
+	FirstName      string `json:"firstName" validate:"required,max=100"`
+	LastName       string `json:"lastName" validate:"required,max=100"`
+	Phone          string `json:"phone" validate:"required,max=50"`
+	StreetAddress  string `json:"streetAddress" validate:"required,max=255"`
+	PostalCode     string `json:"postalCode" validate:"required,max=20"`
+	City           string `json:"city" validate:"required,max=100"`
+	AdditionalInfo string `json:"additionalInfo" validate:"max=1000"`
+	NationalID     string `json:"nationalId" validate:"omitempty,max=13"`
+}
+
+// CustomerBookingResponse represents a booking in a customer's booking history
+type CustomerBookingResponse struct {
+	ID            int64                         `json:"id"`
+	BookingNumber string                        `json:"bookingNumber"`
+	Status        string                        `json:"status"`
+	TotalAmount   float64                       `json:"totalAmount"`
+	CreatedAt     time.Time                     `json:"createdAt"`
+	Items         []CustomerBookingItemResponse `json:"items"`
+}
+
+// CustomerBookingItemResponse represents a booked service in a customer's booking history
+type CustomerBookingItemResponse struct {
+	ServiceID    int64   `json:"serviceId"`
+	ServiceName  string  `json:"serviceName"`
+	Quantity     int     `json:"quantity"`
+	UnitPrice    float64 `json:"unitPrice"`
+	TotalPrice   float64 `json:"totalPrice"`
+	PurchaseType string  `json:"purchaseType"`
+}
+
+// DataExportResponse represents the time-limited link to a customer's data export
+type DataExportResponse struct {
+	DownloadURL string    `json:"downloadUrl"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
+
+// ErasureResponse represents the result of a customer erasing their account.
+// Retained records, such as payments that must be kept for bookkeeping, are
+// pseudonymized after RetainedUntil.
+type ErasureResponse struct {
+	ErasedRecords   int        `json:"erasedRecords"`
+	RetainedRecords int        `json:"retainedRecords"`
+	RetainedUntil   *time.Time `json:"retainedUntil,omitempty"`
+}
+
+// MapCustomerToResponse maps an entity.Customer to a CustomerResponse
+func MapCustomerToResponse(customer *entity.Customer) CustomerResponse {
+	return CustomerResponse{
+		ID:              customer.ID,
+		FirstName:       customer.FirstName,
+		LastName:        customer.LastName,
+		Email:           customer.Email,
+		Phone:           customer.Phone,
+		StreetAddress:   customer.StreetAddress,
+		PostalCode:      customer.PostalCode,
+		City:            customer.City,
+		AdditionalInfo:  customer.AdditionalInfo,
+		EmailVerifiedAt: customer.EmailVerifiedAt,
+	}
+}
+
+// MapUpdateProfileRequestToEntity maps an UpdateProfileRequest to an entity.Customer
+func MapUpdateProfileRequestToEntity(req UpdateProfileRequest) entity.Customer {
+	return entity.Customer{
+		FirstName:      req.FirstName,
+		LastName:       req.LastName,
+		Phone:          req.Phone,
+		StreetAddress:  req.StreetAddress,
+		PostalCode:     req.PostalCode,
+		City:           req.City,
+		AdditionalInfo: req.AdditionalInfo,
+	}
+}
+
+// MapBookingsToCustomerResponse maps bookings to a customer's booking history
+func MapBookingsToCustomerResponse(bookings []entity.BookingWithItems) []CustomerBookingResponse {
+	response := make([]CustomerBookingResponse, 0, len(bookings))
+	for _, booking := range bookings {
+		items := make([]CustomerBookingItemResponse, 0, len(booking.Items))
+		for _, item := range booking.Items {
+			items = append(items, CustomerBookingItemResponse{
+				ServiceID:    item.ServiceID,
+				ServiceName:  item.ServiceName,
+				Quantity:     item.Quantity,
+				UnitPrice:    item.UnitPrice,
+				TotalPrice:   item.TotalPrice,
+				PurchaseType: item.PurchaseType,
+			})
+		}
+
+		response = append(response, CustomerBookingResponse{
+			ID:            booking.Booking.ID,
+			BookingNumber: booking.Booking.BookingNumber,
+			Status:        booking.Booking.Status,
+			TotalAmount:   booking.Booking.TotalAmount,
+			CreatedAt:     booking.Booking.CreatedAt,
+			Items:         items,
+		})
+	}
+	return response
+}
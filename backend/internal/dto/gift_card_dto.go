@@ -0,0 +1,27 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// GiftCardResponse represents the balance of a gift card in the API response
+type GiftCardResponse struct {
+	Code      string     `json:"code"`
+	Balance   float64    `json:"balance"`
+	Currency  string     `json:"currency"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	IsActive  bool       `json:"isActive"`
+}
+
+// MapGiftCardToResponse maps an entity.GiftCard to a GiftCardResponse
+func MapGiftCardToResponse(giftCard entity.GiftCard) GiftCardResponse {
+	return GiftCardResponse{
+		Code:      giftCard.Code,
+		Balance:   giftCard.Balance,
+		Currency:  giftCard.Currency,
+		ExpiresAt: giftCard.ExpiresAt,
+		IsActive:  giftCard.IsActive,
+	}
+}
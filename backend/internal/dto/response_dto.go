@@ -28,6 +28,13 @@ type ValidationError struct {
 // ValidationErrors represents a collection of validation errors
 type ValidationErrors []ValidationError
 
+// PaginationResponse represents cursor pagination metadata in an API response
+type PaginationResponse struct {
+	Limit      int    `json:"limit"`
+	HasMore    bool   `json:"hasMore"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
 // NewSuccessResponse creates a new success response
 func NewSuccessResponse(data interface{}) Response {
 	return Response{
@@ -73,26 +80,44 @@ func NewHealthCheckResponse(version string) HealthCheckResponse {
 
 // Error codes
 const (
-	ErrorCodeInvalidRequest      = "INVALID_REQUEST"
-	ErrorCodeValidationFailed    = "VALIDATION_FAILED"
-	ErrorCodeResourceNotFound    = "RESOURCE_NOT_FOUND"
-	ErrorCodeInternalServerError = "INTERNAL_SERVER_ERROR"
-	ErrorCodeUnauthorized        = "UNAUTHORIZED"
-	ErrorCodeForbidden           = "FORBIDDEN"
-	ErrorCodePaymentFailed       = "PAYMENT_FAILED"
-	ErrorCodeBookingFailed       = "BOOKING_FAILED"
+	ErrorCodeInvalidRequest       = "INVALID_REQUEST"
+	ErrorCodeValidationFailed     = "VALIDATION_FAILED"
+	ErrorCodeResourceNotFound     = "RESOURCE_NOT_FOUND"
+	ErrorCodeInternalServerError  = "INTERNAL_SERVER_ERROR"
+	ErrorCodeUnauthorized         = "UNAUTHORIZED"
+	ErrorCodeForbidden            = "FORBIDDEN"
+	ErrorCodePaymentFailed        = "PAYMENT_FAILED"
+	ErrorCodeBookingFailed        = "BOOKING_FAILED"
+	ErrorCodeInvalidPromoCode     = "INVALID_PROMO_CODE"
+	ErrorCodeInvalidGiftCard      = "INVALID_GIFT_CARD"
+	ErrorCodeQuoteExpired         = "QUOTE_EXPIRED"
+	ErrorCodeRateLimitExceeded    = "RATE_LIMIT_EXCEEDED"
+	ErrorCodeTermsNotAccepted     = "TERMS_NOT_ACCEPTED"
+	ErrorCodePaymentConflict      = "PAYMENT_CONFLICT"
+	ErrorCodeBookingConflict      = "BOOKING_CONFLICT"
+	ErrorCodeIdempotencyKeyReused = "IDEMPOTENCY_KEY_REUSED"
+	ErrorCodeRequestInProgress    = "REQUEST_IN_PROGRESS"
 )
 
 // HTTP status code mapping
 var errorCodeToStatusCode = map[string]int{
-	ErrorCodeInvalidRequest:      http.StatusBadRequest,
-	ErrorCodeValidationFailed:    http.StatusUnprocessableEntity,
-	ErrorCodeResourceNotFound:    http.StatusNotFound,
-	ErrorCodeInternalServerError: http.StatusInternalServerError,
-	ErrorCodeUnauthorized:        http.StatusUnauthorized,
-	ErrorCodeForbidden:           http.StatusForbidden,
-	ErrorCodePaymentFailed:       http.StatusBadRequest,
-	ErrorCodeBookingFailed:       http.StatusBadRequest,
+	ErrorCodeInvalidRequest:       http.StatusBadRequest,
+	ErrorCodeValidationFailed:     http.StatusUnprocessableEntity,
+	ErrorCodeResourceNotFound:     http.StatusNotFound,
+	ErrorCodeInternalServerError:  http.StatusInternalServerError,
+	ErrorCodeUnauthorized:         http.StatusUnauthorized,
+	ErrorCodeForbidden:            http.StatusForbidden,
+	ErrorCodePaymentFailed:        http.StatusBadRequest,
+	ErrorCodeBookingFailed:        http.StatusBadRequest,
+	ErrorCodeInvalidPromoCode:     http.StatusBadRequest,
+	ErrorCodeInvalidGiftCard:      http.StatusBadRequest,
+	ErrorCodeQuoteExpired:         http.StatusConflict,
+	ErrorCodeRateLimitExceeded:    http.StatusTooManyRequests,
+	ErrorCodeTermsNotAccepted:     http.StatusConflict,
+	ErrorCodePaymentConflict:      http.StatusConflict,
+	ErrorCodeBookingConflict:      http.StatusConflict,
+	ErrorCodeIdempotencyKeyReused: http.StatusUnprocessableEntity,
+	ErrorCodeRequestInProgress:    http.StatusConflict,
 }
 
 // GetStatusCodeForErrorCode returns the HTTP status code for an error code
@@ -1,63 +1,134 @@
 package dto
 
-import "github.com/svenskhalsovard/api/internal/entity"
+import (
+	"time"
+
+	"github.com/svenskhalsovard/api/internal/entity"
+)
 
 // ServiceResponse represents a service in the API response
 type ServiceResponse struct {
 	ID                  int64    `json:"id"`
 	Name                string   `json:"name"`
+	Slug                string   `json:"slug"`
 	ShortDescription    string   `json:"shortDescription"`
 	Description         string   `json:"description"`
 	Price               float64  `json:"price"`
-	DiscountedPrice     *float64 `json:"discountedPrice,omitempty"`
+	DiscountedPrice     *float64   `json:"discountedPrice,omitempty"`
+	EffectivePrice      float64    `json:"effectivePrice"`
+	CampaignName        string     `json:"campaignName,omitempty"`
+	DiscountValidUntil  *time.Time `json:"discountValidUntil,omitempty"`
 	IsSubscription      bool     `json:"isSubscription"`
 	SubscriptionInterval string   `json:"subscriptionInterval,omitempty"`
+	IsGiftCard          bool     `json:"isGiftCard"`
 	Image               string   `json:"image"`
+	CategoryID          *int64   `json:"categoryId,omitempty"`
 	Features            []string `json:"features,omitempty"`
 }
 
+// ServiceRequest represents a staff request to create or update a service. The
+// slug is generated from the name.
+type ServiceRequest struct {
+	Name                 string  `json:"name" validate:"required,max=255"`
+	ShortDescription     string  `json:"shortDescription" validate:"required,max=255"`
+	Description          string  `json:"description" validate:"required"`
+	Price                float64 `json:"price" validate:"gte=0"`
+	IsSubscription       bool    `json:"isSubscription"`
+	SubscriptionInterval string  `json:"subscriptionInterval" validate:"required_if=IsSubscription true,max=50"`
+	IsGiftCard           bool    `json:"isGiftCard"`
+	Image                string  `json:"image" validate:"required,max=255"`
+	CategoryID           *int64  `json:"categoryId,omitempty" validate:"omitempty,min=1"`
+	IsActive             bool    `json:"isActive"`
+}
+
 // ServiceListResponse represents a list of services in the API response
 type ServiceListResponse struct {
-	Services []ServiceResponse `json:"services"`
-	Count    int               `json:"count"`
+	Services   []ServiceResponse   `json:"services"`
+	Count      int                 `json:"count"`
+	Pagination *PaginationResponse `json:"pagination,omitempty"`
 }
 
-// MapServiceToResponse maps an entity.Service to a ServiceResponse
-func MapServiceToResponse(service entity.Service, features []entity.ServiceFeature) ServiceResponse {
+// MapServiceToResponse maps an entity.Service to a ServiceResponse. The price is
+// the service's effective price; without one the list price applies.
+func MapServiceToResponse(service entity.Service, features []entity.ServiceFeature, price *entity.ServicePrice) ServiceResponse {
 	featureStrings := make([]string, 0, len(features))
 	for _, feature := range features {
 		featureStrings = append(featureStrings, feature.Feature)
 	}
 
-	return ServiceResponse{
+	response := ServiceResponse{
 		ID:                  service.ID,
 		Name:                service.Name,
+		Slug:                service.Slug,
 		ShortDescription:    service.ShortDescription,
 		Description:         service.Description,
 		Price:               service.Price,
-		DiscountedPrice:     service.DiscountedPrice,
+		EffectivePrice:      service.Price,
 		IsSubscription:      service.IsSubscription,
 		SubscriptionInterval: service.SubscriptionInterval,
+		IsGiftCard:          service.IsGiftCard,
 		Image:               service.Image,
+		CategoryID:          service.CategoryID,
 		Features:            featureStrings,
 	}
+
+	if price != nil && price.Price < price.ListPrice {
+		discountedPrice := price.Price
+		response.DiscountedPrice = &discountedPrice
+		response.EffectivePrice = price.Price
+		response.CampaignName = price.CampaignName
+		response.DiscountValidUntil = price.ValidUntil
+	}
+
+	return response
+}
+
+// MapServiceRequestToEntity maps a ServiceRequest to an entity.Service
+func MapServiceRequestToEntity(req ServiceRequest) entity.Service {
+	return entity.Service{
+		Name:                 req.Name,
+		ShortDescription:     req.ShortDescription,
+		Description:          req.Description,
+		Price:                req.Price,
+		IsSubscription:       req.IsSubscription,
+		SubscriptionInterval: req.SubscriptionInterval,
+		IsGiftCard:           req.IsGiftCard,
+		Image:                req.Image,
+		CategoryID:           req.CategoryID,
+		IsActive:             req.IsActive,
+	}
 }
 
 // MapServiceWithFeaturesToResponse maps an entity.ServiceWithFeatures to a ServiceResponse
 func MapServiceWithFeaturesToResponse(serviceWithFeatures entity.ServiceWithFeatures) ServiceResponse {
-	return MapServiceToResponse(serviceWithFeatures.Service, serviceWithFeatures.Features)
+	return MapServiceToResponse(serviceWithFeatures.Service, serviceWithFeatures.Features, serviceWithFeatures.Price)
 }
 
 // MapServicesToResponse maps a slice of entity.Service to a ServiceListResponse
-func MapServicesToResponse(services []entity.Service, featuresMap map[int64][]entity.ServiceFeature) ServiceListResponse {
+func MapServicesToResponse(services []entity.Service, featuresMap map[int64][]entity.ServiceFeature, prices map[int64]entity.ServicePrice) ServiceListResponse {
 	responseServices := make([]ServiceResponse, 0, len(services))
 	for _, service := range services {
 		features := featuresMap[service.ID]
-		responseServices = append(responseServices, MapServiceToResponse(service, features))
+		var price *entity.ServicePrice
+		if servicePrice, ok := prices[service.ID]; ok {
+			price = &servicePrice
+		}
+		responseServices = append(responseServices, MapServiceToResponse(service, features, price))
 	}
 
 	return ServiceListResponse{
 		Services: responseServices,
 		Count:    len(responseServices),
 	}
-}
\ No newline at end of file
+}
+
+// MapServicePageToResponse maps an entity.ServicePage to a ServiceListResponse with pagination metadata
+func MapServicePageToResponse(page entity.ServicePage) ServiceListResponse {
+	response := MapServicesToResponse(page.Services, nil, page.Prices)
+	response.Pagination = &PaginationResponse{
+		Limit:      page.Limit,
+		HasMore:    page.HasMore,
+		NextCursor: page.NextCursor,
+	}
+	return response
+}
@@ -0,0 +1,385 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// keySize is the AES-256 key size in bytes
+const keySize = 32
+
+// envelopePrefix marks values encrypted by Encrypt
+const envelopePrefix = "enc:"
+
+// legacyKeyVersion is the key that encrypted values written before envelope
+// encryption was introduced. Those values are plain base64 without
+// envelopePrefix.
+const legacyKeyVersion = 1
+
+// Common encryption errors
+var (
+	ErrInvalidCiphertext = errors.New("invalid ciphertext")
+	ErrUnknownKeyVersion = errors.New("unknown encryption key version")
+)
+
+// keyFile is the layout of the key file. Keys maps a key version to a base64
+// encoded 32-byte master key. New values are encrypted under ActiveVersion;
+// older versions are kept so existing values can be decrypted until they have
+// been re-encrypted. BlindIndexKey is a base64 encoded key for blind indexes.
+type keyFile struct {
+	ActiveVersion int               `json:"activeVersion"`
+	Keys          map[string]string `json:"keys"`
+	BlindIndexKey string            `json:"blindIndexKey"`
+}
+
+// Keyring encrypts individual database fields with envelope encryption. Each
+// value is encrypted with its own random data key, which is in turn encrypted
+// with a versioned master key from the key file. Both are stored together as
+//
+//	enc:<key version>:<encrypted data key>:<encrypted value>
+//
+// Rotating a master key therefore only requires the data keys to be
+// re-encrypted, see Rewrap.
+type Keyring struct {
+	activeVersion int
+	keys          map[int]cipher.AEAD
+	blindIndexKey []byte
+}
+
+// LoadKeyring reads a Keyring from a JSON key file
+func LoadKeyring(path string) (*Keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	var file keyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse key file: %w", err)
+	}
+
+	keys := make(map[int]cipher.AEAD, len(file.Keys))
+	for version, encodedKey := range file.Keys {
+		v, err := strconv.Atoi(version)
+		if err != nil || v < 1 {
+			return nil, fmt.Errorf("invalid key version %q", version)
+		}
+
+		aead, err := newAEAD(encodedKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key version %d: %w", v, err)
+		}
+		keys[v] = aead
+	}
+
+	if _, ok := keys[file.ActiveVersion]; !ok {
+		return nil, fmt.Errorf("active key version %d is not in the key file", file.ActiveVersion)
+	}
+
+	blindIndexKey, err := base64.StdEncoding.DecodeString(file.BlindIndexKey)
+	if err != nil || len(blindIndexKey) < keySize {
+		return nil, fmt.Errorf("blind index key must be at least %d base64 encoded bytes", keySize)
+	}
+
+	return &Keyring{
+		activeVersion: file.ActiveVersion,
+		keys:          keys,
+		blindIndexKey: blindIndexKey,
+	}, nil
+}
+
+// ActiveVersion returns the version of the master key new values are encrypted under
+func (k *Keyring) ActiveVersion() int {
+	return k.activeVersion
+}
+
+// Encrypt encrypts plaintext with a new data key under the active master key
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	dataKey := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return "", fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	dataAEAD, err := aeadFromKey(dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := seal(dataAEAD, []byte(plaintext), nil)
+	if err != nil {
+		return "", err
+	}
+
+	wrappedKey, err := seal(k.keys[k.activeVersion], dataKey, versionData(k.activeVersion))
+	if err != nil {
+		return "", err
+	}
+
+	return formatEnvelope(k.activeVersion, wrappedKey, payload), nil
+}
+
+// Decrypt decrypts a value returned by Encrypt. Values written before envelope
+// encryption are decrypted with the legacy key version.
+func (k *Keyring) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return k.decryptLegacy(value)
+	}
+
+	version, wrappedKey, payload, err := parseEnvelope(value)
+	if err != nil {
+		return "", err
+	}
+
+	dataAEAD, err := k.unwrapKey(version, wrappedKey)
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := open(dataAEAD, payload, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+// NeedsRewrap reports whether an encrypted value is not under the active master key
+func (k *Keyring) NeedsRewrap(value string) bool {
+	if !IsEncrypted(value) {
+		return true
+	}
+
+	version, _, _, err := parseEnvelope(value)
+	return err != nil || version != k.activeVersion
+}
+
+// Rewrap re-encrypts the data key of a value under the active master key,
+// leaving the encrypted value itself as is. Legacy values are encrypted anew.
+func (k *Keyring) Rewrap(value string) (string, error) {
+	if !IsEncrypted(value) {
+		plaintext, err := k.decryptLegacy(value)
+		if err != nil {
+			return "", err
+		}
+		return k.Encrypt(plaintext)
+	}
+
+	version, wrappedKey, payload, err := parseEnvelope(value)
+	if err != nil {
+		return "", err
+	}
+	if version == k.activeVersion {
+		return value, nil
+	}
+
+	aead, ok := k.keys[version]
+	if !ok {
+		return "", ErrUnknownKeyVersion
+	}
+
+	dataKey, err := open(aead, wrappedKey, versionData(version))
+	if err != nil {
+		return "", err
+	}
+
+	rewrapped, err := seal(k.keys[k.activeVersion], dataKey, versionData(k.activeVersion))
+	if err != nil {
+		return "", err
+	}
+
+	return formatEnvelope(k.activeVersion, rewrapped, payload), nil
+}
+
+// BlindIndex returns a keyed hash of value for equality lookups on an
+// encrypted column. Callers normalize value first, for example by lowercasing
+// email addresses, so equal values get equal indexes.
+func (k *Keyring) BlindIndex(value string) string {
+	mac := hmac.New(sha256.New, k.blindIndexKey)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// IsEncrypted reports whether value was encrypted by Encrypt
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, envelopePrefix)
+}
+
+// Helper functions
+
+// decryptLegacy decrypts a value encrypted directly with the legacy key
+func (k *Keyring) decryptLegacy(value string) (string, error) {
+	aead, ok := k.keys[legacyKeyVersion]
+	if !ok {
+		return "", ErrUnknownKeyVersion
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+
+	plaintext, err := open(aead, sealed, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+// unwrapKey decrypts the data key of a value
+func (k *Keyring) unwrapKey(version int, wrappedKey []byte) (cipher.AEAD, error) {
+	aead, ok := k.keys[version]
+	if !ok {
+		return nil, ErrUnknownKeyVersion
+	}
+
+	dataKey, err := open(aead, wrappedKey, versionData(version))
+	if err != nil {
+		return nil, err
+	}
+
+	return aeadFromKey(dataKey)
+}
+
+// formatEnvelope encodes an encrypted value
+func formatEnvelope(version int, wrappedKey []byte, payload []byte) string {
+	return envelopePrefix + strconv.Itoa(version) + ":" +
+		base64.RawStdEncoding.EncodeToString(wrappedKey) + ":" +
+		base64.RawStdEncoding.EncodeToString(payload)
+}
+
+// parseEnvelope decodes an encrypted value
+func parseEnvelope(value string) (int, []byte, []byte, error) {
+	parts := strings.Split(strings.TrimPrefix(value, envelopePrefix), ":")
+	if len(parts) != 3 {
+		return 0, nil, nil, ErrInvalidCiphertext
+	}
+
+	version, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, nil, nil, ErrInvalidCiphertext
+	}
+
+	wrappedKey, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return 0, nil, nil, ErrInvalidCiphertext
+	}
+
+	payload, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return 0, nil, nil, ErrInvalidCiphertext
+	}
+
+	return version, wrappedKey, payload, nil
+}
+
+// versionData binds a wrapped data key to the version of its master key
+func versionData(version int) []byte {
+	return []byte("v" + strconv.Itoa(version))
+}
+
+// GenerateKeyFile writes a key file with a new random master key as version 1
+// and a new blind index key. An existing file is never overwritten, since the
+// data encrypted under its keys could no longer be read.
+func GenerateKeyFile(path string) error {
+	randomKey := func() (string, error) {
+		key := make([]byte, keySize)
+		if _, err := io.ReadFull(rand.Reader, key); err != nil {
+			return "", fmt.Errorf("failed to generate key: %w", err)
+		}
+		return base64.StdEncoding.EncodeToString(key), nil
+	}
+
+	masterKey, err := randomKey()
+	if err != nil {
+		return err
+	}
+	blindIndexKey, err := randomKey()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(keyFile{
+		ActiveVersion: 1,
+		Keys:          map[string]string{"1": masterKey},
+		BlindIndexKey: blindIndexKey,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode key file: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create key file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+
+	return nil
+}
+
+// newAEAD creates an AES-256-GCM AEAD from a base64 encoded key
+func newAEAD(encodedKey string) (cipher.AEAD, error) {
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode key: %w", err)
+	}
+	if len(key) != keySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", keySize, len(key))
+	}
+	return aeadFromKey(key)
+}
+
+// aeadFromKey creates an AES-256-GCM AEAD from a raw key
+func aeadFromKey(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+
+	return aead, nil
+}
+
+// seal encrypts plaintext with a random nonce, which is prepended to the result
+func seal(aead cipher.AEAD, plaintext []byte, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open decrypts a value returned by seal
+func open(aead cipher.AEAD, sealed []byte, additionalData []byte) ([]byte, error) {
+	nonceSize := aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, ErrInvalidCiphertext
+	}
+
+	plaintext, err := aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], additionalData)
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+
+	return plaintext, nil
+}
@@ -0,0 +1,36 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditEntry records a change to an entity: who or what made it, in which
+// request, and the values before and after. Entries are never changed or
+// removed.
+type AuditEntry struct {
+	ID         int64           `db:"id" json:"id"`
+	EntityType string          `db:"entity_type" json:"entityType"`
+	EntityID   int64           `db:"entity_id" json:"entityId"`
+	Action     string          `db:"action" json:"action"`
+	OldValues  json.RawMessage `db:"old_values" json:"oldValues,omitempty"`
+	NewValues  json.RawMessage `db:"new_values" json:"newValues,omitempty"`
+	ActorType  string          `db:"actor_type" json:"actorType"`
+	ActorID    string          `db:"actor_id" json:"actorId,omitempty"`
+	RequestID  string          `db:"request_id" json:"requestId,omitempty"`
+	CreatedAt  time.Time       `db:"created_at" json:"createdAt"`
+}
+
+// AuditEntityType represents the kinds of entities whose changes are audited
+const (
+	AuditEntityPayment  = "payment"
+	AuditEntityBooking  = "booking"
+	AuditEntityCustomer = "customer"
+)
+
+// AuditAction represents the kinds of audited changes
+const (
+	AuditActionCreated       = "created"
+	AuditActionStatusChanged = "status_changed"
+	AuditActionErased        = "erased"
+)
@@ -0,0 +1,26 @@
+package entity
+
+import "time"
+
+// LoginCode represents a one-time code emailed to a customer to log in. Only a
+// hash of the code is stored.
+type LoginCode struct {
+	ID        int64      `db:"id" json:"id"`
+	Email     string     `db:"email" json:"email"`
+	CodeHash  string     `db:"code_hash" json:"-"`
+	Attempts  int        `db:"attempts" json:"attempts"`
+	ExpiresAt time.Time  `db:"expires_at" json:"expiresAt"`
+	UsedAt    *time.Time `db:"used_at" json:"usedAt,omitempty"`
+	CreatedAt time.Time  `db:"created_at" json:"createdAt"`
+}
+
+// CustomerSession represents a logged in customer. The client holds the session
+// token; only a hash of it is stored.
+type CustomerSession struct {
+	ID         int64      `db:"id" json:"id"`
+	CustomerID int64      `db:"customer_id" json:"customerId"`
+	TokenHash  string     `db:"token_hash" json:"-"`
+	ExpiresAt  time.Time  `db:"expires_at" json:"expiresAt"`
+	RevokedAt  *time.Time `db:"revoked_at" json:"revokedAt,omitempty"`
+	CreatedAt  time.Time  `db:"created_at" json:"createdAt"`
+}
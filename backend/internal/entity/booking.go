@@ -1,8 +1,13 @@
 package entity
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
-// Booking represents a customer booking for a health service
+// Booking represents a customer booking for a health service. The status
+// timestamps record when the booking entered each status of its lifecycle.
 type Booking struct {
 	ID            int64      `db:"id" json:"id"`
 	PaymentID     int64      `db:"payment_id" json:"paymentId"`
@@ -11,9 +16,17 @@ type Booking struct {
 	TotalAmount   float64    `db:"total_amount" json:"totalAmount"`
 	BookingNumber string     `db:"booking_number" json:"bookingNumber"`
 	Notes         string     `db:"notes" json:"notes,omitempty"`
+	ConfirmedAt   *time.Time `db:"confirmed_at" json:"confirmedAt,omitempty"`
+	CheckedInAt   *time.Time `db:"checked_in_at" json:"checkedInAt,omitempty"`
+	CompletedAt   *time.Time `db:"completed_at" json:"completedAt,omitempty"`
+	CancelledAt   *time.Time `db:"cancelled_at" json:"cancelledAt,omitempty"`
+	NoShowAt      *time.Time `db:"no_show_at" json:"noShowAt,omitempty"`
 	CreatedAt     time.Time  `db:"created_at" json:"createdAt"`
 	UpdatedAt     time.Time  `db:"updated_at" json:"updatedAt"`
 	DeletedAt     *time.Time `db:"deleted_at" json:"deletedAt,omitempty"`
+
+	// Customer details given for this booking
+	CustomerSnapshot `json:"customer"`
 }
 
 // BookingItem represents an item in a booking
@@ -32,30 +45,141 @@ type BookingItem struct {
 	DeletedAt      *time.Time `db:"deleted_at" json:"deletedAt,omitempty"`
 }
 
-// Customer represents a customer who makes bookings
+// CustomerSnapshot holds the customer details given for a payment or booking.
+// It is stored with the order so that later changes to the customer record do
+// not change past orders.
+type CustomerSnapshot struct {
+	FirstName           string  `db:"customer_first_name" json:"firstName"`
+	LastName            string  `db:"customer_last_name" json:"lastName"`
+	Email               string  `db:"customer_email" json:"email"`
+	Phone               string  `db:"customer_phone" json:"phone"`
+	StreetAddress       string  `db:"customer_street_address" json:"streetAddress"`
+	PostalCode          string  `db:"customer_postal_code" json:"postalCode"`
+	City                string  `db:"customer_city" json:"city"`
+	AdditionalInfo      string  `db:"customer_additional_info" json:"additionalInfo,omitempty"`
+	NationalIDEncrypted *string `db:"customer_national_id_encrypted" json:"-"`
+}
+
+// Snapshot returns the customer's current details as a snapshot
+func (c Customer) Snapshot() CustomerSnapshot {
+	return CustomerSnapshot{
+		FirstName:           c.FirstName,
+		LastName:            c.LastName,
+		Email:               c.Email,
+		Phone:               c.Phone,
+		StreetAddress:       c.StreetAddress,
+		PostalCode:          c.PostalCode,
+		City:                c.City,
+		AdditionalInfo:      c.AdditionalInfo,
+		NationalIDEncrypted: c.NationalIDEncrypted,
+	}
+}
+
+// ToCustomer returns the snapshot as the details of the customer with the given ID
+func (s CustomerSnapshot) ToCustomer(id int64) Customer {
+	return Customer{
+		ID:                  id,
+		FirstName:           s.FirstName,
+		LastName:            s.LastName,
+		Email:               s.Email,
+		Phone:               s.Phone,
+		StreetAddress:       s.StreetAddress,
+		PostalCode:          s.PostalCode,
+		City:                s.City,
+		AdditionalInfo:      s.AdditionalInfo,
+		NationalIDEncrypted: s.NationalIDEncrypted,
+	}
+}
+
+// Customer represents a customer who makes bookings. EmailVerifiedAt is set once
+// the customer has logged in with a code sent to their email.
+// NationalIDEncrypted holds the encrypted personnummer or samordningsnummer, if
+// given.
 type Customer struct {
-	ID             int64      `db:"id" json:"id"`
-	FirstName      string     `db:"first_name" json:"firstName"`
-	LastName       string     `db:"last_name" json:"lastName"`
-	Email          string     `db:"email" json:"email"`
-	Phone          string     `db:"phone" json:"phone"`
-	StreetAddress  string     `db:"street_address" json:"streetAddress"`
-	PostalCode     string     `db:"postal_code" json:"postalCode"`
-	City           string     `db:"city" json:"city"`
-	AdditionalInfo string     `db:"additional_info" json:"additionalInfo,omitempty"`
-	CreatedAt      time.Time  `db:"created_at" json:"createdAt"`
-	UpdatedAt      time.Time  `db:"updated_at" json:"updatedAt"`
-	DeletedAt      *time.Time `db:"deleted_at" json:"deletedAt,omitempty"`
+	ID                  int64      `db:"id" json:"id"`
+	FirstName           string     `db:"first_name" json:"firstName"`
+	LastName            string     `db:"last_name" json:"lastName"`
+	Email               string     `db:"email" json:"email"`
+	Phone               string     `db:"phone" json:"phone"`
+	StreetAddress       string     `db:"street_address" json:"streetAddress"`
+	PostalCode          string     `db:"postal_code" json:"postalCode"`
+	City                string     `db:"city" json:"city"`
+	AdditionalInfo      string     `db:"additional_info" json:"additionalInfo,omitempty"`
+	NationalIDEncrypted *string    `db:"national_id_encrypted" json:"-"`
+	EmailVerifiedAt     *time.Time `db:"email_verified_at" json:"emailVerifiedAt,omitempty"`
+	CreatedAt           time.Time  `db:"created_at" json:"createdAt"`
+	UpdatedAt           time.Time  `db:"updated_at" json:"updatedAt"`
+	DeletedAt           *time.Time `db:"deleted_at" json:"deletedAt,omitempty"`
 }
 
 // BookingStatus represents the possible status values for a booking
 const (
 	BookingStatusPending   = "pending"
 	BookingStatusConfirmed = "confirmed"
-	BookingStatusCancelled = "cancelled"
+	BookingStatusCheckedIn = "checked_in"
 	BookingStatusCompleted = "completed"
+	BookingStatusCancelled = "cancelled"
+	BookingStatusNoShow    = "no_show"
 )
 
+// ErrInvalidBookingTransition is returned when a booking may not move from its
+// status to another
+var ErrInvalidBookingTransition = errors.New("invalid booking status transition")
+
+// bookingTransitions lists the statuses a booking may move to from each status.
+// A booking is confirmed, the customer checks in at the clinic and the booking
+// is completed after the visit. Confirmed bookings may be cancelled, or marked
+// as no-show when the customer does not come. Completed, cancelled and no-show
+// bookings are final.
+var bookingTransitions = map[string][]string{
+	BookingStatusPending:   {BookingStatusConfirmed, BookingStatusCancelled},
+	BookingStatusConfirmed: {BookingStatusCheckedIn, BookingStatusCancelled, BookingStatusNoShow},
+	BookingStatusCheckedIn: {BookingStatusCompleted},
+	BookingStatusCompleted: {},
+	BookingStatusCancelled: {},
+	BookingStatusNoShow:    {},
+}
+
+// BookingTransitionError is returned when a booking may not move from one status
+// to another. It matches ErrInvalidBookingTransition with errors.Is.
+type BookingTransitionError struct {
+	BookingID int64
+	From      string
+	To        string
+}
+
+// Error implements the error interface
+func (e *BookingTransitionError) Error() string {
+	return fmt.Sprintf("booking %d cannot move from %s to %s", e.BookingID, e.From, e.To)
+}
+
+// Is reports whether target is ErrInvalidBookingTransition
+func (e *BookingTransitionError) Is(target error) bool {
+	return target == ErrInvalidBookingTransition
+}
+
+// IsValidBookingStatus reports whether status is a booking status
+func IsValidBookingStatus(status string) bool {
+	_, ok := bookingTransitions[status]
+	return ok
+}
+
+// ValidateBookingTransition returns a *BookingTransitionError when a booking may
+// not move from one status to another. Keeping the same status is allowed.
+func ValidateBookingTransition(bookingID int64, from string, to string) error {
+	if from == to {
+		return nil
+	}
+
+	for _, allowed := range bookingTransitions[from] {
+		if allowed == to {
+			return nil
+		}
+	}
+
+	return &BookingTransitionError{BookingID: bookingID, From: from, To: to}
+}
+
 // BookingWithItems represents a booking with its items
 type BookingWithItems struct {
 	Booking  Booking       `json:"booking"`
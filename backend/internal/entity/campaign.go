@@ -0,0 +1,48 @@
+package entity
+
+import "time"
+
+// PriceCampaign represents a time-bound discount on services. A campaign applies
+// from StartsAt until EndsAt (open-ended when nil) to every service when
+// AppliesToAll is set, otherwise only to the services in ServiceIDs.
+type PriceCampaign struct {
+	ID            int64      `db:"id" json:"id"`
+	Name          string     `db:"name" json:"name"`
+	DiscountType  string     `db:"discount_type" json:"discountType"`
+	DiscountValue float64    `db:"discount_value" json:"discountValue"`
+	StartsAt      time.Time  `db:"starts_at" json:"startsAt"`
+	EndsAt        *time.Time `db:"ends_at" json:"endsAt,omitempty"`
+	AppliesToAll  bool       `db:"applies_to_all" json:"appliesToAll"`
+	IsActive      bool       `db:"is_active" json:"isActive"`
+	ServiceIDs    []int64    `db:"-" json:"serviceIds,omitempty"`
+	CreatedAt     time.Time  `db:"created_at" json:"createdAt"`
+	UpdatedAt     time.Time  `db:"updated_at" json:"updatedAt"`
+	DeletedAt     *time.Time `db:"deleted_at" json:"deletedAt,omitempty"`
+}
+
+// PriceCampaignService links a campaign to a service it targets
+type PriceCampaignService struct {
+	CampaignID int64 `db:"campaign_id" json:"campaignId"`
+	ServiceID  int64 `db:"service_id" json:"serviceId"`
+}
+
+// DiscountType represents the ways a discount can be calculated
+const (
+	DiscountTypePercentage  = "percentage"
+	DiscountTypeFixedAmount = "fixed_amount"
+)
+
+// ServicePrice represents the price of a service at a point in time
+type ServicePrice struct {
+	ServiceID    int64      `json:"serviceId"`
+	ListPrice    float64    `json:"listPrice"`
+	Price        float64    `json:"price"`
+	CampaignID   *int64     `json:"campaignId,omitempty"`
+	CampaignName string     `json:"campaignName,omitempty"`
+	ValidFrom    *time.Time `json:"validFrom,omitempty"`
+	ValidUntil   *time.Time `json:"validUntil,omitempty"`
+	// ChangedAt is the last time campaigns changed prices before the price
+	// was resolved, when known. Prices go back to the list price when a
+	// campaign ends, so it can be later than ValidFrom.
+	ChangedAt *time.Time `json:"-"`
+}
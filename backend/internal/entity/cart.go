@@ -0,0 +1,44 @@
+package entity
+
+import "time"
+
+// Cart represents a customer's shopping cart, identified by a token the client keeps
+type Cart struct {
+	ID        int64      `db:"id" json:"id"`
+	Token     string     `db:"token" json:"token"`
+	Status    string     `db:"status" json:"status"`
+	PaymentID *int64     `db:"payment_id" json:"paymentId,omitempty"`
+	ExpiresAt time.Time  `db:"expires_at" json:"expiresAt"`
+	CreatedAt time.Time  `db:"created_at" json:"createdAt"`
+	UpdatedAt time.Time  `db:"updated_at" json:"updatedAt"`
+	DeletedAt *time.Time `db:"deleted_at" json:"deletedAt,omitempty"`
+}
+
+// CartStatus represents the possible status values for a cart
+const (
+	CartStatusOpen       = "open"
+	CartStatusCheckedOut = "checked_out"
+)
+
+// CartItem represents an item in a cart. The service name and prices are not
+// stored; they are resolved from the catalog whenever the cart is read.
+type CartItem struct {
+	ID           int64     `db:"id" json:"id"`
+	CartID       int64     `db:"cart_id" json:"cartId"`
+	ServiceID    int64     `db:"service_id" json:"serviceId"`
+	Quantity     int       `db:"quantity" json:"quantity"`
+	PurchaseType string    `db:"purchase_type" json:"purchaseType"`
+	ServiceName  string    `db:"-" json:"serviceName"`
+	ListPrice    float64   `db:"-" json:"listPrice"`
+	UnitPrice    float64   `db:"-" json:"unitPrice"`
+	TotalPrice   float64   `db:"-" json:"totalPrice"`
+	CreatedAt    time.Time `db:"created_at" json:"createdAt"`
+	UpdatedAt    time.Time `db:"updated_at" json:"updatedAt"`
+}
+
+// CartWithItems represents a cart with its items and current total
+type CartWithItems struct {
+	Cart        Cart       `json:"cart"`
+	Items       []CartItem `json:"items"`
+	TotalAmount float64    `json:"totalAmount"`
+}
@@ -0,0 +1,24 @@
+package entity
+
+import "time"
+
+// ServiceCategory represents a category in the service catalog. Categories form
+// a tree through ParentID; top-level categories have no parent.
+type ServiceCategory struct {
+	ID          int64      `db:"id" json:"id"`
+	ParentID    *int64     `db:"parent_id" json:"parentId,omitempty"`
+	Slug        string     `db:"slug" json:"slug"`
+	Name        string     `db:"name" json:"name"`
+	Description string     `db:"description" json:"description,omitempty"`
+	SortOrder   int        `db:"sort_order" json:"sortOrder"`
+	IsActive    bool       `db:"is_active" json:"isActive"`
+	CreatedAt   time.Time  `db:"created_at" json:"createdAt"`
+	UpdatedAt   time.Time  `db:"updated_at" json:"updatedAt"`
+	DeletedAt   *time.Time `db:"deleted_at" json:"deletedAt,omitempty"`
+}
+
+// ServiceCategoryNode represents a category with its child categories
+type ServiceCategoryNode struct {
+	Category ServiceCategory       `json:"category"`
+	Children []ServiceCategoryNode `json:"children"`
+}
@@ -0,0 +1,23 @@
+package entity
+
+import "time"
+
+// CheckoutRecovery records the reminder email sent for an abandoned checkout.
+// The token in the email lets the customer resume the checkout or opt out of
+// further reminders. A payment gets at most one reminder.
+type CheckoutRecovery struct {
+	ID        int64      `db:"id" json:"id"`
+	PaymentID int64      `db:"payment_id" json:"paymentId"`
+	Token     string     `db:"token" json:"token"`
+	Email     string     `db:"email" json:"email"`
+	SentAt    *time.Time `db:"sent_at" json:"sentAt,omitempty"`
+	ResumedAt *time.Time `db:"resumed_at" json:"resumedAt,omitempty"`
+	CreatedAt time.Time  `db:"created_at" json:"createdAt"`
+}
+
+// EmailOptOut records an email address that no longer wants checkout reminders
+type EmailOptOut struct {
+	ID        int64     `db:"id" json:"id"`
+	Email     string    `db:"email" json:"email"`
+	CreatedAt time.Time `db:"created_at" json:"createdAt"`
+}
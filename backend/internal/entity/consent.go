@@ -0,0 +1,35 @@
+package entity
+
+import "time"
+
+// CustomerConsent records a consent given or withdrawn by a customer. Consents
+// are append-only: every change adds a record, and the current state of a
+// consent is its latest record. PolicyVersion is the version of the terms or
+// privacy policy the customer was shown.
+type CustomerConsent struct {
+	ID            int64     `db:"id" json:"id"`
+	CustomerID    int64     `db:"customer_id" json:"customerId"`
+	ConsentType   string    `db:"consent_type" json:"consentType"`
+	Granted       bool      `db:"granted" json:"granted"`
+	PolicyVersion string    `db:"policy_version" json:"policyVersion"`
+	Source        string    `db:"source" json:"source"`
+	IPAddress     string    `db:"ip_address" json:"ipAddress"`
+	UserAgent     string    `db:"user_agent" json:"userAgent"`
+	CreatedAt     time.Time `db:"created_at" json:"createdAt"`
+}
+
+// ConsentType represents what a customer consents to
+const (
+	ConsentTypeTerms             = "terms"
+	ConsentTypeMarketingEmail    = "marketing_email"
+	ConsentTypeSMSReminders      = "sms_reminders"
+	ConsentTypeCheckoutReminders = "checkout_reminders"
+)
+
+// ConsentSource represents where a consent was given or withdrawn
+const (
+	ConsentSourceCheckout  = "checkout"
+	ConsentSourceAccount   = "account"
+	ConsentSourceEmailLink = "email_link"
+	ConsentSourceErasure   = "erasure"
+)
@@ -0,0 +1,16 @@
+package entity
+
+import "time"
+
+// ContactMessage represents a message sent through the contact form
+type ContactMessage struct {
+	ID            int64     `db:"id" json:"id"`
+	Name          string    `db:"name" json:"name"`
+	Email         string    `db:"email" json:"email"`
+	Subject       string    `db:"subject" json:"subject"`
+	Message       string    `db:"message" json:"message"`
+	IPAddress     string    `db:"ip_address" json:"ipAddress"`
+	UserAgent     string    `db:"user_agent" json:"userAgent"`
+	OutboxEmailID *int64    `db:"outbox_email_id" json:"outboxEmailId,omitempty"`
+	CreatedAt     time.Time `db:"created_at" json:"createdAt"`
+}
@@ -0,0 +1,38 @@
+package entity
+
+import "time"
+
+// DataExport represents an archive of the personal data held about a customer,
+// made for a request under GDPR Article 15. The archive is stored encrypted and
+// can be downloaded with a token until it expires, after which it is deleted.
+// Only a hash of the token is stored.
+type DataExport struct {
+	ID               int64      `db:"id" json:"id"`
+	CustomerID       int64      `db:"customer_id" json:"customerId"`
+	TokenHash        string     `db:"token_hash" json:"-"`
+	RequestedBy      string     `db:"requested_by" json:"requestedBy"`
+	ArchiveEncrypted *string    `db:"archive_encrypted" json:"-"`
+	ExpiresAt        time.Time  `db:"expires_at" json:"expiresAt"`
+	DownloadedAt     *time.Time `db:"downloaded_at" json:"downloadedAt,omitempty"`
+	CreatedAt        time.Time  `db:"created_at" json:"createdAt"`
+}
+
+// DataExportRequestedBy represents who asked for a data export
+const (
+	DataExportRequestedByCustomer = "customer"
+	DataExportRequestedByStaff    = "staff"
+)
+
+// CustomerData holds the personal data stored about a customer, apart from the
+// customer record itself. Messages, notifications and opt-outs are matched on
+// the email addresses the customer has used. Consents hold every consent record,
+// not only the current ones.
+type CustomerData struct {
+	Payments           []PaymentWithItems `json:"payments"`
+	Bookings           []BookingWithItems `json:"bookings"`
+	ContactMessages    []ContactMessage   `json:"contactMessages"`
+	Notifications      []OutboxEmail      `json:"notifications"`
+	CheckoutRecoveries []CheckoutRecovery `json:"checkoutRecoveries"`
+	EmailOptOuts       []EmailOptOut      `json:"emailOptOuts"`
+	Consents           []CustomerConsent  `json:"consents"`
+}
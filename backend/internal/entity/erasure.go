@@ -0,0 +1,35 @@
+package entity
+
+import "time"
+
+// PersonalRecord identifies a row with personal data of a customer that is
+// subject to a retention rule when the customer is erased
+type PersonalRecord struct {
+	TableName string    `db:"table_name" json:"tableName"`
+	RecordID  int64     `db:"record_id" json:"recordId"`
+	CreatedAt time.Time `db:"created_at" json:"createdAt"`
+}
+
+// RetainedRecord is a row of an erased customer that must be kept, for example
+// for bookkeeping, and is pseudonymized once RetainUntil has passed
+type RetainedRecord struct {
+	ID          int64     `db:"id" json:"id"`
+	CustomerID  int64     `db:"customer_id" json:"customerId"`
+	TableName   string    `db:"table_name" json:"tableName"`
+	RecordID    int64     `db:"record_id" json:"recordId"`
+	RetainUntil time.Time `db:"retain_until" json:"retainUntil"`
+	CreatedAt   time.Time `db:"created_at" json:"createdAt"`
+}
+
+// Tables with personal data that have a retention rule
+const (
+	RetentionTablePayments           = "payments"
+	RetentionTableBookings           = "bookings"
+	RetentionTableContactMessages    = "contact_messages"
+	RetentionTableEmailOutbox        = "email_outbox"
+	RetentionTableCheckoutRecoveries = "checkout_recoveries"
+	RetentionTableCustomerConsents   = "customer_consents"
+)
+
+// ErasedPlaceholder replaces the names of erased customers
+const ErasedPlaceholder = "[erased]"
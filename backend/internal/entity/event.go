@@ -0,0 +1,60 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// PaymentEvent records something that happened to a payment, for the timeline
+// support follows an order by. Details hold event specific values such as the
+// payment method chosen or the reason a payment failed.
+type PaymentEvent struct {
+	ID        int64           `db:"id" json:"id"`
+	PaymentID int64           `db:"payment_id" json:"paymentId"`
+	EventType string          `db:"event_type" json:"eventType"`
+	Details   json.RawMessage `db:"details" json:"details,omitempty"`
+	CreatedAt time.Time       `db:"created_at" json:"createdAt"`
+}
+
+// BookingEvent records something that happened to a booking
+type BookingEvent struct {
+	ID        int64           `db:"id" json:"id"`
+	BookingID int64           `db:"booking_id" json:"bookingId"`
+	EventType string          `db:"event_type" json:"eventType"`
+	Details   json.RawMessage `db:"details" json:"details,omitempty"`
+	CreatedAt time.Time       `db:"created_at" json:"createdAt"`
+}
+
+// PaymentEventType represents the kinds of payment events
+const (
+	PaymentEventCreated          = "created"
+	PaymentEventSveaOrderCreated = "svea_order_created"
+	PaymentEventRetried          = "retried"
+	PaymentEventMethodChosen     = "method_chosen"
+	PaymentEventSucceeded        = "succeeded"
+	PaymentEventFailed           = "failed"
+	PaymentEventStatusChanged    = "status_changed"
+	PaymentEventTakenAfterFinal  = "taken_after_final"
+)
+
+// BookingEventType represents the kinds of booking events
+const (
+	BookingEventCreated       = "created"
+	BookingEventStatusChanged = "status_changed"
+)
+
+// TimelineEvent is a payment or booking event in the timeline of an order.
+// Source is either TimelineSourcePayment or TimelineSourceBooking.
+type TimelineEvent struct {
+	Source    string          `json:"source"`
+	SourceID  int64           `json:"sourceId"`
+	EventType string          `json:"eventType"`
+	Details   json.RawMessage `json:"details,omitempty"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+// TimelineSource represents where a timeline event comes from
+const (
+	TimelineSourcePayment = "payment"
+	TimelineSourceBooking = "booking"
+)
@@ -0,0 +1,44 @@
+package entity
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrInsufficientGiftCardBalance is returned when a gift card cannot cover a redemption
+var ErrInsufficientGiftCardBalance = errors.New("insufficient gift card balance")
+
+// GiftCard represents a gift card bought through checkout. The balance can be
+// redeemed over several orders until it is used up or the card expires.
+type GiftCard struct {
+	ID                int64      `db:"id" json:"id"`
+	Code              string     `db:"code" json:"code"`
+	InitialAmount     float64    `db:"initial_amount" json:"initialAmount"`
+	Balance           float64    `db:"balance" json:"balance"`
+	Currency          string     `db:"currency" json:"currency"`
+	PurchasePaymentID *int64     `db:"purchase_payment_id" json:"purchasePaymentId,omitempty"`
+	ExpiresAt         *time.Time `db:"expires_at" json:"expiresAt,omitempty"`
+	IsActive          bool       `db:"is_active" json:"isActive"`
+	CreatedAt         time.Time  `db:"created_at" json:"createdAt"`
+	UpdatedAt         time.Time  `db:"updated_at" json:"updatedAt"`
+	DeletedAt         *time.Time `db:"deleted_at" json:"deletedAt,omitempty"`
+}
+
+// GiftCardTransaction represents an entry in the balance ledger of a gift card.
+// Amount is positive when it adds to the balance and negative when it draws from it.
+type GiftCardTransaction struct {
+	ID           int64     `db:"id" json:"id"`
+	GiftCardID   int64     `db:"gift_card_id" json:"giftCardId"`
+	PaymentID    *int64    `db:"payment_id" json:"paymentId,omitempty"`
+	Type         string    `db:"type" json:"type"`
+	Amount       float64   `db:"amount" json:"amount"`
+	BalanceAfter float64   `db:"balance_after" json:"balanceAfter"`
+	CreatedAt    time.Time `db:"created_at" json:"createdAt"`
+}
+
+// GiftCardTransactionType represents the kinds of gift card ledger entries
+const (
+	GiftCardTransactionIssue   = "issue"
+	GiftCardTransactionRedeem  = "redeem"
+	GiftCardTransactionRelease = "release"
+)
@@ -0,0 +1,21 @@
+package entity
+
+import "time"
+
+// IdempotencyKey records a request made with an Idempotency-Key header, so that
+// retries of it get the stored response instead of being processed again. Scope
+// is the method and path of the request and Fingerprint a hash of the request,
+// which a retry must match. The response is stored once the request completes,
+// and the record is removed when it expires.
+type IdempotencyKey struct {
+	ID           int64      `db:"id"`
+	Scope        string     `db:"scope"`
+	Key          string     `db:"idempotency_key"`
+	Fingerprint  string     `db:"fingerprint"`
+	StatusCode   int        `db:"status_code"`
+	ContentType  string     `db:"content_type"`
+	ResponseBody []byte     `db:"response_body"`
+	CreatedAt    time.Time  `db:"created_at"`
+	CompletedAt  *time.Time `db:"completed_at"`
+	ExpiresAt    time.Time  `db:"expires_at"`
+}
@@ -0,0 +1,28 @@
+package entity
+
+import "time"
+
+// OutboxEmail represents an email waiting in the outbox. Emails are stored with
+// the change that causes them and delivered by a background job, so they are
+// neither lost when sending fails nor sent for changes that were rolled back.
+type OutboxEmail struct {
+	ID          int64      `db:"id" json:"id"`
+	Recipient   string     `db:"recipient" json:"recipient"`
+	ReplyTo     *string    `db:"reply_to" json:"replyTo,omitempty"`
+	Subject     string     `db:"subject" json:"subject"`
+	Body        string     `db:"body" json:"body"`
+	Status      string     `db:"status" json:"status"`
+	Attempts    int        `db:"attempts" json:"attempts"`
+	LastError   *string    `db:"last_error" json:"lastError,omitempty"`
+	AvailableAt time.Time  `db:"available_at" json:"availableAt"`
+	SentAt      *time.Time `db:"sent_at" json:"sentAt,omitempty"`
+	CreatedAt   time.Time  `db:"created_at" json:"createdAt"`
+	UpdatedAt   time.Time  `db:"updated_at" json:"updatedAt"`
+}
+
+// OutboxStatus represents the possible status values for an outbox email
+const (
+	OutboxStatusPending = "pending"
+	OutboxStatusSent    = "sent"
+	OutboxStatusFailed  = "failed"
+)
@@ -1,6 +1,10 @@
 package entity
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 // Payment represents a payment for a booking
 type Payment struct {
@@ -10,13 +14,22 @@ type Payment struct {
 	Amount            float64    `db:"amount" json:"amount"`
 	Currency          string     `db:"currency" json:"currency"`
 	Status            string     `db:"status" json:"status"`
+	Version           int        `db:"version" json:"-"`
 	PaymentMethod     string     `db:"payment_method" json:"paymentMethod"`
 	OrderReference    string     `db:"order_reference" json:"orderReference"`
 	TransactionType   string     `db:"transaction_type" json:"transactionType"`
+	PromoCodeID       *int64     `db:"promo_code_id" json:"promoCodeId,omitempty"`
+	DiscountAmount    float64    `db:"discount_amount" json:"discountAmount"`
+	GiftCardID        *int64     `db:"gift_card_id" json:"giftCardId,omitempty"`
+	GiftCardAmount    float64    `db:"gift_card_amount" json:"giftCardAmount"`
+	RecoveryConsent   bool       `db:"recovery_consent" json:"recoveryConsent"`
 	ErrorMessage      string     `db:"error_message" json:"errorMessage,omitempty"`
 	CreatedAt         time.Time  `db:"created_at" json:"createdAt"`
 	UpdatedAt         time.Time  `db:"updated_at" json:"updatedAt"`
 	DeletedAt         *time.Time `db:"deleted_at" json:"deletedAt,omitempty"`
+
+	// Customer details given at checkout
+	CustomerSnapshot `json:"customer"`
 }
 
 // PaymentStatus represents the possible status values for a payment
@@ -29,6 +42,59 @@ const (
 	PaymentStatusRefunded  = "refunded"
 )
 
+// Payment status errors
+var (
+	ErrInvalidPaymentTransition = errors.New("invalid payment status transition")
+	ErrPaymentVersionConflict   = errors.New("payment was changed by another request")
+)
+
+// paymentTransitions lists the statuses a payment may move to from each status.
+// A payment fully covered by a gift card goes from initiated to success without
+// a Svea order. Failed and cancelled payments are final, and a successful
+// payment can only be refunded.
+var paymentTransitions = map[string][]string{
+	PaymentStatusInitiated: {PaymentStatusPending, PaymentStatusSuccess, PaymentStatusFailed, PaymentStatusCancelled},
+	PaymentStatusPending:   {PaymentStatusSuccess, PaymentStatusFailed, PaymentStatusCancelled},
+	PaymentStatusSuccess:   {PaymentStatusRefunded},
+	PaymentStatusFailed:    {},
+	PaymentStatusCancelled: {},
+	PaymentStatusRefunded:  {},
+}
+
+// PaymentTransitionError is returned when a payment may not move from one status
+// to another. It matches ErrInvalidPaymentTransition with errors.Is.
+type PaymentTransitionError struct {
+	PaymentID int64
+	From      string
+	To        string
+}
+
+// Error implements the error interface
+func (e *PaymentTransitionError) Error() string {
+	return fmt.Sprintf("payment %d cannot move from %s to %s", e.PaymentID, e.From, e.To)
+}
+
+// Is reports whether target is ErrInvalidPaymentTransition
+func (e *PaymentTransitionError) Is(target error) bool {
+	return target == ErrInvalidPaymentTransition
+}
+
+// ValidatePaymentTransition returns a *PaymentTransitionError when a payment may
+// not move from one status to another. Keeping the same status is allowed.
+func ValidatePaymentTransition(paymentID int64, from string, to string) error {
+	if from == to {
+		return nil
+	}
+
+	for _, allowed := range paymentTransitions[from] {
+		if allowed == to {
+			return nil
+		}
+	}
+
+	return &PaymentTransitionError{PaymentID: paymentID, From: from, To: to}
+}
+
 // TransactionType represents the type of transaction
 const (
 	TransactionTypeOneTime     = "one-time"
@@ -41,6 +107,7 @@ const (
 	PaymentMethodInvoice   = "invoice"
 	PaymentMethodDirectDebit = "direct_debit"
 	PaymentMethodSwish     = "swish"
+	PaymentMethodGiftCard  = "gift_card"
 )
 
 // Currency represents the currencies supported
@@ -58,6 +125,8 @@ type PaymentItem struct {
 	Quantity        int        `db:"quantity" json:"quantity"`
 	UnitPrice       float64    `db:"unit_price" json:"unitPrice"`
 	TotalPrice      float64    `db:"total_price" json:"totalPrice"`
+	DiscountAmount  float64    `db:"discount_amount" json:"discountAmount"`
+	VatPercent      int        `db:"vat_percent" json:"vatPercent"`
 	PurchaseType    string     `db:"purchase_type" json:"purchaseType"`
 	CreatedAt       time.Time  `db:"created_at" json:"createdAt"`
 	UpdatedAt       time.Time  `db:"updated_at" json:"updatedAt"`
@@ -0,0 +1,42 @@
+package entity
+
+import "time"
+
+// PromoCode represents a discount code entered at checkout. Codes are valid from
+// StartsAt until ExpiresAt (no expiry when nil) and apply to every service when
+// AppliesToAll is set, otherwise only to the services in ServiceIDs. Nil limits
+// and minimum order amounts are not enforced.
+type PromoCode struct {
+	ID                 int64      `db:"id" json:"id"`
+	Code               string     `db:"code" json:"code"`
+	Description        *string    `db:"description" json:"description,omitempty"`
+	DiscountType       string     `db:"discount_type" json:"discountType"`
+	DiscountValue      float64    `db:"discount_value" json:"discountValue"`
+	MaxUses            *int       `db:"max_uses" json:"maxUses,omitempty"`
+	MaxUsesPerCustomer *int       `db:"max_uses_per_customer" json:"maxUsesPerCustomer,omitempty"`
+	MinOrderAmount     *float64   `db:"min_order_amount" json:"minOrderAmount,omitempty"`
+	StartsAt           time.Time  `db:"starts_at" json:"startsAt"`
+	ExpiresAt          *time.Time `db:"expires_at" json:"expiresAt,omitempty"`
+	AppliesToAll       bool       `db:"applies_to_all" json:"appliesToAll"`
+	IsActive           bool       `db:"is_active" json:"isActive"`
+	ServiceIDs         []int64    `db:"-" json:"serviceIds,omitempty"`
+	CreatedAt          time.Time  `db:"created_at" json:"createdAt"`
+	UpdatedAt          time.Time  `db:"updated_at" json:"updatedAt"`
+	DeletedAt          *time.Time `db:"deleted_at" json:"deletedAt,omitempty"`
+}
+
+// PromoCodeService links a promo code to a service it is restricted to
+type PromoCodeService struct {
+	PromoCodeID int64 `db:"promo_code_id" json:"promoCodeId"`
+	ServiceID   int64 `db:"service_id" json:"serviceId"`
+}
+
+// PromoCodeRedemption records the use of a promo code by a successful payment
+type PromoCodeRedemption struct {
+	ID             int64     `db:"id" json:"id"`
+	PromoCodeID    int64     `db:"promo_code_id" json:"promoCodeId"`
+	PaymentID      int64     `db:"payment_id" json:"paymentId"`
+	CustomerID     int64     `db:"customer_id" json:"customerId"`
+	DiscountAmount float64   `db:"discount_amount" json:"discountAmount"`
+	CreatedAt      time.Time `db:"created_at" json:"createdAt"`
+}
@@ -0,0 +1,44 @@
+package entity
+
+import "time"
+
+// CheckoutQuote represents an order priced by the server. The client confirms the
+// quote by its ID to start the payment, so the amounts charged are always the
+// amounts quoted. A quote can be used once and only until ExpiresAt.
+type CheckoutQuote struct {
+	ID              string              `db:"id" json:"id"`
+	CartToken       *string             `db:"cart_token" json:"cartToken,omitempty"`
+	TransactionType string              `db:"transaction_type" json:"transactionType"`
+	PromoCodeID     *int64              `db:"promo_code_id" json:"promoCodeId,omitempty"`
+	PromoCode       *string             `db:"promo_code" json:"promoCode,omitempty"`
+	GiftCardID      *int64              `db:"gift_card_id" json:"giftCardId,omitempty"`
+	GiftCardCode    *string             `db:"gift_card_code" json:"giftCardCode,omitempty"`
+	Subtotal        float64             `db:"subtotal" json:"subtotal"`
+	DiscountAmount  float64             `db:"discount_amount" json:"discountAmount"`
+	GiftCardAmount  float64             `db:"gift_card_amount" json:"giftCardAmount"`
+	VatAmount       float64             `db:"vat_amount" json:"vatAmount"`
+	TotalAmount     float64             `db:"total_amount" json:"totalAmount"`
+	Currency        string              `db:"currency" json:"currency"`
+	ExpiresAt       time.Time           `db:"expires_at" json:"expiresAt"`
+	UsedAt          *time.Time          `db:"used_at" json:"usedAt,omitempty"`
+	PaymentID       *int64              `db:"payment_id" json:"paymentId,omitempty"`
+	Items           []CheckoutQuoteItem `db:"-" json:"items"`
+	CreatedAt       time.Time           `db:"created_at" json:"createdAt"`
+}
+
+// CheckoutQuoteItem represents a priced line of a quote. TotalPrice is the line
+// total before DiscountAmount; prices include VAT.
+type CheckoutQuoteItem struct {
+	ID             int64   `db:"id" json:"id"`
+	QuoteID        string  `db:"quote_id" json:"quoteId"`
+	ServiceID      int64   `db:"service_id" json:"serviceId"`
+	ServiceName    string  `db:"service_name" json:"serviceName"`
+	Quantity       int     `db:"quantity" json:"quantity"`
+	PurchaseType   string  `db:"purchase_type" json:"purchaseType"`
+	ListPrice      float64 `db:"list_price" json:"listPrice"`
+	UnitPrice      float64 `db:"unit_price" json:"unitPrice"`
+	TotalPrice     float64 `db:"total_price" json:"totalPrice"`
+	DiscountAmount float64 `db:"discount_amount" json:"discountAmount"`
+	VatPercent     int     `db:"vat_percent" json:"vatPercent"`
+	VatAmount      float64 `db:"vat_amount" json:"vatAmount"`
+}
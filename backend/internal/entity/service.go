@@ -6,19 +6,29 @@ import "time"
 type Service struct {
 	ID                  int64      `db:"id" json:"id"`
 	Name                string     `db:"name" json:"name"`
+	Slug                string     `db:"slug" json:"slug"`
 	ShortDescription    string     `db:"short_description" json:"shortDescription"`
 	Description         string     `db:"description" json:"description"`
 	Price               float64    `db:"price" json:"price"`
-	DiscountedPrice     *float64   `db:"discounted_price" json:"discountedPrice,omitempty"`
 	IsSubscription      bool       `db:"is_subscription" json:"isSubscription"`
 	SubscriptionInterval string     `db:"subscription_interval" json:"subscriptionInterval,omitempty"`
+	IsGiftCard          bool       `db:"is_gift_card" json:"isGiftCard"`
 	Image               string     `db:"image" json:"image"`
+	CategoryID          *int64     `db:"category_id" json:"categoryId,omitempty"`
 	IsActive            bool       `db:"is_active" json:"isActive"`
 	CreatedAt           time.Time  `db:"created_at" json:"createdAt"`
 	UpdatedAt           time.Time  `db:"updated_at" json:"updatedAt"`
 	DeletedAt           *time.Time `db:"deleted_at" json:"deletedAt,omitempty"`
 }
 
+// ServiceSlugRedirect maps a previous slug of a renamed service to the service
+type ServiceSlugRedirect struct {
+	ID        int64     `db:"id" json:"id"`
+	ServiceID int64     `db:"service_id" json:"serviceId"`
+	Slug      string    `db:"slug" json:"slug"`
+	CreatedAt time.Time `db:"created_at" json:"createdAt"`
+}
+
 // ServiceFeature represents a feature of a service
 type ServiceFeature struct {
 	ID        int64      `db:"id" json:"id"`
@@ -33,4 +43,48 @@ type ServiceFeature struct {
 type ServiceWithFeatures struct {
 	Service  Service         `json:"service"`
 	Features []ServiceFeature `json:"features"`
-}
\ No newline at end of file
+	Price    *ServicePrice    `json:"price,omitempty"`
+}
+
+// ServiceFilter holds the search, filter, sort and pagination options for listing services
+type ServiceFilter struct {
+	Search           string
+	Category         string  // Category slug, matches the category and its subcategories
+	CategoryIDs      []int64 // Resolved IDs of Category and its subcategories
+	SubscriptionOnly bool
+	MinPrice         *float64
+	MaxPrice         *float64
+	SortBy           string
+	SortOrder        string
+	Cursor           string
+	Limit            int
+}
+
+// ServiceCursor marks the position of the last service on a page
+type ServiceCursor struct {
+	SortValue string `json:"v"`
+	ID        int64  `json:"id"`
+}
+
+// ServicePage represents one page of services
+type ServicePage struct {
+	Services   []Service
+	Prices     map[int64]ServicePrice
+	NextCursor string
+	HasMore    bool
+	Limit      int
+}
+
+// ServiceSort represents the fields services can be sorted by
+const (
+	ServiceSortID        = "id"
+	ServiceSortName      = "name"
+	ServiceSortPrice     = "price"
+	ServiceSortCreatedAt = "createdAt"
+)
+
+// SortOrder represents the sort directions supported
+const (
+	SortOrderAsc  = "asc"
+	SortOrderDesc = "desc"
+)
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog/log"
+	"github.com/svenskhalsovard/api/internal/dto"
+	"github.com/svenskhalsovard/api/internal/middleware"
+	"github.com/svenskhalsovard/api/internal/service"
+)
+
+// AuthHandler handles customer login requests
+type AuthHandler struct {
+	service AuthService
+}
+
+// AuthService defines the interface for customer login business logic
+type AuthService interface {
+	RequestLoginCode(ctx context.Context, email string) error
+	VerifyLoginCode(ctx context.Context, email string, code string) (*service.LoginResult, error)
+	Logout(ctx context.Context, token string) error
+}
+
+// NewAuthHandler creates a new AuthHandler
+func NewAuthHandler(service AuthService) *AuthHandler {
+	return &AuthHandler{
+		service: service,
+	}
+}
+
+// RequestLoginCode handles the request to email a one-time login code
+func (h *AuthHandler) RequestLoginCode(w http.ResponseWriter, r *http.Request) {
+	var req dto.LoginCodeRequest
+	if !parseAuthRequest(w, r, &req) {
+		return
+	}
+
+	if err := h.service.RequestLoginCode(r.Context(), req.Email); err != nil {
+		if errors.Is(err, service.ErrLoginCodeRateLimited) {
+			RespondJSON(w, http.StatusTooManyRequests, dto.NewErrorResponse(
+				dto.ErrorCodeRateLimitExceeded,
+				err.Error(),
+				nil,
+			))
+			return
+		}
+
+		log.Error().Err(err).Msg("Failed to request login code")
+		RespondError(w, err)
+		return
+	}
+
+	RespondJSON(w, http.StatusAccepted, dto.NewSuccessResponse(map[string]string{
+		"status": "code_sent",
+	}))
+}
+
+// VerifyLoginCode handles a login with a one-time code
+func (h *AuthHandler) VerifyLoginCode(w http.ResponseWriter, r *http.Request) {
+	var req dto.VerifyLoginCodeRequest
+	if !parseAuthRequest(w, r, &req) {
+		return
+	}
+
+	result, err := h.service.VerifyLoginCode(r.Context(), req.Email, req.Code)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidLoginCode) {
+			RespondJSON(w, http.StatusUnauthorized, dto.NewErrorResponse(
+				dto.ErrorCodeUnauthorized,
+				err.Error(),
+				nil,
+			))
+			return
+		}
+
+		log.Error().Err(err).Msg("Failed to verify login code")
+		RespondError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	RespondJSON(w, http.StatusOK, dto.NewSuccessResponse(dto.LoginResponse{
+		Token:     result.Token,
+		ExpiresAt: result.ExpiresAt,
+		Customer:  dto.MapCustomerToResponse(result.Customer),
+	}))
+}
+
+// Logout handles the request to end the current session
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.Logout(r.Context(), middleware.BearerToken(r)); err != nil {
+		log.Error().Err(err).Msg("Failed to log out")
+		RespondError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseAuthRequest parses and validates a login request body
+func parseAuthRequest(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := ParseJSON(r, v); err != nil {
+		log.Debug().Err(err).Msg("Invalid login request")
+
+		// Report the fields that failed validation
+		var validationErrors validator.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			RespondError(w, err)
+			return false
+		}
+
+		RespondJSON(w, http.StatusBadRequest, dto.NewErrorResponse(
+			dto.ErrorCodeInvalidRequest,
+			"Invalid login request",
+			err.Error(),
+		))
+		return false
+	}
+	return true
+}
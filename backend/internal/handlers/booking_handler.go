@@ -1,12 +1,15 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/svenskhalsovard/api/internal/dto"
 	"github.com/svenskhalsovard/api/internal/entity"
+	"github.com/svenskhalsovard/api/internal/service"
 )
 
 // BookingHandler handles booking-related requests
@@ -16,8 +19,11 @@ type BookingHandler struct {
 
 // BookingService defines the interface for booking business logic
 type BookingService interface {
-	CreateBooking(ctx http.Context, paymentID int64, customer *entity.Customer) (*entity.Booking, error)
-	GetBooking(ctx http.Context, id int64) (*entity.BookingWithItems, error)
+	CreateBooking(ctx context.Context, paymentID int64, customer *entity.Customer) (*entity.Booking, error)
+	GetBooking(ctx context.Context, id int64) (*entity.BookingWithItems, error)
+	CheckIn(ctx context.Context, id int64) (*entity.BookingWithItems, error)
+	MarkNoShow(ctx context.Context, id int64) (*entity.BookingWithItems, error)
+	CompleteBooking(ctx context.Context, id int64) (*entity.BookingWithItems, error)
 }
 
 // NewBookingHandler creates a new BookingHandler
@@ -41,6 +47,11 @@ type BookingResponse struct {
 	TotalAmount   float64                 `json:"totalAmount"`
 	Customer      *CustomerResponse       `json:"customer,omitempty"`
 	Items         []BookingItemResponse   `json:"items,omitempty"`
+	ConfirmedAt   *string                 `json:"confirmedAt,omitempty"`
+	CheckedInAt   *string                 `json:"checkedInAt,omitempty"`
+	CompletedAt   *string                 `json:"completedAt,omitempty"`
+	CancelledAt   *string                 `json:"cancelledAt,omitempty"`
+	NoShowAt      *string                 `json:"noShowAt,omitempty"`
 	CreatedAt     string                  `json:"createdAt"`
 }
 
@@ -106,15 +117,12 @@ func (h *BookingHandler) CreateBooking(w http.ResponseWriter, r *http.Request) {
 		var statusCode int
 		var errorCode string
 		
-		if errors.Is(err, entity.ErrPaymentNotFound) {
+		if errors.Is(err, service.ErrPaymentNotFound) {
 			statusCode = http.StatusNotFound
 			errorCode = dto.ErrorCodeResourceNotFound
-		} else if errors.Is(err, entity.ErrPaymentNotSuccessful) {
+		} else if errors.Is(err, service.ErrPaymentNotSuccessful) {
 			statusCode = http.StatusBadRequest
 			errorCode = dto.ErrorCodeInvalidRequest
-		} else if errors.Is(err, entity.ErrBookingAlreadyExists) {
-			statusCode = http.StatusConflict
-			errorCode = dto.ErrorCodeInvalidRequest
 		} else {
 			statusCode = http.StatusInternalServerError
 			errorCode = dto.ErrorCodeInternalServerError
@@ -149,6 +157,65 @@ func (h *BookingHandler) CreateBooking(w http.ResponseWriter, r *http.Request) {
 	RespondJSON(w, http.StatusCreated, dto.NewSuccessResponse(response))
 }
 
+// CheckIn handles the staff request to mark that the customer of a booking has
+// arrived
+func (h *BookingHandler) CheckIn(w http.ResponseWriter, r *http.Request) {
+	h.moveBooking(w, r, h.service.CheckIn)
+}
+
+// MarkNoShow handles the staff request to mark that the customer of a booking
+// did not come
+func (h *BookingHandler) MarkNoShow(w http.ResponseWriter, r *http.Request) {
+	h.moveBooking(w, r, h.service.MarkNoShow)
+}
+
+// CompleteBooking handles the staff request to mark the visit of a booking as
+// done
+func (h *BookingHandler) CompleteBooking(w http.ResponseWriter, r *http.Request) {
+	h.moveBooking(w, r, h.service.CompleteBooking)
+}
+
+// moveBooking moves the booking of the request to a new status with move and
+// responds with the updated booking
+func (h *BookingHandler) moveBooking(w http.ResponseWriter, r *http.Request, move func(ctx context.Context, id int64) (*entity.BookingWithItems, error)) {
+	bookingID, err := ParseIDParam(r, "id")
+	if err != nil {
+		RespondJSON(w, http.StatusBadRequest, dto.NewErrorResponse(
+			dto.ErrorCodeInvalidRequest,
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	booking, err := move(r.Context(), bookingID)
+	if err != nil {
+		var statusCode int
+		var errorCode string
+
+		if errors.Is(err, service.ErrBookingNotFound) {
+			statusCode = http.StatusNotFound
+			errorCode = dto.ErrorCodeResourceNotFound
+		} else if errors.Is(err, entity.ErrInvalidBookingTransition) {
+			statusCode = http.StatusConflict
+			errorCode = dto.ErrorCodeBookingConflict
+		} else {
+			log.Error().Err(err).Int64("bookingID", bookingID).Msg("Failed to update booking status")
+			statusCode = http.StatusInternalServerError
+			errorCode = dto.ErrorCodeInternalServerError
+		}
+
+		RespondJSON(w, statusCode, dto.NewErrorResponse(
+			errorCode,
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, dto.NewSuccessResponse(mapBookingToResponse(*booking)))
+}
+
 // mapBookingToResponse maps a BookingWithItems to a BookingResponse
 func mapBookingToResponse(booking entity.BookingWithItems) BookingResponse {
 	response := BookingResponse{
@@ -156,6 +223,11 @@ func mapBookingToResponse(booking entity.BookingWithItems) BookingResponse {
 		BookingNumber: booking.Booking.BookingNumber,
 		Status:        booking.Booking.Status,
 		TotalAmount:   booking.Booking.TotalAmount,
+		ConfirmedAt:   formatBookingTime(booking.Booking.ConfirmedAt),
+		CheckedInAt:   formatBookingTime(booking.Booking.CheckedInAt),
+		CompletedAt:   formatBookingTime(booking.Booking.CompletedAt),
+		CancelledAt:   formatBookingTime(booking.Booking.CancelledAt),
+		NoShowAt:      formatBookingTime(booking.Booking.NoShowAt),
 		CreatedAt:     booking.Booking.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
 		Customer: &CustomerResponse{
 			FirstName:      booking.Customer.FirstName,
@@ -183,4 +255,13 @@ func mapBookingToResponse(booking entity.BookingWithItems) BookingResponse {
 	}
 
 	return response
-}
\ No newline at end of file
+}
+
+// formatBookingTime formats the time a booking entered a status, if it did
+func formatBookingTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	formatted := t.Format("2006-01-02T15:04:05Z07:00")
+	return &formatted
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog/log"
+	"github.com/svenskhalsovard/api/internal/dto"
+	"github.com/svenskhalsovard/api/internal/entity"
+	"github.com/svenskhalsovard/api/internal/service"
+)
+
+// CampaignHandler handles staff requests for price campaigns
+type CampaignHandler struct {
+	service CampaignService
+}
+
+// CampaignService defines the interface for price campaign business logic
+type CampaignService interface {
+	CreateCampaign(ctx context.Context, campaign *entity.PriceCampaign) error
+	EndCampaign(ctx context.Context, id int64, endsAt time.Time) error
+}
+
+// NewCampaignHandler creates a new CampaignHandler
+func NewCampaignHandler(service CampaignService) *CampaignHandler {
+	return &CampaignHandler{
+		service: service,
+	}
+}
+
+// CreateCampaign handles the staff request to start a price campaign
+func (h *CampaignHandler) CreateCampaign(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateCampaignRequest
+	if err := ParseJSON(r, &req); err != nil {
+		log.Debug().Err(err).Msg("Invalid campaign request")
+
+		// Report the fields that failed validation
+		var validationErrors validator.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			RespondError(w, err)
+			return
+		}
+
+		RespondJSON(w, http.StatusBadRequest, dto.NewErrorResponse(
+			dto.ErrorCodeInvalidRequest,
+			"Invalid campaign request",
+			err.Error(),
+		))
+		return
+	}
+
+	campaign := dto.MapCreateCampaignRequestToEntity(req)
+	if err := h.service.CreateCampaign(r.Context(), &campaign); err != nil {
+		if errors.Is(err, service.ErrInvalidCampaign) {
+			RespondJSON(w, http.StatusBadRequest, dto.NewErrorResponse(
+				dto.ErrorCodeInvalidRequest,
+				err.Error(),
+				nil,
+			))
+			return
+		}
+
+		log.Error().Err(err).Msg("Failed to create campaign")
+		RespondJSON(w, http.StatusInternalServerError, dto.NewErrorResponse(
+			dto.ErrorCodeInternalServerError,
+			"Failed to create campaign",
+			nil,
+		))
+		return
+	}
+
+	RespondJSON(w, http.StatusCreated, dto.NewSuccessResponse(dto.MapCampaignToResponse(campaign)))
+}
+
+// EndCampaign handles the staff request to end a price campaign now
+func (h *CampaignHandler) EndCampaign(w http.ResponseWriter, r *http.Request) {
+	campaignID, err := ParseIDParam(r, "id")
+	if err != nil {
+		RespondJSON(w, http.StatusBadRequest, dto.NewErrorResponse(
+			dto.ErrorCodeInvalidRequest,
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	if err := h.service.EndCampaign(r.Context(), campaignID, time.Now()); err != nil {
+		if errors.Is(err, service.ErrCampaignNotFound) {
+			RespondJSON(w, http.StatusNotFound, dto.NewErrorResponse(
+				dto.ErrorCodeResourceNotFound,
+				err.Error(),
+				nil,
+			))
+			return
+		}
+
+		log.Error().Err(err).Int64("campaignID", campaignID).Msg("Failed to end campaign")
+		RespondJSON(w, http.StatusInternalServerError, dto.NewErrorResponse(
+			dto.ErrorCodeInternalServerError,
+			"Failed to end campaign",
+			nil,
+		))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/svenskhalsovard/api/internal/dto"
+	"github.com/svenskhalsovard/api/internal/entity"
+	"github.com/svenskhalsovard/api/internal/service"
+)
+
+// CartHandler handles server-side cart requests
+type CartHandler struct {
+	service CartService
+}
+
+// CartService defines the interface for cart business logic
+type CartService interface {
+	GetCart(ctx context.Context, token string) (*entity.CartWithItems, error)
+	ReplaceCart(ctx context.Context, token string, items []entity.CartItem) (*entity.CartWithItems, error)
+	UpdateCart(ctx context.Context, token string, changes []entity.CartItem) (*entity.CartWithItems, error)
+}
+
+// NewCartHandler creates a new CartHandler
+func NewCartHandler(service CartService) *CartHandler {
+	return &CartHandler{
+		service: service,
+	}
+}
+
+// GetCart handles the request to get a cart
+func (h *CartHandler) GetCart(w http.ResponseWriter, r *http.Request) {
+	token, ok := parseCartToken(w, r)
+	if !ok {
+		return
+	}
+
+	cart, err := h.service.GetCart(r.Context(), token)
+	if err != nil {
+		respondCartError(w, err)
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, dto.NewSuccessResponse(dto.MapCartToResponse(*cart)))
+}
+
+// ReplaceCart handles the request to set the items of a cart, creating it when needed
+func (h *CartHandler) ReplaceCart(w http.ResponseWriter, r *http.Request) {
+	token, ok := parseCartToken(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.CartRequest
+	if err := ParseJSON(r, &req); err != nil {
+		log.Debug().Err(err).Msg("Invalid cart request")
+		RespondJSON(w, http.StatusBadRequest, dto.NewErrorResponse(
+			dto.ErrorCodeInvalidRequest,
+			"Invalid cart request",
+			err.Error(),
+		))
+		return
+	}
+
+	cart, err := h.service.ReplaceCart(r.Context(), token, dto.MapCartRequestToItems(req))
+	if err != nil {
+		respondCartError(w, err)
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, dto.NewSuccessResponse(dto.MapCartToResponse(*cart)))
+}
+
+// UpdateCart handles the request to change item quantities in a cart
+func (h *CartHandler) UpdateCart(w http.ResponseWriter, r *http.Request) {
+	token, ok := parseCartToken(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.CartRequest
+	if err := ParseJSON(r, &req); err != nil {
+		log.Debug().Err(err).Msg("Invalid cart request")
+		RespondJSON(w, http.StatusBadRequest, dto.NewErrorResponse(
+			dto.ErrorCodeInvalidRequest,
+			"Invalid cart request",
+			err.Error(),
+		))
+		return
+	}
+
+	cart, err := h.service.UpdateCart(r.Context(), token, dto.MapCartRequestToItems(req))
+	if err != nil {
+		respondCartError(w, err)
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, dto.NewSuccessResponse(dto.MapCartToResponse(*cart)))
+}
+
+// Helper functions
+
+// parseCartToken reads the cart token from the URL, which must be a UUID generated
+// by the client, and responds with an error when it is not
+func parseCartToken(w http.ResponseWriter, r *http.Request) (string, bool) {
+	token := chi.URLParam(r, "token")
+	if err := validate.Var(token, "required,uuid"); err != nil {
+		RespondJSON(w, http.StatusBadRequest, dto.NewErrorResponse(
+			dto.ErrorCodeInvalidRequest,
+			"Invalid cart token",
+			nil,
+		))
+		return "", false
+	}
+	return token, true
+}
+
+// respondCartError maps cart errors to API error responses
+func respondCartError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrCartNotFound):
+		RespondJSON(w, http.StatusNotFound, dto.NewErrorResponse(
+			dto.ErrorCodeResourceNotFound,
+			"Cart not found",
+			nil,
+		))
+	case errors.Is(err, service.ErrCartCheckedOut), errors.Is(err, service.ErrInvalidCart):
+		RespondJSON(w, http.StatusBadRequest, dto.NewErrorResponse(
+			dto.ErrorCodeInvalidRequest,
+			err.Error(),
+			nil,
+		))
+	default:
+		log.Error().Err(err).Msg("Failed to handle cart request")
+		RespondError(w, err)
+	}
+}
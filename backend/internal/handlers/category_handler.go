@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/svenskhalsovard/api/internal/dto"
+	"github.com/svenskhalsovard/api/internal/entity"
+	"github.com/svenskhalsovard/api/internal/service"
+)
+
+// CategoryHandler handles service category requests
+type CategoryHandler struct {
+	categoryService CategoryService
+	serviceService  ServiceService
+}
+
+// CategoryService defines the interface for category business logic
+type CategoryService interface {
+	GetCategoryTree(ctx context.Context) ([]entity.ServiceCategoryNode, error)
+	GetCategoryBySlug(ctx context.Context, slug string) (*entity.ServiceCategory, error)
+}
+
+// NewCategoryHandler creates a new CategoryHandler
+func NewCategoryHandler(categoryService CategoryService, serviceService ServiceService) *CategoryHandler {
+	return &CategoryHandler{
+		categoryService: categoryService,
+		serviceService:  serviceService,
+	}
+}
+
+// GetCategories handles the request to get the category tree
+func (h *CategoryHandler) GetCategories(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	tree, err := h.categoryService.GetCategoryTree(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get categories")
+		RespondError(w, err)
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, dto.NewSuccessResponse(dto.MapCategoryTreeToResponse(tree)))
+}
+
+// GetCategoryServices handles the request to list the services in a category and its subcategories
+func (h *CategoryHandler) GetCategoryServices(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	slug := chi.URLParam(r, "slug")
+
+	category, err := h.categoryService.GetCategoryBySlug(ctx, slug)
+	if err != nil {
+		if errors.Is(err, service.ErrCategoryNotFound) {
+			RespondJSON(w, http.StatusNotFound, dto.NewErrorResponse(
+				dto.ErrorCodeResourceNotFound,
+				"Category not found",
+				nil,
+			))
+			return
+		}
+
+		log.Error().Err(err).Str("slug", slug).Msg("Failed to get category")
+		RespondError(w, err)
+		return
+	}
+
+	filter, err := parseServiceFilter(r)
+	if err != nil {
+		RespondJSON(w, http.StatusBadRequest, dto.NewErrorResponse(
+			dto.ErrorCodeInvalidRequest,
+			err.Error(),
+			nil,
+		))
+		return
+	}
+	filter.Category = category.Slug
+
+	page, err := h.serviceService.SearchServices(ctx, filter)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidServiceFilter) {
+			RespondJSON(w, http.StatusBadRequest, dto.NewErrorResponse(
+				dto.ErrorCodeInvalidRequest,
+				err.Error(),
+				nil,
+			))
+			return
+		}
+
+		log.Error().Err(err).Str("slug", slug).Msg("Failed to get category services")
+		RespondError(w, err)
+		return
+	}
+
+	RespondCacheableJSON(w, r, dto.NewSuccessResponse(dto.MapCategoryServicesToResponse(*category, *page)), latestUpdate(page.Services, page.Prices))
+}
@@ -1,12 +1,14 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"net/http"
 
 	"github.com/rs/zerolog/log"
 	"github.com/svenskhalsovard/api/internal/dto"
 	"github.com/svenskhalsovard/api/internal/entity"
+	"github.com/svenskhalsovard/api/internal/middleware"
 	"github.com/svenskhalsovard/api/internal/service"
 )
 
@@ -17,9 +19,10 @@ type CheckoutHandler struct {
 
 // CheckoutService defines the interface for checkout business logic
 type CheckoutService interface {
-	InitiateCheckout(ctx http.Context, req *service.CheckoutRequest) (*service.CheckoutResult, error)
-	ProcessPayment(ctx http.Context, paymentID int64, paymentMethod string) error
-	VerifyPayment(ctx http.Context, paymentID int64) (*entity.Payment, error)
+	CreateQuote(ctx context.Context, req *service.QuoteRequest) (*entity.CheckoutQuote, error)
+	InitiateCheckout(ctx context.Context, req *service.CheckoutRequest) (*service.CheckoutResult, error)
+	ProcessPayment(ctx context.Context, paymentID int64, paymentMethod string) (*service.PaymentResult, error)
+	VerifyPayment(ctx context.Context, paymentID int64) (*entity.Payment, error)
 }
 
 // NewCheckoutHandler creates a new CheckoutHandler
@@ -29,35 +32,27 @@ func NewCheckoutHandler(service CheckoutService) *CheckoutHandler {
 	}
 }
 
-// InitiateCheckout handles the request to initiate a checkout
-func (h *CheckoutHandler) InitiateCheckout(w http.ResponseWriter, r *http.Request) {
+// CreateQuote handles the request to price an order on the server
+func (h *CheckoutHandler) CreateQuote(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	var req dto.CheckoutRequest
+	var req dto.QuoteRequest
 	if err := ParseJSON(r, &req); err != nil {
-		log.Debug().Err(err).Msg("Invalid checkout request")
+		log.Debug().Err(err).Msg("Invalid quote request")
 		RespondJSON(w, http.StatusBadRequest, dto.NewErrorResponse(
 			dto.ErrorCodeInvalidRequest,
-			"Invalid checkout request",
+			"Invalid quote request",
 			err.Error(),
 		))
 		return
 	}
 
 	// Map DTO to service model
-	serviceReq := &service.CheckoutRequest{
-		Customer: entity.Customer{
-			FirstName:      req.Customer.FirstName,
-			LastName:       req.Customer.LastName,
-			Email:          req.Customer.Email,
-			Phone:          req.Customer.Phone,
-			StreetAddress:  req.Customer.StreetAddress,
-			PostalCode:     req.Customer.PostalCode,
-			City:           req.Customer.City,
-			AdditionalInfo: req.Customer.AdditionalInfo,
-		},
-		Items:       make([]service.CheckoutItem, 0, len(req.Items)),
-		TotalAmount: req.TotalAmount,
+	serviceReq := &service.QuoteRequest{
+		Items:        make([]service.CheckoutItem, 0, len(req.Items)),
+		PromoCode:    req.PromoCode,
+		GiftCardCode: req.GiftCardCode,
+		CartToken:    req.CartToken,
 	}
 
 	for _, item := range req.Items {
@@ -65,50 +60,141 @@ func (h *CheckoutHandler) InitiateCheckout(w http.ResponseWriter, r *http.Reques
 			ServiceID:    item.ServiceID,
 			Quantity:     item.Quantity,
 			PurchaseType: item.PurchaseType,
-			Price:        item.Price,
 		})
 	}
 
-	result, err := h.service.InitiateCheckout(ctx, serviceReq)
+	quote, err := h.service.CreateQuote(ctx, serviceReq)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to initiate checkout")
-		
-		// Handle business logic errors
-		var statusCode int
-		var errorCode string
-		
-		if errors.Is(err, service.ErrInvalidServices) || 
-		   errors.Is(err, service.ErrInvalidPurchaseType) || 
-		   errors.Is(err, service.ErrInvalidPrice) {
-			statusCode = http.StatusBadRequest
-			errorCode = dto.ErrorCodeInvalidRequest
-		} else {
-			statusCode = http.StatusInternalServerError
-			errorCode = dto.ErrorCodeInternalServerError
-		}
-		
-		RespondJSON(w, statusCode, dto.NewErrorResponse(
-			errorCode,
+		log.Error().Err(err).Msg("Failed to create quote")
+		respondCheckoutError(w, err)
+		return
+	}
+
+	RespondJSON(w, http.StatusCreated, dto.NewSuccessResponse(dto.MapQuoteToResponse(*quote)))
+}
+
+// InitiateCheckout handles the request to initiate a checkout for a quote
+func (h *CheckoutHandler) InitiateCheckout(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req dto.CheckoutRequest
+	if err := ParseJSON(r, &req); err != nil {
+		log.Debug().Err(err).Msg("Invalid checkout request")
+		RespondJSON(w, http.StatusBadRequest, dto.NewErrorResponse(
+			dto.ErrorCodeInvalidRequest,
+			"Invalid checkout request",
 			err.Error(),
-			nil,
 		))
 		return
 	}
 
-	// Map service model to DTO
+	// Map DTO to service model
+	serviceReq := &service.CheckoutRequest{
+		Customer:        dto.MapCustomerRequestToEntity(req.Customer),
+		QuoteID:         req.QuoteID,
+		RecoveryConsent: req.RecoveryConsent,
+		NationalID:      req.Customer.NationalID,
+		Consents: service.CheckoutConsents{
+			TermsVersion:   req.Consents.TermsVersion,
+			MarketingEmail: req.Consents.MarketingEmail,
+			SMSReminders:   req.Consents.SMSReminders,
+		},
+		Origin: service.ConsentOrigin{
+			Source:    entity.ConsentSourceCheckout,
+			IPAddress: ClientIP(r),
+			UserAgent: r.UserAgent(),
+		},
+	}
+
+	// Place the order for the logged in customer, if any
+	if customer := middleware.CustomerFromContext(ctx); customer != nil {
+		serviceReq.CustomerID = customer.ID
+	}
+
+	result, err := h.service.InitiateCheckout(ctx, serviceReq)
+	if err != nil {
+		log.Error().Err(err).Str("quoteID", req.QuoteID).Msg("Failed to initiate checkout")
+		respondCheckoutError(w, err)
+		return
+	}
+
+	RespondJSON(w, http.StatusCreated, dto.NewSuccessResponse(mapCheckoutResult(result)))
+}
+
+// mapCheckoutResult maps a checkout result to a CheckoutResponse. Orders paid in
+// full by gift card are completed at once and include their booking.
+func mapCheckoutResult(result *service.CheckoutResult) dto.CheckoutResponse {
 	response := dto.CheckoutResponse{
 		PaymentID:      result.PaymentID,
 		SveaOrderID:    result.SveaOrderID,
 		Status:         result.Status,
 		OrderReference: result.PaymentID,
+		DiscountAmount: result.DiscountAmount,
+		GiftCardAmount: result.GiftCardAmount,
+		TotalAmount:    result.TotalAmount,
 		CheckoutUI: dto.CheckoutUIResponse{
 			HTML:      result.SveaCheckoutUI.HTML,
 			JavaScript: result.SveaCheckoutUI.JavaScript,
 			Snippet:   result.SveaCheckoutUI.Snippet,
 		},
 	}
+	if result.Booking != nil {
+		booking := dto.MapPaymentResultToResponse(result.Booking.Status, result.Booking.Booking, result.Booking.GiftCards)
+		response.Booking = &booking
+	}
+	return response
+}
+
+// respondCheckoutError maps quote and checkout errors to error responses
+func respondCheckoutError(w http.ResponseWriter, err error) {
+	var statusCode int
+	var errorCode string
 
-	RespondJSON(w, http.StatusCreated, dto.NewSuccessResponse(response))
+	if errors.Is(err, service.ErrInvalidServices) ||
+		errors.Is(err, service.ErrInvalidPurchaseType) ||
+		errors.Is(err, service.ErrInvalidNationalID) {
+		statusCode = http.StatusBadRequest
+		errorCode = dto.ErrorCodeInvalidRequest
+	} else if errors.Is(err, service.ErrPromoCodeNotFound) ||
+		errors.Is(err, service.ErrPromoCodeNotValid) ||
+		errors.Is(err, service.ErrPromoCodeUsageLimit) ||
+		errors.Is(err, service.ErrPromoCodeMinimumNotMet) ||
+		errors.Is(err, service.ErrPromoCodeNotApplicable) {
+		statusCode = http.StatusBadRequest
+		errorCode = dto.ErrorCodeInvalidPromoCode
+	} else if errors.Is(err, service.ErrGiftCardNotFound) ||
+		errors.Is(err, service.ErrGiftCardNotValid) ||
+		errors.Is(err, service.ErrGiftCardNotApplicable) ||
+		errors.Is(err, entity.ErrInsufficientGiftCardBalance) {
+		statusCode = http.StatusBadRequest
+		errorCode = dto.ErrorCodeInvalidGiftCard
+	} else if errors.Is(err, service.ErrCartNotFound) ||
+		errors.Is(err, service.ErrQuoteNotFound) {
+		statusCode = http.StatusNotFound
+		errorCode = dto.ErrorCodeResourceNotFound
+	} else if errors.Is(err, service.ErrCartCheckedOut) ||
+		errors.Is(err, service.ErrInvalidCart) {
+		statusCode = http.StatusBadRequest
+		errorCode = dto.ErrorCodeInvalidRequest
+	} else if errors.Is(err, service.ErrQuoteNotValid) {
+		statusCode = http.StatusConflict
+		errorCode = dto.ErrorCodeQuoteExpired
+	} else if errors.Is(err, service.ErrTermsNotAccepted) {
+		statusCode = http.StatusConflict
+		errorCode = dto.ErrorCodeTermsNotAccepted
+	} else if isPaymentConflict(err) {
+		statusCode = http.StatusConflict
+		errorCode = dto.ErrorCodePaymentConflict
+	} else {
+		statusCode = http.StatusInternalServerError
+		errorCode = dto.ErrorCodeInternalServerError
+	}
+
+	RespondJSON(w, statusCode, dto.NewErrorResponse(
+		errorCode,
+		err.Error(),
+		nil,
+	))
 }
 
 // ProcessPayment handles the request to process a payment
@@ -126,7 +212,7 @@ func (h *CheckoutHandler) ProcessPayment(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err := h.service.ProcessPayment(ctx, req.PaymentID, req.PaymentMethod)
+	result, err := h.service.ProcessPayment(ctx, req.PaymentID, req.PaymentMethod)
 	if err != nil {
 		log.Error().Err(err).Int64("paymentID", req.PaymentID).Str("method", req.PaymentMethod).Msg("Failed to process payment")
 		
@@ -135,9 +221,13 @@ func (h *CheckoutHandler) ProcessPayment(w http.ResponseWriter, r *http.Request)
 		var errorCode string
 		
 		if errors.Is(err, service.ErrPaymentNotFound) || 
-		   errors.Is(err, service.ErrInvalidPaymentStatus) {
+		   errors.Is(err, service.ErrNationalIDRequired) ||
+		   errors.Is(err, service.ErrCustomerUnderage) {
 			statusCode = http.StatusBadRequest
 			errorCode = dto.ErrorCodeInvalidRequest
+		} else if isPaymentConflict(err) {
+			statusCode = http.StatusConflict
+			errorCode = dto.ErrorCodePaymentConflict
 		} else if errors.Is(err, service.ErrPaymentFailed) {
 			statusCode = http.StatusBadRequest
 			errorCode = dto.ErrorCodePaymentFailed
@@ -157,9 +247,13 @@ func (h *CheckoutHandler) ProcessPayment(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	RespondJSON(w, http.StatusOK, dto.NewSuccessResponse(map[string]string{
-		"status": "success",
-	}))
+	// The payment was taken but its booking is created later
+	statusCode := http.StatusOK
+	if result.Status == service.PaymentResultBookingPending {
+		statusCode = http.StatusAccepted
+	}
+
+	RespondJSON(w, statusCode, dto.NewSuccessResponse(dto.MapPaymentResultToResponse(result.Status, result.Booking, result.GiftCards)))
 }
 
 // VerifyPayment handles the request to verify a payment
@@ -186,6 +280,9 @@ func (h *CheckoutHandler) VerifyPayment(w http.ResponseWriter, r *http.Request)
 		if errors.Is(err, service.ErrPaymentNotFound) {
 			statusCode = http.StatusNotFound
 			errorCode = dto.ErrorCodeResourceNotFound
+		} else if isPaymentConflict(err) {
+			statusCode = http.StatusConflict
+			errorCode = dto.ErrorCodePaymentConflict
 		} else {
 			statusCode = http.StatusInternalServerError
 			errorCode = dto.ErrorCodeInternalServerError
@@ -201,4 +298,12 @@ func (h *CheckoutHandler) VerifyPayment(w http.ResponseWriter, r *http.Request)
 
 	response := dto.MapPaymentToResponse(*payment)
 	RespondJSON(w, http.StatusOK, dto.NewSuccessResponse(response))
-}
\ No newline at end of file
+}
+
+// isPaymentConflict reports whether an error means the payment is in a status
+// that does not allow the request, or was changed by a concurrent request
+func isPaymentConflict(err error) bool {
+	return errors.Is(err, service.ErrInvalidPaymentStatus) ||
+		errors.Is(err, entity.ErrInvalidPaymentTransition) ||
+		errors.Is(err, entity.ErrPaymentVersionConflict)
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/svenskhalsovard/api/internal/dto"
+	"github.com/svenskhalsovard/api/internal/entity"
+	"github.com/svenskhalsovard/api/internal/service"
+)
+
+// CheckoutRecoveryHandler handles requests from abandoned checkout reminder links
+type CheckoutRecoveryHandler struct {
+	service CheckoutRecoveryService
+}
+
+// CheckoutRecoveryService defines the interface for checkout recovery business logic
+type CheckoutRecoveryService interface {
+	ResumeCheckout(ctx context.Context, token string) (*service.CheckoutResult, error)
+	OptOut(ctx context.Context, token string, origin service.ConsentOrigin) error
+}
+
+// NewCheckoutRecoveryHandler creates a new CheckoutRecoveryHandler
+func NewCheckoutRecoveryHandler(service CheckoutRecoveryService) *CheckoutRecoveryHandler {
+	return &CheckoutRecoveryHandler{
+		service: service,
+	}
+}
+
+// ResumeCheckout handles the request to resume an abandoned checkout
+func (h *CheckoutRecoveryHandler) ResumeCheckout(w http.ResponseWriter, r *http.Request) {
+	token, ok := parseRecoveryToken(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.ResumeCheckout(r.Context(), token)
+	if err != nil {
+		respondRecoveryError(w, err)
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, dto.NewSuccessResponse(mapCheckoutResult(result)))
+}
+
+// OptOut handles the request to stop checkout reminder emails
+func (h *CheckoutRecoveryHandler) OptOut(w http.ResponseWriter, r *http.Request) {
+	token, ok := parseRecoveryToken(w, r)
+	if !ok {
+		return
+	}
+
+	origin := service.ConsentOrigin{
+		Source:    entity.ConsentSourceEmailLink,
+		IPAddress: ClientIP(r),
+		UserAgent: r.UserAgent(),
+	}
+
+	if err := h.service.OptOut(r.Context(), token, origin); err != nil {
+		respondRecoveryError(w, err)
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, dto.NewSuccessResponse(map[string]string{
+		"status": "opted_out",
+	}))
+}
+
+// parseRecoveryToken reads and validates the reminder token URL parameter
+func parseRecoveryToken(w http.ResponseWriter, r *http.Request) (string, bool) {
+	token := chi.URLParam(r, "token")
+	if err := validate.Var(token, "required,uuid"); err != nil {
+		RespondJSON(w, http.StatusBadRequest, dto.NewErrorResponse(
+			dto.ErrorCodeInvalidRequest,
+			"Invalid recovery token",
+			nil,
+		))
+		return "", false
+	}
+	return token, true
+}
+
+// respondRecoveryError maps checkout recovery errors to API error responses
+func respondRecoveryError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrRecoveryNotFound):
+		RespondJSON(w, http.StatusNotFound, dto.NewErrorResponse(
+			dto.ErrorCodeResourceNotFound,
+			"Checkout not found",
+			nil,
+		))
+	case errors.Is(err, service.ErrCheckoutNotResumable):
+		RespondJSON(w, http.StatusConflict, dto.NewErrorResponse(
+			dto.ErrorCodeInvalidRequest,
+			err.Error(),
+			nil,
+		))
+	default:
+		log.Error().Err(err).Msg("Failed to handle checkout recovery request")
+		RespondError(w, err)
+	}
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/svenskhalsovard/api/internal/dto"
+	"github.com/svenskhalsovard/api/internal/entity"
+	"github.com/svenskhalsovard/api/internal/middleware"
+	"github.com/svenskhalsovard/api/internal/service"
+)
+
+// ConsentHandler handles requests about customer consents
+type ConsentHandler struct {
+	service ConsentService
+}
+
+// ConsentService defines the interface for consent business logic
+type ConsentService interface {
+	TermsVersion() string
+	PrivacyPolicyVersion() string
+	GetConsents(ctx context.Context, customerID int64) ([]entity.CustomerConsent, error)
+	WithdrawConsent(ctx context.Context, customerID int64, consentType string, origin service.ConsentOrigin) error
+}
+
+// NewConsentHandler creates a new ConsentHandler
+func NewConsentHandler(service ConsentService) *ConsentHandler {
+	return &ConsentHandler{
+		service: service,
+	}
+}
+
+// GetPolicy handles the request for the current terms and privacy policy
+// versions, which checkout consents are given under
+func (h *ConsentHandler) GetPolicy(w http.ResponseWriter, r *http.Request) {
+	RespondJSON(w, http.StatusOK, dto.NewSuccessResponse(dto.ConsentPolicyResponse{
+		TermsVersion:         h.service.TermsVersion(),
+		PrivacyPolicyVersion: h.service.PrivacyPolicyVersion(),
+	}))
+}
+
+// GetConsents handles the request for the logged in customer's consents
+func (h *ConsentHandler) GetConsents(w http.ResponseWriter, r *http.Request) {
+	customer := middleware.CustomerFromContext(r.Context())
+
+	consents, err := h.service.GetConsents(r.Context(), customer.ID)
+	if err != nil {
+		RespondError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	RespondJSON(w, http.StatusOK, dto.NewSuccessResponse(dto.MapConsentsToResponse(consents)))
+}
+
+// WithdrawConsent handles the request of the logged in customer to withdraw a
+// consent
+func (h *ConsentHandler) WithdrawConsent(w http.ResponseWriter, r *http.Request) {
+	customer := middleware.CustomerFromContext(r.Context())
+	consentType := chi.URLParam(r, "type")
+
+	origin := service.ConsentOrigin{
+		Source:    entity.ConsentSourceAccount,
+		IPAddress: ClientIP(r),
+		UserAgent: r.UserAgent(),
+	}
+
+	if err := h.service.WithdrawConsent(r.Context(), customer.ID, consentType, origin); err != nil {
+		if errors.Is(err, service.ErrInvalidConsentType) ||
+			errors.Is(err, service.ErrConsentNotWithdrawable) {
+			RespondJSON(w, http.StatusBadRequest, dto.NewErrorResponse(
+				dto.ErrorCodeInvalidRequest,
+				err.Error(),
+				nil,
+			))
+			return
+		}
+
+		log.Error().Err(err).Int64("customerID", customer.ID).Str("consentType", consentType).Msg("Failed to withdraw consent")
+		RespondError(w, err)
+		return
+	}
+
+	consents, err := h.service.GetConsents(r.Context(), customer.ID)
+	if err != nil {
+		RespondError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	RespondJSON(w, http.StatusOK, dto.NewSuccessResponse(dto.MapConsentsToResponse(consents)))
+}
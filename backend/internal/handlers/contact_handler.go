@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog/log"
+	"github.com/svenskhalsovard/api/internal/dto"
+	"github.com/svenskhalsovard/api/internal/service"
+)
+
+// ContactHandler handles contact form requests
+type ContactHandler struct {
+	service ContactService
+}
+
+// ContactService defines the interface for contact form business logic
+type ContactService interface {
+	IssueFormToken() string
+	SubmitMessage(ctx context.Context, req *service.ContactRequest) error
+}
+
+// NewContactHandler creates a new ContactHandler
+func NewContactHandler(service ContactService) *ContactHandler {
+	return &ContactHandler{
+		service: service,
+	}
+}
+
+// GetFormToken handles the request for a token to send with the contact form
+func (h *ContactHandler) GetFormToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	RespondJSON(w, http.StatusOK, dto.NewSuccessResponse(dto.ContactFormTokenResponse{
+		FormToken: h.service.IssueFormToken(),
+	}))
+}
+
+// SubmitMessage handles a contact form submission
+func (h *ContactHandler) SubmitMessage(w http.ResponseWriter, r *http.Request) {
+	var req dto.ContactRequest
+	if err := ParseJSON(r, &req); err != nil {
+		log.Debug().Err(err).Msg("Invalid contact request")
+
+		// Report the fields that failed validation
+		var validationErrors validator.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			RespondError(w, err)
+			return
+		}
+
+		RespondJSON(w, http.StatusBadRequest, dto.NewErrorResponse(
+			dto.ErrorCodeInvalidRequest,
+			"Invalid contact request",
+			err.Error(),
+		))
+		return
+	}
+
+	err := h.service.SubmitMessage(r.Context(), &service.ContactRequest{
+		Name:      req.Name,
+		Email:     req.Email,
+		Subject:   req.Subject,
+		Message:   req.Message,
+		Website:   req.Website,
+		FormToken: req.FormToken,
+		IPAddress: ClientIP(r),
+		UserAgent: r.UserAgent(),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidFormToken), errors.Is(err, service.ErrSubmittedTooQuickly):
+			RespondJSON(w, http.StatusBadRequest, dto.NewErrorResponse(
+				dto.ErrorCodeInvalidRequest,
+				err.Error(),
+				nil,
+			))
+		case errors.Is(err, service.ErrContactRateLimited):
+			RespondJSON(w, http.StatusTooManyRequests, dto.NewErrorResponse(
+				dto.ErrorCodeRateLimitExceeded,
+				err.Error(),
+				nil,
+			))
+		default:
+			log.Error().Err(err).Msg("Failed to submit contact message")
+			RespondError(w, err)
+		}
+		return
+	}
+
+	RespondJSON(w, http.StatusCreated, dto.NewSuccessResponse(map[string]string{
+		"status": "received",
+	}))
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog/log"
+	"github.com/svenskhalsovard/api/internal/dto"
+	"github.com/svenskhalsovard/api/internal/entity"
+	"github.com/svenskhalsovard/api/internal/middleware"
+	"github.com/svenskhalsovard/api/internal/personnummer"
+	"github.com/svenskhalsovard/api/internal/service"
+)
+
+// CustomerHandler handles requests of logged in customers for their own account
+type CustomerHandler struct {
+	service CustomerService
+}
+
+// CustomerService defines the interface for customer account business logic
+type CustomerService interface {
+	UpdateProfile(ctx context.Context, customerID int64, profile entity.Customer, nationalID string) (*entity.Customer, error)
+	NationalID(customer *entity.Customer) (personnummer.Number, error)
+	GetBookingHistory(ctx context.Context, customerID int64) ([]entity.BookingWithItems, error)
+}
+
+// NewCustomerHandler creates a new CustomerHandler
+func NewCustomerHandler(service CustomerService) *CustomerHandler {
+	return &CustomerHandler{
+		service: service,
+	}
+}
+
+// GetProfile handles the request for the logged in customer's profile
+func (h *CustomerHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	customer := middleware.CustomerFromContext(r.Context())
+
+	response, err := h.mapProfile(customer)
+	if err != nil {
+		RespondError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	RespondJSON(w, http.StatusOK, dto.NewSuccessResponse(response))
+}
+
+// UpdateProfile handles the request to update the logged in customer's profile
+func (h *CustomerHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	customer := middleware.CustomerFromContext(r.Context())
+
+	var req dto.UpdateProfileRequest
+	if err := ParseJSON(r, &req); err != nil {
+		log.Debug().Err(err).Msg("Invalid profile update request")
+
+		// Report the fields that failed validation
+		var validationErrors validator.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			RespondError(w, err)
+			return
+		}
+
+		RespondJSON(w, http.StatusBadRequest, dto.NewErrorResponse(
+			dto.ErrorCodeInvalidRequest,
+			"Invalid profile update request",
+			err.Error(),
+		))
+		return
+	}
+
+	updated, err := h.service.UpdateProfile(r.Context(), customer.ID, dto.MapUpdateProfileRequestToEntity(req), req.NationalID)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidNationalID) {
+			RespondJSON(w, http.StatusBadRequest, dto.NewErrorResponse(
+				dto.ErrorCodeInvalidRequest,
+				err.Error(),
+				nil,
+			))
+			return
+		}
+		if errors.Is(err, service.ErrCustomerNotFound) {
+			RespondJSON(w, http.StatusNotFound, dto.NewErrorResponse(
+				dto.ErrorCodeResourceNotFound,
+				"Customer not found",
+				nil,
+			))
+			return
+		}
+
+		log.Error().Err(err).Int64("customerID", customer.ID).Msg("Failed to update profile")
+		RespondError(w, err)
+		return
+	}
+
+	response, err := h.mapProfile(updated)
+	if err != nil {
+		RespondError(w, err)
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, dto.NewSuccessResponse(response))
+}
+
+// GetBookings handles the request for the logged in customer's booking history
+func (h *CustomerHandler) GetBookings(w http.ResponseWriter, r *http.Request) {
+	customer := middleware.CustomerFromContext(r.Context())
+
+	bookings, err := h.service.GetBookingHistory(r.Context(), customer.ID)
+	if err != nil {
+		log.Error().Err(err).Int64("customerID", customer.ID).Msg("Failed to get booking history")
+		RespondError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	RespondJSON(w, http.StatusOK, dto.NewSuccessResponse(dto.MapBookingsToCustomerResponse(bookings)))
+}
+
+// mapProfile maps a customer to a CustomerResponse with the national ID masked
+func (h *CustomerHandler) mapProfile(customer *entity.Customer) (dto.CustomerResponse, error) {
+	response := dto.MapCustomerToResponse(customer)
+
+	nationalID, err := h.service.NationalID(customer)
+	if err != nil {
+		return response, err
+	}
+	response.NationalID = nationalID.Masked()
+
+	return response, nil
+}
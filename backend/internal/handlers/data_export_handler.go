@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/svenskhalsovard/api/internal/dto"
+	"github.com/svenskhalsovard/api/internal/entity"
+	"github.com/svenskhalsovard/api/internal/middleware"
+	"github.com/svenskhalsovard/api/internal/service"
+)
+
+// DataExportHandler handles requests for GDPR data exports
+type DataExportHandler struct {
+	service DataExportService
+}
+
+// DataExportService defines the interface for data export business logic
+type DataExportService interface {
+	CreateExport(ctx context.Context, customerID int64, requestedBy string) (*service.DataExportLink, error)
+	DownloadExport(ctx context.Context, token string) (*service.DataExportArchive, error)
+}
+
+// NewDataExportHandler creates a new DataExportHandler
+func NewDataExportHandler(service DataExportService) *DataExportHandler {
+	return &DataExportHandler{
+		service: service,
+	}
+}
+
+// CreateExport handles the request of the logged in customer for an export of
+// their personal data
+func (h *DataExportHandler) CreateExport(w http.ResponseWriter, r *http.Request) {
+	customer := middleware.CustomerFromContext(r.Context())
+
+	link, err := h.service.CreateExport(r.Context(), customer.ID, entity.DataExportRequestedByCustomer)
+	if err != nil {
+		log.Error().Err(err).Int64("customerID", customer.ID).Msg("Failed to create data export")
+		RespondError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	RespondJSON(w, http.StatusCreated, dto.NewSuccessResponse(dto.DataExportResponse{
+		DownloadURL: link.URL,
+		ExpiresAt:   link.ExpiresAt,
+	}))
+}
+
+// DownloadExport handles the request to download a data export archive
+func (h *DataExportHandler) DownloadExport(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+	if err := validate.Var(token, "required,hexadecimal,len=64"); err != nil {
+		RespondJSON(w, http.StatusBadRequest, dto.NewErrorResponse(
+			dto.ErrorCodeInvalidRequest,
+			"Invalid download token",
+			nil,
+		))
+		return
+	}
+
+	archive, err := h.service.DownloadExport(r.Context(), token)
+	if err != nil {
+		var statusCode int
+		var errorCode string
+
+		if errors.Is(err, service.ErrDataExportNotFound) {
+			statusCode = http.StatusNotFound
+			errorCode = dto.ErrorCodeResourceNotFound
+		} else if errors.Is(err, service.ErrDataExportExpired) {
+			statusCode = http.StatusGone
+			errorCode = dto.ErrorCodeResourceNotFound
+		} else {
+			log.Error().Err(err).Msg("Failed to download data export")
+			statusCode = http.StatusInternalServerError
+			errorCode = dto.ErrorCodeInternalServerError
+		}
+
+		RespondJSON(w, statusCode, dto.NewErrorResponse(
+			errorCode,
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", archive.FileName))
+	w.Header().Set("Content-Length", strconv.Itoa(len(archive.Data)))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(archive.Data)
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+	"github.com/svenskhalsovard/api/internal/dto"
+	"github.com/svenskhalsovard/api/internal/middleware"
+	"github.com/svenskhalsovard/api/internal/service"
+)
+
+// ErasureHandler handles requests of customers to erase their account
+type ErasureHandler struct {
+	service ErasureService
+}
+
+// ErasureService defines the interface for erasure business logic
+type ErasureService interface {
+	EraseCustomer(ctx context.Context, customerID int64) (*service.ErasureResult, error)
+}
+
+// NewErasureHandler creates a new ErasureHandler
+func NewErasureHandler(service ErasureService) *ErasureHandler {
+	return &ErasureHandler{
+		service: service,
+	}
+}
+
+// EraseAccount handles the request of the logged in customer to erase their
+// account and personal data
+func (h *ErasureHandler) EraseAccount(w http.ResponseWriter, r *http.Request) {
+	customer := middleware.CustomerFromContext(r.Context())
+
+	result, err := h.service.EraseCustomer(r.Context(), customer.ID)
+	if err != nil {
+		log.Error().Err(err).Int64("customerID", customer.ID).Msg("Failed to erase customer")
+		RespondError(w, err)
+		return
+	}
+
+	response := dto.ErasureResponse{
+		ErasedRecords:   result.Erased,
+		RetainedRecords: result.Retained,
+	}
+	if !result.RetainedUntil.IsZero() {
+		response.RetainedUntil = &result.RetainedUntil
+	}
+
+	RespondJSON(w, http.StatusOK, dto.NewSuccessResponse(response))
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/svenskhalsovard/api/internal/dto"
+	"github.com/svenskhalsovard/api/internal/entity"
+	"github.com/svenskhalsovard/api/internal/service"
+)
+
+// GiftCardHandler handles gift card requests
+type GiftCardHandler struct {
+	service GiftCardService
+}
+
+// GiftCardService defines the interface for gift card business logic
+type GiftCardService interface {
+	GetGiftCard(ctx context.Context, code string) (*entity.GiftCard, error)
+}
+
+// NewGiftCardHandler creates a new GiftCardHandler
+func NewGiftCardHandler(service GiftCardService) *GiftCardHandler {
+	return &GiftCardHandler{
+		service: service,
+	}
+}
+
+// GetGiftCard handles the request to check the balance of a gift card
+func (h *GiftCardHandler) GetGiftCard(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	code := chi.URLParam(r, "code")
+
+	giftCard, err := h.service.GetGiftCard(ctx, code)
+	if err != nil {
+		if errors.Is(err, service.ErrGiftCardNotFound) {
+			RespondJSON(w, http.StatusNotFound, dto.NewErrorResponse(
+				dto.ErrorCodeResourceNotFound,
+				"Gift card not found",
+				nil,
+			))
+			return
+		}
+
+		log.Error().Err(err).Msg("Failed to get gift card")
+		RespondError(w, err)
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, dto.NewSuccessResponse(dto.MapGiftCardToResponse(*giftCard)))
+}
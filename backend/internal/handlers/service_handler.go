@@ -1,12 +1,19 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
 	"github.com/rs/zerolog/log"
 	"github.com/svenskhalsovard/api/internal/dto"
 	"github.com/svenskhalsovard/api/internal/entity"
+	"github.com/svenskhalsovard/api/internal/service"
+	"github.com/svenskhalsovard/api/internal/slug"
 )
 
 // ServiceHandler handles service-related requests
@@ -16,9 +23,13 @@ type ServiceHandler struct {
 
 // ServiceService defines the interface for service business logic
 type ServiceService interface {
-	GetServices(ctx http.Context) ([]entity.Service, error)
-	GetServiceByID(ctx http.Context, id int64) (*entity.Service, error)
-	GetServiceWithFeatures(ctx http.Context, id int64) (*entity.ServiceWithFeatures, error)
+	GetServices(ctx context.Context) ([]entity.Service, error)
+	SearchServices(ctx context.Context, filter entity.ServiceFilter) (*entity.ServicePage, error)
+	GetServiceByID(ctx context.Context, id int64) (*entity.Service, error)
+	GetServiceWithFeatures(ctx context.Context, id int64) (*entity.ServiceWithFeatures, error)
+	GetServiceWithFeaturesBySlug(ctx context.Context, slug string) (*entity.ServiceWithFeatures, error)
+	CreateService(ctx context.Context, service *entity.Service) error
+	UpdateService(ctx context.Context, service *entity.Service) error
 }
 
 // NewServiceHandler creates a new ServiceHandler
@@ -28,34 +39,112 @@ func NewServiceHandler(service ServiceService) *ServiceHandler {
 	}
 }
 
-// GetServices handles the request to get all services
+// GetServices handles the request to list services with optional search, filters, sorting and pagination
 func (h *ServiceHandler) GetServices(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	services, err := h.service.GetServices(ctx)
+	filter, err := parseServiceFilter(r)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to get services")
-		RespondError(w, err)
+		RespondJSON(w, http.StatusBadRequest, dto.NewErrorResponse(
+			dto.ErrorCodeInvalidRequest,
+			err.Error(),
+			nil,
+		))
 		return
 	}
 
-	// Simple implementation without features
-	response := make([]dto.ServiceResponse, 0, len(services))
-	for _, service := range services {
-		response = append(response, dto.MapServiceToResponse(service, nil))
+	page, err := h.service.SearchServices(ctx, filter)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidServiceFilter) || errors.Is(err, service.ErrCategoryNotFound) {
+			RespondJSON(w, http.StatusBadRequest, dto.NewErrorResponse(
+				dto.ErrorCodeInvalidRequest,
+				err.Error(),
+				nil,
+			))
+			return
+		}
+
+		log.Error().Err(err).Msg("Failed to get services")
+		RespondError(w, err)
+		return
 	}
 
-	RespondJSON(w, http.StatusOK, dto.NewSuccessResponse(dto.ServiceListResponse{
-		Services: response,
-		Count:    len(response),
-	}))
+	RespondCacheableJSON(w, r, dto.NewSuccessResponse(dto.MapServicePageToResponse(*page)), latestUpdate(page.Services, page.Prices))
 }
 
-// GetServiceByID handles the request to get a service by ID
+// GetServiceByID handles the request to get a service by numeric ID or by slug.
+// Requests for a slug the service had before being renamed are redirected to its current slug.
 func (h *ServiceHandler) GetServiceByID(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+	idOrSlug := chi.URLParam(r, "id")
+
+	var serviceWithFeatures *entity.ServiceWithFeatures
+	var err error
+	if slug.IsNumeric(idOrSlug) {
+		id, parseErr := ParseIDParam(r, "id")
+		if parseErr != nil {
+			RespondJSON(w, http.StatusBadRequest, dto.NewErrorResponse(
+				dto.ErrorCodeInvalidRequest,
+				parseErr.Error(),
+				nil,
+			))
+			return
+		}
+		serviceWithFeatures, err = h.service.GetServiceWithFeatures(ctx, id)
+	} else {
+		serviceWithFeatures, err = h.service.GetServiceWithFeaturesBySlug(ctx, idOrSlug)
+	}
 
-	id, err := ParseIDParam(r, "id")
+	if err != nil {
+		var movedErr *service.ServiceMovedError
+		if errors.As(err, &movedErr) {
+			location := strings.TrimSuffix(r.URL.Path, idOrSlug) + movedErr.Slug
+			http.Redirect(w, r, location, http.StatusMovedPermanently)
+			return
+		}
+
+		if errors.Is(err, service.ErrServiceNotFound) {
+			RespondJSON(w, http.StatusNotFound, dto.NewErrorResponse(
+				dto.ErrorCodeResourceNotFound,
+				"Service not found",
+				nil,
+			))
+			return
+		}
+
+		log.Error().Err(err).Str("service", idOrSlug).Msg("Failed to get service")
+		RespondError(w, err)
+		return
+	}
+
+	response := dto.MapServiceWithFeaturesToResponse(*serviceWithFeatures)
+	prices := map[int64]entity.ServicePrice{}
+	if serviceWithFeatures.Price != nil {
+		prices[serviceWithFeatures.Service.ID] = *serviceWithFeatures.Price
+	}
+	RespondCacheableJSON(w, r, dto.NewSuccessResponse(response), latestUpdate([]entity.Service{serviceWithFeatures.Service}, prices))
+}
+
+// CreateService handles the staff request to add a service
+func (h *ServiceHandler) CreateService(w http.ResponseWriter, r *http.Request) {
+	req, ok := parseServiceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	svc := dto.MapServiceRequestToEntity(req)
+	if err := h.service.CreateService(r.Context(), &svc); err != nil {
+		respondServiceWriteError(w, err, "Failed to create service")
+		return
+	}
+
+	RespondJSON(w, http.StatusCreated, dto.NewSuccessResponse(dto.MapServiceToResponse(svc, nil, nil)))
+}
+
+// UpdateService handles the staff request to change a service. Renaming a
+// service gives it a new slug; the old one redirects to it.
+func (h *ServiceHandler) UpdateService(w http.ResponseWriter, r *http.Request) {
+	serviceID, err := ParseIDParam(r, "id")
 	if err != nil {
 		RespondJSON(w, http.StatusBadRequest, dto.NewErrorResponse(
 			dto.ErrorCodeInvalidRequest,
@@ -65,22 +154,122 @@ func (h *ServiceHandler) GetServiceByID(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	serviceWithFeatures, err := h.service.GetServiceWithFeatures(ctx, id)
-	if err != nil {
-		log.Error().Err(err).Int64("serviceID", id).Msg("Failed to get service")
-		RespondError(w, err)
+	req, ok := parseServiceRequest(w, r)
+	if !ok {
 		return
 	}
 
-	if serviceWithFeatures == nil {
-		RespondJSON(w, http.StatusNotFound, dto.NewErrorResponse(
-			dto.ErrorCodeResourceNotFound,
-			"Service not found",
+	svc := dto.MapServiceRequestToEntity(req)
+	svc.ID = serviceID
+	if err := h.service.UpdateService(r.Context(), &svc); err != nil {
+		if errors.Is(err, service.ErrServiceNotFound) {
+			RespondJSON(w, http.StatusNotFound, dto.NewErrorResponse(
+				dto.ErrorCodeResourceNotFound,
+				"Service not found",
+				nil,
+			))
+			return
+		}
+
+		respondServiceWriteError(w, err, "Failed to update service")
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, dto.NewSuccessResponse(dto.MapServiceToResponse(svc, nil, nil)))
+}
+
+// parseServiceRequest reads a staff service request, responding with the
+// validation errors when it is invalid
+func parseServiceRequest(w http.ResponseWriter, r *http.Request) (dto.ServiceRequest, bool) {
+	var req dto.ServiceRequest
+	if err := ParseJSON(r, &req); err != nil {
+		log.Debug().Err(err).Msg("Invalid service request")
+
+		// Report the fields that failed validation
+		var validationErrors validator.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			RespondError(w, err)
+			return req, false
+		}
+
+		RespondJSON(w, http.StatusBadRequest, dto.NewErrorResponse(
+			dto.ErrorCodeInvalidRequest,
+			"Invalid service request",
+			err.Error(),
+		))
+		return req, false
+	}
+
+	return req, true
+}
+
+// respondServiceWriteError responds to an error from creating or updating a service
+func respondServiceWriteError(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, service.ErrCategoryNotFound) {
+		RespondJSON(w, http.StatusBadRequest, dto.NewErrorResponse(
+			dto.ErrorCodeInvalidRequest,
+			err.Error(),
 			nil,
 		))
 		return
 	}
 
-	response := dto.MapServiceWithFeaturesToResponse(*serviceWithFeatures)
-	RespondJSON(w, http.StatusOK, dto.NewSuccessResponse(response))
-}
\ No newline at end of file
+	log.Error().Err(err).Msg(message)
+	RespondJSON(w, http.StatusInternalServerError, dto.NewErrorResponse(
+		dto.ErrorCodeInternalServerError,
+		message,
+		nil,
+	))
+}
+
+// parseServiceFilter reads the service listing query parameters.
+// Sorting accepts a field name with an optional "-" prefix for descending order,
+// e.g. ?sort=-price, or an explicit ?order=desc.
+func parseServiceFilter(r *http.Request) (entity.ServiceFilter, error) {
+	query := r.URL.Query()
+
+	filter := entity.ServiceFilter{
+		Search:    strings.TrimSpace(query.Get("q")),
+		Category:  strings.TrimSpace(query.Get("category")),
+		SortBy:    query.Get("sort"),
+		SortOrder: strings.ToLower(query.Get("order")),
+		Cursor:    query.Get("cursor"),
+	}
+
+	if strings.HasPrefix(filter.SortBy, "-") {
+		filter.SortBy = strings.TrimPrefix(filter.SortBy, "-")
+		filter.SortOrder = entity.SortOrderDesc
+	}
+
+	var err error
+	if filter.SubscriptionOnly, err = ParseQueryBool(r, "subscriptionOnly"); err != nil {
+		return filter, err
+	}
+	if filter.MinPrice, err = ParseQueryFloat(r, "minPrice"); err != nil {
+		return filter, err
+	}
+	if filter.MaxPrice, err = ParseQueryFloat(r, "maxPrice"); err != nil {
+		return filter, err
+	}
+	if filter.Limit, err = ParseQueryInt(r, "limit", 0); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+// latestUpdate returns the most recent UpdatedAt of the given services, or the
+// last time campaigns changed their prices when that is later. Campaigns
+// ending count as changes, so the time never goes back when a price does.
+func latestUpdate(services []entity.Service, prices map[int64]entity.ServicePrice) time.Time {
+	var latest time.Time
+	for _, service := range services {
+		if service.UpdatedAt.After(latest) {
+			latest = service.UpdatedAt
+		}
+		if price, ok := prices[service.ID]; ok && price.ChangedAt != nil && price.ChangedAt.After(latest) {
+			latest = *price.ChangedAt
+		}
+	}
+	return latest
+}
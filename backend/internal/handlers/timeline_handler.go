@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+	"github.com/svenskhalsovard/api/internal/dto"
+	"github.com/svenskhalsovard/api/internal/entity"
+	"github.com/svenskhalsovard/api/internal/service"
+)
+
+// TimelineHandler handles staff requests for the timelines of orders
+type TimelineHandler struct {
+	service TimelineService
+}
+
+// TimelineService defines the interface for order timeline business logic
+type TimelineService interface {
+	GetPaymentTimeline(ctx context.Context, paymentID int64) ([]entity.TimelineEvent, error)
+	GetBookingTimeline(ctx context.Context, bookingID int64) ([]entity.TimelineEvent, error)
+}
+
+// NewTimelineHandler creates a new TimelineHandler
+func NewTimelineHandler(service TimelineService) *TimelineHandler {
+	return &TimelineHandler{
+		service: service,
+	}
+}
+
+// GetPaymentTimeline handles the request for the timeline of a payment
+func (h *TimelineHandler) GetPaymentTimeline(w http.ResponseWriter, r *http.Request) {
+	h.respondTimeline(w, r, h.service.GetPaymentTimeline)
+}
+
+// GetBookingTimeline handles the request for the timeline of a booking
+func (h *TimelineHandler) GetBookingTimeline(w http.ResponseWriter, r *http.Request) {
+	h.respondTimeline(w, r, h.service.GetBookingTimeline)
+}
+
+// respondTimeline responds with the timeline get returns for the ID of the
+// request
+func (h *TimelineHandler) respondTimeline(w http.ResponseWriter, r *http.Request, get func(ctx context.Context, id int64) ([]entity.TimelineEvent, error)) {
+	id, err := ParseIDParam(r, "id")
+	if err != nil {
+		RespondJSON(w, http.StatusBadRequest, dto.NewErrorResponse(
+			dto.ErrorCodeInvalidRequest,
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	timeline, err := get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, service.ErrPaymentNotFound) || errors.Is(err, service.ErrBookingNotFound) {
+			RespondJSON(w, http.StatusNotFound, dto.NewErrorResponse(
+				dto.ErrorCodeResourceNotFound,
+				err.Error(),
+				nil,
+			))
+			return
+		}
+
+		log.Error().Err(err).Int64("id", id).Msg("Failed to get timeline")
+		RespondError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	RespondJSON(w, http.StatusOK, dto.NewSuccessResponse(timeline))
+}
@@ -1,12 +1,17 @@
 package handlers
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
@@ -30,6 +35,59 @@ func RespondJSON(w http.ResponseWriter, statusCode int, payload interface{}) {
 	w.Write(response)
 }
 
+// RespondCacheableJSON sends a 200 JSON response with a strong ETag computed from
+// the response body and a Last-Modified header, or a 304 Not Modified response
+// when the request's conditional headers show the client copy is current
+func RespondCacheableJSON(w http.ResponseWriter, r *http.Request, payload interface{}, lastModified time.Time) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to marshal JSON response")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	sum := sha256.Sum256(response)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	lastModified = lastModified.UTC().Truncate(time.Second)
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "public, no-cache")
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+	}
+
+	if isNotModified(r, etag, lastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
+
+// isNotModified evaluates If-None-Match and, when it is absent, If-Modified-Since
+func isNotModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		for _, candidate := range strings.Split(ifNoneMatch, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ifModifiedSince := r.Header.Get("If-Modified-Since"); ifModifiedSince != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(ifModifiedSince)
+		if err == nil && !lastModified.After(since) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // RespondError sends an error response
 func RespondError(w http.ResponseWriter, err error) {
 	var validationErrors validator.ValidationErrors
@@ -109,4 +167,53 @@ func ParseIDParam(r *http.Request, paramName string) (int64, error) {
 		return 0, fmt.Errorf("invalid %s parameter: %w", paramName, err)
 	}
 	return id, nil
-}
\ No newline at end of file
+}
+
+// ParseQueryInt parses an optional integer query parameter, returning defaultValue when absent
+func ParseQueryInt(r *http.Request, paramName string, defaultValue int) (int, error) {
+	valueStr := r.URL.Query().Get(paramName)
+	if valueStr == "" {
+		return defaultValue, nil
+	}
+	value, err := strconv.Atoi(valueStr)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s parameter: %w", paramName, err)
+	}
+	return value, nil
+}
+
+// ParseQueryFloat parses an optional decimal query parameter, returning nil when absent
+func ParseQueryFloat(r *http.Request, paramName string) (*float64, error) {
+	valueStr := r.URL.Query().Get(paramName)
+	if valueStr == "" {
+		return nil, nil
+	}
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s parameter: %w", paramName, err)
+	}
+	return &value, nil
+}
+
+// ParseQueryBool parses an optional boolean query parameter, returning false when absent
+func ParseQueryBool(r *http.Request, paramName string) (bool, error) {
+	valueStr := r.URL.Query().Get(paramName)
+	if valueStr == "" {
+		return false, nil
+	}
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s parameter: %w", paramName, err)
+	}
+	return value, nil
+}
+
+// ClientIP returns the IP address of the client. The RealIP middleware has
+// already applied X-Forwarded-For and X-Real-IP to RemoteAddr.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
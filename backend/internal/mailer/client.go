@@ -0,0 +1,77 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net/mail"
+	"net/smtp"
+	"time"
+
+	"github.com/svenskhalsovard/api/internal/config"
+)
+
+// Message represents a plain text email. ReplyTo is optional.
+type Message struct {
+	To      string
+	ReplyTo string
+	Subject string
+	Body    string
+}
+
+// Client sends email through an SMTP server
+type Client struct {
+	config config.MailConfig
+}
+
+// NewClient creates a new mail client
+func NewClient(config config.MailConfig) *Client {
+	return &Client{
+		config: config,
+	}
+}
+
+// Send sends a plain text email. The server is authenticated against only when
+// a username is configured.
+func (c *Client) Send(ctx context.Context, message Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, err := mail.ParseAddress(message.To); err != nil {
+		return fmt.Errorf("invalid recipient address: %w", err)
+	}
+
+	from := mail.Address{Name: c.config.FromName, Address: c.config.From}
+
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "From: %s\r\n", from.String())
+	fmt.Fprintf(&body, "To: %s\r\n", message.To)
+	if message.ReplyTo != "" {
+		replyTo, err := mail.ParseAddress(message.ReplyTo)
+		if err != nil {
+			return fmt.Errorf("invalid reply-to address: %w", err)
+		}
+		fmt.Fprintf(&body, "Reply-To: %s\r\n", replyTo.String())
+	}
+	fmt.Fprintf(&body, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&body, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	body.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	body.WriteString("\r\n")
+	body.WriteString(message.Body)
+
+	var auth smtp.Auth
+	if c.config.Username != "" {
+		auth = smtp.PlainAuth("", c.config.Username, c.config.Password, c.config.Host)
+	}
+
+	addr := fmt.Sprintf("%s:%d", c.config.Host, c.config.Port)
+	if err := smtp.SendMail(addr, auth, c.config.From, []string{message.To}, body.Bytes()); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/svenskhalsovard/api/internal/audit"
+)
+
+// AuditActor is a middleware that attributes the changes a request makes to an
+// anonymous customer. The customer auth middlewares replace the actor with the
+// logged in customer.
+func AuditActor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := audit.WithActor(r.Context(), audit.Customer(0))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
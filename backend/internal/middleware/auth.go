@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/svenskhalsovard/api/internal/audit"
+	"github.com/svenskhalsovard/api/internal/dto"
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// customerContextKey is the context key of the logged in customer
+type customerContextKey struct{}
+
+// SessionAuthenticator resolves a session token to the customer it belongs to
+type SessionAuthenticator interface {
+	AuthenticateSession(ctx context.Context, token string) (*entity.Customer, error)
+}
+
+// CustomerAuth is a middleware that requires a valid customer session token in
+// the Authorization header and adds the customer to the request context
+func CustomerAuth(auth SessionAuthenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			customer, err := auth.AuthenticateSession(r.Context(), BearerToken(r))
+			if err != nil || customer == nil {
+				response := dto.NewErrorResponse(
+					dto.ErrorCodeUnauthorized,
+					"Login required",
+					nil,
+				)
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnauthorized)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithCustomer(r.Context(), customer)))
+		})
+	}
+}
+
+// OptionalCustomerAuth is a middleware that adds the customer to the request
+// context when a valid session token is sent, and otherwise lets the request
+// through as anonymous
+func OptionalCustomerAuth(auth SessionAuthenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := BearerToken(r)
+			if token == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			customer, err := auth.AuthenticateSession(r.Context(), token)
+			if err != nil || customer == nil {
+				log.Debug().Err(err).Msg("Ignoring invalid session token")
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithCustomer(r.Context(), customer)))
+		})
+	}
+}
+
+// BearerToken returns the bearer token of the Authorization header, if any
+func BearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// WithCustomer returns a copy of ctx carrying the logged in customer, who is
+// also the actor of the changes the request makes
+func WithCustomer(ctx context.Context, customer *entity.Customer) context.Context {
+	ctx = audit.WithActor(ctx, audit.Customer(customer.ID))
+	return context.WithValue(ctx, customerContextKey{}, customer)
+}
+
+// CustomerFromContext returns the logged in customer, or nil for anonymous requests
+func CustomerFromContext(ctx context.Context) *entity.Customer {
+	customer, _ := ctx.Value(customerContextKey{}).(*entity.Customer)
+	return customer
+}
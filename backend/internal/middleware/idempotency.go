@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/svenskhalsovard/api/internal/dto"
+	"github.com/svenskhalsovard/api/internal/entity"
+	"github.com/svenskhalsovard/api/internal/service"
+)
+
+// IdempotencyKeyHeader is the request header carrying the idempotency key
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength is the longest idempotency key accepted
+const maxIdempotencyKeyLength = 255
+
+// IdempotencyStore claims idempotency keys and stores the responses of their
+// requests
+type IdempotencyStore interface {
+	Begin(ctx context.Context, scope string, key string, fingerprint string) (*entity.IdempotencyKey, bool, error)
+	Complete(ctx context.Context, id int64, statusCode int, contentType string, body []byte) error
+	Release(ctx context.Context, id int64) error
+}
+
+// Idempotency is a middleware that makes requests with an Idempotency-Key header
+// safe to retry. The first request with a key is processed and its response
+// stored; retries with the same key and body get the stored response, marked
+// with an Idempotent-Replayed header. A key reused with a different body is
+// rejected with 422, and a retry arriving while the first request is still
+// processed with 409. Keys are scoped to the caller, so one client cannot
+// replay the response of another. Responses with a server error are not
+// stored, so the request can be retried. Requests without the header are
+// processed as usual.
+func Idempotency(store IdempotencyStore) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := strings.TrimSpace(r.Header.Get(IdempotencyKeyHeader))
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if len(key) > maxIdempotencyKeyLength {
+				writeIdempotencyError(w, http.StatusBadRequest, dto.ErrorCodeInvalidRequest, "Idempotency-Key is too long")
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				writeIdempotencyError(w, http.StatusBadRequest, dto.ErrorCodeInvalidRequest, "Failed to read request body")
+				return
+			}
+			r.Body.Close()
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			scope := r.Method + " " + r.URL.Path + " " + idempotencyCaller(r)
+			record, claimed, err := store.Begin(r.Context(), scope, key, requestFingerprint(scope, body))
+			if err != nil {
+				switch {
+				case errors.Is(err, service.ErrIdempotencyKeyReused):
+					writeIdempotencyError(w, http.StatusUnprocessableEntity, dto.ErrorCodeIdempotencyKeyReused, err.Error())
+				case errors.Is(err, service.ErrIdempotencyKeyInProgress):
+					w.Header().Set("Retry-After", "1")
+					writeIdempotencyError(w, http.StatusConflict, dto.ErrorCodeRequestInProgress, err.Error())
+				default:
+					writeIdempotencyError(w, http.StatusInternalServerError, dto.ErrorCodeInternalServerError, "Internal server error")
+				}
+				return
+			}
+
+			if !claimed {
+				if record.ContentType != "" {
+					w.Header().Set("Content-Type", record.ContentType)
+				}
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(record.StatusCode)
+				w.Write(record.ResponseBody)
+				return
+			}
+
+			// The key is released unless the response is stored, including when
+			// the handler panics. The request may be cancelled by then, so the
+			// key is updated without its context.
+			recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			stored := false
+			defer func() {
+				if !stored {
+					store.Release(context.Background(), record.ID)
+				}
+			}()
+
+			next.ServeHTTP(recorder, r)
+
+			if recorder.status >= http.StatusInternalServerError {
+				return
+			}
+
+			if err := store.Complete(context.Background(), record.ID, recorder.status, recorder.Header().Get("Content-Type"), recorder.body.Bytes()); err != nil {
+				return
+			}
+			stored = true
+		})
+	}
+}
+
+// idempotencyCaller identifies who made a request: the hash of their session
+// token when they are logged in, and otherwise their IP address. The middleware
+// runs before authentication, so the session is not looked up.
+func idempotencyCaller(r *http.Request) string {
+	if token := BearerToken(r); token != "" {
+		hash := sha256.Sum256([]byte(token))
+		return "session:" + hex.EncodeToString(hash[:])
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// requestFingerprint hashes the scope and body of a request, so a retry can be
+// told apart from a different request reusing the key
+func requestFingerprint(scope string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(scope))
+	hash.Write([]byte{0})
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// writeIdempotencyError writes a JSON error response
+func writeIdempotencyError(w http.ResponseWriter, statusCode int, errorCode string, message string) {
+	response := dto.NewErrorResponse(errorCode, message, nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(response)
+}
+
+// responseRecorder passes a response through to the client while keeping a copy
+// of its status and body
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+// WriteHeader records the status code and passes it on
+func (r *responseRecorder) WriteHeader(statusCode int) {
+	if !r.wroteHeader {
+		r.status = statusCode
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write records the body and passes it on
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
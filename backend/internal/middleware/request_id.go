@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/go-chi/chi/v5/middleware"
@@ -20,7 +21,7 @@ func RequestID(next http.Handler) http.Handler {
 		w.Header().Set("X-Request-ID", requestID)
 
 		// Add request ID to context
-		ctx := middleware.WithValue(r.Context(), middleware.RequestIDKey, requestID)
+		ctx := context.WithValue(r.Context(), middleware.RequestIDKey, requestID)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
\ No newline at end of file
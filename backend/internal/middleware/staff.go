@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+
+	"github.com/svenskhalsovard/api/internal/audit"
+	"github.com/svenskhalsovard/api/internal/dto"
+)
+
+// StaffAuth is a middleware that requires a staff API key in the Authorization
+// header. apiKeys maps each key to the name of the member of staff using it, and
+// the changes the request makes are attributed to them.
+func StaffAuth(apiKeys map[string]string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			name, ok := staffName(apiKeys, BearerToken(r))
+			if !ok {
+				response := dto.NewErrorResponse(
+					dto.ErrorCodeUnauthorized,
+					"Staff API key required",
+					nil,
+				)
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnauthorized)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+
+			ctx := audit.WithActor(r.Context(), audit.Admin(name))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// staffName returns the name of the member of staff an API key belongs to. All
+// keys are compared in constant time so the response time does not reveal them.
+func staffName(apiKeys map[string]string, token string) (string, bool) {
+	if token == "" {
+		return "", false
+	}
+
+	var name string
+	found := false
+	for key, staff := range apiKeys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(token)) == 1 {
+			name = staff
+			found = true
+		}
+	}
+	return name, found
+}
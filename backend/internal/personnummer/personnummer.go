@@ -0,0 +1,266 @@
+package personnummer
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Common personnummer errors
+var (
+	ErrInvalidFormat   = errors.New("invalid personnummer format")
+	ErrInvalidDate     = errors.New("invalid personnummer date")
+	ErrInvalidChecksum = errors.New("invalid personnummer checksum")
+)
+
+// coordinationDayOffset is added to the day of birth in a samordningsnummer
+const coordinationDayOffset = 60
+
+// Number is a validated Swedish personnummer or samordningsnummer.
+//
+// String and MarshalJSON return the masked form so the number does not leak
+// into logs or API responses by accident. Use Value where the full number is
+// explicitly required, such as for storage or payment providers.
+type Number struct {
+	digits       string // YYYYMMDDNNNC
+	birthDate    time.Time
+	coordination bool
+}
+
+// Parse validates a personnummer or samordningsnummer. It accepts the forms
+// YYYYMMDDNNNC, YYYYMMDD-NNNC, YYMMDDNNNC, YYMMDD-NNNC and YYMMDD+NNNC, where +
+// marks a person aged 100 or more. For the short forms, the century is the one
+// that puts the birth date at most 100 years before now.
+func Parse(input string, now time.Time) (Number, error) {
+	s := strings.TrimSpace(input)
+
+	separator := byte(0)
+	if n := len(s); n == 11 || n == 13 {
+		separator = s[n-5]
+		if separator != '-' && separator != '+' {
+			return Number{}, ErrInvalidFormat
+		}
+		s = s[:n-5] + s[n-4:]
+	}
+
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return Number{}, ErrInvalidFormat
+		}
+	}
+
+	var digits string
+	switch len(s) {
+	case 12:
+		if separator == '+' {
+			return Number{}, ErrInvalidFormat
+		}
+		digits = s
+	case 10:
+		century, err := resolveCentury(s, separator == '+', now)
+		if err != nil {
+			return Number{}, err
+		}
+		digits = century + s
+	default:
+		return Number{}, ErrInvalidFormat
+	}
+
+	if !luhnValid(digits[2:]) {
+		return Number{}, ErrInvalidChecksum
+	}
+
+	birthDate, coordination, err := parseBirthDate(digits[:8])
+	if err != nil {
+		return Number{}, err
+	}
+
+	if birthDate.After(now) {
+		return Number{}, ErrInvalidDate
+	}
+
+	return Number{
+		digits:       digits,
+		birthDate:    birthDate,
+		coordination: coordination,
+	}, nil
+}
+
+// Value returns the full number as YYYYMMDDNNNC
+func (n Number) Value() string {
+	return n.digits
+}
+
+// Masked returns the number with the last four digits hidden, as YYYYMMDD-****
+func (n Number) Masked() string {
+	if n.digits == "" {
+		return ""
+	}
+	return n.digits[:8] + "-****"
+}
+
+// String returns the masked number
+func (n Number) String() string {
+	return n.Masked()
+}
+
+// MarshalJSON encodes the masked number
+func (n Number) MarshalJSON() ([]byte, error) {
+	return json.Marshal(n.Masked())
+}
+
+// IsZero reports whether n is the zero Number
+func (n Number) IsZero() bool {
+	return n.digits == ""
+}
+
+// BirthDate returns the date of birth, with the day corrected for a
+// samordningsnummer
+func (n Number) BirthDate() time.Time {
+	return n.birthDate
+}
+
+// IsCoordinationNumber reports whether n is a samordningsnummer
+func (n Number) IsCoordinationNumber() bool {
+	return n.coordination
+}
+
+// Age returns the age in whole years at the given time
+func (n Number) Age(at time.Time) int {
+	age := at.Year() - n.birthDate.Year()
+	if at.Month() < n.birthDate.Month() || (at.Month() == n.birthDate.Month() && at.Day() < n.birthDate.Day()) {
+		age--
+	}
+	return age
+}
+
+// Mask hides anything that looks like a personnummer in s. It is meant for
+// free text, such as error messages from other systems, before it is logged.
+func Mask(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+
+	for i := 0; i < len(s); {
+		if run := digitRun(s, i); run == 10 || run == 12 {
+			b.WriteString(s[i : i+run-4])
+			b.WriteString("****")
+			i += run
+			continue
+		} else if run == 6 || run == 8 {
+			if j := i + run; j+5 <= len(s) && (s[j] == '-' || s[j] == '+') && digitRun(s, j+1) == 4 {
+				b.WriteString(s[i : j+1])
+				b.WriteString("****")
+				i = j + 5
+				continue
+			}
+		} else if run > 0 {
+			b.WriteString(s[i : i+run])
+			i += run
+			continue
+		}
+
+		b.WriteByte(s[i])
+		i++
+	}
+
+	return b.String()
+}
+
+// Helper functions
+
+// resolveCentury returns the century digits of a ten-digit number. The birth
+// date is placed in the current century unless it would be later than now, in
+// which case it is placed in the previous one; + moves it back one more.
+func resolveCentury(s string, centenarian bool, now time.Time) (string, error) {
+	year := now.Year() - now.Year()%100 + atoi(s[0:2])
+	month := atoi(s[2:4])
+	day := atoi(s[4:6])
+	if day > coordinationDayOffset {
+		day -= coordinationDayOffset
+	}
+
+	// An invalid date is rejected by parseBirthDate, so it only needs to be
+	// compared roughly here
+	if time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC).After(now) {
+		year -= 100
+	}
+	if centenarian {
+		year -= 100
+	}
+
+	if year < 1800 {
+		return "", ErrInvalidDate
+	}
+
+	century := year / 100
+	return string([]byte{byte('0' + century/10), byte('0' + century%10)}), nil
+}
+
+// parseBirthDate parses YYYYMMDD, where the day may carry the
+// samordningsnummer offset
+func parseBirthDate(s string) (time.Time, bool, error) {
+	year := atoi(s[0:4])
+	month := atoi(s[4:6])
+	day := atoi(s[6:8])
+
+	coordination := false
+	if day > coordinationDayOffset {
+		day -= coordinationDayOffset
+		coordination = true
+	}
+
+	if month < 1 || month > 12 || day < 1 {
+		return time.Time{}, false, ErrInvalidDate
+	}
+
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if date.Month() != time.Month(month) {
+		return time.Time{}, false, ErrInvalidDate
+	}
+
+	return date, coordination, nil
+}
+
+// luhnValid checks the Luhn checksum of a ten-digit YYMMDDNNNC number
+func luhnValid(s string) bool {
+	sum := 0
+	for i := 0; i < len(s); i++ {
+		d := int(s[i] - '0')
+		if i%2 == 0 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// digitRun returns the number of consecutive digits in s starting at i,
+// or 0 when the run is preceded by a digit
+func digitRun(s string, i int) int {
+	if i > 0 && isDigit(s[i-1]) {
+		return 0
+	}
+	j := i
+	for j < len(s) && isDigit(s[j]) {
+		j++
+	}
+	return j - i
+}
+
+// isDigit reports whether c is an ASCII digit
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// atoi converts a string of ASCII digits to an int
+func atoi(s string) int {
+	n := 0
+	for i := 0; i < len(s); i++ {
+		n = n*10 + int(s[i]-'0')
+	}
+	return n
+}
@@ -0,0 +1,131 @@
+package personnummer
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	now := time.Date(2024, time.June, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		input        string
+		want         string
+		coordination bool
+		err          error
+	}{
+		{name: "twelve digits", input: "198112189876", want: "198112189876"},
+		{name: "twelve digits with separator", input: "19811218-9876", want: "198112189876"},
+		{name: "ten digits", input: "8112189876", want: "198112189876"},
+		{name: "ten digits with separator", input: "811218-9876", want: "198112189876"},
+		{name: "surrounding space", input: " 811218-9876 ", want: "198112189876"},
+		{name: "wrong checksum", input: "811218-9875", err: ErrInvalidChecksum},
+		{name: "letters", input: "81121A-9876", err: ErrInvalidFormat},
+		{name: "wrong length", input: "81121898765", err: ErrInvalidFormat},
+		{name: "wrong separator", input: "811218/9876", err: ErrInvalidFormat},
+		{name: "plus on twelve digits", input: "19811218+9876", err: ErrInvalidFormat},
+		{name: "birthday earlier this year", input: "240301-1238", want: "202403011238"},
+		{name: "birthday today", input: "240615-1239", want: "202406151239"},
+		{name: "birthday later this year", input: "240616-1238", want: "192406161238"},
+		{name: "birthday later in the year", input: "241201-1237", want: "192412011237"},
+		{name: "earlier year of this century", input: "050101-1233", want: "200501011233"},
+		{name: "centenarian", input: "811218+9876", want: "188112189876"},
+		{name: "samordningsnummer", input: "701063-1237", want: "197010631237", coordination: true},
+		{name: "invalid date", input: "19810230-9872", err: ErrInvalidDate},
+		{name: "future date", input: "20241201-1237", err: ErrInvalidDate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			number, err := Parse(tt.input, now)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("Parse(%q) error = %v, want %v", tt.input, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.input, err)
+			}
+			if got := number.Value(); got != tt.want {
+				t.Errorf("Parse(%q) = %s, want %s", tt.input, got, tt.want)
+			}
+			if got := number.IsCoordinationNumber(); got != tt.coordination {
+				t.Errorf("Parse(%q).IsCoordinationNumber() = %v, want %v", tt.input, got, tt.coordination)
+			}
+		})
+	}
+}
+
+func TestBirthDate(t *testing.T) {
+	now := time.Date(2024, time.June, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		input string
+		want  time.Time
+	}{
+		{input: "811218-9876", want: time.Date(1981, time.December, 18, 0, 0, 0, 0, time.UTC)},
+		{input: "701063-1237", want: time.Date(1970, time.October, 3, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			number, err := Parse(tt.input, now)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.input, err)
+			}
+			if got := number.BirthDate(); !got.Equal(tt.want) {
+				t.Errorf("BirthDate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAge(t *testing.T) {
+	number, err := Parse("19811218-9876", time.Date(2024, time.June, 15, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Parse error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want int
+	}{
+		{name: "day before birthday", at: time.Date(2023, time.December, 17, 0, 0, 0, 0, time.UTC), want: 41},
+		{name: "on birthday", at: time.Date(2023, time.December, 18, 0, 0, 0, 0, time.UTC), want: 42},
+		{name: "month before birthday", at: time.Date(2024, time.November, 30, 0, 0, 0, 0, time.UTC), want: 42},
+		{name: "after birthday", at: time.Date(2024, time.December, 19, 0, 0, 0, 0, time.UTC), want: 43},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := number.Age(tt.at); got != tt.want {
+				t.Errorf("Age(%v) = %d, want %d", tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMask(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "198112189876", want: "19811218****"},
+		{input: "kund 811218-9876 saknas", want: "kund 811218-**** saknas"},
+		{input: "19811218+9876", want: "19811218+****"},
+		{input: "order 12345", want: "order 12345"},
+		{input: "1234567890123", want: "1234567890123"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := Mask(tt.input); got != tt.want {
+				t.Errorf("Mask(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
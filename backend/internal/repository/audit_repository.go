@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/svenskhalsovard/api/internal/audit"
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// AuditRepository handles database operations for the audit log. Entries are
+// written by the other repositories in the transaction of the change itself.
+type AuditRepository struct {
+	db *sqlx.DB
+}
+
+// NewAuditRepository creates a new AuditRepository
+func NewAuditRepository(database *Database) *AuditRepository {
+	return &AuditRepository{
+		db: database.DB,
+	}
+}
+
+// GetEntries retrieves the audit log of an entity, oldest first
+func (r *AuditRepository) GetEntries(ctx context.Context, entityType string, entityID int64) ([]entity.AuditEntry, error) {
+	query := `
+		SELECT id, entity_type, entity_id, action, old_values, new_values,
+		       actor_type, actor_id, request_id, created_at
+		FROM audit_log
+		WHERE entity_type = ?
+		AND entity_id = ?
+		ORDER BY id
+	`
+
+	var entries []entity.AuditEntry
+	if err := r.db.SelectContext(ctx, &entries, query, entityType, entityID); err != nil {
+		return nil, fmt.Errorf("failed to get audit entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// recordAudit adds an entry to the audit log in tx. The actor and request are
+// taken from ctx. oldValues and newValues are stored as JSON and may be nil.
+func recordAudit(ctx context.Context, tx *sqlx.Tx, entityType string, entityID int64, action string, oldValues, newValues interface{}) error {
+	oldJSON, err := auditValues(oldValues)
+	if err != nil {
+		return err
+	}
+
+	newJSON, err := auditValues(newValues)
+	if err != nil {
+		return err
+	}
+
+	actor := audit.ActorFromContext(ctx)
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO audit_log (
+			entity_type, entity_id, action, old_values, new_values,
+			actor_type, actor_id, request_id, created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		entityType,
+		entityID,
+		action,
+		oldJSON,
+		newJSON,
+		actor.Type,
+		actor.ID,
+		audit.RequestID(ctx),
+		now(),
+	); err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+
+	return nil
+}
+
+// auditValues encodes audited values as JSON, or returns nil when there are none
+func auditValues(values interface{}) ([]byte, error) {
+	if values == nil {
+		return nil, nil
+	}
+
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit values: %w", err)
+	}
+
+	return encoded, nil
+}
@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// AuthRepository handles database operations for login codes and customer sessions
+type AuthRepository struct {
+	db *sqlx.DB
+}
+
+// NewAuthRepository creates a new AuthRepository
+func NewAuthRepository(database *Database) *AuthRepository {
+	return &AuthRepository{
+		db: database.DB,
+	}
+}
+
+// CreateLoginCode stores a login code and queues the email that delivers it
+func (r *AuthRepository) CreateLoginCode(ctx context.Context, code *entity.LoginCode, email *entity.OutboxEmail) error {
+	return withTransaction(ctx, r.db, func(tx *sqlx.Tx) error {
+		code.CreatedAt = now()
+
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO login_codes (email, code_hash, expires_at, created_at)
+			VALUES (?, ?, ?, ?)
+		`, code.Email, code.CodeHash, code.ExpiresAt.UTC(), code.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to create login code: %w", err)
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to get last insert ID: %w", err)
+		}
+		code.ID = id
+
+		return enqueueEmail(ctx, tx, email)
+	})
+}
+
+// CountLoginCodesSince counts the login codes issued to an email since the given time
+func (r *AuthRepository) CountLoginCodesSince(ctx context.Context, email string, since time.Time) (int, error) {
+	var count int
+	if err := r.db.GetContext(ctx, &count, `
+		SELECT COUNT(*)
+		FROM login_codes
+		WHERE email = ?
+		AND created_at >= ?
+	`, email, since.UTC()); err != nil {
+		return 0, fmt.Errorf("failed to count login codes: %w", err)
+	}
+
+	return count, nil
+}
+
+// GetActiveLoginCode retrieves the latest unused, unexpired login code for an email
+func (r *AuthRepository) GetActiveLoginCode(ctx context.Context, email string) (*entity.LoginCode, error) {
+	query := `
+		SELECT id, email, code_hash, attempts, expires_at, used_at, created_at
+		FROM login_codes
+		WHERE email = ?
+		AND used_at IS NULL
+		AND expires_at > ?
+		ORDER BY id DESC
+		LIMIT 1
+	`
+
+	var code entity.LoginCode
+	if err := r.db.GetContext(ctx, &code, query, email, now()); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // No active code
+		}
+		return nil, fmt.Errorf("failed to get login code: %w", err)
+	}
+
+	return &code, nil
+}
+
+// IncrementLoginCodeAttempts records an attempt to use a login code. It reports
+// false when the code already had maxAttempts attempts, so concurrent guesses
+// cannot exceed the limit.
+func (r *AuthRepository) IncrementLoginCodeAttempts(ctx context.Context, id int64, maxAttempts int) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE login_codes
+		SET attempts = attempts + 1
+		WHERE id = ?
+		AND attempts < ?
+	`, id, maxAttempts)
+	if err != nil {
+		return false, fmt.Errorf("failed to increment login code attempts: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+// UseLoginCode marks a login code as used. It reports false when the code was
+// already used, so each code logs in only once.
+func (r *AuthRepository) UseLoginCode(ctx context.Context, id int64) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE login_codes
+		SET used_at = ?
+		WHERE id = ?
+		AND used_at IS NULL
+	`, now(), id)
+	if err != nil {
+		return false, fmt.Errorf("failed to use login code: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+// CreateSession stores a new customer session
+func (r *AuthRepository) CreateSession(ctx context.Context, session *entity.CustomerSession) error {
+	session.CreatedAt = now()
+
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO customer_sessions (customer_id, token_hash, expires_at, created_at)
+		VALUES (?, ?, ?, ?)
+	`, session.CustomerID, session.TokenHash, session.ExpiresAt.UTC(), session.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+	session.ID = id
+
+	return nil
+}
+
+// GetActiveSession retrieves an unexpired, unrevoked session by token hash
+func (r *AuthRepository) GetActiveSession(ctx context.Context, tokenHash string) (*entity.CustomerSession, error) {
+	query := `
+		SELECT id, customer_id, token_hash, expires_at, revoked_at, created_at
+		FROM customer_sessions
+		WHERE token_hash = ?
+		AND revoked_at IS NULL
+		AND expires_at > ?
+	`
+
+	var session entity.CustomerSession
+	if err := r.db.GetContext(ctx, &session, query, tokenHash, now()); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Session not found
+		}
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	return &session, nil
+}
+
+// RevokeSession ends a session by token hash
+func (r *AuthRepository) RevokeSession(ctx context.Context, tokenHash string) error {
+	if _, err := r.db.ExecContext(ctx, `
+		UPDATE customer_sessions
+		SET revoked_at = ?
+		WHERE token_hash = ?
+		AND revoked_at IS NULL
+	`, now(), tokenHash); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	return nil
+}
@@ -9,87 +9,140 @@ import (
 
 	"github.com/jmoiron/sqlx"
 	"github.com/rs/zerolog/log"
+	"github.com/svenskhalsovard/api/internal/encryption"
 	"github.com/svenskhalsovard/api/internal/entity"
 )
 
-// BookingRepository handles database operations for bookings
+// maxBookingNumberAttempts is how many booking numbers are tried when creating a
+// booking before giving up
+const maxBookingNumberAttempts = 5
+
+// BookingRepository handles database operations for bookings. Customers created
+// for bookings are encrypted at rest with keyring.
 type BookingRepository struct {
-	db *sqlx.DB
+	db      *sqlx.DB
+	keyring *encryption.Keyring
 }
 
 // NewBookingRepository creates a new BookingRepository
-func NewBookingRepository(database *Database) *BookingRepository {
+func NewBookingRepository(database *Database, keyring *encryption.Keyring) *BookingRepository {
 	return &BookingRepository{
-		db: database.DB,
+		db:      database.DB,
+		keyring: keyring,
 	}
 }
 
-// CreateBooking creates a new booking with items and customer data
-func (r *BookingRepository) CreateBooking(ctx context.Context, tx *sqlx.Tx, booking *entity.Booking, customer *entity.Customer, items []entity.BookingItem) error {
-	var err error
+// CreateBooking creates a new booking with items and a snapshot of the customer
+// data. A payment has at most one booking: when it already has one, nothing is
+// created and booking is filled with the existing booking instead, so concurrent
+// callers all get the same booking. It returns whether the booking was created.
+func (r *BookingRepository) CreateBooking(ctx context.Context, tx *sqlx.Tx, booking *entity.Booking, customer *entity.Customer, items []entity.BookingItem) (bool, error) {
+	// Lock the payment so concurrent callers create its booking once
+	var lockedID int64
+	if err := tx.GetContext(ctx, &lockedID, `
+		SELECT id
+		FROM payments
+		WHERE id = ?
+		FOR UPDATE
+	`, booking.PaymentID); err != nil {
+		return false, fmt.Errorf("failed to lock payment: %w", err)
+	}
+
+	existing, err := r.getBookingByPaymentIDForUpdate(ctx, tx, booking.PaymentID)
+	if err != nil {
+		return false, err
+	}
+	if existing != nil {
+		*booking = *existing
+		return false, nil
+	}
 	
-	// Create or update customer
+	// Link the booking to the customer. Existing customer details are never
+	// changed here; profiles are updated only by the customer when logged in.
 	var customerID int64
 	if customer.ID > 0 {
-		// Update existing customer
-		if err = r.updateCustomer(ctx, tx, customer); err != nil {
-			return fmt.Errorf("failed to update customer: %w", err)
-		}
 		customerID = customer.ID
 	} else {
 		// Check if customer exists with the same email
 		existingCustomer, err := r.getCustomerByEmail(ctx, tx, customer.Email)
 		if err != nil {
-			return fmt.Errorf("failed to check for existing customer: %w", err)
+			return false, fmt.Errorf("failed to check for existing customer: %w", err)
 		}
 		
 		if existingCustomer != nil {
-			// Update existing customer data
 			customer.ID = existingCustomer.ID
-			if err = r.updateCustomer(ctx, tx, customer); err != nil {
-				return fmt.Errorf("failed to update existing customer: %w", err)
-			}
 			customerID = customer.ID
 		} else {
 			// Create new customer
 			if err = r.createCustomer(ctx, tx, customer); err != nil {
-				return fmt.Errorf("failed to create customer: %w", err)
+				return false, fmt.Errorf("failed to create customer: %w", err)
 			}
 			customerID = customer.ID
 		}
 	}
 	
-	// Set customer ID in booking
+	// Set customer ID in booking. The details given for the booking are kept in
+	// its own snapshot.
 	booking.CustomerID = customerID
+	booking.CustomerSnapshot = customer.Snapshot()
 	
-	// Generate booking number
-	bookingNumber, err := r.generateBookingNumber(ctx, tx)
-	if err != nil {
-		return fmt.Errorf("failed to generate booking number: %w", err)
-	}
-	booking.BookingNumber = bookingNumber
-	
-	// Create booking
-	if err = r.createBookingRecord(ctx, tx, booking); err != nil {
-		return fmt.Errorf("failed to create booking record: %w", err)
+	// Create booking. Concurrent bookings of other payments may take the same
+	// booking number, in which case the next numbers are tried.
+	for attempt := 0; ; attempt++ {
+		bookingNumber, err := r.generateBookingNumber(ctx, tx, attempt)
+		if err != nil {
+			return false, fmt.Errorf("failed to generate booking number: %w", err)
+		}
+		booking.BookingNumber = bookingNumber
+
+		err = r.createBookingRecord(ctx, tx, booking)
+		if err == nil {
+			break
+		}
+		if isDuplicateKey(err, "payment_id") {
+			// The unique key on the payment ID catches a booking created
+			// without the payment lock
+			existing, err := r.getBookingByPaymentIDForUpdate(ctx, tx, booking.PaymentID)
+			if err != nil {
+				return false, err
+			}
+			if existing == nil {
+				return false, fmt.Errorf("booking of payment %d exists but could not be read", booking.PaymentID)
+			}
+			*booking = *existing
+			return false, nil
+		}
+		if !isDuplicateKey(err, "booking_number") || attempt+1 == maxBookingNumberAttempts {
+			return false, fmt.Errorf("failed to create booking record: %w", err)
+		}
 	}
 	
 	// Create booking items
 	for i := range items {
 		items[i].BookingID = booking.ID
 		if err = r.createBookingItem(ctx, tx, &items[i]); err != nil {
-			return fmt.Errorf("failed to create booking item: %w", err)
+			return false, fmt.Errorf("failed to create booking item: %w", err)
 		}
 	}
 	
-	return nil
+	if err := recordAudit(ctx, tx, entity.AuditEntityBooking, booking.ID, entity.AuditActionCreated, nil, map[string]string{
+		"status": booking.Status,
+	}); err != nil {
+		return false, err
+	}
+
+	return true, nil
 }
 
 // GetBookingByID retrieves a booking by ID
 func (r *BookingRepository) GetBookingByID(ctx context.Context, id int64) (*entity.Booking, error) {
 	query := `
-		SELECT id, payment_id, customer_id, status, total_amount, booking_number, 
-		       notes, created_at, updated_at, deleted_at
+		SELECT id, payment_id, customer_id, customer_first_name, customer_last_name,
+		       customer_email, customer_phone, customer_street_address, customer_postal_code,
+		       customer_city, customer_additional_info, customer_national_id_encrypted,
+		       status, total_amount, booking_number, 
+		       notes, confirmed_at, checked_in_at, completed_at, cancelled_at, no_show_at,
+		       created_at, updated_at, deleted_at
 		FROM bookings
 		WHERE ` + softDeleteCondition("bookings") + `
 		AND id = ?
@@ -103,14 +156,22 @@ func (r *BookingRepository) GetBookingByID(ctx context.Context, id int64) (*enti
 		return nil, fmt.Errorf("failed to get booking by ID: %w", err)
 	}
 
+	if err := decryptSnapshot(r.keyring, &booking.CustomerSnapshot); err != nil {
+		return nil, err
+	}
+
 	return &booking, nil
 }
 
 // GetBookingByPaymentID retrieves a booking by payment ID
 func (r *BookingRepository) GetBookingByPaymentID(ctx context.Context, paymentID int64) (*entity.Booking, error) {
 	query := `
-		SELECT id, payment_id, customer_id, status, total_amount, booking_number, 
-		       notes, created_at, updated_at, deleted_at
+		SELECT id, payment_id, customer_id, customer_first_name, customer_last_name,
+		       customer_email, customer_phone, customer_street_address, customer_postal_code,
+		       customer_city, customer_additional_info, customer_national_id_encrypted,
+		       status, total_amount, booking_number, 
+		       notes, confirmed_at, checked_in_at, completed_at, cancelled_at, no_show_at,
+		       created_at, updated_at, deleted_at
 		FROM bookings
 		WHERE ` + softDeleteCondition("bookings") + `
 		AND payment_id = ?
@@ -124,6 +185,40 @@ func (r *BookingRepository) GetBookingByPaymentID(ctx context.Context, paymentID
 		return nil, fmt.Errorf("failed to get booking by payment ID: %w", err)
 	}
 
+	if err := decryptSnapshot(r.keyring, &booking.CustomerSnapshot); err != nil {
+		return nil, err
+	}
+
+	return &booking, nil
+}
+
+// getBookingByPaymentIDForUpdate retrieves the booking of a payment in tx with a
+// locking read, which sees bookings committed after tx started
+func (r *BookingRepository) getBookingByPaymentIDForUpdate(ctx context.Context, tx *sqlx.Tx, paymentID int64) (*entity.Booking, error) {
+	query := `
+		SELECT id, payment_id, customer_id, customer_first_name, customer_last_name,
+		       customer_email, customer_phone, customer_street_address, customer_postal_code,
+		       customer_city, customer_additional_info, customer_national_id_encrypted,
+		       status, total_amount, booking_number,
+		       notes, confirmed_at, checked_in_at, completed_at, cancelled_at, no_show_at,
+		       created_at, updated_at, deleted_at
+		FROM bookings
+		WHERE payment_id = ?
+		FOR UPDATE
+	`
+
+	var booking entity.Booking
+	if err := tx.GetContext(ctx, &booking, query, paymentID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Booking not found
+		}
+		return nil, fmt.Errorf("failed to get booking by payment ID: %w", err)
+	}
+
+	if err := decryptSnapshot(r.keyring, &booking.CustomerSnapshot); err != nil {
+		return nil, err
+	}
+
 	return &booking, nil
 }
 
@@ -138,12 +233,6 @@ func (r *BookingRepository) GetBookingWithItems(ctx context.Context, id int64) (
 		return nil, nil // Booking not found
 	}
 
-	// Get customer
-	customer, err := r.GetCustomerByID(ctx, booking.CustomerID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get customer: %w", err)
-	}
-
 	// Get booking items
 	items, err := r.GetBookingItems(ctx, id)
 	if err != nil {
@@ -152,11 +241,80 @@ func (r *BookingRepository) GetBookingWithItems(ctx context.Context, id int64) (
 
 	return &entity.BookingWithItems{
 		Booking:  *booking,
-		Customer: *customer,
+		Customer: booking.CustomerSnapshot.ToCustomer(booking.CustomerID),
 		Items:    items,
 	}, nil
 }
 
+// GetBookingsByCustomerID retrieves the bookings of a customer with their items, newest first
+func (r *BookingRepository) GetBookingsByCustomerID(ctx context.Context, customerID int64) ([]entity.BookingWithItems, error) {
+	query := `
+		SELECT id, payment_id, customer_id, customer_first_name, customer_last_name,
+		       customer_email, customer_phone, customer_street_address, customer_postal_code,
+		       customer_city, customer_additional_info, customer_national_id_encrypted,
+		       status, total_amount, booking_number, 
+		       notes, confirmed_at, checked_in_at, completed_at, cancelled_at, no_show_at,
+		       created_at, updated_at, deleted_at
+		FROM bookings
+		WHERE ` + softDeleteCondition("bookings") + `
+		AND customer_id = ?
+		ORDER BY created_at DESC, id DESC
+	`
+
+	var bookings []entity.Booking
+	if err := r.db.SelectContext(ctx, &bookings, query, customerID); err != nil {
+		return nil, fmt.Errorf("failed to get bookings by customer ID: %w", err)
+	}
+
+	for i := range bookings {
+		if err := decryptSnapshot(r.keyring, &bookings[i].CustomerSnapshot); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(bookings) == 0 {
+		return []entity.BookingWithItems{}, nil
+	}
+
+	bookingIDs := make([]int64, 0, len(bookings))
+	for _, booking := range bookings {
+		bookingIDs = append(bookingIDs, booking.ID)
+	}
+
+	itemsQuery, args, err := sqlx.In(`
+		SELECT id, booking_id, service_id, service_name, quantity, unit_price, 
+		       total_price, purchase_type, is_subscription, created_at, updated_at, deleted_at
+		FROM booking_items
+		WHERE `+softDeleteCondition("booking_items")+`
+		AND booking_id IN (?)
+		ORDER BY id
+	`, bookingIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	var items []entity.BookingItem
+	if err := r.db.SelectContext(ctx, &items, r.db.Rebind(itemsQuery), args...); err != nil {
+		return nil, fmt.Errorf("failed to get booking items: %w", err)
+	}
+
+	itemsByBooking := make(map[int64][]entity.BookingItem, len(bookings))
+	for _, item := range items {
+		itemsByBooking[item.BookingID] = append(itemsByBooking[item.BookingID], item)
+	}
+
+	result := make([]entity.BookingWithItems, 0, len(bookings))
+	for _, booking := range bookings {
+		result = append(result, entity.BookingWithItems{
+			Booking:  booking,
+			Customer: booking.CustomerSnapshot.ToCustomer(booking.CustomerID),
+			Items:    itemsByBooking[booking.ID],
+		})
+	}
+
+	return result, nil
+}
+
 // GetBookingItems retrieves the items for a booking
 func (r *BookingRepository) GetBookingItems(ctx context.Context, bookingID int64) ([]entity.BookingItem, error) {
 	query := `
@@ -180,7 +338,8 @@ func (r *BookingRepository) GetBookingItems(ctx context.Context, bookingID int64
 func (r *BookingRepository) GetCustomerByID(ctx context.Context, id int64) (*entity.Customer, error) {
 	query := `
 		SELECT id, first_name, last_name, email, phone, street_address, 
-		       postal_code, city, additional_info, created_at, updated_at, deleted_at
+		       postal_code, city, additional_info, national_id_encrypted, email_verified_at,
+		       created_at, updated_at, deleted_at
 		FROM customers
 		WHERE ` + softDeleteCondition("customers") + `
 		AND id = ?
@@ -194,59 +353,129 @@ func (r *BookingRepository) GetCustomerByID(ctx context.Context, id int64) (*ent
 		return nil, fmt.Errorf("failed to get customer by ID: %w", err)
 	}
 
+	if err := decryptCustomer(r.keyring, &customer); err != nil {
+		return nil, err
+	}
+
 	return &customer, nil
 }
 
-// UpdateBookingStatus updates the status of a booking
-func (r *BookingRepository) UpdateBookingStatus(ctx context.Context, bookingID int64, status string) error {
-	query := `
-		UPDATE bookings
-		SET status = ?,
-		    updated_at = ?
-		WHERE id = ?
-		AND ` + softDeleteCondition("bookings")
+// bookingStatusTimestamps maps booking statuses to the column recording when a
+// booking entered them
+var bookingStatusTimestamps = map[string]string{
+	entity.BookingStatusConfirmed: "confirmed_at",
+	entity.BookingStatusCheckedIn: "checked_in_at",
+	entity.BookingStatusCompleted: "completed_at",
+	entity.BookingStatusCancelled: "cancelled_at",
+	entity.BookingStatusNoShow:    "no_show_at",
+}
 
-	result, err := r.db.ExecContext(ctx, query, status, now(), bookingID)
-	if err != nil {
-		return fmt.Errorf("failed to update booking status: %w", err)
-	}
+// UpdateBookingStatus moves a booking to a new status and records when it
+// entered it. The move must be allowed by the booking state machine, otherwise
+// a *entity.BookingTransitionError is returned. A change of status is recorded
+// in the audit log in the same transaction. It returns the status the booking
+// had, read while the booking is locked.
+func (r *BookingRepository) UpdateBookingStatus(ctx context.Context, bookingID int64, status string) (string, error) {
+	var oldStatus string
+
+	err := withTransaction(ctx, r.db, func(tx *sqlx.Tx) error {
+		if err := tx.GetContext(ctx, &oldStatus, `
+			SELECT status
+			FROM bookings
+			WHERE id = ?
+			AND `+softDeleteCondition("bookings")+`
+			FOR UPDATE
+		`, bookingID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("booking not found or already deleted")
+			}
+			return fmt.Errorf("failed to get booking status: %w", err)
+		}
+
+		if err := entity.ValidateBookingTransition(bookingID, oldStatus, status); err != nil {
+			return err
+		}
+
+		if status == oldStatus {
+			return nil
+		}
+
+		// No booking moves back to pending, so every new status has a timestamp
+		query := `
+			UPDATE bookings
+			SET status = ?,
+			    ` + bookingStatusTimestamps[status] + ` = ?,
+			    updated_at = ?
+			WHERE id = ?
+		`
+
+		timestamp := now()
+		if _, err := tx.ExecContext(ctx, query, status, timestamp, timestamp, bookingID); err != nil {
+			return fmt.Errorf("failed to update booking status: %w", err)
+		}
 
-	rows, err := result.RowsAffected()
+		return recordAudit(ctx, tx, entity.AuditEntityBooking, bookingID, entity.AuditActionStatusChanged,
+			map[string]string{"status": oldStatus},
+			map[string]string{"status": status},
+		)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return "", err
 	}
 
-	if rows == 0 {
-		return fmt.Errorf("booking not found or already deleted")
-	}
+	return oldStatus, nil
+}
 
-	return nil
+// Transaction runs fn in a database transaction
+func (r *BookingRepository) Transaction(fn func(*sqlx.Tx) error) error {
+	return withTransaction(context.Background(), r.db, fn)
 }
 
 // Helper methods
 
 // createBookingRecord creates a new booking record
 func (r *BookingRepository) createBookingRecord(ctx context.Context, tx *sqlx.Tx, booking *entity.Booking) error {
+	snapshot, err := encryptSnapshot(r.keyring, booking.CustomerSnapshot)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		INSERT INTO bookings (
-			payment_id, customer_id, status, total_amount, booking_number, 
-			notes, created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			payment_id, customer_id, customer_first_name, customer_last_name,
+			customer_email, customer_phone, customer_street_address, customer_postal_code,
+			customer_city, customer_additional_info, customer_national_id_encrypted,
+			status, total_amount, booking_number, 
+			notes, confirmed_at, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	now := now()
 	booking.CreatedAt = now
 	booking.UpdatedAt = now
+	if booking.Status == entity.BookingStatusConfirmed {
+		booking.ConfirmedAt = &now
+	}
 
 	result, err := tx.ExecContext(
 		ctx,
 		query,
 		booking.PaymentID,
 		booking.CustomerID,
+		snapshot.FirstName,
+		snapshot.LastName,
+		snapshot.Email,
+		snapshot.Phone,
+		snapshot.StreetAddress,
+		snapshot.PostalCode,
+		snapshot.City,
+		snapshot.AdditionalInfo,
+		booking.NationalIDEncrypted,
 		booking.Status,
 		booking.TotalAmount,
 		booking.BookingNumber,
 		booking.Notes,
+		booking.ConfirmedAt,
 		booking.CreatedAt,
 		booking.UpdatedAt,
 	)
@@ -307,28 +536,35 @@ func (r *BookingRepository) createBookingItem(ctx context.Context, tx *sqlx.Tx,
 func (r *BookingRepository) createCustomer(ctx context.Context, tx *sqlx.Tx, customer *entity.Customer) error {
 	query := `
 		INSERT INTO customers (
-			first_name, last_name, email, phone, street_address, 
-			postal_code, city, additional_info, created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			first_name, last_name, email, email_hash, phone, street_address, 
+			postal_code, city, additional_info, national_id_encrypted, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	now := now()
 	customer.CreatedAt = now
 	customer.UpdatedAt = now
 
+	encrypted, err := encryptCustomer(r.keyring, customer)
+	if err != nil {
+		return err
+	}
+
 	result, err := tx.ExecContext(
 		ctx,
 		query,
-		customer.FirstName,
-		customer.LastName,
-		customer.Email,
-		customer.Phone,
-		customer.StreetAddress,
-		customer.PostalCode,
-		customer.City,
-		customer.AdditionalInfo,
-		customer.CreatedAt,
-		customer.UpdatedAt,
+		encrypted.FirstName,
+		encrypted.LastName,
+		encrypted.Email,
+		emailHash(r.keyring, customer.Email),
+		encrypted.Phone,
+		encrypted.StreetAddress,
+		encrypted.PostalCode,
+		encrypted.City,
+		encrypted.AdditionalInfo,
+		encrypted.NationalIDEncrypted,
+		encrypted.CreatedAt,
+		encrypted.UpdatedAt,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create customer: %w", err)
@@ -343,76 +579,36 @@ func (r *BookingRepository) createCustomer(ctx context.Context, tx *sqlx.Tx, cus
 	return nil
 }
 
-// updateCustomer updates an existing customer
-func (r *BookingRepository) updateCustomer(ctx context.Context, tx *sqlx.Tx, customer *entity.Customer) error {
-	query := `
-		UPDATE customers
-		SET first_name = ?,
-			last_name = ?,
-			phone = ?,
-			street_address = ?,
-			postal_code = ?,
-			city = ?,
-			additional_info = ?,
-			updated_at = ?
-		WHERE id = ?
-		AND ` + softDeleteCondition("customers")
-	
-	now := now()
-	customer.UpdatedAt = now
-
-	result, err := tx.ExecContext(
-		ctx,
-		query,
-		customer.FirstName,
-		customer.LastName,
-		customer.Phone,
-		customer.StreetAddress,
-		customer.PostalCode,
-		customer.City,
-		customer.AdditionalInfo,
-		customer.UpdatedAt,
-		customer.ID,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to update customer: %w", err)
-	}
-
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
-
-	if rows == 0 {
-		return fmt.Errorf("customer not found or already deleted")
-	}
-
-	return nil
-}
-
 // getCustomerByEmail retrieves a customer by email
 func (r *BookingRepository) getCustomerByEmail(ctx context.Context, tx *sqlx.Tx, email string) (*entity.Customer, error) {
+	emailCondition, args := customerEmailCondition(r.keyring, "customers", email)
 	query := `
 		SELECT id, first_name, last_name, email, phone, street_address, 
-		       postal_code, city, additional_info, created_at, updated_at, deleted_at
+		       postal_code, city, additional_info, national_id_encrypted, email_verified_at,
+		       created_at, updated_at, deleted_at
 		FROM customers
 		WHERE ` + softDeleteCondition("customers") + `
-		AND email = ?
-	`
+		AND ` + emailCondition
 
 	var customer entity.Customer
-	if err := tx.GetContext(ctx, &customer, query, email); err != nil {
+	if err := tx.GetContext(ctx, &customer, query, args...); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil // Customer not found
 		}
 		return nil, fmt.Errorf("failed to get customer by email: %w", err)
 	}
 
+	if err := decryptCustomer(r.keyring, &customer); err != nil {
+		return nil, err
+	}
+
 	return &customer, nil
 }
 
-// generateBookingNumber generates a unique booking number
-func (r *BookingRepository) generateBookingNumber(ctx context.Context, tx *sqlx.Tx) (string, error) {
+// generateBookingNumber generates a booking number following the latest one of
+// the year. skip numbers are skipped, for retrying after a number was taken by a
+// booking the transaction cannot see.
+func (r *BookingRepository) generateBookingNumber(ctx context.Context, tx *sqlx.Tx, skip int) (string, error) {
 	// Get current year and latest booking sequence number
 	var seq int
 	query := `
@@ -427,7 +623,7 @@ func (r *BookingRepository) generateBookingNumber(ctx context.Context, tx *sqlx.
 	}
 
 	// Increment sequence and format booking number
-	seq++
+	seq += 1 + skip
 	bookingNumber := fmt.Sprintf("%s-%06d", year, seq)
 	return bookingNumber, nil
 }
\ No newline at end of file
@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// CampaignRepository handles database operations for price campaigns
+type CampaignRepository struct {
+	db *sqlx.DB
+}
+
+// NewCampaignRepository creates a new CampaignRepository
+func NewCampaignRepository(database *Database) *CampaignRepository {
+	return &CampaignRepository{
+		db: database.DB,
+	}
+}
+
+// GetCurrentCampaigns retrieves active campaigns that have not ended at the given
+// time, including campaigns that start later, with their targeted services
+func (r *CampaignRepository) GetCurrentCampaigns(ctx context.Context, at time.Time) ([]entity.PriceCampaign, error) {
+	query := `
+		SELECT id, name, discount_type, discount_value, starts_at, ends_at,
+		       applies_to_all, is_active, created_at, updated_at, deleted_at
+		FROM price_campaigns
+		WHERE ` + softDeleteCondition("price_campaigns") + `
+		AND is_active = true
+		AND (ends_at IS NULL OR ends_at > ?)
+		ORDER BY starts_at, id
+	`
+
+	var campaigns []entity.PriceCampaign
+	if err := r.db.SelectContext(ctx, &campaigns, query, at); err != nil {
+		return nil, fmt.Errorf("failed to get current campaigns: %w", err)
+	}
+
+	if len(campaigns) == 0 {
+		return campaigns, nil
+	}
+
+	campaignIDs := make([]int64, 0, len(campaigns))
+	for _, campaign := range campaigns {
+		campaignIDs = append(campaignIDs, campaign.ID)
+	}
+
+	targetQuery, args, err := sqlx.In(`
+		SELECT campaign_id, service_id
+		FROM price_campaign_services
+		WHERE campaign_id IN (?)
+		ORDER BY campaign_id, service_id
+	`, campaignIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	var targets []entity.PriceCampaignService
+	if err := r.db.SelectContext(ctx, &targets, r.db.Rebind(targetQuery), args...); err != nil {
+		return nil, fmt.Errorf("failed to get campaign services: %w", err)
+	}
+
+	serviceIDsByCampaign := make(map[int64][]int64, len(campaigns))
+	for _, target := range targets {
+		serviceIDsByCampaign[target.CampaignID] = append(serviceIDsByCampaign[target.CampaignID], target.ServiceID)
+	}
+
+	for i := range campaigns {
+		campaigns[i].ServiceIDs = serviceIDsByCampaign[campaigns[i].ID]
+	}
+
+	return campaigns, nil
+}
+
+// GetLastCampaignChange returns the last time at or before at that a campaign
+// started, ended or was changed, or nil when there are no campaigns. Deleted
+// and inactive campaigns are included, since their removal changed prices too.
+func (r *CampaignRepository) GetLastCampaignChange(ctx context.Context, at time.Time) (*time.Time, error) {
+	query := `
+		SELECT MAX(GREATEST(
+			updated_at,
+			CASE WHEN starts_at <= ? THEN starts_at ELSE updated_at END,
+			CASE WHEN ends_at <= ? THEN ends_at ELSE updated_at END
+		))
+		FROM price_campaigns
+	`
+
+	var changedAt sql.NullTime
+	if err := r.db.GetContext(ctx, &changedAt, query, at, at); err != nil {
+		return nil, fmt.Errorf("failed to get last campaign change: %w", err)
+	}
+
+	if !changedAt.Valid {
+		return nil, nil
+	}
+
+	return &changedAt.Time, nil
+}
+
+// CreateCampaign creates a new campaign with its targeted services
+func (r *CampaignRepository) CreateCampaign(ctx context.Context, campaign *entity.PriceCampaign) error {
+	return withTransaction(ctx, r.db, func(tx *sqlx.Tx) error {
+		query := `
+			INSERT INTO price_campaigns (
+				name, discount_type, discount_value, starts_at, ends_at,
+				applies_to_all, is_active, created_at, updated_at
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`
+
+		now := now()
+		campaign.CreatedAt = now
+		campaign.UpdatedAt = now
+
+		result, err := tx.ExecContext(
+			ctx,
+			query,
+			campaign.Name,
+			campaign.DiscountType,
+			campaign.DiscountValue,
+			campaign.StartsAt,
+			campaign.EndsAt,
+			campaign.AppliesToAll,
+			campaign.IsActive,
+			campaign.CreatedAt,
+			campaign.UpdatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create campaign: %w", err)
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to get last insert ID: %w", err)
+		}
+		campaign.ID = id
+
+		for _, serviceID := range campaign.ServiceIDs {
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO price_campaign_services (campaign_id, service_id)
+				VALUES (?, ?)
+			`, campaign.ID, serviceID); err != nil {
+				return fmt.Errorf("failed to add campaign service: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// EndCampaign ends a campaign at the given time. It reports false when the
+// campaign does not exist.
+func (r *CampaignRepository) EndCampaign(ctx context.Context, id int64, endsAt time.Time) (bool, error) {
+	query := `
+		UPDATE price_campaigns
+		SET ends_at = ?,
+			updated_at = ?
+		WHERE id = ?
+		AND ` + softDeleteCondition("price_campaigns")
+
+	result, err := r.db.ExecContext(ctx, query, endsAt, now(), id)
+	if err != nil {
+		return false, fmt.Errorf("failed to end campaign: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rows > 0, nil
+}
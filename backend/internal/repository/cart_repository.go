@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// CartRepository handles database operations for carts
+type CartRepository struct {
+	db *sqlx.DB
+}
+
+// NewCartRepository creates a new CartRepository
+func NewCartRepository(database *Database) *CartRepository {
+	return &CartRepository{
+		db: database.DB,
+	}
+}
+
+// GetCartByToken retrieves an unexpired cart with its items by token
+func (r *CartRepository) GetCartByToken(ctx context.Context, token string) (*entity.CartWithItems, error) {
+	query := `
+		SELECT id, token, status, payment_id, expires_at, created_at, updated_at, deleted_at
+		FROM carts
+		WHERE ` + softDeleteCondition("carts") + `
+		AND token = ?
+		AND expires_at > ?
+	`
+
+	var cart entity.Cart
+	if err := r.db.GetContext(ctx, &cart, query, token, now()); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Cart not found
+		}
+		return nil, fmt.Errorf("failed to get cart by token: %w", err)
+	}
+
+	var items []entity.CartItem
+	if err := r.db.SelectContext(ctx, &items, `
+		SELECT id, cart_id, service_id, quantity, purchase_type, created_at, updated_at
+		FROM cart_items
+		WHERE cart_id = ?
+		ORDER BY id
+	`, cart.ID); err != nil {
+		return nil, fmt.Errorf("failed to get cart items: %w", err)
+	}
+
+	return &entity.CartWithItems{
+		Cart:  cart,
+		Items: items,
+	}, nil
+}
+
+// SaveCart creates the cart for its token, or updates the existing one, and
+// replaces its items. An expired cart with the same token is reopened.
+func (r *CartRepository) SaveCart(ctx context.Context, cart *entity.Cart, items []entity.CartItem) error {
+	return withTransaction(ctx, r.db, func(tx *sqlx.Tx) error {
+		now := now()
+		cart.UpdatedAt = now
+
+		var existingID int64
+		err := tx.GetContext(ctx, &existingID, `
+			SELECT id
+			FROM carts
+			WHERE token = ?
+			FOR UPDATE
+		`, cart.Token)
+
+		switch {
+		case err == nil:
+			cart.ID = existingID
+			if _, err := tx.ExecContext(ctx, `
+				UPDATE carts
+				SET status = ?,
+				    payment_id = ?,
+				    expires_at = ?,
+				    updated_at = ?,
+				    deleted_at = NULL
+				WHERE id = ?
+			`, cart.Status, cart.PaymentID, cart.ExpiresAt, cart.UpdatedAt, cart.ID); err != nil {
+				return fmt.Errorf("failed to update cart: %w", err)
+			}
+
+			if _, err := tx.ExecContext(ctx, `DELETE FROM cart_items WHERE cart_id = ?`, cart.ID); err != nil {
+				return fmt.Errorf("failed to clear cart items: %w", err)
+			}
+		case errors.Is(err, sql.ErrNoRows):
+			cart.CreatedAt = now
+			result, err := tx.ExecContext(ctx, `
+				INSERT INTO carts (
+					token, status, payment_id, expires_at, created_at, updated_at
+				) VALUES (?, ?, ?, ?, ?, ?)
+			`, cart.Token, cart.Status, cart.PaymentID, cart.ExpiresAt, cart.CreatedAt, cart.UpdatedAt)
+			if err != nil {
+				return fmt.Errorf("failed to create cart: %w", err)
+			}
+
+			id, err := result.LastInsertId()
+			if err != nil {
+				return fmt.Errorf("failed to get last insert ID: %w", err)
+			}
+			cart.ID = id
+		default:
+			return fmt.Errorf("failed to lock cart: %w", err)
+		}
+
+		for i := range items {
+			item := &items[i]
+			item.CartID = cart.ID
+			item.CreatedAt = now
+			item.UpdatedAt = now
+
+			result, err := tx.ExecContext(ctx, `
+				INSERT INTO cart_items (
+					cart_id, service_id, quantity, purchase_type, created_at, updated_at
+				) VALUES (?, ?, ?, ?, ?, ?)
+			`, item.CartID, item.ServiceID, item.Quantity, item.PurchaseType, item.CreatedAt, item.UpdatedAt)
+			if err != nil {
+				return fmt.Errorf("failed to create cart item: %w", err)
+			}
+
+			id, err := result.LastInsertId()
+			if err != nil {
+				return fmt.Errorf("failed to get last insert ID: %w", err)
+			}
+			item.ID = id
+		}
+
+		return nil
+	})
+}
+
+// MarkCartCheckedOut marks a cart as checked out by a payment
+func (r *CartRepository) MarkCartCheckedOut(ctx context.Context, token string, paymentID int64) error {
+	query := `
+		UPDATE carts
+		SET status = ?,
+		    payment_id = ?,
+		    updated_at = ?
+		WHERE token = ?
+		AND ` + softDeleteCondition("carts")
+
+	result, err := r.db.ExecContext(ctx, query, entity.CartStatusCheckedOut, paymentID, now(), token)
+	if err != nil {
+		return fmt.Errorf("failed to mark cart checked out: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("cart not found or already deleted")
+	}
+
+	return nil
+}
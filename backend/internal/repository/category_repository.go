@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// CategoryRepository handles database operations for service categories
+type CategoryRepository struct {
+	db *sqlx.DB
+}
+
+// NewCategoryRepository creates a new CategoryRepository
+func NewCategoryRepository(database *Database) *CategoryRepository {
+	return &CategoryRepository{
+		db: database.DB,
+	}
+}
+
+// GetCategories retrieves all active categories ordered for display
+func (r *CategoryRepository) GetCategories(ctx context.Context) ([]entity.ServiceCategory, error) {
+	query := `
+		SELECT id, parent_id, slug, name, description, sort_order, is_active,
+		       created_at, updated_at, deleted_at
+		FROM service_categories
+		WHERE ` + softDeleteCondition("service_categories") + `
+		AND is_active = true
+		ORDER BY sort_order, name, id
+	`
+
+	var categories []entity.ServiceCategory
+	if err := r.db.SelectContext(ctx, &categories, query); err != nil {
+		return nil, fmt.Errorf("failed to get categories: %w", err)
+	}
+
+	return categories, nil
+}
+
+// GetCategoryBySlug retrieves an active category by slug
+func (r *CategoryRepository) GetCategoryBySlug(ctx context.Context, slug string) (*entity.ServiceCategory, error) {
+	query := `
+		SELECT id, parent_id, slug, name, description, sort_order, is_active,
+		       created_at, updated_at, deleted_at
+		FROM service_categories
+		WHERE ` + softDeleteCondition("service_categories") + `
+		AND is_active = true
+		AND slug = ?
+	`
+
+	var category entity.ServiceCategory
+	if err := r.db.GetContext(ctx, &category, query, slug); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Category not found
+		}
+		return nil, fmt.Errorf("failed to get category by slug: %w", err)
+	}
+
+	return &category, nil
+}
+
+// CreateCategory creates a new category
+func (r *CategoryRepository) CreateCategory(ctx context.Context, category *entity.ServiceCategory) error {
+	query := `
+		INSERT INTO service_categories (
+			parent_id, slug, name, description, sort_order, is_active,
+			created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	now := now()
+	category.CreatedAt = now
+	category.UpdatedAt = now
+
+	result, err := r.db.ExecContext(
+		ctx,
+		query,
+		category.ParentID,
+		category.Slug,
+		category.Name,
+		category.Description,
+		category.SortOrder,
+		category.IsActive,
+		category.CreatedAt,
+		category.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create category: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+
+	category.ID = id
+	return nil
+}
+
+// UpdateCategory updates an existing category
+func (r *CategoryRepository) UpdateCategory(ctx context.Context, category *entity.ServiceCategory) error {
+	query := `
+		UPDATE service_categories
+		SET parent_id = ?,
+			slug = ?,
+			name = ?,
+			description = ?,
+			sort_order = ?,
+			is_active = ?,
+			updated_at = ?
+		WHERE id = ?
+		AND ` + softDeleteCondition("service_categories")
+
+	category.UpdatedAt = now()
+
+	result, err := r.db.ExecContext(
+		ctx,
+		query,
+		category.ParentID,
+		category.Slug,
+		category.Name,
+		category.Description,
+		category.SortOrder,
+		category.IsActive,
+		category.UpdatedAt,
+		category.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update category: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("category not found or already deleted")
+	}
+
+	return nil
+}
+
+// AssignServiceCategory assigns a service to a category, or removes its category when categoryID is nil
+func (r *CategoryRepository) AssignServiceCategory(ctx context.Context, serviceID int64, categoryID *int64) error {
+	query := `
+		UPDATE services
+		SET category_id = ?,
+			updated_at = ?
+		WHERE id = ?
+		AND ` + softDeleteCondition("services")
+
+	result, err := r.db.ExecContext(ctx, query, categoryID, now(), serviceID)
+	if err != nil {
+		return fmt.Errorf("failed to assign service category: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("service not found or already deleted")
+	}
+
+	return nil
+}
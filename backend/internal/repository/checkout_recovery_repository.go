@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// CheckoutRecoveryRepository handles database operations for abandoned checkout
+// reminders and email opt-outs
+type CheckoutRecoveryRepository struct {
+	db *sqlx.DB
+}
+
+// NewCheckoutRecoveryRepository creates a new CheckoutRecoveryRepository
+func NewCheckoutRecoveryRepository(database *Database) *CheckoutRecoveryRepository {
+	return &CheckoutRecoveryRepository{
+		db: database.DB,
+	}
+}
+
+// ClaimRecovery records that a reminder is about to be sent for a payment. It
+// reports false when the payment already has a reminder, so each payment is only
+// contacted once.
+func (r *CheckoutRecoveryRepository) ClaimRecovery(ctx context.Context, recovery *entity.CheckoutRecovery) (bool, error) {
+	recovery.CreatedAt = now()
+
+	result, err := r.db.ExecContext(ctx, `
+		INSERT IGNORE INTO checkout_recoveries (payment_id, token, email, created_at)
+		VALUES (?, ?, ?, ?)
+	`, recovery.PaymentID, recovery.Token, recovery.Email, recovery.CreatedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim checkout recovery: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return false, nil
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return false, fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+	recovery.ID = id
+
+	return true, nil
+}
+
+// ReleaseRecovery removes a claimed reminder that could not be sent, so a later
+// run can try again
+func (r *CheckoutRecoveryRepository) ReleaseRecovery(ctx context.Context, id int64) error {
+	if _, err := r.db.ExecContext(ctx, `
+		DELETE FROM checkout_recoveries
+		WHERE id = ?
+		AND sent_at IS NULL
+	`, id); err != nil {
+		return fmt.Errorf("failed to release checkout recovery: %w", err)
+	}
+
+	return nil
+}
+
+// MarkRecoverySent records when the reminder was sent
+func (r *CheckoutRecoveryRepository) MarkRecoverySent(ctx context.Context, id int64) error {
+	if _, err := r.db.ExecContext(ctx, `
+		UPDATE checkout_recoveries
+		SET sent_at = ?
+		WHERE id = ?
+	`, now(), id); err != nil {
+		return fmt.Errorf("failed to mark checkout recovery sent: %w", err)
+	}
+
+	return nil
+}
+
+// MarkRecoveryResumed records when the customer first followed the reminder link
+func (r *CheckoutRecoveryRepository) MarkRecoveryResumed(ctx context.Context, id int64) error {
+	if _, err := r.db.ExecContext(ctx, `
+		UPDATE checkout_recoveries
+		SET resumed_at = ?
+		WHERE id = ?
+		AND resumed_at IS NULL
+	`, now(), id); err != nil {
+		return fmt.Errorf("failed to mark checkout recovery resumed: %w", err)
+	}
+
+	return nil
+}
+
+// GetRecoveryByToken retrieves a sent reminder by its token
+func (r *CheckoutRecoveryRepository) GetRecoveryByToken(ctx context.Context, token string) (*entity.CheckoutRecovery, error) {
+	query := `
+		SELECT id, payment_id, token, email, sent_at, resumed_at, created_at
+		FROM checkout_recoveries
+		WHERE token = ?
+		AND sent_at IS NOT NULL
+	`
+
+	var recovery entity.CheckoutRecovery
+	if err := r.db.GetContext(ctx, &recovery, query, token); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Recovery not found
+		}
+		return nil, fmt.Errorf("failed to get checkout recovery by token: %w", err)
+	}
+
+	return &recovery, nil
+}
+
+// IsEmailOptedOut reports whether an email address has opted out of reminders
+func (r *CheckoutRecoveryRepository) IsEmailOptedOut(ctx context.Context, email string) (bool, error) {
+	var count int
+	if err := r.db.GetContext(ctx, &count, `
+		SELECT COUNT(*)
+		FROM email_opt_outs
+		WHERE email = ?
+	`, email); err != nil {
+		return false, fmt.Errorf("failed to check email opt-out: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+// CreateEmailOptOut opts an email address out of reminders. Opting out twice is
+// not an error.
+func (r *CheckoutRecoveryRepository) CreateEmailOptOut(ctx context.Context, email string) error {
+	if _, err := r.db.ExecContext(ctx, `
+		INSERT IGNORE INTO email_opt_outs (email, created_at)
+		VALUES (?, ?)
+	`, email, now()); err != nil {
+		return fmt.Errorf("failed to create email opt-out: %w", err)
+	}
+
+	return nil
+}
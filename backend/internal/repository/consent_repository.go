@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// ConsentRepository handles database operations for customer consents. Consents
+// are only ever inserted; the current state of a consent is its latest record.
+type ConsentRepository struct {
+	db *sqlx.DB
+}
+
+// NewConsentRepository creates a new ConsentRepository
+func NewConsentRepository(database *Database) *ConsentRepository {
+	return &ConsentRepository{
+		db: database.DB,
+	}
+}
+
+// CreateConsents records the given consents in one transaction
+func (r *ConsentRepository) CreateConsents(ctx context.Context, consents []entity.CustomerConsent) error {
+	if len(consents) == 0 {
+		return nil
+	}
+
+	return withTransaction(ctx, r.db, func(tx *sqlx.Tx) error {
+		now := now()
+
+		for i := range consents {
+			consent := &consents[i]
+			consent.CreatedAt = now
+
+			result, err := tx.ExecContext(ctx, `
+				INSERT INTO customer_consents (
+					customer_id, consent_type, granted, policy_version, source,
+					ip_address, user_agent, created_at
+				) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			`,
+				consent.CustomerID,
+				consent.ConsentType,
+				consent.Granted,
+				consent.PolicyVersion,
+				consent.Source,
+				consent.IPAddress,
+				consent.UserAgent,
+				consent.CreatedAt,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to create consent: %w", err)
+			}
+
+			id, err := result.LastInsertId()
+			if err != nil {
+				return fmt.Errorf("failed to get last insert ID: %w", err)
+			}
+			consent.ID = id
+		}
+
+		return nil
+	})
+}
+
+// GetCurrentConsents retrieves the latest record of each consent of a customer
+func (r *ConsentRepository) GetCurrentConsents(ctx context.Context, customerID int64) ([]entity.CustomerConsent, error) {
+	query := `
+		SELECT id, customer_id, consent_type, granted, policy_version, source,
+		       ip_address, user_agent, created_at
+		FROM customer_consents
+		WHERE id IN (
+			SELECT MAX(id)
+			FROM customer_consents
+			WHERE customer_id = ?
+			GROUP BY consent_type
+		)
+		ORDER BY consent_type
+	`
+
+	var consents []entity.CustomerConsent
+	if err := r.db.SelectContext(ctx, &consents, query, customerID); err != nil {
+		return nil, fmt.Errorf("failed to get current consents: %w", err)
+	}
+
+	return consents, nil
+}
+
+// GetLatestConsent retrieves the latest record of a consent of a customer
+func (r *ConsentRepository) GetLatestConsent(ctx context.Context, customerID int64, consentType string) (*entity.CustomerConsent, error) {
+	query := `
+		SELECT id, customer_id, consent_type, granted, policy_version, source,
+		       ip_address, user_agent, created_at
+		FROM customer_consents
+		WHERE customer_id = ?
+		AND consent_type = ?
+		ORDER BY id DESC
+		LIMIT 1
+	`
+
+	var consent entity.CustomerConsent
+	if err := r.db.GetContext(ctx, &consent, query, customerID, consentType); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // No consent recorded
+		}
+		return nil, fmt.Errorf("failed to get latest consent: %w", err)
+	}
+
+	return &consent, nil
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// ContactRepository handles database operations for contact form messages
+type ContactRepository struct {
+	db *sqlx.DB
+}
+
+// NewContactRepository creates a new ContactRepository
+func NewContactRepository(database *Database) *ContactRepository {
+	return &ContactRepository{
+		db: database.DB,
+	}
+}
+
+// CreateContactMessage stores a contact message and queues the email forwarding
+// it to staff in the same transaction
+func (r *ContactRepository) CreateContactMessage(ctx context.Context, message *entity.ContactMessage, email *entity.OutboxEmail) error {
+	return withTransaction(ctx, r.db, func(tx *sqlx.Tx) error {
+		if err := enqueueEmail(ctx, tx, email); err != nil {
+			return err
+		}
+
+		message.OutboxEmailID = &email.ID
+		message.CreatedAt = now()
+
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO contact_messages (
+				name, email, subject, message, ip_address, user_agent,
+				outbox_email_id, created_at
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`,
+			message.Name,
+			message.Email,
+			message.Subject,
+			message.Message,
+			message.IPAddress,
+			message.UserAgent,
+			message.OutboxEmailID,
+			message.CreatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create contact message: %w", err)
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to get last insert ID: %w", err)
+		}
+		message.ID = id
+
+		return nil
+	})
+}
+
+// CountMessagesFromIP counts the contact messages sent from an IP address since the given time
+func (r *ContactRepository) CountMessagesFromIP(ctx context.Context, ipAddress string, since time.Time) (int, error) {
+	var count int
+	if err := r.db.GetContext(ctx, &count, `
+		SELECT COUNT(*)
+		FROM contact_messages
+		WHERE ip_address = ?
+		AND created_at >= ?
+	`, ipAddress, since.UTC()); err != nil {
+		return 0, fmt.Errorf("failed to count contact messages: %w", err)
+	}
+
+	return count, nil
+}
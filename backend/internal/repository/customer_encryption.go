@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/svenskhalsovard/api/internal/encryption"
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// customerFields returns pointers to the customer fields that are encrypted at
+// rest. The national ID is not included; it is encrypted by the services
+// before it reaches the repositories.
+func customerFields(customer *entity.Customer) []*string {
+	return []*string{
+		&customer.FirstName,
+		&customer.LastName,
+		&customer.Email,
+		&customer.Phone,
+		&customer.StreetAddress,
+		&customer.PostalCode,
+		&customer.City,
+		&customer.AdditionalInfo,
+	}
+}
+
+// encryptCustomer returns a copy of customer with its personal data encrypted,
+// ready to be written to the customers table
+func encryptCustomer(keyring *encryption.Keyring, customer *entity.Customer) (*entity.Customer, error) {
+	encrypted := *customer
+	for _, field := range customerFields(&encrypted) {
+		if *field == "" {
+			continue
+		}
+
+		value, err := keyring.Encrypt(*field)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt customer: %w", err)
+		}
+		*field = value
+	}
+
+	return &encrypted, nil
+}
+
+// decryptCustomer decrypts the personal data of a customer read from the
+// customers table. Values that are not encrypted yet are left as is, so rows
+// written before encryption was enabled can be read until they are migrated.
+func decryptCustomer(keyring *encryption.Keyring, customer *entity.Customer) error {
+	for _, field := range customerFields(customer) {
+		if !encryption.IsEncrypted(*field) {
+			continue
+		}
+
+		value, err := keyring.Decrypt(*field)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt customer %d: %w", customer.ID, err)
+		}
+		*field = value
+	}
+
+	return nil
+}
+
+// emailHash returns the blind index of an email address
+func emailHash(keyring *encryption.Keyring, email string) string {
+	return keyring.BlindIndex(strings.ToLower(strings.TrimSpace(email)))
+}
+
+// customerEmailCondition returns a condition matching the customer with the
+// given email on table, and its arguments. Rows that have not been migrated yet
+// have no hash and are matched on the plaintext email. Erased customers are
+// never matched.
+func customerEmailCondition(keyring *encryption.Keyring, table string, email string) (string, []interface{}) {
+	condition := fmt.Sprintf(
+		"(%[1]s.erased_at IS NULL AND (%[1]s.email_hash = ? OR (%[1]s.email_hash IS NULL AND %[1]s.email = ?)))",
+		table,
+	)
+	return condition, []interface{}{emailHash(keyring, email), email}
+}
+
+// snapshotFields returns pointers to the fields of a customer snapshot that are
+// encrypted at rest, matching customerFields
+func snapshotFields(snapshot *entity.CustomerSnapshot) []*string {
+	return []*string{
+		&snapshot.FirstName,
+		&snapshot.LastName,
+		&snapshot.Email,
+		&snapshot.Phone,
+		&snapshot.StreetAddress,
+		&snapshot.PostalCode,
+		&snapshot.City,
+		&snapshot.AdditionalInfo,
+	}
+}
+
+// encryptSnapshot returns a copy of snapshot with its personal data encrypted,
+// ready to be written to the payments or bookings table
+func encryptSnapshot(keyring *encryption.Keyring, snapshot entity.CustomerSnapshot) (entity.CustomerSnapshot, error) {
+	for _, field := range snapshotFields(&snapshot) {
+		if *field == "" {
+			continue
+		}
+
+		value, err := keyring.Encrypt(*field)
+		if err != nil {
+			return entity.CustomerSnapshot{}, fmt.Errorf("failed to encrypt customer snapshot: %w", err)
+		}
+		*field = value
+	}
+
+	return snapshot, nil
+}
+
+// decryptSnapshot decrypts the customer snapshot of a payment or booking.
+// Values that are not encrypted, such as those of rows written before
+// encryption was enabled and erased rows, are left as is.
+func decryptSnapshot(keyring *encryption.Keyring, snapshot *entity.CustomerSnapshot) error {
+	for _, field := range snapshotFields(snapshot) {
+		if !encryption.IsEncrypted(*field) {
+			continue
+		}
+
+		value, err := keyring.Decrypt(*field)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt customer snapshot: %w", err)
+		}
+		*field = value
+	}
+
+	return nil
+}
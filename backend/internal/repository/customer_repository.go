@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/svenskhalsovard/api/internal/encryption"
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// CustomerRepository handles database operations for customer accounts. The
+// personal data of customers is encrypted at rest with keyring.
+type CustomerRepository struct {
+	db      *sqlx.DB
+	keyring *encryption.Keyring
+}
+
+// NewCustomerRepository creates a new CustomerRepository
+func NewCustomerRepository(database *Database, keyring *encryption.Keyring) *CustomerRepository {
+	return &CustomerRepository{
+		db:      database.DB,
+		keyring: keyring,
+	}
+}
+
+// GetCustomerByID retrieves a customer by ID
+func (r *CustomerRepository) GetCustomerByID(ctx context.Context, id int64) (*entity.Customer, error) {
+	query := `
+		SELECT id, first_name, last_name, email, phone, street_address, 
+		       postal_code, city, additional_info, national_id_encrypted, email_verified_at,
+		       created_at, updated_at, deleted_at
+		FROM customers
+		WHERE ` + softDeleteCondition("customers") + `
+		AND id = ?
+	`
+
+	var customer entity.Customer
+	if err := r.db.GetContext(ctx, &customer, query, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Customer not found
+		}
+		return nil, fmt.Errorf("failed to get customer by ID: %w", err)
+	}
+
+	if err := decryptCustomer(r.keyring, &customer); err != nil {
+		return nil, err
+	}
+
+	return &customer, nil
+}
+
+// GetCustomerByEmail retrieves a customer by email
+func (r *CustomerRepository) GetCustomerByEmail(ctx context.Context, email string) (*entity.Customer, error) {
+	emailCondition, args := customerEmailCondition(r.keyring, "customers", email)
+	query := `
+		SELECT id, first_name, last_name, email, phone, street_address, 
+		       postal_code, city, additional_info, national_id_encrypted, email_verified_at,
+		       created_at, updated_at, deleted_at
+		FROM customers
+		WHERE ` + softDeleteCondition("customers") + `
+		AND ` + emailCondition
+
+	var customer entity.Customer
+	if err := r.db.GetContext(ctx, &customer, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Customer not found
+		}
+		return nil, fmt.Errorf("failed to get customer by email: %w", err)
+	}
+
+	if err := decryptCustomer(r.keyring, &customer); err != nil {
+		return nil, err
+	}
+
+	return &customer, nil
+}
+
+// CreateCustomer creates a new customer. When a customer with the same email
+// already exists, that customer is returned unchanged instead, so an existing
+// profile is never overwritten. Customers are looked up before inserting, since
+// rows not yet reencrypted have no email hash for the unique key to match.
+func (r *CustomerRepository) CreateCustomer(ctx context.Context, customer *entity.Customer) (*entity.Customer, error) {
+	existing, err := r.GetCustomerByEmail(ctx, customer.Email)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return existing, nil
+	}
+
+	now := now()
+	customer.CreatedAt = now
+	customer.UpdatedAt = now
+
+	encrypted, err := encryptCustomer(r.keyring, customer)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO customers (
+			first_name, last_name, email, email_hash, phone, street_address, 
+			postal_code, city, additional_info, national_id_encrypted, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		encrypted.FirstName,
+		encrypted.LastName,
+		encrypted.Email,
+		emailHash(r.keyring, customer.Email),
+		encrypted.Phone,
+		encrypted.StreetAddress,
+		encrypted.PostalCode,
+		encrypted.City,
+		encrypted.AdditionalInfo,
+		encrypted.NationalIDEncrypted,
+		encrypted.CreatedAt,
+		encrypted.UpdatedAt,
+	)
+	if isDuplicateKey(err, "email_hash") {
+		// The customer was created concurrently, or exists but is deleted
+		existing, err = r.GetCustomerByEmail(ctx, customer.Email)
+		if err != nil {
+			return nil, err
+		}
+		if existing == nil {
+			return nil, fmt.Errorf("customer with email exists but is deleted")
+		}
+		return existing, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create customer: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+	customer.ID = id
+
+	return customer, nil
+}
+
+// UpdateCustomerProfile updates the contact details of a customer. The email
+// address is not changed.
+func (r *CustomerRepository) UpdateCustomerProfile(ctx context.Context, customer *entity.Customer) error {
+	query := `
+		UPDATE customers
+		SET first_name = ?,
+			last_name = ?,
+			phone = ?,
+			street_address = ?,
+			postal_code = ?,
+			city = ?,
+			additional_info = ?,
+			national_id_encrypted = ?,
+			updated_at = ?
+		WHERE id = ?
+		AND ` + softDeleteCondition("customers")
+
+	customer.UpdatedAt = now()
+
+	encrypted, err := encryptCustomer(r.keyring, customer)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.ExecContext(
+		ctx,
+		query,
+		encrypted.FirstName,
+		encrypted.LastName,
+		encrypted.Phone,
+		encrypted.StreetAddress,
+		encrypted.PostalCode,
+		encrypted.City,
+		encrypted.AdditionalInfo,
+		encrypted.NationalIDEncrypted,
+		encrypted.UpdatedAt,
+		encrypted.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update customer: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("customer not found or already deleted")
+	}
+
+	return nil
+}
+
+// MarkEmailVerified records that a customer proved ownership of their email
+func (r *CustomerRepository) MarkEmailVerified(ctx context.Context, id int64) error {
+	if _, err := r.db.ExecContext(ctx, `
+		UPDATE customers
+		SET email_verified_at = COALESCE(email_verified_at, ?)
+		WHERE id = ?
+	`, now(), id); err != nil {
+		return fmt.Errorf("failed to mark customer email verified: %w", err)
+	}
+
+	return nil
+}
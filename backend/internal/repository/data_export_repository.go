@@ -0,0 +1,354 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/svenskhalsovard/api/internal/encryption"
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// DataExportRepository handles database operations for GDPR data exports
+type DataExportRepository struct {
+	db      *sqlx.DB
+	keyring *encryption.Keyring
+}
+
+// NewDataExportRepository creates a new DataExportRepository
+func NewDataExportRepository(database *Database, keyring *encryption.Keyring) *DataExportRepository {
+	return &DataExportRepository{
+		db:      database.DB,
+		keyring: keyring,
+	}
+}
+
+// GetCustomerData retrieves the personal data stored about a customer. Unlike
+// the queries used by the shop, it includes deleted rows, since they are still
+// held. Messages, notifications and opt-outs are matched on the email of the
+// customer and the emails given at checkout.
+func (r *DataExportRepository) GetCustomerData(ctx context.Context, customer *entity.Customer) (*entity.CustomerData, error) {
+	data := &entity.CustomerData{}
+
+	payments, err := r.getPayments(ctx, customer.ID)
+	if err != nil {
+		return nil, err
+	}
+	data.Payments = payments
+
+	bookings, err := r.getBookings(ctx, customer.ID)
+	if err != nil {
+		return nil, err
+	}
+	data.Bookings = bookings
+
+	checkoutEmails := make([]string, 0, len(payments))
+	for _, payment := range payments {
+		checkoutEmails = append(checkoutEmails, payment.Payment.Email)
+	}
+	emails := customerEmails(customer, checkoutEmails)
+
+	data.ContactMessages = []entity.ContactMessage{}
+	if err := r.selectIn(ctx, &data.ContactMessages, `
+		SELECT id, name, email, subject, message, ip_address, user_agent,
+		       outbox_email_id, created_at
+		FROM contact_messages
+		WHERE email IN (?)
+		ORDER BY id
+	`, emails); err != nil {
+		return nil, fmt.Errorf("failed to get contact messages: %w", err)
+	}
+
+	data.Notifications = []entity.OutboxEmail{}
+	if err := r.selectIn(ctx, &data.Notifications, `
+		SELECT id, recipient, reply_to, subject, body, status, attempts, last_error,
+		       available_at, sent_at, created_at, updated_at
+		FROM email_outbox
+		WHERE recipient IN (?)
+		ORDER BY id
+	`, emails); err != nil {
+		return nil, fmt.Errorf("failed to get notifications: %w", err)
+	}
+
+	data.EmailOptOuts = []entity.EmailOptOut{}
+	if err := r.selectIn(ctx, &data.EmailOptOuts, `
+		SELECT id, email, created_at
+		FROM email_opt_outs
+		WHERE email IN (?)
+		ORDER BY id
+	`, emails); err != nil {
+		return nil, fmt.Errorf("failed to get email opt-outs: %w", err)
+	}
+
+	data.Consents = []entity.CustomerConsent{}
+	if err := r.db.SelectContext(ctx, &data.Consents, `
+		SELECT id, customer_id, consent_type, granted, policy_version, source,
+		       ip_address, user_agent, created_at
+		FROM customer_consents
+		WHERE customer_id = ?
+		ORDER BY id
+	`, customer.ID); err != nil {
+		return nil, fmt.Errorf("failed to get consents: %w", err)
+	}
+
+	data.CheckoutRecoveries = []entity.CheckoutRecovery{}
+	if len(payments) > 0 {
+		paymentIDs := make([]int64, 0, len(payments))
+		for _, payment := range payments {
+			paymentIDs = append(paymentIDs, payment.Payment.ID)
+		}
+
+		if err := r.selectIn(ctx, &data.CheckoutRecoveries, `
+			SELECT id, payment_id, token, email, sent_at, resumed_at, created_at
+			FROM checkout_recoveries
+			WHERE payment_id IN (?)
+			ORDER BY id
+		`, paymentIDs); err != nil {
+			return nil, fmt.Errorf("failed to get checkout recoveries: %w", err)
+		}
+	}
+
+	return data, nil
+}
+
+// CreateDataExport creates a new data export
+func (r *DataExportRepository) CreateDataExport(ctx context.Context, export *entity.DataExport) error {
+	export.CreatedAt = now()
+
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO data_exports (
+			customer_id, token_hash, requested_by, archive_encrypted, expires_at, created_at
+		) VALUES (?, ?, ?, ?, ?, ?)
+	`,
+		export.CustomerID,
+		export.TokenHash,
+		export.RequestedBy,
+		export.ArchiveEncrypted,
+		export.ExpiresAt,
+		export.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create data export: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+	export.ID = id
+
+	return nil
+}
+
+// GetDataExportByTokenHash retrieves a data export by the hash of its token
+func (r *DataExportRepository) GetDataExportByTokenHash(ctx context.Context, tokenHash string) (*entity.DataExport, error) {
+	query := `
+		SELECT id, customer_id, token_hash, requested_by, archive_encrypted,
+		       expires_at, downloaded_at, created_at
+		FROM data_exports
+		WHERE token_hash = ?
+	`
+
+	var export entity.DataExport
+	if err := r.db.GetContext(ctx, &export, query, tokenHash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Data export not found
+		}
+		return nil, fmt.Errorf("failed to get data export: %w", err)
+	}
+
+	return &export, nil
+}
+
+// MarkDataExportDownloaded records the first download of a data export
+func (r *DataExportRepository) MarkDataExportDownloaded(ctx context.Context, id int64) error {
+	if _, err := r.db.ExecContext(ctx, `
+		UPDATE data_exports
+		SET downloaded_at = COALESCE(downloaded_at, ?)
+		WHERE id = ?
+	`, now(), id); err != nil {
+		return fmt.Errorf("failed to mark data export downloaded: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpiredArchives removes the archives of data exports that expired
+// before the given time and returns how many were removed
+func (r *DataExportRepository) DeleteExpiredArchives(ctx context.Context, before time.Time) (int, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE data_exports
+		SET archive_encrypted = NULL
+		WHERE expires_at < ?
+		AND archive_encrypted IS NOT NULL
+	`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired data export archives: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return int(rows), nil
+}
+
+// getPayments retrieves all payments of a customer with their items
+func (r *DataExportRepository) getPayments(ctx context.Context, customerID int64) ([]entity.PaymentWithItems, error) {
+	var payments []entity.Payment
+	if err := r.db.SelectContext(ctx, &payments, `
+		SELECT id, external_payment_id, customer_id, customer_first_name, customer_last_name,
+		       customer_email, customer_phone, customer_street_address, customer_postal_code,
+		       customer_city, customer_additional_info, customer_national_id_encrypted,
+		       amount, currency, status,
+		       payment_method, order_reference, transaction_type, promo_code_id,
+		       discount_amount, gift_card_id, gift_card_amount, recovery_consent, error_message,
+		       created_at, updated_at, deleted_at
+		FROM payments
+		WHERE customer_id = ?
+		ORDER BY id
+	`, customerID); err != nil {
+		return nil, fmt.Errorf("failed to get payments: %w", err)
+	}
+
+	for i := range payments {
+		if err := decryptSnapshot(r.keyring, &payments[i].CustomerSnapshot); err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]entity.PaymentWithItems, 0, len(payments))
+	if len(payments) == 0 {
+		return result, nil
+	}
+
+	paymentIDs := make([]int64, 0, len(payments))
+	for _, payment := range payments {
+		paymentIDs = append(paymentIDs, payment.ID)
+	}
+
+	var items []entity.PaymentItem
+	if err := r.selectIn(ctx, &items, `
+		SELECT id, payment_id, service_id, service_name, quantity, unit_price,
+		       total_price, discount_amount, vat_percent, purchase_type, created_at,
+		       updated_at, deleted_at
+		FROM payment_items
+		WHERE payment_id IN (?)
+		ORDER BY id
+	`, paymentIDs); err != nil {
+		return nil, fmt.Errorf("failed to get payment items: %w", err)
+	}
+
+	itemsByPayment := make(map[int64][]entity.PaymentItem, len(payments))
+	for _, item := range items {
+		itemsByPayment[item.PaymentID] = append(itemsByPayment[item.PaymentID], item)
+	}
+
+	for _, payment := range payments {
+		result = append(result, entity.PaymentWithItems{
+			Payment:  payment,
+			Items:    itemsByPayment[payment.ID],
+			Customer: payment.CustomerSnapshot.ToCustomer(payment.CustomerID),
+		})
+	}
+
+	return result, nil
+}
+
+// getBookings retrieves all bookings of a customer with their items
+func (r *DataExportRepository) getBookings(ctx context.Context, customerID int64) ([]entity.BookingWithItems, error) {
+	var bookings []entity.Booking
+	if err := r.db.SelectContext(ctx, &bookings, `
+		SELECT id, payment_id, customer_id, customer_first_name, customer_last_name,
+		       customer_email, customer_phone, customer_street_address, customer_postal_code,
+		       customer_city, customer_additional_info, customer_national_id_encrypted,
+		       status, total_amount, booking_number,
+		       notes, confirmed_at, checked_in_at, completed_at, cancelled_at, no_show_at,
+		       created_at, updated_at, deleted_at
+		FROM bookings
+		WHERE customer_id = ?
+		ORDER BY id
+	`, customerID); err != nil {
+		return nil, fmt.Errorf("failed to get bookings: %w", err)
+	}
+
+	for i := range bookings {
+		if err := decryptSnapshot(r.keyring, &bookings[i].CustomerSnapshot); err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]entity.BookingWithItems, 0, len(bookings))
+	if len(bookings) == 0 {
+		return result, nil
+	}
+
+	bookingIDs := make([]int64, 0, len(bookings))
+	for _, booking := range bookings {
+		bookingIDs = append(bookingIDs, booking.ID)
+	}
+
+	var items []entity.BookingItem
+	if err := r.selectIn(ctx, &items, `
+		SELECT id, booking_id, service_id, service_name, quantity, unit_price,
+		       total_price, purchase_type, is_subscription, created_at, updated_at, deleted_at
+		FROM booking_items
+		WHERE booking_id IN (?)
+		ORDER BY id
+	`, bookingIDs); err != nil {
+		return nil, fmt.Errorf("failed to get booking items: %w", err)
+	}
+
+	itemsByBooking := make(map[int64][]entity.BookingItem, len(bookings))
+	for _, item := range items {
+		itemsByBooking[item.BookingID] = append(itemsByBooking[item.BookingID], item)
+	}
+
+	for _, booking := range bookings {
+		result = append(result, entity.BookingWithItems{
+			Booking:  booking,
+			Customer: booking.CustomerSnapshot.ToCustomer(booking.CustomerID),
+			Items:    itemsByBooking[booking.ID],
+		})
+	}
+
+	return result, nil
+}
+
+// selectIn runs a query with a single IN (?) argument
+func (r *DataExportRepository) selectIn(ctx context.Context, dest interface{}, query string, values interface{}) error {
+	query, args, err := sqlx.In(query, values)
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	return r.db.SelectContext(ctx, dest, r.db.Rebind(query), args...)
+}
+
+// customerEmails returns the distinct email addresses of a customer and the
+// emails they gave at checkout
+func customerEmails(customer *entity.Customer, checkoutEmails []string) []string {
+	seen := make(map[string]bool)
+	emails := make([]string, 0, len(checkoutEmails)+1)
+
+	add := func(email string) {
+		key := strings.ToLower(strings.TrimSpace(email))
+		if key == "" || seen[key] {
+			return
+		}
+		seen[key] = true
+		emails = append(emails, email)
+	}
+
+	add(customer.Email)
+	for _, email := range checkoutEmails {
+		add(email)
+	}
+
+	return emails
+}
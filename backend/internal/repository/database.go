@@ -1,13 +1,16 @@
 package repository
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
 	"github.com/rs/zerolog/log"
 
-	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/lib/pq"
 	"github.com/svenskhalsovard/api/internal/config"
 )
@@ -110,6 +113,34 @@ func (d *Database) Transaction(fn func(*sqlx.Tx) error) error {
 	return nil
 }
 
+// withTransaction runs fn in a transaction on db, committing on success and rolling back on error or panic
+func withTransaction(ctx context.Context, db *sqlx.DB, fn func(*sqlx.Tx) error) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			log.Error().Err(rbErr).Msg("Failed to rollback transaction")
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
 // Helper function to add soft delete condition to queries
 func softDeleteCondition(tableName string) string {
 	return fmt.Sprintf("%s.deleted_at IS NULL", tableName)
@@ -118,4 +149,20 @@ func softDeleteCondition(tableName string) string {
 // Helper function to get current time in UTC
 func now() time.Time {
 	return time.Now().UTC()
+}
+
+// mysqlErrDuplicateEntry is the MySQL error number of a unique key violation
+const mysqlErrDuplicateEntry = 1062
+
+// isDuplicateKey reports whether err is a violation of the unique key with the
+// given name
+func isDuplicateKey(err error, key string) bool {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) || mysqlErr.Number != mysqlErrDuplicateEntry {
+		return false
+	}
+
+	// MySQL 8 prefixes the key name with the table name
+	return strings.HasSuffix(mysqlErr.Message, "'"+key+"'") ||
+		strings.HasSuffix(mysqlErr.Message, "."+key+"'")
 }
\ No newline at end of file
@@ -0,0 +1,377 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/svenskhalsovard/api/internal/encryption"
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// pseudonymizeStatements holds, per table with a retention rule, the statement
+// that removes the personal data from rows with the given IDs. The reasons
+// payments failed may hold personal data echoed by Svea, so they are removed from
+// the payment events together with the error message.
+var pseudonymizeStatements = map[string]string{
+	entity.RetentionTablePayments: `
+		UPDATE payments
+		LEFT JOIN payment_events
+			ON payment_events.payment_id = payments.id
+			AND payment_events.event_type = '` + entity.PaymentEventFailed + `'
+		SET customer_first_name = '` + entity.ErasedPlaceholder + `',
+			customer_last_name = '` + entity.ErasedPlaceholder + `',
+			customer_email = '',
+			customer_phone = '',
+			customer_street_address = '',
+			customer_postal_code = '',
+			customer_city = '',
+			customer_additional_info = '',
+			customer_national_id_encrypted = NULL,
+			error_message = NULL,
+			payment_events.details = NULL
+		WHERE payments.id IN (?)
+	`,
+	entity.RetentionTableBookings: `
+		UPDATE bookings
+		SET customer_first_name = '` + entity.ErasedPlaceholder + `',
+			customer_last_name = '` + entity.ErasedPlaceholder + `',
+			customer_email = '',
+			customer_phone = '',
+			customer_street_address = '',
+			customer_postal_code = '',
+			customer_city = '',
+			customer_additional_info = '',
+			customer_national_id_encrypted = NULL,
+			notes = NULL
+		WHERE id IN (?)
+	`,
+	entity.RetentionTableContactMessages: `
+		UPDATE contact_messages
+		SET name = '` + entity.ErasedPlaceholder + `',
+			email = '',
+			subject = '',
+			message = '',
+			ip_address = '',
+			user_agent = ''
+		WHERE id IN (?)
+	`,
+	entity.RetentionTableEmailOutbox: `
+		UPDATE email_outbox
+		SET recipient = '',
+			reply_to = NULL,
+			subject = '',
+			body = '',
+			last_error = NULL
+		WHERE id IN (?)
+	`,
+	entity.RetentionTableCheckoutRecoveries: `
+		UPDATE checkout_recoveries
+		SET email = ''
+		WHERE id IN (?)
+	`,
+	entity.RetentionTableCustomerConsents: `
+		UPDATE customer_consents
+		SET ip_address = '',
+			user_agent = ''
+		WHERE id IN (?)
+	`,
+}
+
+// ErasureRepository handles database operations for erasing customers
+type ErasureRepository struct {
+	db      *sqlx.DB
+	keyring *encryption.Keyring
+}
+
+// NewErasureRepository creates a new ErasureRepository
+func NewErasureRepository(database *Database, keyring *encryption.Keyring) *ErasureRepository {
+	return &ErasureRepository{
+		db:      database.DB,
+		keyring: keyring,
+	}
+}
+
+// GetPersonalRecords retrieves the rows with personal data of a customer in the
+// tables that have a retention rule, together with the email addresses they
+// are matched on: the email of the customer and the emails given at checkout
+func (r *ErasureRepository) GetPersonalRecords(ctx context.Context, customer *entity.Customer) ([]entity.PersonalRecord, []string, error) {
+	// The snapshots are encrypted with a fresh nonce each, so the emails are
+	// made distinct after decrypting them
+	var checkoutEmails []string
+	if err := r.db.SelectContext(ctx, &checkoutEmails, `
+		SELECT customer_email
+		FROM payments
+		WHERE customer_id = ?
+	`, customer.ID); err != nil {
+		return nil, nil, fmt.Errorf("failed to get checkout emails: %w", err)
+	}
+	for i, email := range checkoutEmails {
+		if !encryption.IsEncrypted(email) {
+			continue
+		}
+		value, err := r.keyring.Decrypt(email)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decrypt checkout email: %w", err)
+		}
+		checkoutEmails[i] = value
+	}
+	emails := customerEmails(customer, checkoutEmails)
+
+	query, args, err := sqlx.In(`
+		SELECT 'payments' AS table_name, id AS record_id, created_at
+		FROM payments
+		WHERE customer_id = ?
+		UNION ALL
+		SELECT 'bookings', id, created_at
+		FROM bookings
+		WHERE customer_id = ?
+		UNION ALL
+		SELECT 'checkout_recoveries', checkout_recoveries.id, checkout_recoveries.created_at
+		FROM checkout_recoveries
+		JOIN payments ON payments.id = checkout_recoveries.payment_id
+		WHERE payments.customer_id = ?
+		UNION ALL
+		SELECT 'customer_consents', id, created_at
+		FROM customer_consents
+		WHERE customer_id = ?
+		UNION ALL
+		SELECT 'contact_messages', id, created_at
+		FROM contact_messages
+		WHERE email IN (?)
+		UNION ALL
+		SELECT 'email_outbox', id, created_at
+		FROM email_outbox
+		WHERE recipient IN (?)
+	`, customer.ID, customer.ID, customer.ID, customer.ID, emails, emails)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	var records []entity.PersonalRecord
+	if err := r.db.SelectContext(ctx, &records, r.db.Rebind(query), args...); err != nil {
+		return nil, nil, fmt.Errorf("failed to get personal records: %w", err)
+	}
+
+	return records, emails, nil
+}
+
+// EraseCustomer erases a customer in one transaction. The customer row is
+// pseudonymized and deleted, so it can no longer be found by email or logged
+// into; login codes, sessions, opt-outs and data export archives are removed
+// and the consents given are withdrawn; the rows in erase are pseudonymized and
+// the rows in retain are kept until their retention ends. It returns false when
+// the customer was already erased.
+func (r *ErasureRepository) EraseCustomer(ctx context.Context, customerID int64, emails []string, erase []entity.PersonalRecord, retain []entity.RetainedRecord) (bool, error) {
+	erased := false
+
+	err := withTransaction(ctx, r.db, func(tx *sqlx.Tx) error {
+		now := now()
+
+		result, err := tx.ExecContext(ctx, `
+			UPDATE customers
+			SET first_name = ?,
+				last_name = ?,
+				email = ?,
+				email_hash = NULL,
+				phone = '',
+				street_address = '',
+				postal_code = '',
+				city = '',
+				additional_info = NULL,
+				national_id_encrypted = NULL,
+				erased_at = ?,
+				deleted_at = COALESCE(deleted_at, ?)
+			WHERE id = ?
+			AND erased_at IS NULL
+		`,
+			entity.ErasedPlaceholder,
+			entity.ErasedPlaceholder,
+			fmt.Sprintf("erased-%d", customerID),
+			now,
+			now,
+			customerID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to pseudonymize customer: %w", err)
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if rows == 0 {
+			return nil
+		}
+		erased = true
+
+		if err := recordAudit(ctx, tx, entity.AuditEntityCustomer, customerID, entity.AuditActionErased, nil, nil); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			DELETE FROM customer_sessions
+			WHERE customer_id = ?
+		`, customerID); err != nil {
+			return fmt.Errorf("failed to delete customer sessions: %w", err)
+		}
+
+		// Consents are append-only, so they are withdrawn with new records
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO customer_consents (
+				customer_id, consent_type, granted, policy_version, source, created_at
+			)
+			SELECT customer_id, consent_type, FALSE, policy_version, ?, ?
+			FROM customer_consents
+			WHERE id IN (
+				SELECT MAX(id)
+				FROM customer_consents
+				WHERE customer_id = ?
+				GROUP BY consent_type
+			)
+			AND granted = TRUE
+			AND consent_type <> ?
+		`, entity.ConsentSourceErasure, now, customerID, entity.ConsentTypeTerms); err != nil {
+			return fmt.Errorf("failed to withdraw consents: %w", err)
+		}
+
+		// Retained payments must not lead to checkout reminders
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE payments
+			SET recovery_consent = FALSE
+			WHERE customer_id = ?
+		`, customerID); err != nil {
+			return fmt.Errorf("failed to withdraw checkout reminder consent: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE data_exports
+			SET archive_encrypted = NULL
+			WHERE customer_id = ?
+		`, customerID); err != nil {
+			return fmt.Errorf("failed to delete data export archives: %w", err)
+		}
+
+		if len(emails) > 0 {
+			for _, statement := range []string{
+				`DELETE FROM login_codes WHERE email IN (?)`,
+				`DELETE FROM email_opt_outs WHERE email IN (?)`,
+			} {
+				query, args, err := sqlx.In(statement, emails)
+				if err != nil {
+					return fmt.Errorf("failed to build query: %w", err)
+				}
+				if _, err := tx.ExecContext(ctx, tx.Rebind(query), args...); err != nil {
+					return fmt.Errorf("failed to delete records by email: %w", err)
+				}
+			}
+		}
+
+		if err := pseudonymizeRecords(ctx, tx, erase); err != nil {
+			return err
+		}
+
+		for _, record := range retain {
+			if _, err := tx.ExecContext(ctx, `
+				INSERT IGNORE INTO retained_records (
+					customer_id, table_name, record_id, retain_until, created_at
+				) VALUES (?, ?, ?, ?, ?)
+			`,
+				customerID,
+				record.TableName,
+				record.RecordID,
+				record.RetainUntil,
+				now,
+			); err != nil {
+				return fmt.Errorf("failed to create retained record: %w", err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return erased, nil
+}
+
+// GetExpiredRetainedRecords retrieves up to limit retained records whose
+// retention ended before the given time
+func (r *ErasureRepository) GetExpiredRetainedRecords(ctx context.Context, before time.Time, limit int) ([]entity.RetainedRecord, error) {
+	query := `
+		SELECT id, customer_id, table_name, record_id, retain_until, created_at
+		FROM retained_records
+		WHERE retain_until < ?
+		ORDER BY retain_until, id
+		LIMIT ?
+	`
+
+	var records []entity.RetainedRecord
+	if err := r.db.SelectContext(ctx, &records, query, before, limit); err != nil {
+		return nil, fmt.Errorf("failed to get expired retained records: %w", err)
+	}
+
+	return records, nil
+}
+
+// PseudonymizeRetainedRecords pseudonymizes retained records whose retention
+// has ended and removes them from retained_records
+func (r *ErasureRepository) PseudonymizeRetainedRecords(ctx context.Context, records []entity.RetainedRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	return withTransaction(ctx, r.db, func(tx *sqlx.Tx) error {
+		personalRecords := make([]entity.PersonalRecord, 0, len(records))
+		ids := make([]int64, 0, len(records))
+		for _, record := range records {
+			personalRecords = append(personalRecords, entity.PersonalRecord{
+				TableName: record.TableName,
+				RecordID:  record.RecordID,
+			})
+			ids = append(ids, record.ID)
+		}
+
+		if err := pseudonymizeRecords(ctx, tx, personalRecords); err != nil {
+			return err
+		}
+
+		query, args, err := sqlx.In(`DELETE FROM retained_records WHERE id IN (?)`, ids)
+		if err != nil {
+			return fmt.Errorf("failed to build query: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, tx.Rebind(query), args...); err != nil {
+			return fmt.Errorf("failed to delete retained records: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// pseudonymizeRecords removes the personal data from the given rows
+func pseudonymizeRecords(ctx context.Context, tx *sqlx.Tx, records []entity.PersonalRecord) error {
+	idsByTable := make(map[string][]int64)
+	for _, record := range records {
+		idsByTable[record.TableName] = append(idsByTable[record.TableName], record.RecordID)
+	}
+
+	for table, ids := range idsByTable {
+		statement, ok := pseudonymizeStatements[table]
+		if !ok {
+			return fmt.Errorf("no pseudonymization rule for table %s", table)
+		}
+
+		query, args, err := sqlx.In(statement, ids)
+		if err != nil {
+			return fmt.Errorf("failed to build query: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx, tx.Rebind(query), args...); err != nil {
+			return fmt.Errorf("failed to pseudonymize %s: %w", table, err)
+		}
+	}
+
+	return nil
+}
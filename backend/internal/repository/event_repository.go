@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// EventRepository handles database operations for payment and booking events
+type EventRepository struct {
+	db *sqlx.DB
+}
+
+// NewEventRepository creates a new EventRepository
+func NewEventRepository(database *Database) *EventRepository {
+	return &EventRepository{
+		db: database.DB,
+	}
+}
+
+// CreatePaymentEvent records an event of a payment. details are stored as JSON
+// and may be nil.
+func (r *EventRepository) CreatePaymentEvent(ctx context.Context, paymentID int64, eventType string, details map[string]string) error {
+	encoded, err := eventDetails(details)
+	if err != nil {
+		return err
+	}
+
+	if _, err := r.db.ExecContext(ctx, `
+		INSERT INTO payment_events (payment_id, event_type, details, created_at)
+		VALUES (?, ?, ?, ?)
+	`, paymentID, eventType, encoded, now()); err != nil {
+		return fmt.Errorf("failed to create payment event: %w", err)
+	}
+
+	return nil
+}
+
+// CreateBookingEvent records an event of a booking. details are stored as JSON
+// and may be nil.
+func (r *EventRepository) CreateBookingEvent(ctx context.Context, bookingID int64, eventType string, details map[string]string) error {
+	encoded, err := eventDetails(details)
+	if err != nil {
+		return err
+	}
+
+	if _, err := r.db.ExecContext(ctx, `
+		INSERT INTO booking_events (booking_id, event_type, details, created_at)
+		VALUES (?, ?, ?, ?)
+	`, bookingID, eventType, encoded, now()); err != nil {
+		return fmt.Errorf("failed to create booking event: %w", err)
+	}
+
+	return nil
+}
+
+// GetPaymentEvents retrieves the events of a payment, oldest first
+func (r *EventRepository) GetPaymentEvents(ctx context.Context, paymentID int64) ([]entity.PaymentEvent, error) {
+	query := `
+		SELECT id, payment_id, event_type, details, created_at
+		FROM payment_events
+		WHERE payment_id = ?
+		ORDER BY id
+	`
+
+	var events []entity.PaymentEvent
+	if err := r.db.SelectContext(ctx, &events, query, paymentID); err != nil {
+		return nil, fmt.Errorf("failed to get payment events: %w", err)
+	}
+
+	return events, nil
+}
+
+// GetBookingEvents retrieves the events of a booking, oldest first
+func (r *EventRepository) GetBookingEvents(ctx context.Context, bookingID int64) ([]entity.BookingEvent, error) {
+	query := `
+		SELECT id, booking_id, event_type, details, created_at
+		FROM booking_events
+		WHERE booking_id = ?
+		ORDER BY id
+	`
+
+	var events []entity.BookingEvent
+	if err := r.db.SelectContext(ctx, &events, query, bookingID); err != nil {
+		return nil, fmt.Errorf("failed to get booking events: %w", err)
+	}
+
+	return events, nil
+}
+
+// eventDetails encodes event details as JSON, or returns nil when there are none
+func eventDetails(details map[string]string) ([]byte, error) {
+	if len(details) == 0 {
+		return nil, nil
+	}
+
+	encoded, err := json.Marshal(details)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event details: %w", err)
+	}
+
+	return encoded, nil
+}
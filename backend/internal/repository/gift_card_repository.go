@@ -0,0 +1,310 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// GiftCardRepository handles database operations for gift cards and their balance ledger
+type GiftCardRepository struct {
+	db *sqlx.DB
+}
+
+// NewGiftCardRepository creates a new GiftCardRepository
+func NewGiftCardRepository(database *Database) *GiftCardRepository {
+	return &GiftCardRepository{
+		db: database.DB,
+	}
+}
+
+// GetGiftCardByCode retrieves a gift card by its code, ignoring case. Codes are
+// stored in upper case, so the code given is upper-cased rather than the
+// column, which keeps the lookup on the unique index.
+func (r *GiftCardRepository) GetGiftCardByCode(ctx context.Context, code string) (*entity.GiftCard, error) {
+	query := `
+		SELECT id, code, initial_amount, balance, currency, purchase_payment_id,
+		       expires_at, is_active, created_at, updated_at, deleted_at
+		FROM gift_cards
+		WHERE ` + softDeleteCondition("gift_cards") + `
+		AND code = ?
+	`
+
+	var giftCard entity.GiftCard
+	if err := r.db.GetContext(ctx, &giftCard, query, strings.ToUpper(code)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Gift card not found
+		}
+		return nil, fmt.Errorf("failed to get gift card: %w", err)
+	}
+
+	return &giftCard, nil
+}
+
+// GetGiftCardsByPurchasePaymentID retrieves the gift cards bought with a payment
+func (r *GiftCardRepository) GetGiftCardsByPurchasePaymentID(ctx context.Context, paymentID int64) ([]entity.GiftCard, error) {
+	query := `
+		SELECT id, code, initial_amount, balance, currency, purchase_payment_id,
+		       expires_at, is_active, created_at, updated_at, deleted_at
+		FROM gift_cards
+		WHERE ` + softDeleteCondition("gift_cards") + `
+		AND purchase_payment_id = ?
+		ORDER BY id
+	`
+
+	var giftCards []entity.GiftCard
+	if err := r.db.SelectContext(ctx, &giftCards, query, paymentID); err != nil {
+		return nil, fmt.Errorf("failed to get gift cards by payment: %w", err)
+	}
+
+	return giftCards, nil
+}
+
+// GetGiftCardPaymentItems retrieves the items of a payment that buy gift cards
+func (r *GiftCardRepository) GetGiftCardPaymentItems(ctx context.Context, paymentID int64) ([]entity.PaymentItem, error) {
+	query := `
+		SELECT payment_items.id, payment_items.payment_id, payment_items.service_id,
+		       payment_items.service_name, payment_items.quantity, payment_items.unit_price,
+		       payment_items.total_price, payment_items.discount_amount, payment_items.vat_percent,
+		       payment_items.purchase_type, payment_items.created_at, payment_items.updated_at,
+		       payment_items.deleted_at
+		FROM payment_items
+		JOIN services ON services.id = payment_items.service_id
+		WHERE ` + softDeleteCondition("payment_items") + `
+		AND services.is_gift_card = true
+		AND payment_items.payment_id = ?
+		ORDER BY payment_items.id
+	`
+
+	var items []entity.PaymentItem
+	if err := r.db.SelectContext(ctx, &items, query, paymentID); err != nil {
+		return nil, fmt.Errorf("failed to get gift card payment items: %w", err)
+	}
+
+	return items, nil
+}
+
+// CreateGiftCards issues the gift cards bought with a payment, records their
+// opening balances in the ledger and queues the email delivering their codes.
+// When the payment already has gift cards nothing is created and the existing
+// cards are returned, so issuing is safe to repeat. It runs in the given
+// transaction, which settles the payment.
+func (r *GiftCardRepository) CreateGiftCards(ctx context.Context, tx *sqlx.Tx, paymentID int64, giftCards []entity.GiftCard, email *entity.OutboxEmail) ([]entity.GiftCard, error) {
+	var issued []entity.GiftCard
+
+	// Lock the payment so concurrent success callbacks issue the cards once
+	var lockedID int64
+	if err := tx.GetContext(ctx, &lockedID, `
+		SELECT id
+		FROM payments
+		WHERE id = ?
+		FOR UPDATE
+	`, paymentID); err != nil {
+		return nil, fmt.Errorf("failed to lock payment: %w", err)
+	}
+
+	if err := tx.SelectContext(ctx, &issued, `
+		SELECT id, code, initial_amount, balance, currency, purchase_payment_id,
+		       expires_at, is_active, created_at, updated_at, deleted_at
+		FROM gift_cards
+		WHERE purchase_payment_id = ?
+		ORDER BY id
+	`, paymentID); err != nil {
+		return nil, fmt.Errorf("failed to get existing gift cards: %w", err)
+	}
+
+	if len(issued) > 0 {
+		return issued, nil
+	}
+
+	now := now()
+	for i := range giftCards {
+		giftCard := &giftCards[i]
+		giftCard.PurchasePaymentID = &paymentID
+		giftCard.Balance = giftCard.InitialAmount
+		giftCard.CreatedAt = now
+		giftCard.UpdatedAt = now
+
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO gift_cards (
+				code, initial_amount, balance, currency, purchase_payment_id,
+				expires_at, is_active, created_at, updated_at
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`,
+			giftCard.Code,
+			giftCard.InitialAmount,
+			giftCard.Balance,
+			giftCard.Currency,
+			giftCard.PurchasePaymentID,
+			giftCard.ExpiresAt,
+			giftCard.IsActive,
+			giftCard.CreatedAt,
+			giftCard.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create gift card: %w", err)
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get last insert ID: %w", err)
+		}
+		giftCard.ID = id
+
+		if err := r.createTransaction(ctx, tx, &entity.GiftCardTransaction{
+			GiftCardID:   giftCard.ID,
+			PaymentID:    &paymentID,
+			Type:         entity.GiftCardTransactionIssue,
+			Amount:       giftCard.InitialAmount,
+			BalanceAfter: giftCard.Balance,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := enqueueEmail(ctx, tx, email); err != nil {
+		return nil, err
+	}
+
+	return giftCards, nil
+}
+
+// RedeemGiftCard draws an amount from a gift card for a payment within the given
+// transaction. The card row is locked so concurrent redemptions cannot overdraw it.
+func (r *GiftCardRepository) RedeemGiftCard(ctx context.Context, tx *sqlx.Tx, giftCardID int64, paymentID int64, amount float64) error {
+	var balance float64
+	if err := tx.GetContext(ctx, &balance, `
+		SELECT balance
+		FROM gift_cards
+		WHERE id = ?
+		AND `+softDeleteCondition("gift_cards")+`
+		FOR UPDATE
+	`, giftCardID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("gift card not found or already deleted")
+		}
+		return fmt.Errorf("failed to lock gift card: %w", err)
+	}
+
+	if balance < amount {
+		return entity.ErrInsufficientGiftCardBalance
+	}
+
+	balanceAfter := math.Round((balance-amount)*100) / 100
+	if err := r.updateBalance(ctx, tx, giftCardID, balanceAfter); err != nil {
+		return err
+	}
+
+	return r.createTransaction(ctx, tx, &entity.GiftCardTransaction{
+		GiftCardID:   giftCardID,
+		PaymentID:    &paymentID,
+		Type:         entity.GiftCardTransactionRedeem,
+		Amount:       -amount,
+		BalanceAfter: balanceAfter,
+	})
+}
+
+// ReleaseGiftCard returns to the gift card whatever a payment drew from it and has
+// not been released yet. Payments without a redemption are ignored.
+func (r *GiftCardRepository) ReleaseGiftCard(ctx context.Context, paymentID int64) error {
+	return withTransaction(ctx, r.db, func(tx *sqlx.Tx) error {
+		var outstanding []struct {
+			GiftCardID int64   `db:"gift_card_id"`
+			Amount     float64 `db:"amount"`
+		}
+		if err := tx.SelectContext(ctx, &outstanding, `
+			SELECT gift_card_id, SUM(amount) AS amount
+			FROM gift_card_transactions
+			WHERE payment_id = ?
+			AND type IN (?, ?)
+			GROUP BY gift_card_id
+		`, paymentID, entity.GiftCardTransactionRedeem, entity.GiftCardTransactionRelease); err != nil {
+			return fmt.Errorf("failed to get gift card redemptions: %w", err)
+		}
+
+		for _, redemption := range outstanding {
+			amount := math.Round(-redemption.Amount*100) / 100
+			if amount <= 0 {
+				continue
+			}
+
+			var balance float64
+			if err := tx.GetContext(ctx, &balance, `
+				SELECT balance
+				FROM gift_cards
+				WHERE id = ?
+				FOR UPDATE
+			`, redemption.GiftCardID); err != nil {
+				return fmt.Errorf("failed to lock gift card: %w", err)
+			}
+
+			balanceAfter := math.Round((balance+amount)*100) / 100
+			if err := r.updateBalance(ctx, tx, redemption.GiftCardID, balanceAfter); err != nil {
+				return err
+			}
+
+			if err := r.createTransaction(ctx, tx, &entity.GiftCardTransaction{
+				GiftCardID:   redemption.GiftCardID,
+				PaymentID:    &paymentID,
+				Type:         entity.GiftCardTransactionRelease,
+				Amount:       amount,
+				BalanceAfter: balanceAfter,
+			}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Helper methods
+
+// updateBalance sets the balance of a gift card
+func (r *GiftCardRepository) updateBalance(ctx context.Context, tx *sqlx.Tx, giftCardID int64, balance float64) error {
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE gift_cards
+		SET balance = ?,
+		    updated_at = ?
+		WHERE id = ?
+	`, balance, now(), giftCardID); err != nil {
+		return fmt.Errorf("failed to update gift card balance: %w", err)
+	}
+
+	return nil
+}
+
+// createTransaction adds an entry to the gift card ledger
+func (r *GiftCardRepository) createTransaction(ctx context.Context, tx *sqlx.Tx, transaction *entity.GiftCardTransaction) error {
+	transaction.CreatedAt = now()
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO gift_card_transactions (
+			gift_card_id, payment_id, type, amount, balance_after, created_at
+		) VALUES (?, ?, ?, ?, ?, ?)
+	`,
+		transaction.GiftCardID,
+		transaction.PaymentID,
+		transaction.Type,
+		transaction.Amount,
+		transaction.BalanceAfter,
+		transaction.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create gift card transaction: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+	transaction.ID = id
+
+	return nil
+}
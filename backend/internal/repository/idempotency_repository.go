@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// IdempotencyRepository handles database operations for idempotency keys
+type IdempotencyRepository struct {
+	db *sqlx.DB
+}
+
+// NewIdempotencyRepository creates a new IdempotencyRepository
+func NewIdempotencyRepository(database *Database) *IdempotencyRepository {
+	return &IdempotencyRepository{
+		db: database.DB,
+	}
+}
+
+// ClaimKey records that a request with an idempotency key is being processed.
+// An expired record of the key is replaced, as is a record whose request has
+// not completed since staleBefore, which was abandoned. It reports false when
+// the key is already taken by another request in the same scope.
+func (r *IdempotencyRepository) ClaimKey(ctx context.Context, record *entity.IdempotencyKey, staleBefore time.Time) (bool, error) {
+	claimed := false
+
+	err := withTransaction(ctx, r.db, func(tx *sqlx.Tx) error {
+		record.CreatedAt = now()
+
+		if _, err := tx.ExecContext(ctx, `
+			DELETE FROM idempotency_keys
+			WHERE scope = ?
+			AND idempotency_key = ?
+			AND (
+				expires_at < ?
+				OR (completed_at IS NULL AND created_at < ?)
+			)
+		`, record.Scope, record.Key, record.CreatedAt, staleBefore); err != nil {
+			return fmt.Errorf("failed to delete expired idempotency key: %w", err)
+		}
+
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO idempotency_keys (
+				scope, idempotency_key, fingerprint, created_at, expires_at
+			) VALUES (?, ?, ?, ?, ?)
+		`, record.Scope, record.Key, record.Fingerprint, record.CreatedAt, record.ExpiresAt)
+		if err != nil {
+			if isDuplicateKey(err, "scope") {
+				return nil
+			}
+			return fmt.Errorf("failed to claim idempotency key: %w", err)
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to get last insert ID: %w", err)
+		}
+		record.ID = id
+		claimed = true
+
+		return nil
+	})
+
+	return claimed, err
+}
+
+// GetKey retrieves the record of an idempotency key in a scope
+func (r *IdempotencyRepository) GetKey(ctx context.Context, scope string, key string) (*entity.IdempotencyKey, error) {
+	query := `
+		SELECT id, scope, idempotency_key, fingerprint, status_code, content_type,
+		       response_body, created_at, completed_at, expires_at
+		FROM idempotency_keys
+		WHERE scope = ?
+		AND idempotency_key = ?
+	`
+
+	var record entity.IdempotencyKey
+	if err := r.db.GetContext(ctx, &record, query, scope, key); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Key not found
+		}
+		return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+
+	return &record, nil
+}
+
+// CompleteKey stores the response of the request an idempotency key was claimed
+// for
+func (r *IdempotencyRepository) CompleteKey(ctx context.Context, id int64, statusCode int, contentType string, body []byte) error {
+	if _, err := r.db.ExecContext(ctx, `
+		UPDATE idempotency_keys
+		SET status_code = ?,
+		    content_type = ?,
+		    response_body = ?,
+		    completed_at = ?
+		WHERE id = ?
+	`, statusCode, contentType, body, now(), id); err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+// ReleaseKey removes a claimed idempotency key whose request did not complete,
+// so a retry is processed again
+func (r *IdempotencyRepository) ReleaseKey(ctx context.Context, id int64) error {
+	if _, err := r.db.ExecContext(ctx, `
+		DELETE FROM idempotency_keys
+		WHERE id = ?
+		AND completed_at IS NULL
+	`, id); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpiredKeys removes the idempotency keys that expired before the given
+// time. It returns the number of keys removed.
+func (r *IdempotencyRepository) DeleteExpiredKeys(ctx context.Context, before time.Time) (int, error) {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM idempotency_keys
+		WHERE expires_at < ?
+	`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return int(rows), nil
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// OutboxRepository handles database operations for the email outbox
+type OutboxRepository struct {
+	db *sqlx.DB
+}
+
+// NewOutboxRepository creates a new OutboxRepository
+func NewOutboxRepository(database *Database) *OutboxRepository {
+	return &OutboxRepository{
+		db: database.DB,
+	}
+}
+
+// EnqueueEmail adds an email to the outbox
+func (r *OutboxRepository) EnqueueEmail(ctx context.Context, email *entity.OutboxEmail) error {
+	return withTransaction(ctx, r.db, func(tx *sqlx.Tx) error {
+		return enqueueEmail(ctx, tx, email)
+	})
+}
+
+// GetPendingEmails retrieves pending emails that are due for delivery, oldest first
+func (r *OutboxRepository) GetPendingEmails(ctx context.Context, limit int) ([]entity.OutboxEmail, error) {
+	query := `
+		SELECT id, recipient, reply_to, subject, body, status, attempts, last_error,
+		       available_at, sent_at, created_at, updated_at
+		FROM email_outbox
+		WHERE status = ?
+		AND available_at <= ?
+		ORDER BY id
+		LIMIT ?
+	`
+
+	var emails []entity.OutboxEmail
+	if err := r.db.SelectContext(ctx, &emails, query, entity.OutboxStatusPending, now(), limit); err != nil {
+		return nil, fmt.Errorf("failed to get pending emails: %w", err)
+	}
+
+	return emails, nil
+}
+
+// MarkEmailSent marks an outbox email as delivered
+func (r *OutboxRepository) MarkEmailSent(ctx context.Context, id int64) error {
+	now := now()
+
+	if _, err := r.db.ExecContext(ctx, `
+		UPDATE email_outbox
+		SET status = ?,
+		    attempts = attempts + 1,
+		    sent_at = ?,
+		    updated_at = ?
+		WHERE id = ?
+	`, entity.OutboxStatusSent, now, now, id); err != nil {
+		return fmt.Errorf("failed to mark email sent: %w", err)
+	}
+
+	return nil
+}
+
+// MarkEmailFailed records a failed delivery attempt. The email is retried at
+// retryAt, or given up on when retryAt is nil.
+func (r *OutboxRepository) MarkEmailFailed(ctx context.Context, id int64, errorMessage string, retryAt *time.Time) error {
+	status := entity.OutboxStatusPending
+	availableAt := now()
+	if retryAt != nil {
+		availableAt = retryAt.UTC()
+	} else {
+		status = entity.OutboxStatusFailed
+	}
+
+	if _, err := r.db.ExecContext(ctx, `
+		UPDATE email_outbox
+		SET status = ?,
+		    attempts = attempts + 1,
+		    last_error = ?,
+		    available_at = ?,
+		    updated_at = ?
+		WHERE id = ?
+	`, status, errorMessage, availableAt, now(), id); err != nil {
+		return fmt.Errorf("failed to mark email failed: %w", err)
+	}
+
+	return nil
+}
+
+// enqueueEmail adds an email to the outbox within an existing transaction
+func enqueueEmail(ctx context.Context, tx *sqlx.Tx, email *entity.OutboxEmail) error {
+	now := now()
+	email.Status = entity.OutboxStatusPending
+	email.AvailableAt = now
+	email.CreatedAt = now
+	email.UpdatedAt = now
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO email_outbox (
+			recipient, reply_to, subject, body, status, available_at,
+			created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		email.Recipient,
+		email.ReplyTo,
+		email.Subject,
+		email.Body,
+		email.Status,
+		email.AvailableAt,
+		email.CreatedAt,
+		email.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue email: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+	email.ID = id
+
+	return nil
+}
@@ -5,21 +5,26 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/rs/zerolog/log"
+	"github.com/svenskhalsovard/api/internal/encryption"
 	"github.com/svenskhalsovard/api/internal/entity"
 )
 
-// PaymentRepository handles database operations for payments
+// PaymentRepository handles database operations for payments. The customer
+// snapshots stored with payments are encrypted at rest with keyring.
 type PaymentRepository struct {
-	db *sqlx.DB
+	db      *sqlx.DB
+	keyring *encryption.Keyring
 }
 
 // NewPaymentRepository creates a new PaymentRepository
-func NewPaymentRepository(database *Database) *PaymentRepository {
+func NewPaymentRepository(database *Database, keyring *encryption.Keyring) *PaymentRepository {
 	return &PaymentRepository{
-		db: database.DB,
+		db:      database.DB,
+		keyring: keyring,
 	}
 }
 
@@ -38,14 +43,20 @@ func (r *PaymentRepository) CreatePayment(ctx context.Context, tx *sqlx.Tx, paym
 		}
 	}
 	
-	return nil
+	return recordAudit(ctx, tx, entity.AuditEntityPayment, payment.ID, entity.AuditActionCreated, nil, map[string]string{
+		"status": payment.Status,
+	})
 }
 
 // GetPaymentByID retrieves a payment by ID
 func (r *PaymentRepository) GetPaymentByID(ctx context.Context, id int64) (*entity.Payment, error) {
 	query := `
-		SELECT id, external_payment_id, customer_id, amount, currency, status, 
-		       payment_method, order_reference, transaction_type, error_message, 
+		SELECT id, external_payment_id, customer_id, customer_first_name, customer_last_name,
+		       customer_email, customer_phone, customer_street_address, customer_postal_code,
+		       customer_city, customer_additional_info, customer_national_id_encrypted,
+		       amount, currency, status, version,
+		       payment_method, order_reference, transaction_type, promo_code_id,
+		       discount_amount, gift_card_id, gift_card_amount, recovery_consent, error_message,
 		       created_at, updated_at, deleted_at
 		FROM payments
 		WHERE ` + softDeleteCondition("payments") + `
@@ -60,14 +71,22 @@ func (r *PaymentRepository) GetPaymentByID(ctx context.Context, id int64) (*enti
 		return nil, fmt.Errorf("failed to get payment by ID: %w", err)
 	}
 
+	if err := decryptSnapshot(r.keyring, &payment.CustomerSnapshot); err != nil {
+		return nil, err
+	}
+
 	return &payment, nil
 }
 
 // GetPaymentByExternalID retrieves a payment by external payment ID
 func (r *PaymentRepository) GetPaymentByExternalID(ctx context.Context, externalID string) (*entity.Payment, error) {
 	query := `
-		SELECT id, external_payment_id, customer_id, amount, currency, status, 
-		       payment_method, order_reference, transaction_type, error_message, 
+		SELECT id, external_payment_id, customer_id, customer_first_name, customer_last_name,
+		       customer_email, customer_phone, customer_street_address, customer_postal_code,
+		       customer_city, customer_additional_info, customer_national_id_encrypted,
+		       amount, currency, status, version,
+		       payment_method, order_reference, transaction_type, promo_code_id,
+		       discount_amount, gift_card_id, gift_card_amount, recovery_consent, error_message,
 		       created_at, updated_at, deleted_at
 		FROM payments
 		WHERE ` + softDeleteCondition("payments") + `
@@ -82,14 +101,22 @@ func (r *PaymentRepository) GetPaymentByExternalID(ctx context.Context, external
 		return nil, fmt.Errorf("failed to get payment by external ID: %w", err)
 	}
 
+	if err := decryptSnapshot(r.keyring, &payment.CustomerSnapshot); err != nil {
+		return nil, err
+	}
+
 	return &payment, nil
 }
 
 // GetPaymentByOrderReference retrieves a payment by order reference
 func (r *PaymentRepository) GetPaymentByOrderReference(ctx context.Context, orderReference string) (*entity.Payment, error) {
 	query := `
-		SELECT id, external_payment_id, customer_id, amount, currency, status, 
-		       payment_method, order_reference, transaction_type, error_message, 
+		SELECT id, external_payment_id, customer_id, customer_first_name, customer_last_name,
+		       customer_email, customer_phone, customer_street_address, customer_postal_code,
+		       customer_city, customer_additional_info, customer_national_id_encrypted,
+		       amount, currency, status, version,
+		       payment_method, order_reference, transaction_type, promo_code_id,
+		       discount_amount, gift_card_id, gift_card_amount, recovery_consent, error_message,
 		       created_at, updated_at, deleted_at
 		FROM payments
 		WHERE ` + softDeleteCondition("payments") + `
@@ -104,6 +131,10 @@ func (r *PaymentRepository) GetPaymentByOrderReference(ctx context.Context, orde
 		return nil, fmt.Errorf("failed to get payment by order reference: %w", err)
 	}
 
+	if err := decryptSnapshot(r.keyring, &payment.CustomerSnapshot); err != nil {
+		return nil, err
+	}
+
 	return &payment, nil
 }
 
@@ -118,13 +149,6 @@ func (r *PaymentRepository) GetPaymentWithItems(ctx context.Context, id int64) (
 		return nil, nil // Payment not found
 	}
 
-	// Get customer
-	customerRepository := &BookingRepository{db: r.db}
-	customer, err := customerRepository.GetCustomerByID(ctx, payment.CustomerID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get customer: %w", err)
-	}
-
 	// Get payment items
 	items, err := r.GetPaymentItems(ctx, id)
 	if err != nil {
@@ -134,7 +158,7 @@ func (r *PaymentRepository) GetPaymentWithItems(ctx context.Context, id int64) (
 	return &entity.PaymentWithItems{
 		Payment:  *payment,
 		Items:    items,
-		Customer: *customer,
+		Customer: payment.CustomerSnapshot.ToCustomer(payment.CustomerID),
 	}, nil
 }
 
@@ -142,7 +166,8 @@ func (r *PaymentRepository) GetPaymentWithItems(ctx context.Context, id int64) (
 func (r *PaymentRepository) GetPaymentItems(ctx context.Context, paymentID int64) ([]entity.PaymentItem, error) {
 	query := `
 		SELECT id, payment_id, service_id, service_name, quantity, unit_price, 
-		       total_price, purchase_type, created_at, updated_at, deleted_at
+		       total_price, discount_amount, vat_percent, purchase_type, created_at,
+		       updated_at, deleted_at
 		FROM payment_items
 		WHERE ` + softDeleteCondition("payment_items") + `
 		AND payment_id = ?
@@ -157,17 +182,56 @@ func (r *PaymentRepository) GetPaymentItems(ctx context.Context, paymentID int64
 	return items, nil
 }
 
-// UpdatePaymentStatus updates the status of a payment
-func (r *PaymentRepository) UpdatePaymentStatus(ctx context.Context, id int64, status string, errorMessage string) error {
+// UpdatePaymentStatus moves a payment to a new status in its own transaction.
+// See UpdatePaymentStatusTx.
+func (r *PaymentRepository) UpdatePaymentStatus(ctx context.Context, id int64, expectedVersion int, status string, errorMessage string) error {
+	return withTransaction(ctx, r.db, func(tx *sqlx.Tx) error {
+		return r.UpdatePaymentStatusTx(ctx, tx, id, expectedVersion, status, errorMessage)
+	})
+}
+
+// UpdatePaymentStatusTx moves a payment to a new status in tx. expectedVersion
+// is the version of the payment the caller read before deciding on the move;
+// entity.ErrPaymentVersionConflict is returned when the payment was changed
+// since. The move must be allowed by the payment state machine, otherwise a
+// *entity.PaymentTransitionError is returned. A change of status is recorded in
+// the audit log in the same transaction.
+func (r *PaymentRepository) UpdatePaymentStatusTx(ctx context.Context, tx *sqlx.Tx, id int64, expectedVersion int, status string, errorMessage string) error {
+	var current struct {
+		Status  string `db:"status"`
+		Version int    `db:"version"`
+	}
+	if err := tx.GetContext(ctx, &current, `
+		SELECT status, version
+		FROM payments
+		WHERE id = ?
+		AND `+softDeleteCondition("payments")+`
+		FOR UPDATE`, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("payment not found or already deleted")
+		}
+		return fmt.Errorf("failed to get payment status: %w", err)
+	}
+
+	if current.Version != expectedVersion {
+		return entity.ErrPaymentVersionConflict
+	}
+
+	if err := entity.ValidatePaymentTransition(id, current.Status, status); err != nil {
+		return err
+	}
+
 	query := `
 		UPDATE payments
 		SET status = ?,
 		    error_message = ?,
+		    version = version + 1,
 		    updated_at = ?
 		WHERE id = ?
-		AND ` + softDeleteCondition("payments")
+		AND version = ?
+	`
 
-	result, err := r.db.ExecContext(ctx, query, status, errorMessage, now(), id)
+	result, err := tx.ExecContext(ctx, query, status, errorMessage, now(), id, expectedVersion)
 	if err != nil {
 		return fmt.Errorf("failed to update payment status: %w", err)
 	}
@@ -178,10 +242,17 @@ func (r *PaymentRepository) UpdatePaymentStatus(ctx context.Context, id int64, s
 	}
 
 	if rows == 0 {
-		return fmt.Errorf("payment not found or already deleted")
+		return entity.ErrPaymentVersionConflict
 	}
 
-	return nil
+	if status == current.Status {
+		return nil
+	}
+
+	return recordAudit(ctx, tx, entity.AuditEntityPayment, id, entity.AuditActionStatusChanged,
+		map[string]string{"status": current.Status},
+		map[string]string{"status": status},
+	)
 }
 
 // UpdatePaymentExternalID updates the external payment ID of a payment
@@ -213,8 +284,12 @@ func (r *PaymentRepository) UpdatePaymentExternalID(ctx context.Context, id int6
 // FindIncompletePayments finds payments with initiated or pending status older than the given duration
 func (r *PaymentRepository) FindIncompletePayments(ctx context.Context, maxAge string) ([]entity.Payment, error) {
 	query := `
-		SELECT id, external_payment_id, customer_id, amount, currency, status, 
-		       payment_method, order_reference, transaction_type, error_message, 
+		SELECT id, external_payment_id, customer_id, customer_first_name, customer_last_name,
+		       customer_email, customer_phone, customer_street_address, customer_postal_code,
+		       customer_city, customer_additional_info, customer_national_id_encrypted,
+		       amount, currency, status, version,
+		       payment_method, order_reference, transaction_type, promo_code_id,
+		       discount_amount, gift_card_id, gift_card_amount, recovery_consent, error_message,
 		       created_at, updated_at, deleted_at
 		FROM payments
 		WHERE ` + softDeleteCondition("payments") + `
@@ -234,19 +309,132 @@ func (r *PaymentRepository) FindIncompletePayments(ctx context.Context, maxAge s
 		return nil, fmt.Errorf("failed to find incomplete payments: %w", err)
 	}
 
+	if err := r.decryptPayments(payments); err != nil {
+		return nil, err
+	}
+
+	return payments, nil
+}
+
+// FindUnsettledPayments finds the payments last updated between since and
+// before that are either successful without a booking, or pending at Svea
+// where they may have been paid without the payment being recorded
+func (r *PaymentRepository) FindUnsettledPayments(ctx context.Context, since time.Time, before time.Time) ([]entity.Payment, error) {
+	query := `
+		SELECT id, external_payment_id, customer_id, customer_first_name, customer_last_name,
+		       customer_email, customer_phone, customer_street_address, customer_postal_code,
+		       customer_city, customer_additional_info, customer_national_id_encrypted,
+		       amount, currency, status, version,
+		       payment_method, order_reference, transaction_type, promo_code_id,
+		       discount_amount, gift_card_id, gift_card_amount, recovery_consent, error_message,
+		       created_at, updated_at, deleted_at
+		FROM payments
+		WHERE ` + softDeleteCondition("payments") + `
+		AND updated_at >= ?
+		AND updated_at < ?
+		AND (
+			(status = ? AND NOT EXISTS (
+				SELECT 1
+				FROM bookings
+				WHERE bookings.payment_id = payments.id
+			))
+			OR (status = ? AND external_payment_id IS NOT NULL AND external_payment_id <> '')
+		)
+		ORDER BY updated_at
+	`
+
+	var payments []entity.Payment
+	if err := r.db.SelectContext(
+		ctx,
+		&payments,
+		query,
+		since,
+		before,
+		entity.PaymentStatusSuccess,
+		entity.PaymentStatusPending,
+	); err != nil {
+		return nil, fmt.Errorf("failed to find unsettled payments: %w", err)
+	}
+
+	if err := r.decryptPayments(payments); err != nil {
+		return nil, err
+	}
+
 	return payments, nil
 }
 
+// FindExpiredPayments finds the initiated or pending payments last updated
+// before the given time, which were abandoned before being paid
+func (r *PaymentRepository) FindExpiredPayments(ctx context.Context, before time.Time) ([]entity.Payment, error) {
+	query := `
+		SELECT id, external_payment_id, customer_id, customer_first_name, customer_last_name,
+		       customer_email, customer_phone, customer_street_address, customer_postal_code,
+		       customer_city, customer_additional_info, customer_national_id_encrypted,
+		       amount, currency, status, version,
+		       payment_method, order_reference, transaction_type, promo_code_id,
+		       discount_amount, gift_card_id, gift_card_amount, recovery_consent, error_message,
+		       created_at, updated_at, deleted_at
+		FROM payments
+		WHERE ` + softDeleteCondition("payments") + `
+		AND status IN (?, ?)
+		AND updated_at < ?
+		ORDER BY updated_at
+	`
+
+	var payments []entity.Payment
+	if err := r.db.SelectContext(
+		ctx,
+		&payments,
+		query,
+		entity.PaymentStatusInitiated,
+		entity.PaymentStatusPending,
+		before,
+	); err != nil {
+		return nil, fmt.Errorf("failed to find expired payments: %w", err)
+	}
+
+	if err := r.decryptPayments(payments); err != nil {
+		return nil, err
+	}
+
+	return payments, nil
+}
+
+// Transaction runs fn in a database transaction
+func (r *PaymentRepository) Transaction(fn func(*sqlx.Tx) error) error {
+	return withTransaction(context.Background(), r.db, fn)
+}
+
 // Helper methods
 
+// decryptPayments decrypts the customer snapshots of payments
+func (r *PaymentRepository) decryptPayments(payments []entity.Payment) error {
+	for i := range payments {
+		if err := decryptSnapshot(r.keyring, &payments[i].CustomerSnapshot); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // createPaymentRecord creates a new payment record
 func (r *PaymentRepository) createPaymentRecord(ctx context.Context, tx *sqlx.Tx, payment *entity.Payment) error {
+	snapshot, err := encryptSnapshot(r.keyring, payment.CustomerSnapshot)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		INSERT INTO payments (
-			external_payment_id, customer_id, amount, currency, status, 
-			payment_method, order_reference, transaction_type, error_message, 
+			external_payment_id, customer_id, customer_first_name, customer_last_name,
+			customer_email, customer_phone, customer_street_address, customer_postal_code,
+			customer_city, customer_additional_info, customer_national_id_encrypted,
+			amount, currency, status, 
+			payment_method, order_reference, transaction_type, promo_code_id,
+			discount_amount, gift_card_id, gift_card_amount, recovery_consent, error_message,
 			created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	now := now()
@@ -258,12 +446,26 @@ func (r *PaymentRepository) createPaymentRecord(ctx context.Context, tx *sqlx.Tx
 		query,
 		payment.ExternalPaymentID,
 		payment.CustomerID,
+		snapshot.FirstName,
+		snapshot.LastName,
+		snapshot.Email,
+		snapshot.Phone,
+		snapshot.StreetAddress,
+		snapshot.PostalCode,
+		snapshot.City,
+		snapshot.AdditionalInfo,
+		payment.NationalIDEncrypted,
 		payment.Amount,
 		payment.Currency,
 		payment.Status,
 		payment.PaymentMethod,
 		payment.OrderReference,
 		payment.TransactionType,
+		payment.PromoCodeID,
+		payment.DiscountAmount,
+		payment.GiftCardID,
+		payment.GiftCardAmount,
+		payment.RecoveryConsent,
 		payment.ErrorMessage,
 		payment.CreatedAt,
 		payment.UpdatedAt,
@@ -286,8 +488,9 @@ func (r *PaymentRepository) createPaymentItem(ctx context.Context, tx *sqlx.Tx,
 	query := `
 		INSERT INTO payment_items (
 			payment_id, service_id, service_name, quantity, unit_price, 
-			total_price, purchase_type, created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			total_price, discount_amount, vat_percent, purchase_type, created_at,
+			updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	now := now()
@@ -303,6 +506,8 @@ func (r *PaymentRepository) createPaymentItem(ctx context.Context, tx *sqlx.Tx,
 		item.Quantity,
 		item.UnitPrice,
 		item.TotalPrice,
+		item.DiscountAmount,
+		item.VatPercent,
 		item.PurchaseType,
 		item.CreatedAt,
 		item.UpdatedAt,
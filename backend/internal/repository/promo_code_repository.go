@@ -0,0 +1,232 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/svenskhalsovard/api/internal/encryption"
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// PromoCodeRepository handles database operations for promo codes. keyring is
+// used to look up customers by email.
+type PromoCodeRepository struct {
+	db      *sqlx.DB
+	keyring *encryption.Keyring
+}
+
+// NewPromoCodeRepository creates a new PromoCodeRepository
+func NewPromoCodeRepository(database *Database, keyring *encryption.Keyring) *PromoCodeRepository {
+	return &PromoCodeRepository{
+		db:      database.DB,
+		keyring: keyring,
+	}
+}
+
+// GetPromoCodeByCode retrieves a promo code by its code, ignoring case, with the
+// services it is restricted to
+func (r *PromoCodeRepository) GetPromoCodeByCode(ctx context.Context, code string) (*entity.PromoCode, error) {
+	query := `
+		SELECT id, code, description, discount_type, discount_value, max_uses,
+		       max_uses_per_customer, min_order_amount, starts_at, expires_at,
+		       applies_to_all, is_active, created_at, updated_at, deleted_at
+		FROM promo_codes
+		WHERE ` + softDeleteCondition("promo_codes") + `
+		AND UPPER(code) = UPPER(?)
+	`
+
+	var promoCode entity.PromoCode
+	if err := r.db.GetContext(ctx, &promoCode, query, code); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Promo code not found
+		}
+		return nil, fmt.Errorf("failed to get promo code: %w", err)
+	}
+
+	if !promoCode.AppliesToAll {
+		var targets []entity.PromoCodeService
+		if err := r.db.SelectContext(ctx, &targets, `
+			SELECT promo_code_id, service_id
+			FROM promo_code_services
+			WHERE promo_code_id = ?
+			ORDER BY service_id
+		`, promoCode.ID); err != nil {
+			return nil, fmt.Errorf("failed to get promo code services: %w", err)
+		}
+
+		promoCode.ServiceIDs = make([]int64, 0, len(targets))
+		for _, target := range targets {
+			promoCode.ServiceIDs = append(promoCode.ServiceIDs, target.ServiceID)
+		}
+	}
+
+	return &promoCode, nil
+}
+
+// CountRedemptions counts the recorded redemptions of a promo code
+func (r *PromoCodeRepository) CountRedemptions(ctx context.Context, promoCodeID int64) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM promo_code_redemptions
+		WHERE promo_code_id = ?
+	`
+
+	var count int
+	if err := r.db.GetContext(ctx, &count, query, promoCodeID); err != nil {
+		return 0, fmt.Errorf("failed to count promo code redemptions: %w", err)
+	}
+
+	return count, nil
+}
+
+// CountCustomerRedemptions counts the recorded redemptions of a promo code by the
+// customer with the given email
+func (r *PromoCodeRepository) CountCustomerRedemptions(ctx context.Context, promoCodeID int64, email string) (int, error) {
+	emailCondition, emailArgs := customerEmailCondition(r.keyring, "customers", email)
+	query := `
+		SELECT COUNT(*)
+		FROM promo_code_redemptions
+		JOIN customers ON customers.id = promo_code_redemptions.customer_id
+		WHERE promo_code_redemptions.promo_code_id = ?
+		AND ` + emailCondition
+
+	args := append([]interface{}{promoCodeID}, emailArgs...)
+
+	var count int
+	if err := r.db.GetContext(ctx, &count, query, args...); err != nil {
+		return 0, fmt.Errorf("failed to count customer promo code redemptions: %w", err)
+	}
+
+	return count, nil
+}
+
+// CreateRedemption records a redemption within the given transaction, doing
+// nothing when the payment already has one so repeated success callbacks are
+// harmless
+func (r *PromoCodeRepository) CreateRedemption(ctx context.Context, tx *sqlx.Tx, redemption *entity.PromoCodeRedemption) error {
+	var existingID int64
+	err := tx.GetContext(ctx, &existingID, `
+		SELECT id
+		FROM promo_code_redemptions
+		WHERE payment_id = ?
+		FOR UPDATE
+	`, redemption.PaymentID)
+	if err == nil {
+		redemption.ID = existingID
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to check existing redemption: %w", err)
+	}
+
+	return r.createRedemption(ctx, tx, redemption)
+}
+
+// ReservePromoCode records the redemption of a payment being initiated within
+// the given transaction. The promo code row is locked while its usage limits
+// are checked, so concurrent checkouts cannot exceed them. It reports false
+// when a limit is reached, for the customer with the given email or overall.
+func (r *PromoCodeRepository) ReservePromoCode(ctx context.Context, tx *sqlx.Tx, redemption *entity.PromoCodeRedemption, email string) (bool, error) {
+	var limits struct {
+		MaxUses            *int `db:"max_uses"`
+		MaxUsesPerCustomer *int `db:"max_uses_per_customer"`
+	}
+	if err := tx.GetContext(ctx, &limits, `
+		SELECT max_uses, max_uses_per_customer
+		FROM promo_codes
+		WHERE id = ?
+		FOR UPDATE
+	`, redemption.PromoCodeID); err != nil {
+		return false, fmt.Errorf("failed to lock promo code: %w", err)
+	}
+
+	// The counts are locking reads so they see the redemptions committed by
+	// checkouts that held the promo code lock before this one
+	if limits.MaxUses != nil {
+		var count int
+		if err := tx.GetContext(ctx, &count, `
+			SELECT COUNT(*)
+			FROM promo_code_redemptions
+			WHERE promo_code_id = ?
+			LOCK IN SHARE MODE
+		`, redemption.PromoCodeID); err != nil {
+			return false, fmt.Errorf("failed to count promo code redemptions: %w", err)
+		}
+		if count >= *limits.MaxUses {
+			return false, nil
+		}
+	}
+
+	if limits.MaxUsesPerCustomer != nil && email != "" {
+		emailCondition, emailArgs := customerEmailCondition(r.keyring, "customers", email)
+		query := `
+			SELECT COUNT(*)
+			FROM promo_code_redemptions
+			JOIN customers ON customers.id = promo_code_redemptions.customer_id
+			WHERE promo_code_redemptions.promo_code_id = ?
+			AND ` + emailCondition + `
+			LOCK IN SHARE MODE`
+
+		args := append([]interface{}{redemption.PromoCodeID}, emailArgs...)
+
+		var count int
+		if err := tx.GetContext(ctx, &count, query, args...); err != nil {
+			return false, fmt.Errorf("failed to count customer promo code redemptions: %w", err)
+		}
+		if count >= *limits.MaxUsesPerCustomer {
+			return false, nil
+		}
+	}
+
+	if err := r.createRedemption(ctx, tx, redemption); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ReleasePromoCode removes the redemption reserved by a payment that was not
+// paid, so the use counts towards the limits again
+func (r *PromoCodeRepository) ReleasePromoCode(ctx context.Context, paymentID int64) error {
+	if _, err := r.db.ExecContext(ctx, `
+		DELETE FROM promo_code_redemptions
+		WHERE payment_id = ?
+	`, paymentID); err != nil {
+		return fmt.Errorf("failed to release promo code redemption: %w", err)
+	}
+
+	return nil
+}
+
+// Helper methods
+
+// createRedemption inserts a redemption within the given transaction
+func (r *PromoCodeRepository) createRedemption(ctx context.Context, tx *sqlx.Tx, redemption *entity.PromoCodeRedemption) error {
+	redemption.CreatedAt = now()
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO promo_code_redemptions (
+			promo_code_id, payment_id, customer_id, discount_amount, created_at
+		) VALUES (?, ?, ?, ?, ?)
+	`,
+		redemption.PromoCodeID,
+		redemption.PaymentID,
+		redemption.CustomerID,
+		redemption.DiscountAmount,
+		redemption.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create promo code redemption: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+	redemption.ID = id
+
+	return nil
+}
@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// QuoteRepository handles database operations for checkout quotes
+type QuoteRepository struct {
+	db *sqlx.DB
+}
+
+// NewQuoteRepository creates a new QuoteRepository
+func NewQuoteRepository(database *Database) *QuoteRepository {
+	return &QuoteRepository{
+		db: database.DB,
+	}
+}
+
+// CreateQuote creates a new quote with its items
+func (r *QuoteRepository) CreateQuote(ctx context.Context, quote *entity.CheckoutQuote) error {
+	return withTransaction(ctx, r.db, func(tx *sqlx.Tx) error {
+		quote.CreatedAt = now()
+
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO checkout_quotes (
+				id, cart_token, transaction_type, promo_code_id, promo_code,
+				gift_card_id, gift_card_code, subtotal, discount_amount,
+				gift_card_amount, vat_amount, total_amount, currency, expires_at,
+				created_at
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`,
+			quote.ID,
+			quote.CartToken,
+			quote.TransactionType,
+			quote.PromoCodeID,
+			quote.PromoCode,
+			quote.GiftCardID,
+			quote.GiftCardCode,
+			quote.Subtotal,
+			quote.DiscountAmount,
+			quote.GiftCardAmount,
+			quote.VatAmount,
+			quote.TotalAmount,
+			quote.Currency,
+			quote.ExpiresAt,
+			quote.CreatedAt,
+		); err != nil {
+			return fmt.Errorf("failed to create quote: %w", err)
+		}
+
+		for i := range quote.Items {
+			item := &quote.Items[i]
+			item.QuoteID = quote.ID
+
+			result, err := tx.ExecContext(ctx, `
+				INSERT INTO checkout_quote_items (
+					quote_id, service_id, service_name, quantity, purchase_type,
+					list_price, unit_price, total_price, discount_amount,
+					vat_percent, vat_amount
+				) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			`,
+				item.QuoteID,
+				item.ServiceID,
+				item.ServiceName,
+				item.Quantity,
+				item.PurchaseType,
+				item.ListPrice,
+				item.UnitPrice,
+				item.TotalPrice,
+				item.DiscountAmount,
+				item.VatPercent,
+				item.VatAmount,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to create quote item: %w", err)
+			}
+
+			id, err := result.LastInsertId()
+			if err != nil {
+				return fmt.Errorf("failed to get last insert ID: %w", err)
+			}
+			item.ID = id
+		}
+
+		return nil
+	})
+}
+
+// GetQuoteByID retrieves a quote with its items by ID
+func (r *QuoteRepository) GetQuoteByID(ctx context.Context, id string) (*entity.CheckoutQuote, error) {
+	query := `
+		SELECT id, cart_token, transaction_type, promo_code_id, promo_code,
+		       gift_card_id, gift_card_code, subtotal, discount_amount,
+		       gift_card_amount, vat_amount, total_amount, currency, expires_at,
+		       used_at, payment_id, created_at
+		FROM checkout_quotes
+		WHERE id = ?
+	`
+
+	var quote entity.CheckoutQuote
+	if err := r.db.GetContext(ctx, &quote, query, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Quote not found
+		}
+		return nil, fmt.Errorf("failed to get quote by ID: %w", err)
+	}
+
+	if err := r.db.SelectContext(ctx, &quote.Items, `
+		SELECT id, quote_id, service_id, service_name, quantity, purchase_type,
+		       list_price, unit_price, total_price, discount_amount, vat_percent,
+		       vat_amount
+		FROM checkout_quote_items
+		WHERE quote_id = ?
+		ORDER BY id
+	`, id); err != nil {
+		return nil, fmt.Errorf("failed to get quote items: %w", err)
+	}
+
+	return &quote, nil
+}
+
+// ClaimQuote marks an unused, unexpired quote as used. It reports false when the
+// quote was already used or has expired, so a quote is only ever paid once.
+func (r *QuoteRepository) ClaimQuote(ctx context.Context, id string) (bool, error) {
+	now := now()
+
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE checkout_quotes
+		SET used_at = ?
+		WHERE id = ?
+		AND used_at IS NULL
+		AND expires_at > ?
+	`, now, id, now)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim quote: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+// ReleaseQuote marks a claimed quote as unused again. A quote that is linked to
+// a payment is kept, since it has been paid for.
+func (r *QuoteRepository) ReleaseQuote(ctx context.Context, id string) error {
+	if _, err := r.db.ExecContext(ctx, `
+		UPDATE checkout_quotes
+		SET used_at = NULL
+		WHERE id = ?
+		AND payment_id IS NULL
+	`, id); err != nil {
+		return fmt.Errorf("failed to release quote: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateQuotePayment links a claimed quote to the payment created from it
+func (r *QuoteRepository) UpdateQuotePayment(ctx context.Context, id string, paymentID int64) error {
+	if _, err := r.db.ExecContext(ctx, `
+		UPDATE checkout_quotes
+		SET payment_id = ?
+		WHERE id = ?
+	`, paymentID, id); err != nil {
+		return fmt.Errorf("failed to update quote payment: %w", err)
+	}
+
+	return nil
+}
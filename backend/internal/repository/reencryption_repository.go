@@ -0,0 +1,347 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/svenskhalsovard/api/internal/encryption"
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// ReencryptionRepository re-encrypts stored personal data under the active
+// master key, and encrypts customer rows written before encryption was enabled
+type ReencryptionRepository struct {
+	db      *sqlx.DB
+	keyring *encryption.Keyring
+}
+
+// NewReencryptionRepository creates a new ReencryptionRepository
+func NewReencryptionRepository(database *Database, keyring *encryption.Keyring) *ReencryptionRepository {
+	return &ReencryptionRepository{
+		db:      database.DB,
+		keyring: keyring,
+	}
+}
+
+// encryptedCustomerRow is a customers row as stored, before decryption
+type encryptedCustomerRow struct {
+	ID                  int64     `db:"id"`
+	FirstName           string    `db:"first_name"`
+	LastName            string    `db:"last_name"`
+	Email               string    `db:"email"`
+	EmailHash           *string   `db:"email_hash"`
+	Phone               string    `db:"phone"`
+	StreetAddress       string    `db:"street_address"`
+	PostalCode          string    `db:"postal_code"`
+	City                string    `db:"city"`
+	AdditionalInfo      *string   `db:"additional_info"`
+	NationalIDEncrypted *string   `db:"national_id_encrypted"`
+	UpdatedAt           time.Time `db:"updated_at"`
+}
+
+// encryptedSnapshotRow is the customer snapshot of a payments or bookings row
+// as stored, before decryption
+type encryptedSnapshotRow struct {
+	ID                  int64     `db:"id"`
+	FirstName           string    `db:"customer_first_name"`
+	LastName            string    `db:"customer_last_name"`
+	Email               string    `db:"customer_email"`
+	Phone               string    `db:"customer_phone"`
+	StreetAddress       string    `db:"customer_street_address"`
+	PostalCode          string    `db:"customer_postal_code"`
+	City                string    `db:"customer_city"`
+	AdditionalInfo      string    `db:"customer_additional_info"`
+	NationalIDEncrypted *string   `db:"customer_national_id_encrypted"`
+	UpdatedAt           time.Time `db:"updated_at"`
+}
+
+// ReencryptCustomers processes up to limit customers with an ID above afterID.
+// It returns the last ID processed, or 0 when there are no more customers, and
+// the number of customers that were updated.
+func (r *ReencryptionRepository) ReencryptCustomers(ctx context.Context, afterID int64, limit int) (int64, int, error) {
+	query := `
+		SELECT id, first_name, last_name, email, email_hash, phone, street_address,
+		       postal_code, city, additional_info, national_id_encrypted, updated_at
+		FROM customers
+		WHERE id > ?
+		AND erased_at IS NULL
+		ORDER BY id
+		LIMIT ?
+	`
+
+	var rows []encryptedCustomerRow
+	if err := r.db.SelectContext(ctx, &rows, query, afterID, limit); err != nil {
+		return 0, 0, fmt.Errorf("failed to get customers: %w", err)
+	}
+
+	if len(rows) == 0 {
+		return 0, 0, nil
+	}
+
+	updated := 0
+	for i := range rows {
+		changed, err := r.reencryptCustomer(&rows[i])
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to re-encrypt customer %d: %w", rows[i].ID, err)
+		}
+		if !changed {
+			continue
+		}
+
+		ok, err := r.updateCustomer(ctx, &rows[i])
+		if err != nil {
+			return 0, 0, err
+		}
+		if ok {
+			updated++
+		}
+	}
+
+	return rows[len(rows)-1].ID, updated, nil
+}
+
+// ReencryptSnapshots processes up to limit rows of table with an ID above
+// afterID, encrypting or re-encrypting their customer snapshot. table is either
+// payments or bookings. It returns the last ID processed, or 0 when there are
+// no more rows, and the number of rows that were updated.
+func (r *ReencryptionRepository) ReencryptSnapshots(ctx context.Context, table string, afterID int64, limit int) (int64, int, error) {
+	if table != "payments" && table != "bookings" {
+		return 0, 0, fmt.Errorf("table %s has no customer snapshots", table)
+	}
+
+	query := `
+		SELECT id, customer_first_name, customer_last_name, customer_email,
+		       customer_phone, customer_street_address, customer_postal_code,
+		       customer_city, customer_additional_info, customer_national_id_encrypted,
+		       updated_at
+		FROM ` + table + `
+		WHERE id > ?
+		ORDER BY id
+		LIMIT ?
+	`
+
+	var rows []encryptedSnapshotRow
+	if err := r.db.SelectContext(ctx, &rows, query, afterID, limit); err != nil {
+		return 0, 0, fmt.Errorf("failed to get %s: %w", table, err)
+	}
+
+	if len(rows) == 0 {
+		return 0, 0, nil
+	}
+
+	updated := 0
+	for i := range rows {
+		changed, err := r.reencryptSnapshot(&rows[i])
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to re-encrypt %s %d: %w", table, rows[i].ID, err)
+		}
+		if !changed {
+			continue
+		}
+
+		ok, err := r.updateSnapshot(ctx, table, &rows[i])
+		if err != nil {
+			return 0, 0, err
+		}
+		if ok {
+			updated++
+		}
+	}
+
+	return rows[len(rows)-1].ID, updated, nil
+}
+
+// reencryptCustomer encrypts plaintext fields of a customer row, re-encrypts
+// fields under an old master key and fills in a missing email hash. It
+// reports whether the row changed.
+func (r *ReencryptionRepository) reencryptCustomer(row *encryptedCustomerRow) (bool, error) {
+	changed := false
+
+	if row.EmailHash == nil {
+		email := row.Email
+		if encryption.IsEncrypted(email) {
+			decrypted, err := r.keyring.Decrypt(email)
+			if err != nil {
+				return false, err
+			}
+			email = decrypted
+		}
+
+		hash := emailHash(r.keyring, email)
+		row.EmailHash = &hash
+		changed = true
+	}
+
+	fields := []*string{
+		&row.FirstName,
+		&row.LastName,
+		&row.Email,
+		&row.Phone,
+		&row.StreetAddress,
+		&row.PostalCode,
+		&row.City,
+	}
+	if row.AdditionalInfo != nil {
+		fields = append(fields, row.AdditionalInfo)
+	}
+
+	fieldsChanged, err := r.reencryptFields(fields, row.NationalIDEncrypted)
+	if err != nil {
+		return false, err
+	}
+
+	return changed || fieldsChanged, nil
+}
+
+// reencryptSnapshot encrypts plaintext fields of a customer snapshot and
+// re-encrypts fields under an old master key. Fields of erased snapshots are
+// left as they are. It reports whether the row changed.
+func (r *ReencryptionRepository) reencryptSnapshot(row *encryptedSnapshotRow) (bool, error) {
+	fields := make([]*string, 0, 8)
+	for _, field := range []*string{
+		&row.FirstName,
+		&row.LastName,
+		&row.Email,
+		&row.Phone,
+		&row.StreetAddress,
+		&row.PostalCode,
+		&row.City,
+		&row.AdditionalInfo,
+	} {
+		if *field != entity.ErasedPlaceholder {
+			fields = append(fields, field)
+		}
+	}
+
+	return r.reencryptFields(fields, row.NationalIDEncrypted)
+}
+
+// reencryptFields encrypts the plaintext values in fields and re-encrypts the
+// values under an old master key, together with the national ID, which was
+// always stored encrypted. It reports whether any value changed.
+func (r *ReencryptionRepository) reencryptFields(fields []*string, nationalID *string) (bool, error) {
+	changed := false
+
+	for _, field := range fields {
+		if *field == "" || !r.keyring.NeedsRewrap(*field) {
+			continue
+		}
+
+		var value string
+		var err error
+		if encryption.IsEncrypted(*field) {
+			value, err = r.keyring.Rewrap(*field)
+		} else {
+			value, err = r.keyring.Encrypt(*field)
+		}
+		if err != nil {
+			return false, err
+		}
+
+		*field = value
+		changed = true
+	}
+
+	if nationalID != nil && r.keyring.NeedsRewrap(*nationalID) {
+		value, err := r.keyring.Rewrap(*nationalID)
+		if err != nil {
+			return false, err
+		}
+
+		*nationalID = value
+		changed = true
+	}
+
+	return changed, nil
+}
+
+// updateCustomer writes a re-encrypted customer row. The row is only updated
+// when it has not changed since it was read, and updated_at is kept since the
+// customer data itself is the same. It reports whether the row was updated.
+func (r *ReencryptionRepository) updateCustomer(ctx context.Context, row *encryptedCustomerRow) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE customers
+		SET first_name = ?,
+			last_name = ?,
+			email = ?,
+			email_hash = ?,
+			phone = ?,
+			street_address = ?,
+			postal_code = ?,
+			city = ?,
+			additional_info = ?,
+			national_id_encrypted = ?,
+			updated_at = updated_at
+		WHERE id = ?
+		AND updated_at = ?
+	`,
+		row.FirstName,
+		row.LastName,
+		row.Email,
+		row.EmailHash,
+		row.Phone,
+		row.StreetAddress,
+		row.PostalCode,
+		row.City,
+		row.AdditionalInfo,
+		row.NationalIDEncrypted,
+		row.ID,
+		row.UpdatedAt,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to update customer %d: %w", row.ID, err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+
+// updateSnapshot writes a re-encrypted customer snapshot to a row of table,
+// under the same conditions as updateCustomer. It reports whether the row was
+// updated.
+func (r *ReencryptionRepository) updateSnapshot(ctx context.Context, table string, row *encryptedSnapshotRow) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE `+table+`
+		SET customer_first_name = ?,
+			customer_last_name = ?,
+			customer_email = ?,
+			customer_phone = ?,
+			customer_street_address = ?,
+			customer_postal_code = ?,
+			customer_city = ?,
+			customer_additional_info = ?,
+			customer_national_id_encrypted = ?,
+			updated_at = updated_at
+		WHERE id = ?
+		AND updated_at = ?
+	`,
+		row.FirstName,
+		row.LastName,
+		row.Email,
+		row.Phone,
+		row.StreetAddress,
+		row.PostalCode,
+		row.City,
+		row.AdditionalInfo,
+		row.NationalIDEncrypted,
+		row.ID,
+		row.UpdatedAt,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to update %s %d: %w", table, row.ID, err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rows > 0, nil
+}
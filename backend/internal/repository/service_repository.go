@@ -5,6 +5,9 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/rs/zerolog/log"
@@ -26,8 +29,8 @@ func NewServiceRepository(database *Database) *ServiceRepository {
 // GetServices retrieves all active services
 func (r *ServiceRepository) GetServices(ctx context.Context) ([]entity.Service, error) {
 	query := `
-		SELECT id, name, short_description, description, price, discounted_price, 
-		       is_subscription, subscription_interval, image, is_active, 
+		SELECT id, name, slug, short_description, description, price, 
+		       is_subscription, subscription_interval, is_gift_card, image, category_id, is_active, 
 		       created_at, updated_at, deleted_at
 		FROM services
 		WHERE ` + softDeleteCondition("services") + `
@@ -43,11 +46,95 @@ func (r *ServiceRepository) GetServices(ctx context.Context) ([]entity.Service,
 	return services, nil
 }
 
+// SearchServices retrieves active services matching the filter, ordered by the
+// requested sort field and starting after the given cursor. All matching
+// services are returned when the filter has no limit.
+func (r *ServiceRepository) SearchServices(ctx context.Context, filter entity.ServiceFilter, after *entity.ServiceCursor) ([]entity.Service, error) {
+	conditions := []string{
+		softDeleteCondition("services"),
+		"is_active = true",
+	}
+	args := []interface{}{}
+
+	if filter.Search != "" {
+		pattern := "%" + escapeLike(filter.Search) + "%"
+		conditions = append(conditions, "(name LIKE ? OR short_description LIKE ? OR description LIKE ?)")
+		args = append(args, pattern, pattern, pattern)
+	}
+
+	if len(filter.CategoryIDs) > 0 {
+		conditions = append(conditions, "category_id IN (?"+strings.Repeat(", ?", len(filter.CategoryIDs)-1)+")")
+		for _, categoryID := range filter.CategoryIDs {
+			args = append(args, categoryID)
+		}
+	}
+
+	if filter.SubscriptionOnly {
+		conditions = append(conditions, "is_subscription = true")
+	}
+
+	if filter.MinPrice != nil {
+		conditions = append(conditions, "price >= ?")
+		args = append(args, *filter.MinPrice)
+	}
+
+	if filter.MaxPrice != nil {
+		conditions = append(conditions, "price <= ?")
+		args = append(args, *filter.MaxPrice)
+	}
+
+	sortColumn := serviceSortColumn(filter.SortBy)
+	direction, comparison := "ASC", ">"
+	if filter.SortOrder == entity.SortOrderDesc {
+		direction, comparison = "DESC", "<"
+	}
+
+	// Keyset pagination: continue strictly after the (sort value, id) of the previous page
+	if after != nil {
+		if sortColumn == "id" {
+			conditions = append(conditions, "id "+comparison+" ?")
+			args = append(args, after.ID)
+		} else {
+			sortValue, err := parseServiceCursorValue(filter.SortBy, after.SortValue)
+			if err != nil {
+				return nil, err
+			}
+			conditions = append(conditions, fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", sortColumn, comparison))
+			args = append(args, sortValue, sortValue, after.ID)
+		}
+	}
+
+	orderBy := "id " + direction
+	if sortColumn != "id" {
+		orderBy = sortColumn + " " + direction + ", id " + direction
+	}
+
+	query := `
+		SELECT id, name, slug, short_description, description, price,
+		       is_subscription, subscription_interval, is_gift_card, image, category_id, is_active,
+		       created_at, updated_at, deleted_at
+		FROM services
+		WHERE ` + strings.Join(conditions, "\n\t\tAND ") + `
+		ORDER BY ` + orderBy
+	if filter.Limit > 0 {
+		query += `
+		LIMIT ?`
+		args = append(args, filter.Limit)
+	}
+
+	var services []entity.Service
+	if err := r.db.SelectContext(ctx, &services, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to search services: %w", err)
+	}
+
+	return services, nil
+}
+
 // GetServiceByID retrieves a service by ID
 func (r *ServiceRepository) GetServiceByID(ctx context.Context, id int64) (*entity.Service, error) {
 	query := `
-		SELECT id, name, short_description, description, price, discounted_price, 
-		       is_subscription, subscription_interval, image, is_active, 
+		SELECT id, name, slug, short_description, description, price, 
+		       is_subscription, subscription_interval, is_gift_card, image, category_id, is_active, 
 		       created_at, updated_at, deleted_at
 		FROM services
 		WHERE ` + softDeleteCondition("services") + `
@@ -66,6 +153,90 @@ func (r *ServiceRepository) GetServiceByID(ctx context.Context, id int64) (*enti
 	return &service, nil
 }
 
+// GetServiceBySlug retrieves a service by its current slug
+func (r *ServiceRepository) GetServiceBySlug(ctx context.Context, slug string) (*entity.Service, error) {
+	query := `
+		SELECT id, name, slug, short_description, description, price,
+		       is_subscription, subscription_interval, is_gift_card, image, category_id, is_active,
+		       created_at, updated_at, deleted_at
+		FROM services
+		WHERE ` + softDeleteCondition("services") + `
+		AND is_active = true
+		AND slug = ?
+	`
+
+	var service entity.Service
+	if err := r.db.GetContext(ctx, &service, query, slug); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Service not found
+		}
+		return nil, fmt.Errorf("failed to get service by slug: %w", err)
+	}
+
+	return &service, nil
+}
+
+// GetServiceSlugRedirect retrieves the redirect for a previous service slug
+func (r *ServiceRepository) GetServiceSlugRedirect(ctx context.Context, slug string) (*entity.ServiceSlugRedirect, error) {
+	query := `
+		SELECT id, service_id, slug, created_at
+		FROM service_slug_redirects
+		WHERE slug = ?
+	`
+
+	var redirect entity.ServiceSlugRedirect
+	if err := r.db.GetContext(ctx, &redirect, query, slug); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Redirect not found
+		}
+		return nil, fmt.Errorf("failed to get service slug redirect: %w", err)
+	}
+
+	return &redirect, nil
+}
+
+// GetCatalogVersion returns a value that changes whenever the services, their
+// features, the categories or the price campaigns change, whether through the
+// API or directly in the database. Rows are counted as well as their latest
+// update, so hard deletes change it too.
+func (r *ServiceRepository) GetCatalogVersion(ctx context.Context) (string, error) {
+	query := `
+		SELECT CONCAT_WS(',',
+			(SELECT CONCAT(COUNT(*), '@', COALESCE(MAX(updated_at), '')) FROM services),
+			(SELECT CONCAT(COUNT(*), '@', COALESCE(MAX(updated_at), '')) FROM service_features),
+			(SELECT CONCAT(COUNT(*), '@', COALESCE(MAX(updated_at), '')) FROM service_categories),
+			(SELECT CONCAT(COUNT(*), '@', COALESCE(MAX(updated_at), '')) FROM price_campaigns),
+			(SELECT COUNT(*) FROM price_campaign_services)
+		)
+	`
+
+	var version string
+	if err := r.db.GetContext(ctx, &version, query); err != nil {
+		return "", fmt.Errorf("failed to get catalog version: %w", err)
+	}
+
+	return version, nil
+}
+
+// IsSlugTaken checks whether a slug is used by another service, either as its
+// current slug or as a redirect from an earlier name
+func (r *ServiceRepository) IsSlugTaken(ctx context.Context, slug string, excludeServiceID int64) (bool, error) {
+	query := `
+		SELECT COUNT(*) FROM (
+			SELECT id FROM services WHERE slug = ? AND id <> ?
+			UNION ALL
+			SELECT id FROM service_slug_redirects WHERE slug = ? AND service_id <> ?
+		) AS taken
+	`
+
+	var count int
+	if err := r.db.GetContext(ctx, &count, query, slug, excludeServiceID, slug, excludeServiceID); err != nil {
+		return false, fmt.Errorf("failed to check service slug: %w", err)
+	}
+
+	return count > 0, nil
+}
+
 // GetServiceWithFeatures retrieves a service with its features by ID
 func (r *ServiceRepository) GetServiceWithFeatures(ctx context.Context, id int64) (*entity.ServiceWithFeatures, error) {
 	service, err := r.GetServiceByID(ctx, id)
@@ -113,8 +284,8 @@ func (r *ServiceRepository) GetServicesByIDs(ctx context.Context, ids []int64) (
 	}
 
 	query, args, err := sqlx.In(`
-		SELECT id, name, short_description, description, price, discounted_price, 
-		       is_subscription, subscription_interval, image, is_active, 
+		SELECT id, name, slug, short_description, description, price, 
+		       is_subscription, subscription_interval, is_gift_card, image, category_id, is_active, 
 		       created_at, updated_at, deleted_at
 		FROM services
 		WHERE `+softDeleteCondition("services")+`
@@ -145,10 +316,10 @@ func (r *ServiceRepository) GetServicesByIDs(ctx context.Context, ids []int64) (
 func (r *ServiceRepository) CreateService(ctx context.Context, service *entity.Service) error {
 	query := `
 		INSERT INTO services (
-			name, short_description, description, price, discounted_price,
-			is_subscription, subscription_interval, image, is_active,
+			name, slug, short_description, description, price,
+			is_subscription, subscription_interval, is_gift_card, image, category_id, is_active,
 			created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	now := now()
@@ -159,13 +330,15 @@ func (r *ServiceRepository) CreateService(ctx context.Context, service *entity.S
 		ctx,
 		query,
 		service.Name,
+		service.Slug,
 		service.ShortDescription,
 		service.Description,
 		service.Price,
-		service.DiscountedPrice,
 		service.IsSubscription,
 		service.SubscriptionInterval,
+		service.IsGiftCard,
 		service.Image,
+		service.CategoryID,
 		service.IsActive,
 		service.CreatedAt,
 		service.UpdatedAt,
@@ -183,54 +356,91 @@ func (r *ServiceRepository) CreateService(ctx context.Context, service *entity.S
 	return nil
 }
 
-// UpdateService updates an existing service
-func (r *ServiceRepository) UpdateService(ctx context.Context, service *entity.Service) error {
-	query := `
-		UPDATE services
-		SET name = ?,
-			short_description = ?,
-			description = ?,
-			price = ?,
-			discounted_price = ?,
-			is_subscription = ?,
-			subscription_interval = ?,
-			image = ?,
-			is_active = ?,
-			updated_at = ?
-		WHERE id = ?
-		AND ` + softDeleteCondition("services")
-	
-	service.UpdatedAt = now()
+// UpdateService updates an existing service. When the slug changes, the previous
+// slug is kept as a redirect to the service in the same transaction. It returns
+// false when the service does not exist or is deleted.
+func (r *ServiceRepository) UpdateService(ctx context.Context, service *entity.Service) (bool, error) {
+	found := false
+
+	err := withTransaction(ctx, r.db, func(tx *sqlx.Tx) error {
+		var currentSlug string
+		err := tx.GetContext(ctx, &currentSlug, `
+			SELECT slug
+			FROM services
+			WHERE id = ?
+			AND `+softDeleteCondition("services")+`
+			FOR UPDATE
+		`, service.ID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
+			return fmt.Errorf("failed to get current service slug: %w", err)
+		}
+		found = true
+
+		query := `
+			UPDATE services
+			SET name = ?,
+				slug = ?,
+				short_description = ?,
+				description = ?,
+				price = ?,
+				is_subscription = ?,
+				subscription_interval = ?,
+				is_gift_card = ?,
+				image = ?,
+				category_id = ?,
+				is_active = ?,
+				updated_at = ?
+			WHERE id = ?
+			AND ` + softDeleteCondition("services")
+
+		service.UpdatedAt = now()
+
+		if _, err := tx.ExecContext(
+			ctx,
+			query,
+			service.Name,
+			service.Slug,
+			service.ShortDescription,
+			service.Description,
+			service.Price,
+			service.IsSubscription,
+			service.SubscriptionInterval,
+			service.IsGiftCard,
+			service.Image,
+			service.CategoryID,
+			service.IsActive,
+			service.UpdatedAt,
+			service.ID,
+		); err != nil {
+			return fmt.Errorf("failed to update service: %w", err)
+		}
 
-	result, err := r.db.ExecContext(
-		ctx,
-		query,
-		service.Name,
-		service.ShortDescription,
-		service.Description,
-		service.Price,
-		service.DiscountedPrice,
-		service.IsSubscription,
-		service.SubscriptionInterval,
-		service.Image,
-		service.IsActive,
-		service.UpdatedAt,
-		service.ID,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to update service: %w", err)
-	}
+		if currentSlug == service.Slug {
+			return nil
+		}
 
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
+		// A service renamed back to an earlier slug no longer needs that redirect
+		if _, err := tx.ExecContext(ctx, `DELETE FROM service_slug_redirects WHERE slug = ?`, service.Slug); err != nil {
+			return fmt.Errorf("failed to remove service slug redirect: %w", err)
+		}
 
-	if rows == 0 {
-		return fmt.Errorf("service not found or already deleted")
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO service_slug_redirects (service_id, slug, created_at)
+			VALUES (?, ?, ?)
+		`, service.ID, currentSlug, service.UpdatedAt); err != nil {
+			return fmt.Errorf("failed to create service slug redirect: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return false, err
 	}
 
-	return nil
+	return found, nil
 }
 
 // DeleteService soft-deletes a service
@@ -352,4 +562,46 @@ func (r *ServiceRepository) DeleteServiceFeature(ctx context.Context, id int64)
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// Helper functions
+
+// serviceSortColumn maps a sort field to its database column
+func serviceSortColumn(sortBy string) string {
+	switch sortBy {
+	case entity.ServiceSortName:
+		return "name"
+	case entity.ServiceSortPrice:
+		return "price"
+	case entity.ServiceSortCreatedAt:
+		return "created_at"
+	default:
+		return "id"
+	}
+}
+
+// parseServiceCursorValue converts a cursor sort value back to the type of its column
+func parseServiceCursorValue(sortBy string, value string) (interface{}, error) {
+	switch sortBy {
+	case entity.ServiceSortPrice:
+		price, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid price cursor value: %w", err)
+		}
+		return price, nil
+	case entity.ServiceSortCreatedAt:
+		createdAt, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid createdAt cursor value: %w", err)
+		}
+		return createdAt, nil
+	default:
+		return value, nil
+	}
+}
+
+// escapeLike escapes the wildcard characters of a LIKE pattern
+func escapeLike(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+	return replacer.Replace(value)
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// ErrInvalidAuditEntity is returned for entity types that are not audited
+var ErrInvalidAuditEntity = errors.New("invalid audit entity type")
+
+// AuditRepository defines the interface for audit log data operations
+type AuditRepository interface {
+	GetEntries(ctx context.Context, entityType string, entityID int64) ([]entity.AuditEntry, error)
+}
+
+// AuditService reads the audit log of payments, bookings and customers. The log
+// itself is written by the repositories, together with the changes it records.
+type AuditService struct {
+	repo AuditRepository
+}
+
+// NewAuditService creates a new AuditService
+func NewAuditService(repo AuditRepository) *AuditService {
+	return &AuditService{
+		repo: repo,
+	}
+}
+
+// GetHistory retrieves the audit log of an entity, oldest first
+func (s *AuditService) GetHistory(ctx context.Context, entityType string, entityID int64) ([]entity.AuditEntry, error) {
+	switch entityType {
+	case entity.AuditEntityPayment, entity.AuditEntityBooking, entity.AuditEntityCustomer:
+	default:
+		return nil, ErrInvalidAuditEntity
+	}
+
+	entries, err := s.repo.GetEntries(ctx, entityType, entityID)
+	if err != nil {
+		log.Error().Err(err).Str("entityType", entityType).Int64("entityID", entityID).Msg("Failed to get audit log")
+		return nil, fmt.Errorf("failed to get audit log: %w", err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,271 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// Common authentication errors
+var (
+	ErrInvalidLoginCode     = errors.New("invalid or expired login code")
+	ErrLoginCodeRateLimited = errors.New("too many login codes requested, please try again later")
+	ErrUnauthenticated      = errors.New("not logged in")
+)
+
+// maxLoginCodeAttempts is how many attempts to use a login code are allowed
+const maxLoginCodeAttempts = 5
+
+// AuthRepository defines the interface for login code and session data operations
+type AuthRepository interface {
+	CreateLoginCode(ctx context.Context, code *entity.LoginCode, email *entity.OutboxEmail) error
+	CountLoginCodesSince(ctx context.Context, email string, since time.Time) (int, error)
+	GetActiveLoginCode(ctx context.Context, email string) (*entity.LoginCode, error)
+	IncrementLoginCodeAttempts(ctx context.Context, id int64, maxAttempts int) (bool, error)
+	UseLoginCode(ctx context.Context, id int64) (bool, error)
+	CreateSession(ctx context.Context, session *entity.CustomerSession) error
+	GetActiveSession(ctx context.Context, tokenHash string) (*entity.CustomerSession, error)
+	RevokeSession(ctx context.Context, tokenHash string) error
+}
+
+// CustomerRepository defines the interface for customer data operations
+type CustomerRepository interface {
+	GetCustomerByID(ctx context.Context, id int64) (*entity.Customer, error)
+	GetCustomerByEmail(ctx context.Context, email string) (*entity.Customer, error)
+	CreateCustomer(ctx context.Context, customer *entity.Customer) (*entity.Customer, error)
+	UpdateCustomerProfile(ctx context.Context, customer *entity.Customer) error
+	MarkEmailVerified(ctx context.Context, id int64) error
+}
+
+// AuthService provides passwordless login for customers. A one-time code is
+// emailed to the customer and exchanged for a session token.
+type AuthService struct {
+	repo            AuthRepository
+	customerRepo    CustomerRepository
+	codeTTL         time.Duration
+	sessionTTL      time.Duration
+	maxCodesPerHour int
+}
+
+// NewAuthService creates a new AuthService. Login codes are valid for codeTTL
+// and sessions for sessionTTL; at most maxCodesPerHour codes are sent to an
+// email address an hour.
+func NewAuthService(repo AuthRepository, customerRepo CustomerRepository, codeTTL time.Duration, sessionTTL time.Duration, maxCodesPerHour int) *AuthService {
+	return &AuthService{
+		repo:            repo,
+		customerRepo:    customerRepo,
+		codeTTL:         codeTTL,
+		sessionTTL:      sessionTTL,
+		maxCodesPerHour: maxCodesPerHour,
+	}
+}
+
+// LoginResult represents a successful login. Token is only returned here; the
+// server keeps a hash of it.
+type LoginResult struct {
+	Token     string
+	ExpiresAt time.Time
+	Customer  *entity.Customer
+}
+
+// RequestLoginCode emails a one-time login code. It succeeds whether or not the
+// email belongs to a customer, so it cannot be used to find out who is one.
+func (s *AuthService) RequestLoginCode(ctx context.Context, email string) error {
+	email = normalizeEmail(email)
+
+	count, err := s.repo.CountLoginCodesSince(ctx, email, time.Now().Add(-time.Hour))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to count login codes")
+		return fmt.Errorf("failed to count login codes: %w", err)
+	}
+	if count >= s.maxCodesPerHour {
+		return ErrLoginCodeRateLimited
+	}
+
+	code, err := generateLoginCode()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to generate login code")
+		return fmt.Errorf("failed to generate login code: %w", err)
+	}
+
+	loginCode := &entity.LoginCode{
+		Email:     email,
+		CodeHash:  hashSecret(code),
+		ExpiresAt: time.Now().Add(s.codeTTL),
+	}
+	message := &entity.OutboxEmail{
+		Recipient: email,
+		Subject:   "Din inloggningskod",
+		Body: fmt.Sprintf(
+			"Hej,\n\nDin inloggningskod till Svensk Hälsovård är: %s\n\nKoden gäller i %d minuter. Har du inte försökt logga in kan du bortse från detta meddelande.\n\nMed vänliga hälsningar,\nSvensk Hälsovård\n",
+			code, int(s.codeTTL.Minutes()),
+		),
+	}
+
+	if err := s.repo.CreateLoginCode(ctx, loginCode, message); err != nil {
+		log.Error().Err(err).Msg("Failed to create login code")
+		return fmt.Errorf("failed to create login code: %w", err)
+	}
+
+	return nil
+}
+
+// VerifyLoginCode exchanges a login code for a session. A customer account is
+// created for the email on first login.
+func (s *AuthService) VerifyLoginCode(ctx context.Context, email string, code string) (*LoginResult, error) {
+	email = normalizeEmail(email)
+
+	loginCode, err := s.repo.GetActiveLoginCode(ctx, email)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get login code")
+		return nil, fmt.Errorf("failed to get login code: %w", err)
+	}
+
+	if loginCode == nil {
+		return nil, ErrInvalidLoginCode
+	}
+
+	// Count the attempt before comparing, so concurrent guesses share the limit
+	allowed, err := s.repo.IncrementLoginCodeAttempts(ctx, loginCode.ID, maxLoginCodeAttempts)
+	if err != nil {
+		log.Error().Err(err).Int64("loginCodeID", loginCode.ID).Msg("Failed to record login code attempt")
+		return nil, fmt.Errorf("failed to record login code attempt: %w", err)
+	}
+	if !allowed {
+		return nil, ErrInvalidLoginCode
+	}
+
+	if subtle.ConstantTimeCompare([]byte(loginCode.CodeHash), []byte(hashSecret(strings.TrimSpace(code)))) != 1 {
+		return nil, ErrInvalidLoginCode
+	}
+
+	used, err := s.repo.UseLoginCode(ctx, loginCode.ID)
+	if err != nil {
+		log.Error().Err(err).Int64("loginCodeID", loginCode.ID).Msg("Failed to use login code")
+		return nil, fmt.Errorf("failed to use login code: %w", err)
+	}
+	if !used {
+		return nil, ErrInvalidLoginCode
+	}
+
+	customer, err := s.customerRepo.GetCustomerByEmail(ctx, email)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get customer")
+		return nil, fmt.Errorf("failed to get customer: %w", err)
+	}
+	if customer == nil {
+		customer, err = s.customerRepo.CreateCustomer(ctx, &entity.Customer{Email: email})
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to create customer")
+			return nil, fmt.Errorf("failed to create customer: %w", err)
+		}
+	}
+
+	if customer.EmailVerifiedAt == nil {
+		if err := s.customerRepo.MarkEmailVerified(ctx, customer.ID); err != nil {
+			log.Error().Err(err).Int64("customerID", customer.ID).Msg("Failed to mark customer email verified")
+		}
+		verifiedAt := time.Now().UTC()
+		customer.EmailVerifiedAt = &verifiedAt
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to generate session token")
+		return nil, fmt.Errorf("failed to generate session token: %w", err)
+	}
+
+	session := &entity.CustomerSession{
+		CustomerID: customer.ID,
+		TokenHash:  hashSecret(token),
+		ExpiresAt:  time.Now().Add(s.sessionTTL),
+	}
+	if err := s.repo.CreateSession(ctx, session); err != nil {
+		log.Error().Err(err).Int64("customerID", customer.ID).Msg("Failed to create session")
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return &LoginResult{
+		Token:     token,
+		ExpiresAt: session.ExpiresAt,
+		Customer:  customer,
+	}, nil
+}
+
+// AuthenticateSession returns the customer of an active session token
+func (s *AuthService) AuthenticateSession(ctx context.Context, token string) (*entity.Customer, error) {
+	if token == "" {
+		return nil, ErrUnauthenticated
+	}
+
+	session, err := s.repo.GetActiveSession(ctx, hashSecret(token))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get session")
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	if session == nil {
+		return nil, ErrUnauthenticated
+	}
+
+	customer, err := s.customerRepo.GetCustomerByID(ctx, session.CustomerID)
+	if err != nil {
+		log.Error().Err(err).Int64("customerID", session.CustomerID).Msg("Failed to get customer")
+		return nil, fmt.Errorf("failed to get customer: %w", err)
+	}
+	if customer == nil {
+		return nil, ErrUnauthenticated
+	}
+
+	return customer, nil
+}
+
+// Logout revokes a session token
+func (s *AuthService) Logout(ctx context.Context, token string) error {
+	if err := s.repo.RevokeSession(ctx, hashSecret(token)); err != nil {
+		log.Error().Err(err).Msg("Failed to revoke session")
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	return nil
+}
+
+// Helper functions
+
+// generateLoginCode returns a random six-digit code
+func generateLoginCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// generateToken returns a random 256-bit token
+func generateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashSecret returns the SHA-256 hash of a code or token for storage
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeEmail lowercases and trims an email address
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
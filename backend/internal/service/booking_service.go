@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/jmoiron/sqlx"
@@ -9,27 +10,44 @@ import (
 	"github.com/svenskhalsovard/api/internal/entity"
 )
 
+// Common booking errors
+var (
+	ErrBookingNotFound      = errors.New("booking not found")
+	ErrInvalidBookingStatus = errors.New("invalid booking status")
+	ErrBookingFailed        = errors.New("failed to create booking")
+	ErrPaymentNotSuccessful = errors.New("payment is not successful")
+)
+
 // BookingRepository defines the interface for booking data operations
 type BookingRepository interface {
-	CreateBooking(ctx context.Context, tx *sqlx.Tx, booking *entity.Booking, customer *entity.Customer, items []entity.BookingItem) error
+	CreateBooking(ctx context.Context, tx *sqlx.Tx, booking *entity.Booking, customer *entity.Customer, items []entity.BookingItem) (bool, error)
 	GetBookingByID(ctx context.Context, id int64) (*entity.Booking, error)
 	GetBookingByPaymentID(ctx context.Context, paymentID int64) (*entity.Booking, error)
 	GetBookingWithItems(ctx context.Context, id int64) (*entity.BookingWithItems, error)
-	UpdateBookingStatus(ctx context.Context, bookingID int64, status string) error
+	GetBookingsByCustomerID(ctx context.Context, customerID int64) ([]entity.BookingWithItems, error)
+	UpdateBookingStatus(ctx context.Context, bookingID int64, status string) (string, error)
 	Transaction(fn func(*sqlx.Tx) error) error
 }
 
+// BookingEventRepository defines the interface for recording booking events
+type BookingEventRepository interface {
+	CreateBookingEvent(ctx context.Context, bookingID int64, eventType string, details map[string]string) error
+}
+
 // BookingService provides business logic for bookings
 type BookingService struct {
 	bookingRepo BookingRepository
 	paymentRepo PaymentRepository
+	eventRepo   BookingEventRepository
 }
 
-// NewBookingService creates a new BookingService
-func NewBookingService(bookingRepo BookingRepository, paymentRepo PaymentRepository) *BookingService {
+// NewBookingService creates a new BookingService. What happens to bookings is
+// recorded with eventRepo.
+func NewBookingService(bookingRepo BookingRepository, paymentRepo PaymentRepository, eventRepo BookingEventRepository) *BookingService {
 	return &BookingService{
 		bookingRepo: bookingRepo,
 		paymentRepo: paymentRepo,
+		eventRepo:   eventRepo,
 	}
 }
 
@@ -43,31 +61,42 @@ func (s *BookingService) CreateBooking(ctx context.Context, paymentID int64, cus
 	}
 
 	if paymentWithItems == nil {
-		return nil, fmt.Errorf("payment not found")
+		return nil, ErrPaymentNotFound
 	}
 
 	// Verify payment is successful
 	if paymentWithItems.Payment.Status != entity.PaymentStatusSuccess {
-		return nil, fmt.Errorf("payment is not successful (status: %s)", paymentWithItems.Payment.Status)
+		return nil, fmt.Errorf("%w (status: %s)", ErrPaymentNotSuccessful, paymentWithItems.Payment.Status)
 	}
 
-	// Check if booking already exists for this payment
-	existingBooking, err := s.bookingRepo.GetBookingByPaymentID(ctx, paymentID)
+	// Start database transaction. When the payment already has a booking, that
+	// booking is returned.
+	var booking *entity.Booking
+	var created bool
+	err = s.bookingRepo.Transaction(func(tx *sqlx.Tx) error {
+		booking, created, err = s.CreateBookingTx(ctx, tx, paymentWithItems, customer)
+		return err
+	})
+
 	if err != nil {
-		log.Error().Err(err).Int64("paymentID", paymentID).Msg("Failed to check for existing booking")
-		return nil, fmt.Errorf("failed to check for existing booking: %w", err)
+		log.Error().Err(err).Int64("paymentID", paymentID).Msg("Failed to create booking")
+		return nil, fmt.Errorf("%w: %v", ErrBookingFailed, err)
 	}
 
-	if existingBooking != nil {
-		// Booking already exists for this payment
-		booking, err := s.bookingRepo.GetBookingWithItems(ctx, existingBooking.ID)
-		if err != nil {
-			log.Error().Err(err).Int64("bookingID", existingBooking.ID).Msg("Failed to get existing booking")
-			return nil, fmt.Errorf("failed to get existing booking: %w", err)
-		}
-		return &booking.Booking, nil
+	if created {
+		s.recordCreated(ctx, booking)
 	}
 
+	return booking, nil
+}
+
+// CreateBookingTx creates the booking of a payment in tx without checking the
+// payment, for callers that mark the payment successful in the same
+// transaction. The customer data is taken from the payment unless customer is
+// given. When the payment already has a booking, that booking is returned and
+// created is false. The caller records the event of a created booking once tx
+// is committed.
+func (s *BookingService) CreateBookingTx(ctx context.Context, tx *sqlx.Tx, paymentWithItems *entity.PaymentWithItems, customer *entity.Customer) (booking *entity.Booking, created bool, err error) {
 	// Create booking items from payment items
 	bookingItems := make([]entity.BookingItem, 0, len(paymentWithItems.Items))
 	for _, paymentItem := range paymentWithItems.Items {
@@ -83,8 +112,8 @@ func (s *BookingService) CreateBooking(ctx context.Context, paymentID int64, cus
 	}
 
 	// Create booking record
-	booking := &entity.Booking{
-		PaymentID:   paymentID,
+	booking = &entity.Booking{
+		PaymentID:   paymentWithItems.Payment.ID,
 		Status:      entity.BookingStatusConfirmed,
 		TotalAmount: paymentWithItems.Payment.Amount,
 	}
@@ -94,20 +123,12 @@ func (s *BookingService) CreateBooking(ctx context.Context, paymentID int64, cus
 		customer = &paymentWithItems.Customer
 	}
 
-	// Start database transaction
-	err = s.bookingRepo.Transaction(func(tx *sqlx.Tx) error {
-		if err := s.bookingRepo.CreateBooking(ctx, tx, booking, customer, bookingItems); err != nil {
-			return err
-		}
-		return nil
-	})
-
+	created, err = s.bookingRepo.CreateBooking(ctx, tx, booking, customer, bookingItems)
 	if err != nil {
-		log.Error().Err(err).Int64("paymentID", paymentID).Msg("Failed to create booking")
-		return nil, fmt.Errorf("failed to create booking: %w", err)
+		return nil, false, err
 	}
 
-	return booking, nil
+	return booking, created, nil
 }
 
 // GetBooking retrieves a booking by ID
@@ -119,36 +140,90 @@ func (s *BookingService) GetBooking(ctx context.Context, id int64) (*entity.Book
 	}
 
 	if booking == nil {
-		return nil, fmt.Errorf("booking not found")
+		return nil, ErrBookingNotFound
 	}
 
 	return booking, nil
 }
 
-// UpdateBookingStatus updates the status of a booking
+// UpdateBookingStatus moves a booking to a new status. It returns an error
+// matching entity.ErrInvalidBookingTransition when the booking may not move to
+// the status.
 func (s *BookingService) UpdateBookingStatus(ctx context.Context, id int64, status string) error {
-	if !isValidBookingStatus(status) {
-		return fmt.Errorf("invalid booking status: %s", status)
+	if !entity.IsValidBookingStatus(status) {
+		return fmt.Errorf("%w: %s", ErrInvalidBookingStatus, status)
 	}
 
-	err := s.bookingRepo.UpdateBookingStatus(ctx, id, status)
+	booking, err := s.bookingRepo.GetBookingByID(ctx, id)
+	if err != nil {
+		log.Error().Err(err).Int64("bookingID", id).Msg("Failed to get booking")
+		return fmt.Errorf("failed to get booking: %w", err)
+	}
+
+	if booking == nil {
+		return ErrBookingNotFound
+	}
+
+	// The status the booking moved from is read under the row lock, since it
+	// may have changed since the booking was read
+	oldStatus, err := s.bookingRepo.UpdateBookingStatus(ctx, id, status)
+	if errors.Is(err, entity.ErrInvalidBookingTransition) {
+		return err
+	}
 	if err != nil {
 		log.Error().Err(err).Int64("bookingID", id).Str("status", status).Msg("Failed to update booking status")
 		return fmt.Errorf("failed to update booking status: %w", err)
 	}
 
+	if status != oldStatus {
+		s.recordEvent(ctx, id, entity.BookingEventStatusChanged, map[string]string{
+			"from": oldStatus,
+			"to":   status,
+		})
+	}
+
 	return nil
 }
 
+// CheckIn marks that the customer of a confirmed booking has arrived at the
+// clinic
+func (s *BookingService) CheckIn(ctx context.Context, id int64) (*entity.BookingWithItems, error) {
+	return s.moveBooking(ctx, id, entity.BookingStatusCheckedIn)
+}
+
+// MarkNoShow marks that the customer of a confirmed booking did not come
+func (s *BookingService) MarkNoShow(ctx context.Context, id int64) (*entity.BookingWithItems, error) {
+	return s.moveBooking(ctx, id, entity.BookingStatusNoShow)
+}
+
+// CompleteBooking marks the visit of a checked in booking as done
+func (s *BookingService) CompleteBooking(ctx context.Context, id int64) (*entity.BookingWithItems, error) {
+	return s.moveBooking(ctx, id, entity.BookingStatusCompleted)
+}
+
 // Helper functions
 
-// isValidBookingStatus checks if a booking status is valid
-func isValidBookingStatus(status string) bool {
-	validStatuses := map[string]bool{
-		entity.BookingStatusPending:   true,
-		entity.BookingStatusConfirmed: true,
-		entity.BookingStatusCancelled: true,
-		entity.BookingStatusCompleted: true,
+// recordCreated adds the creation of a booking to its timeline
+func (s *BookingService) recordCreated(ctx context.Context, booking *entity.Booking) {
+	s.recordEvent(ctx, booking.ID, entity.BookingEventCreated, map[string]string{
+		"bookingNumber": booking.BookingNumber,
+		"status":        booking.Status,
+	})
+}
+
+// recordEvent adds an event to the timeline of a booking. The timeline is for
+// support only, so a failure to record it does not fail the booking.
+func (s *BookingService) recordEvent(ctx context.Context, bookingID int64, eventType string, details map[string]string) {
+	if err := s.eventRepo.CreateBookingEvent(ctx, bookingID, eventType, details); err != nil {
+		log.Error().Err(err).Int64("bookingID", bookingID).Str("eventType", eventType).Msg("Failed to record booking event")
 	}
-	return validStatuses[status]
-}
\ No newline at end of file
+}
+
+// moveBooking moves a booking to a new status and returns the updated booking
+func (s *BookingService) moveBooking(ctx context.Context, id int64, status string) (*entity.BookingWithItems, error) {
+	if err := s.UpdateBookingStatus(ctx, id, status); err != nil {
+		return nil, err
+	}
+
+	return s.GetBooking(ctx, id)
+}
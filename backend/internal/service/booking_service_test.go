@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/svenskhalsovard/api/internal/config"
+	"github.com/svenskhalsovard/api/internal/encryption"
+	"github.com/svenskhalsovard/api/internal/entity"
+	"github.com/svenskhalsovard/api/internal/repository"
+)
+
+// testDatabase connects to the MySQL database named by TEST_DATABASE_DSN, which
+// must have the migrations applied and parseTime=true set. Tests that need a
+// database are skipped when it is not set.
+func testDatabase(t *testing.T) *repository.Database {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	database, err := repository.NewDatabase(config.DatabaseConfig{
+		Driver:          "mysql",
+		DSN:             dsn,
+		MaxOpenConns:    20,
+		MaxIdleConns:    20,
+		ConnMaxLifetime: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	t.Cleanup(database.Close)
+
+	return database
+}
+
+// testKeyring returns a keyring with random keys
+func testKeyring(t *testing.T) *encryption.Keyring {
+	t.Helper()
+
+	randomKey := func() string {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		return base64.StdEncoding.EncodeToString(key)
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"activeVersion": 1,
+		"keys":          map[string]string{"1": randomKey()},
+		"blindIndexKey": randomKey(),
+	})
+	if err != nil {
+		t.Fatalf("failed to encode key file: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "keys.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("failed to write key file: %v", err)
+	}
+
+	keyring, err := encryption.LoadKeyring(path)
+	if err != nil {
+		t.Fatalf("failed to load keyring: %v", err)
+	}
+
+	return keyring
+}
+
+func TestCreateBookingConcurrently(t *testing.T) {
+	ctx := context.Background()
+	database := testDatabase(t)
+	keyring := testKeyring(t)
+
+	paymentRepo := repository.NewPaymentRepository(database, keyring)
+	bookingRepo := repository.NewBookingRepository(database, keyring)
+	customerRepo := repository.NewCustomerRepository(database, keyring)
+	bookingService := NewBookingService(bookingRepo, paymentRepo, repository.NewEventRepository(database))
+
+	customer, err := customerRepo.CreateCustomer(ctx, &entity.Customer{
+		FirstName:     "Test",
+		LastName:      "Kund",
+		Email:         uuid.NewString() + "@example.com",
+		Phone:         "0701234567",
+		StreetAddress: "Storgatan 1",
+		PostalCode:    "11122",
+		City:          "Stockholm",
+	})
+	if err != nil {
+		t.Fatalf("failed to create customer: %v", err)
+	}
+
+	payment := &entity.Payment{
+		CustomerID:       customer.ID,
+		CustomerSnapshot: customer.Snapshot(),
+		Amount:           500,
+		Currency:         "SEK",
+		Status:           entity.PaymentStatusSuccess,
+		OrderReference:   uuid.NewString(),
+		TransactionType:  entity.TransactionTypeOneTime,
+	}
+	if err := paymentRepo.Transaction(func(tx *sqlx.Tx) error {
+		return paymentRepo.CreatePayment(ctx, tx, payment, nil)
+	}); err != nil {
+		t.Fatalf("failed to create payment: %v", err)
+	}
+
+	const callers = 10
+	bookingIDs := make([]int64, callers)
+	errs := make([]error, callers)
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+
+			booking, err := bookingService.CreateBooking(ctx, payment.ID, nil)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			bookingIDs[i] = booking.ID
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	for i := 0; i < callers; i++ {
+		if errs[i] != nil {
+			t.Fatalf("caller %d failed to create booking: %v", i, errs[i])
+		}
+		if bookingIDs[i] != bookingIDs[0] {
+			t.Errorf("caller %d got booking %d, want %d", i, bookingIDs[i], bookingIDs[0])
+		}
+	}
+
+	var count int
+	if err := database.DB.GetContext(ctx, &count, `
+		SELECT COUNT(*)
+		FROM bookings
+		WHERE payment_id = ?
+	`, payment.ID); err != nil {
+		t.Fatalf("failed to count bookings: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("payment has %d bookings, want 1", count)
+	}
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// Campaign errors
+var (
+	ErrCampaignNotFound = errors.New("campaign not found")
+	ErrInvalidCampaign  = errors.New("invalid campaign")
+)
+
+// CampaignService lets staff run price campaigns. Campaigns change the prices
+// the catalog shows, so the catalog cache is cleared after every change.
+type CampaignService struct {
+	repo           CampaignRepository
+	serviceService *ServiceService
+}
+
+// NewCampaignService creates a new CampaignService
+func NewCampaignService(repo CampaignRepository, serviceService *ServiceService) *CampaignService {
+	return &CampaignService{
+		repo:           repo,
+		serviceService: serviceService,
+	}
+}
+
+// CreateCampaign creates an active campaign
+func (s *CampaignService) CreateCampaign(ctx context.Context, campaign *entity.PriceCampaign) error {
+	if err := validateCampaign(campaign); err != nil {
+		return err
+	}
+
+	campaign.IsActive = true
+	if campaign.AppliesToAll {
+		campaign.ServiceIDs = nil
+	}
+
+	if err := s.repo.CreateCampaign(ctx, campaign); err != nil {
+		log.Error().Err(err).Str("name", campaign.Name).Msg("Failed to create campaign")
+		return fmt.Errorf("failed to create campaign: %w", err)
+	}
+
+	s.serviceService.InvalidateCatalog()
+
+	return nil
+}
+
+// EndCampaign ends a campaign at the given time
+func (s *CampaignService) EndCampaign(ctx context.Context, id int64, endsAt time.Time) error {
+	ended, err := s.repo.EndCampaign(ctx, id, endsAt.UTC())
+	if err != nil {
+		log.Error().Err(err).Int64("campaignID", id).Msg("Failed to end campaign")
+		return fmt.Errorf("failed to end campaign: %w", err)
+	}
+
+	if !ended {
+		return ErrCampaignNotFound
+	}
+
+	s.serviceService.InvalidateCatalog()
+
+	return nil
+}
+
+// Helper functions
+
+// validateCampaign checks that a campaign gives a sensible discount to at least
+// one service over a non-empty period
+func validateCampaign(campaign *entity.PriceCampaign) error {
+	switch campaign.DiscountType {
+	case entity.DiscountTypePercentage:
+		if campaign.DiscountValue <= 0 || campaign.DiscountValue > 100 {
+			return fmt.Errorf("%w: a percentage must be above 0 and at most 100", ErrInvalidCampaign)
+		}
+	case entity.DiscountTypeFixedAmount:
+		if campaign.DiscountValue <= 0 {
+			return fmt.Errorf("%w: the discount must be above 0", ErrInvalidCampaign)
+		}
+	default:
+		return fmt.Errorf("%w: unknown discount type %q", ErrInvalidCampaign, campaign.DiscountType)
+	}
+
+	if campaign.EndsAt != nil && !campaign.EndsAt.After(campaign.StartsAt) {
+		return fmt.Errorf("%w: the campaign must end after it starts", ErrInvalidCampaign)
+	}
+
+	if !campaign.AppliesToAll && len(campaign.ServiceIDs) == 0 {
+		return fmt.Errorf("%w: the campaign must apply to all services or list some", ErrInvalidCampaign)
+	}
+
+	return nil
+}
@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// Cart errors
+var (
+	ErrCartNotFound   = errors.New("cart not found")
+	ErrCartCheckedOut = errors.New("cart has already been checked out")
+	ErrInvalidCart    = errors.New("invalid cart")
+)
+
+// maxCartItemQuantity is the largest quantity of a service a cart or order may hold
+const maxCartItemQuantity = 10
+
+// CartRepository defines the interface for cart data operations
+type CartRepository interface {
+	GetCartByToken(ctx context.Context, token string) (*entity.CartWithItems, error)
+	SaveCart(ctx context.Context, cart *entity.Cart, items []entity.CartItem) error
+	MarkCartCheckedOut(ctx context.Context, token string, paymentID int64) error
+}
+
+// CartService provides business logic for server-side carts
+type CartService struct {
+	repo           CartRepository
+	serviceService *ServiceService
+	pricingService *PricingService
+	ttl            time.Duration
+}
+
+// NewCartService creates a new CartService. Carts expire when they have not been
+// changed for ttl.
+func NewCartService(repo CartRepository, serviceService *ServiceService, pricingService *PricingService, ttl time.Duration) *CartService {
+	return &CartService{
+		repo:           repo,
+		serviceService: serviceService,
+		pricingService: pricingService,
+		ttl:            ttl,
+	}
+}
+
+// GetCart retrieves a cart with its items priced at the current effective prices
+func (s *CartService) GetCart(ctx context.Context, token string) (*entity.CartWithItems, error) {
+	cart, err := s.repo.GetCartByToken(ctx, token)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get cart")
+		return nil, fmt.Errorf("failed to get cart: %w", err)
+	}
+
+	if cart == nil {
+		return nil, ErrCartNotFound
+	}
+
+	if err := s.priceCart(ctx, cart); err != nil {
+		return nil, err
+	}
+
+	return cart, nil
+}
+
+// ReplaceCart sets the items of a cart, creating the cart when the token is new
+func (s *CartService) ReplaceCart(ctx context.Context, token string, items []entity.CartItem) (*entity.CartWithItems, error) {
+	existing, err := s.repo.GetCartByToken(ctx, token)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get cart")
+		return nil, fmt.Errorf("failed to get cart: %w", err)
+	}
+
+	if existing != nil && existing.Cart.Status == entity.CartStatusCheckedOut {
+		return nil, ErrCartCheckedOut
+	}
+
+	return s.saveCart(ctx, token, mergeCartItems(nil, items))
+}
+
+// UpdateCart changes the quantities of the given items in an existing cart. Items
+// not yet in the cart are added and items with quantity zero are removed.
+func (s *CartService) UpdateCart(ctx context.Context, token string, changes []entity.CartItem) (*entity.CartWithItems, error) {
+	existing, err := s.repo.GetCartByToken(ctx, token)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get cart")
+		return nil, fmt.Errorf("failed to get cart: %w", err)
+	}
+
+	if existing == nil {
+		return nil, ErrCartNotFound
+	}
+
+	if existing.Cart.Status == entity.CartStatusCheckedOut {
+		return nil, ErrCartCheckedOut
+	}
+
+	return s.saveCart(ctx, token, mergeCartItems(existing.Items, changes))
+}
+
+// MarkCheckedOut records that a cart was turned into a payment
+func (s *CartService) MarkCheckedOut(ctx context.Context, token string, paymentID int64) error {
+	if err := s.repo.MarkCartCheckedOut(ctx, token, paymentID); err != nil {
+		log.Error().Err(err).Int64("paymentID", paymentID).Msg("Failed to mark cart checked out")
+		return fmt.Errorf("failed to mark cart checked out: %w", err)
+	}
+
+	return nil
+}
+
+// Helper methods
+
+// saveCart validates the items against the catalog and stores the cart
+func (s *CartService) saveCart(ctx context.Context, token string, items []entity.CartItem) (*entity.CartWithItems, error) {
+	if err := s.validateItems(ctx, items); err != nil {
+		return nil, err
+	}
+
+	cart := &entity.Cart{
+		Token:     token,
+		Status:    entity.CartStatusOpen,
+		ExpiresAt: time.Now().UTC().Add(s.ttl),
+	}
+
+	if err := s.repo.SaveCart(ctx, cart, items); err != nil {
+		log.Error().Err(err).Msg("Failed to save cart")
+		return nil, fmt.Errorf("failed to save cart: %w", err)
+	}
+
+	result := &entity.CartWithItems{
+		Cart:  *cart,
+		Items: items,
+	}
+	if err := s.priceCart(ctx, result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// validateItems checks that every item refers to an available service with a
+// supported purchase type and quantity
+func (s *CartService) validateItems(ctx context.Context, items []entity.CartItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	serviceIDs := make([]int64, 0, len(items))
+	for _, item := range items {
+		serviceIDs = append(serviceIDs, item.ServiceID)
+	}
+
+	services, err := s.serviceService.ValidateServices(ctx, serviceIDs)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCart, err)
+	}
+
+	for _, item := range items {
+		service := services[item.ServiceID]
+
+		if item.PurchaseType != entity.TransactionTypeOneTime && item.PurchaseType != entity.TransactionTypeSubscription {
+			return fmt.Errorf("%w: invalid purchase type %q for service %s", ErrInvalidCart, item.PurchaseType, service.Name)
+		}
+
+		if item.PurchaseType == entity.TransactionTypeSubscription && !service.IsSubscription {
+			return fmt.Errorf("%w: service %s does not support subscription", ErrInvalidCart, service.Name)
+		}
+
+		if item.Quantity <= 0 || item.Quantity > maxCartItemQuantity {
+			return fmt.Errorf("%w: invalid quantity for service %s: %d", ErrInvalidCart, service.Name, item.Quantity)
+		}
+	}
+
+	return nil
+}
+
+// priceCart fills in the service names and current prices of the cart items and
+// the cart total. Items whose service is no longer available are left out.
+func (s *CartService) priceCart(ctx context.Context, cart *entity.CartWithItems) error {
+	cart.TotalAmount = 0
+	if len(cart.Items) == 0 {
+		return nil
+	}
+
+	serviceIDs := make([]int64, 0, len(cart.Items))
+	for _, item := range cart.Items {
+		serviceIDs = append(serviceIDs, item.ServiceID)
+	}
+
+	services, err := s.serviceService.GetServicesByIDs(ctx, serviceIDs)
+	if err != nil {
+		return err
+	}
+
+	serviceList := make([]entity.Service, 0, len(services))
+	for _, service := range services {
+		serviceList = append(serviceList, service)
+	}
+
+	prices, err := s.pricingService.ResolvePrices(ctx, serviceList, time.Now())
+	if err != nil {
+		log.Error().Err(err).Str("token", cart.Cart.Token).Msg("Failed to resolve cart prices")
+		return fmt.Errorf("failed to resolve prices: %w", err)
+	}
+
+	items := make([]entity.CartItem, 0, len(cart.Items))
+	total := 0.0
+	for _, item := range cart.Items {
+		service, ok := services[item.ServiceID]
+		if !ok {
+			continue
+		}
+
+		price := prices[service.ID]
+		item.ServiceName = service.Name
+		item.ListPrice = price.ListPrice
+		item.UnitPrice = price.Price
+		item.TotalPrice = roundToOre(price.Price * float64(item.Quantity))
+		total += item.TotalPrice
+		items = append(items, item)
+	}
+
+	cart.Items = items
+	cart.TotalAmount = roundToOre(total)
+	return nil
+}
+
+// Helper functions
+
+// mergeCartItems applies item changes to a list of cart items, keyed by service
+// and purchase type. A change with quantity zero removes the item.
+func mergeCartItems(items []entity.CartItem, changes []entity.CartItem) []entity.CartItem {
+	merged := make([]entity.CartItem, 0, len(items)+len(changes))
+	merged = append(merged, items...)
+
+	for _, change := range changes {
+		found := false
+		for i := range merged {
+			if merged[i].ServiceID == change.ServiceID && merged[i].PurchaseType == change.PurchaseType {
+				merged[i].Quantity = change.Quantity
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, entity.CartItem{
+				ServiceID:    change.ServiceID,
+				Quantity:     change.Quantity,
+				PurchaseType: change.PurchaseType,
+			})
+		}
+	}
+
+	result := merged[:0]
+	for _, item := range merged {
+		if item.Quantity != 0 {
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
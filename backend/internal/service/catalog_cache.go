@@ -0,0 +1,97 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// catalogCache is an in-process cache for catalog reads. Entries expire after
+// the TTL and the whole cache is cleared whenever the catalog is modified, or
+// its version in the database changes.
+type catalogCache struct {
+	mu      sync.RWMutex
+	ttl     time.Duration
+	version string
+	entries map[string]catalogCacheEntry
+}
+
+// catalogCacheEntry holds a cached value and its expiry time
+type catalogCacheEntry struct {
+	value     interface{}
+	expiresAt time.Time
+}
+
+// newCatalogCache creates a catalog cache. A non-positive TTL disables caching.
+func newCatalogCache(ttl time.Duration) *catalogCache {
+	return &catalogCache{
+		ttl:     ttl,
+		entries: make(map[string]catalogCacheEntry),
+	}
+}
+
+// enabled reports whether the cache stores entries
+func (c *catalogCache) enabled() bool {
+	return c.ttl > 0
+}
+
+// get returns the cached value for key if it exists and has not expired
+func (c *catalogCache) get(key string) (interface{}, bool) {
+	if c.ttl <= 0 {
+		return nil, false
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+
+	return entry.value, true
+}
+
+// set stores a value under key until the TTL elapses
+func (c *catalogCache) set(key string, value interface{}) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Drop expired entries so one-off filter combinations do not accumulate
+	now := time.Now()
+	for k, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			delete(c.entries, k)
+		}
+	}
+
+	c.entries[key] = catalogCacheEntry{
+		value:     value,
+		expiresAt: now.Add(c.ttl),
+	}
+}
+
+// invalidate removes all cached entries
+func (c *catalogCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]catalogCacheEntry)
+}
+
+// syncVersion clears the cache when version differs from the catalog version
+// the entries were read at
+func (c *catalogCache) syncVersion(version string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if version == c.version {
+		return
+	}
+
+	c.version = version
+	c.entries = make(map[string]catalogCacheEntry)
+}
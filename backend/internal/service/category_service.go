@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// ErrCategoryNotFound is returned when a category slug does not match an active category
+var ErrCategoryNotFound = errors.New("category not found")
+
+// CategoryRepository defines the interface for category data operations
+type CategoryRepository interface {
+	GetCategories(ctx context.Context) ([]entity.ServiceCategory, error)
+	GetCategoryBySlug(ctx context.Context, slug string) (*entity.ServiceCategory, error)
+}
+
+// CategoryService provides business logic for service categories
+type CategoryService struct {
+	repo CategoryRepository
+}
+
+// NewCategoryService creates a new CategoryService
+func NewCategoryService(repo CategoryRepository) *CategoryService {
+	return &CategoryService{
+		repo: repo,
+	}
+}
+
+// GetCategoryTree retrieves all active categories arranged as a tree
+func (s *CategoryService) GetCategoryTree(ctx context.Context) ([]entity.ServiceCategoryNode, error) {
+	categories, err := s.repo.GetCategories(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get categories")
+		return nil, fmt.Errorf("failed to get categories: %w", err)
+	}
+
+	return buildCategoryTree(categories), nil
+}
+
+// GetCategoryBySlug retrieves an active category by slug
+func (s *CategoryService) GetCategoryBySlug(ctx context.Context, slug string) (*entity.ServiceCategory, error) {
+	category, err := s.repo.GetCategoryBySlug(ctx, slug)
+	if err != nil {
+		log.Error().Err(err).Str("slug", slug).Msg("Failed to get category by slug")
+		return nil, fmt.Errorf("failed to get category by slug: %w", err)
+	}
+
+	if category == nil {
+		return nil, ErrCategoryNotFound
+	}
+
+	return category, nil
+}
+
+// Helper functions
+
+// buildCategoryTree arranges categories under their parents, keeping the repository order
+// among siblings. Categories whose parent is inactive or missing are dropped.
+func buildCategoryTree(categories []entity.ServiceCategory) []entity.ServiceCategoryNode {
+	childrenByParent := make(map[int64][]entity.ServiceCategory)
+	roots := make([]entity.ServiceCategory, 0)
+	for _, category := range categories {
+		if category.ParentID == nil {
+			roots = append(roots, category)
+			continue
+		}
+		childrenByParent[*category.ParentID] = append(childrenByParent[*category.ParentID], category)
+	}
+
+	var build func(categories []entity.ServiceCategory) []entity.ServiceCategoryNode
+	build = func(categories []entity.ServiceCategory) []entity.ServiceCategoryNode {
+		nodes := make([]entity.ServiceCategoryNode, 0, len(categories))
+		for _, category := range categories {
+			nodes = append(nodes, entity.ServiceCategoryNode{
+				Category: category,
+				Children: build(childrenByParent[category.ID]),
+			})
+		}
+		return nodes
+	}
+
+	return build(roots)
+}
+
+// categoryWithDescendants returns the ID of the category with the given slug
+// followed by the IDs of all its active subcategories
+func categoryWithDescendants(categories []entity.ServiceCategory, slug string) ([]int64, bool) {
+	var root *entity.ServiceCategory
+	childrenByParent := make(map[int64][]int64)
+	for i, category := range categories {
+		if category.Slug == slug {
+			root = &categories[i]
+		}
+		if category.ParentID != nil {
+			childrenByParent[*category.ParentID] = append(childrenByParent[*category.ParentID], category.ID)
+		}
+	}
+
+	if root == nil {
+		return nil, false
+	}
+
+	ids := []int64{root.ID}
+	visited := map[int64]bool{root.ID: true}
+	for i := 0; i < len(ids); i++ {
+		for _, childID := range childrenByParent[ids[i]] {
+			if !visited[childID] {
+				visited[childID] = true
+				ids = append(ids, childID)
+			}
+		}
+	}
+
+	return ids, true
+}
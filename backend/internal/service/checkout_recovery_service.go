@@ -0,0 +1,309 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/svenskhalsovard/api/internal/entity"
+	"github.com/svenskhalsovard/api/internal/mailer"
+)
+
+// Common checkout recovery errors
+var (
+	ErrRecoveryNotFound     = errors.New("checkout recovery not found")
+	ErrCheckoutNotResumable = errors.New("checkout can no longer be resumed")
+)
+
+// CheckoutRecoveryRepository defines the interface for checkout recovery data operations
+type CheckoutRecoveryRepository interface {
+	ClaimRecovery(ctx context.Context, recovery *entity.CheckoutRecovery) (bool, error)
+	ReleaseRecovery(ctx context.Context, id int64) error
+	MarkRecoverySent(ctx context.Context, id int64) error
+	MarkRecoveryResumed(ctx context.Context, id int64) error
+	GetRecoveryByToken(ctx context.Context, token string) (*entity.CheckoutRecovery, error)
+	IsEmailOptedOut(ctx context.Context, email string) (bool, error)
+	CreateEmailOptOut(ctx context.Context, email string) error
+}
+
+// Mailer defines the interface for sending email
+type Mailer interface {
+	Send(ctx context.Context, message mailer.Message) error
+}
+
+// CheckoutRecoveryService reminds customers about abandoned checkouts. A checkout
+// is abandoned when its payment stays initiated or pending. Reminders are only
+// sent while the customer consents to them.
+type CheckoutRecoveryService struct {
+	repo           CheckoutRecoveryRepository
+	paymentService *PaymentService
+	consentService *ConsentService
+	mailer         Mailer
+	resumeURL      string
+	optOutURL      string
+	minAge         time.Duration
+	maxAge         time.Duration
+}
+
+// NewCheckoutRecoveryService creates a new CheckoutRecoveryService. Checkouts are
+// reminded about once they are minAge old, unless they are older than maxAge.
+func NewCheckoutRecoveryService(
+	repo CheckoutRecoveryRepository,
+	paymentService *PaymentService,
+	consentService *ConsentService,
+	mailer Mailer,
+	resumeURL string,
+	optOutURL string,
+	minAge time.Duration,
+	maxAge time.Duration,
+) *CheckoutRecoveryService {
+	return &CheckoutRecoveryService{
+		repo:           repo,
+		paymentService: paymentService,
+		consentService: consentService,
+		mailer:         mailer,
+		resumeURL:      resumeURL,
+		optOutURL:      optOutURL,
+		minAge:         minAge,
+		maxAge:         maxAge,
+	}
+}
+
+// SendReminders emails the customers of abandoned checkouts who consented to a
+// reminder and have not opted out. It returns the number of reminders sent.
+func (s *CheckoutRecoveryService) SendReminders(ctx context.Context) (int, error) {
+	minAge := fmt.Sprintf("%d MINUTE", int(s.minAge.Minutes()))
+	payments, err := s.paymentService.repo.FindIncompletePayments(ctx, minAge)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to find incomplete payments")
+		return 0, fmt.Errorf("failed to find incomplete payments: %w", err)
+	}
+
+	oldest := time.Now().Add(-s.maxAge)
+	sent := 0
+
+	for _, payment := range payments {
+		if !payment.RecoveryConsent || payment.CreatedAt.Before(oldest) {
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			return sent, err
+		}
+
+		ok, err := s.sendReminder(ctx, payment.ID)
+		if err != nil {
+			log.Error().Err(err).Int64("paymentID", payment.ID).Msg("Failed to send checkout reminder")
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+
+	return sent, nil
+}
+
+// Run sends reminders every interval until the context is cancelled
+func (s *CheckoutRecoveryService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sent, err := s.SendReminders(ctx)
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to send checkout reminders")
+				continue
+			}
+			if sent > 0 {
+				log.Info().Int("sent", sent).Msg("Sent checkout reminders")
+			}
+		}
+	}
+}
+
+// ResumeCheckout returns the checkout of a reminder token so the customer can
+// finish paying
+func (s *CheckoutRecoveryService) ResumeCheckout(ctx context.Context, token string) (*CheckoutResult, error) {
+	recovery, err := s.getRecovery(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	paymentWithItems, err := s.paymentService.repo.GetPaymentWithItems(ctx, recovery.PaymentID)
+	if err != nil {
+		log.Error().Err(err).Int64("paymentID", recovery.PaymentID).Msg("Failed to get payment data")
+		return nil, fmt.Errorf("failed to get payment data: %w", err)
+	}
+
+	if paymentWithItems == nil {
+		return nil, ErrRecoveryNotFound
+	}
+
+	payment := paymentWithItems.Payment
+	if payment.Status != entity.PaymentStatusInitiated && payment.Status != entity.PaymentStatusPending {
+		return nil, ErrCheckoutNotResumable
+	}
+
+	orderResponse, err := s.paymentService.ResumeSveaOrder(ctx, payment.ID)
+	if errors.Is(err, ErrInvalidPaymentStatus) {
+		return nil, ErrCheckoutNotResumable
+	}
+	if err != nil {
+		log.Error().Err(err).Int64("paymentID", payment.ID).Msg("Failed to resume Svea order")
+		return nil, fmt.Errorf("failed to resume Svea order: %w", err)
+	}
+
+	if err := s.repo.MarkRecoveryResumed(ctx, recovery.ID); err != nil {
+		log.Error().Err(err).Int64("recoveryID", recovery.ID).Msg("Failed to mark checkout recovery resumed")
+	}
+
+	return &CheckoutResult{
+		PaymentID:      payment.ID,
+		SveaOrderID:    orderResponse.OrderID,
+		SveaCheckoutUI: orderResponse.CheckoutUI,
+		Status:         entity.PaymentStatusPending,
+		Customer:       paymentWithItems.Customer,
+		Items:          paymentWithItems.Items,
+		DiscountAmount: payment.DiscountAmount,
+		GiftCardAmount: payment.GiftCardAmount,
+		TotalAmount:    payment.Amount,
+	}, nil
+}
+
+// OptOut stops further reminders to the email address of a reminder token and
+// withdraws the customer's consent to them
+func (s *CheckoutRecoveryService) OptOut(ctx context.Context, token string, origin ConsentOrigin) error {
+	recovery, err := s.getRecovery(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.CreateEmailOptOut(ctx, recovery.Email); err != nil {
+		log.Error().Err(err).Int64("recoveryID", recovery.ID).Msg("Failed to opt out of checkout reminders")
+		return fmt.Errorf("failed to opt out: %w", err)
+	}
+
+	payment, err := s.paymentService.repo.GetPaymentByID(ctx, recovery.PaymentID)
+	if err != nil {
+		log.Error().Err(err).Int64("paymentID", recovery.PaymentID).Msg("Failed to get payment")
+		return fmt.Errorf("failed to get payment: %w", err)
+	}
+
+	if payment != nil {
+		if err := s.consentService.WithdrawConsent(ctx, payment.CustomerID, entity.ConsentTypeCheckoutReminders, origin); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getRecovery retrieves a sent reminder by token
+func (s *CheckoutRecoveryService) getRecovery(ctx context.Context, token string) (*entity.CheckoutRecovery, error) {
+	recovery, err := s.repo.GetRecoveryByToken(ctx, token)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get checkout recovery")
+		return nil, fmt.Errorf("failed to get checkout recovery: %w", err)
+	}
+
+	if recovery == nil {
+		return nil, ErrRecoveryNotFound
+	}
+
+	return recovery, nil
+}
+
+// sendReminder emails the reminder for one payment. It reports false when the
+// payment was skipped because it was already contacted, the customer opted out
+// or the customer withdrew their consent.
+func (s *CheckoutRecoveryService) sendReminder(ctx context.Context, paymentID int64) (bool, error) {
+	paymentWithItems, err := s.paymentService.repo.GetPaymentWithItems(ctx, paymentID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get payment data: %w", err)
+	}
+
+	if paymentWithItems == nil || len(paymentWithItems.Items) == 0 {
+		return false, nil
+	}
+
+	email := strings.ToLower(strings.TrimSpace(paymentWithItems.Customer.Email))
+	if email == "" {
+		return false, nil
+	}
+
+	optedOut, err := s.repo.IsEmailOptedOut(ctx, email)
+	if err != nil {
+		return false, err
+	}
+	if optedOut {
+		return false, nil
+	}
+
+	// The customer may have withdrawn the consent since the checkout
+	allowed, err := s.consentService.Allows(ctx, paymentWithItems.Payment.CustomerID, entity.ConsentTypeCheckoutReminders)
+	if err != nil {
+		return false, err
+	}
+	if !allowed {
+		return false, nil
+	}
+
+	// Claim the payment before sending so it is contacted only once
+	recovery := &entity.CheckoutRecovery{
+		PaymentID: paymentID,
+		Token:     uuid.NewString(),
+		Email:     email,
+	}
+	claimed, err := s.repo.ClaimRecovery(ctx, recovery)
+	if err != nil {
+		return false, err
+	}
+	if !claimed {
+		return false, nil
+	}
+
+	if err := s.mailer.Send(ctx, s.buildReminder(paymentWithItems, recovery)); err != nil {
+		if releaseErr := s.repo.ReleaseRecovery(ctx, recovery.ID); releaseErr != nil {
+			log.Error().Err(releaseErr).Int64("paymentID", paymentID).Msg("Failed to release checkout recovery after failed send")
+		}
+		return false, err
+	}
+
+	if err := s.repo.MarkRecoverySent(ctx, recovery.ID); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// buildReminder composes the reminder email for an abandoned checkout
+func (s *CheckoutRecoveryService) buildReminder(paymentWithItems *entity.PaymentWithItems, recovery *entity.CheckoutRecovery) mailer.Message {
+	token := url.QueryEscape(recovery.Token)
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "Hej %s,\n\n", paymentWithItems.Customer.FirstName)
+	body.WriteString("Du har en påbörjad beställning hos Svensk Hälsovård som inte är betald ännu:\n\n")
+	for _, item := range paymentWithItems.Items {
+		fmt.Fprintf(&body, "  %d x %s\n", item.Quantity, item.ServiceName)
+	}
+	fmt.Fprintf(&body, "\nAtt betala: %.2f %s\n\n", paymentWithItems.Payment.Amount, paymentWithItems.Payment.Currency)
+	fmt.Fprintf(&body, "Slutför din beställning här:\n%s?token=%s\n\n", s.resumeURL, token)
+	fmt.Fprintf(&body, "Vill du inte få fler påminnelser kan du avregistrera dig här:\n%s?token=%s\n\n", s.optOutURL, token)
+	body.WriteString("Med vänliga hälsningar,\nSvensk Hälsovård\n")
+
+	return mailer.Message{
+		To:      recovery.Email,
+		Subject: "Du har en påbörjad beställning",
+		Body:    body.String(),
+	}
+}
@@ -2,49 +2,115 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 	"github.com/rs/zerolog/log"
 	"github.com/svenskhalsovard/api/internal/entity"
+	"github.com/svenskhalsovard/api/internal/personnummer"
 	"github.com/svenskhalsovard/api/internal/svea"
 )
 
+// Checkout errors
+var (
+	ErrInvalidServices     = errors.New("invalid services")
+	ErrInvalidPurchaseType = errors.New("invalid purchase type")
+	ErrQuoteNotFound       = errors.New("quote not found")
+	ErrQuoteNotValid       = errors.New("quote has expired or has already been used")
+)
+
+// QuoteRepository defines the interface for checkout quote data operations
+type QuoteRepository interface {
+	CreateQuote(ctx context.Context, quote *entity.CheckoutQuote) error
+	GetQuoteByID(ctx context.Context, id string) (*entity.CheckoutQuote, error)
+	ClaimQuote(ctx context.Context, id string) (bool, error)
+	ReleaseQuote(ctx context.Context, id string) error
+	UpdateQuotePayment(ctx context.Context, id string, paymentID int64) error
+}
+
 // CheckoutService provides business logic for the checkout process
 type CheckoutService struct {
-	paymentService *PaymentService
-	bookingService *BookingService
-	serviceService *ServiceService
+	paymentService  *PaymentService
+	bookingService  *BookingService
+	serviceService  *ServiceService
+	pricingService  *PricingService
+	promoService    *PromoCodeService
+	giftCardService *GiftCardService
+	cartService     *CartService
+	customerService *CustomerService
+	consentService  *ConsentService
+	quoteRepo       QuoteRepository
+	quoteTTL        time.Duration
 }
 
-// NewCheckoutService creates a new CheckoutService
+// NewCheckoutService creates a new CheckoutService. Quotes can be confirmed for
+// quoteTTL after they are made.
 func NewCheckoutService(
 	paymentService *PaymentService,
 	bookingService *BookingService,
 	serviceService *ServiceService,
+	pricingService *PricingService,
+	promoService *PromoCodeService,
+	giftCardService *GiftCardService,
+	cartService *CartService,
+	customerService *CustomerService,
+	consentService *ConsentService,
+	quoteRepo QuoteRepository,
+	quoteTTL time.Duration,
 ) *CheckoutService {
 	return &CheckoutService{
-		paymentService: paymentService,
-		bookingService: bookingService,
-		serviceService: serviceService,
+		paymentService:  paymentService,
+		bookingService:  bookingService,
+		serviceService:  serviceService,
+		pricingService:  pricingService,
+		promoService:    promoService,
+		giftCardService: giftCardService,
+		cartService:     cartService,
+		customerService: customerService,
+		consentService:  consentService,
+		quoteRepo:       quoteRepo,
+		quoteTTL:        quoteTTL,
 	}
 }
 
-// CheckoutItem represents an item in a checkout request
+// CheckoutItem represents an item in a quote request. Prices are never taken
+// from the client; they are resolved from the catalog when the quote is made.
 type CheckoutItem struct {
-	ServiceID    int64   `json:"serviceId"`
-	Quantity     int     `json:"quantity"`
-	PurchaseType string  `json:"purchaseType"`
-	Price        float64 `json:"price"`
+	ServiceID    int64  `json:"serviceId"`
+	Quantity     int    `json:"quantity"`
+	PurchaseType string `json:"purchaseType"`
+}
+
+// QuoteRequest represents a request to price an order. When CartToken is set, the
+// items are taken from the cart instead.
+type QuoteRequest struct {
+	Items        []CheckoutItem `json:"items"`
+	PromoCode    string         `json:"promoCode"`
+	GiftCardCode string         `json:"giftCardCode"`
+	CartToken    string         `json:"cartToken"`
 }
 
-// CheckoutRequest represents a checkout request
+// CheckoutRequest represents a checkout request confirming a quote.
+// RecoveryConsent is whether the customer agreed to be reminded by email if the
+// checkout is left unfinished. CustomerID is set when the customer is logged in;
+// otherwise the customer is looked up by the email in Customer. NationalID is
+// the personnummer given at checkout, if any. Consents are recorded with the
+// client details in Origin.
 type CheckoutRequest struct {
-	Customer    entity.Customer `json:"customer"`
-	Items       []CheckoutItem  `json:"items"`
-	TotalAmount float64         `json:"totalAmount"`
+	Customer        entity.Customer  `json:"customer"`
+	CustomerID      int64            `json:"-"`
+	NationalID      string           `json:"-"`
+	QuoteID         string           `json:"quoteId"`
+	RecoveryConsent bool             `json:"recoveryConsent"`
+	Consents        CheckoutConsents `json:"consents"`
+	Origin          ConsentOrigin    `json:"-"`
 }
 
-// CheckoutResult represents the result of a checkout
+// CheckoutResult represents the result of a checkout. TotalAmount is the amount
+// left to pay through Svea, which is zero when a gift card covers the whole order.
 type CheckoutResult struct {
 	PaymentID      int64                `json:"paymentID"`
 	SveaOrderID    string               `json:"sveaOrderID"`
@@ -52,13 +118,26 @@ type CheckoutResult struct {
 	Status         string               `json:"status"`
 	Customer       entity.Customer      `json:"customer"`
 	Items          []entity.PaymentItem `json:"items"`
+	DiscountAmount float64              `json:"discountAmount"`
+	GiftCardAmount float64              `json:"giftCardAmount"`
+	TotalAmount    float64              `json:"totalAmount"`
+	Booking        *PaymentResult       `json:"booking,omitempty"`
 }
 
-// InitiateCheckout handles the checkout process
-func (s *CheckoutService) InitiateCheckout(ctx context.Context, req *CheckoutRequest) (*CheckoutResult, error) {
+// CreateQuote prices an order on the server and stores the quote for the client
+// to confirm. Promo codes are checked against their overall usage limit here and
+// against the per-customer limit when the quote is confirmed.
+func (s *CheckoutService) CreateQuote(ctx context.Context, req *QuoteRequest) (*entity.CheckoutQuote, error) {
+	// Start from the server-side cart when one is given
+	if req.CartToken != "" {
+		if err := s.loadCart(ctx, req); err != nil {
+			return nil, err
+		}
+	}
+
 	// Validate items
 	if len(req.Items) == 0 {
-		return nil, fmt.Errorf("no items in checkout")
+		return nil, fmt.Errorf("%w: no items in checkout", ErrInvalidServices)
 	}
 
 	// Get service IDs
@@ -70,85 +149,293 @@ func (s *CheckoutService) InitiateCheckout(ctx context.Context, req *CheckoutReq
 	// Validate services
 	services, err := s.serviceService.ValidateServices(ctx, serviceIDs)
 	if err != nil {
-		log.Error().Err(err).Interface("serviceIDs", serviceIDs).Msg("Invalid services in checkout")
-		return nil, fmt.Errorf("invalid services: %w", err)
+		log.Debug().Err(err).Interface("serviceIDs", serviceIDs).Msg("Invalid services in checkout")
+		return nil, fmt.Errorf("%w: %v", ErrInvalidServices, err)
 	}
 
-	// Prepare payment items
-	paymentItems := make([]entity.PaymentItem, 0, len(req.Items))
+	// Resolve the effective prices at checkout time
+	serviceList := make([]entity.Service, 0, len(services))
+	for _, service := range services {
+		serviceList = append(serviceList, service)
+	}
+
+	now := time.Now()
+	prices, err := s.pricingService.ResolvePrices(ctx, serviceList, now)
+	if err != nil {
+		log.Error().Err(err).Interface("serviceIDs", serviceIDs).Msg("Failed to resolve prices in checkout")
+		return nil, fmt.Errorf("failed to resolve prices: %w", err)
+	}
+
+	// Price the items
+	items := make([]entity.PaymentItem, 0, len(req.Items))
 	var hasSubscription bool
+	var hasGiftCards bool
 
 	for _, item := range req.Items {
-		service, ok := services[item.ServiceID]
-		if !ok {
-			return nil, fmt.Errorf("service with ID %d not found", item.ServiceID)
-		}
+		service := services[item.ServiceID]
 
 		// Validate purchase type based on service
-		if item.PurchaseType == "subscription" && !service.IsSubscription {
-			return nil, fmt.Errorf("service with ID %d does not support subscription", item.ServiceID)
+		if item.PurchaseType != entity.TransactionTypeOneTime && item.PurchaseType != entity.TransactionTypeSubscription {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidPurchaseType, item.PurchaseType)
 		}
-
-		// Track if we have any subscription items
-		if item.PurchaseType == "subscription" {
-			hasSubscription = true
+		if item.PurchaseType == entity.TransactionTypeSubscription && !service.IsSubscription {
+			return nil, fmt.Errorf("%w: service %s does not support subscription", ErrInvalidPurchaseType, service.Name)
 		}
 
-		// Validate price matches service
-		expectedPrice := service.DiscountedPrice
-		if expectedPrice == nil {
-			expectedPrice = &service.Price
-		}
-		
-		// Allow small difference in price to account for decimal precision issues
-		if !almostEqual(item.Price, *expectedPrice, 0.01) {
-			return nil, fmt.Errorf("price mismatch for service %s: expected %.2f, got %.2f", 
-				service.Name, *expectedPrice, item.Price)
+		// Validate quantity
+		if item.Quantity <= 0 || item.Quantity > maxCartItemQuantity {
+			return nil, fmt.Errorf("%w: invalid quantity for service %s: %d", ErrInvalidServices, service.Name, item.Quantity)
 		}
 
-		// Validate quantity
-		if item.Quantity <= 0 || item.Quantity > 10 {
-			return nil, fmt.Errorf("invalid quantity for service %s: %d", service.Name, item.Quantity)
+		if item.PurchaseType == entity.TransactionTypeSubscription {
+			hasSubscription = true
+		}
+		if service.IsGiftCard {
+			hasGiftCards = true
 		}
 
-		// Create payment item
-		paymentItems = append(paymentItems, entity.PaymentItem{
+		unitPrice := prices[service.ID].Price
+		items = append(items, entity.PaymentItem{
 			ServiceID:    service.ID,
 			ServiceName:  service.Name,
 			Quantity:     item.Quantity,
-			UnitPrice:    item.Price,
-			TotalPrice:   item.Price * float64(item.Quantity),
+			UnitPrice:    unitPrice,
+			TotalPrice:   roundToOre(unitPrice * float64(item.Quantity)),
+			VatPercent:   VatPercent(service),
 			PurchaseType: item.PurchaseType,
 		})
 	}
 
-	// Validate total amount
-	calculatedTotal := 0.0
-	for _, item := range paymentItems {
-		calculatedTotal += item.TotalPrice
+	subtotal := 0.0
+	for _, item := range items {
+		subtotal += item.TotalPrice
 	}
+	subtotal = roundToOre(subtotal)
 
-	if !almostEqual(req.TotalAmount, calculatedTotal, 0.01) {
-		return nil, fmt.Errorf("total amount mismatch: expected %.2f, got %.2f", 
-			calculatedTotal, req.TotalAmount)
+	quote := &entity.CheckoutQuote{
+		ID:              uuid.NewString(),
+		TransactionType: entity.TransactionTypeOneTime,
+		Subtotal:        subtotal,
+		Currency:        entity.CurrencySEK,
+		ExpiresAt:       now.UTC().Add(s.quoteTTL),
 	}
-
-	// Determine transaction type
-	transactionType := entity.TransactionTypeOneTime
 	if hasSubscription {
-		transactionType = entity.TransactionTypeSubscription
+		quote.TransactionType = entity.TransactionTypeSubscription
+	}
+	if req.CartToken != "" {
+		quote.CartToken = &req.CartToken
+	}
+
+	// Apply promo code to everything but gift cards, which keep their face value
+	if req.PromoCode != "" {
+		discountable := make([]entity.PaymentItem, 0, len(items))
+		discountableIndexes := make([]int, 0, len(items))
+		for i, item := range items {
+			if !services[item.ServiceID].IsGiftCard {
+				discountable = append(discountable, item)
+				discountableIndexes = append(discountableIndexes, i)
+			}
+		}
+
+		promoCode, discount, err := s.promoService.ApplyPromoCode(ctx, req.PromoCode, discountable, now)
+		if err != nil {
+			log.Debug().Err(err).Str("promoCode", req.PromoCode).Msg("Promo code rejected in checkout")
+			return nil, err
+		}
+
+		for n, i := range discountableIndexes {
+			items[i].DiscountAmount = discountable[n].DiscountAmount
+		}
+		quote.PromoCodeID = &promoCode.ID
+		quote.PromoCode = &promoCode.Code
+		quote.DiscountAmount = discount
+	}
+
+	orderAmount := roundToOre(subtotal - quote.DiscountAmount)
+
+	// Redeem gift card against the order amount
+	if req.GiftCardCode != "" {
+		if hasGiftCards {
+			return nil, ErrGiftCardNotApplicable
+		}
+
+		giftCard, amount, err := s.giftCardService.ResolveRedemption(ctx, req.GiftCardCode, orderAmount, now)
+		if err != nil {
+			log.Debug().Err(err).Msg("Gift card rejected in checkout")
+			return nil, err
+		}
+		quote.GiftCardID = &giftCard.ID
+		quote.GiftCardCode = &giftCard.Code
+		quote.GiftCardAmount = amount
+	}
+
+	quote.TotalAmount = roundToOre(orderAmount - quote.GiftCardAmount)
+
+	// Break down the lines with VAT, which is included in the prices
+	quote.Items = make([]entity.CheckoutQuoteItem, 0, len(items))
+	for _, item := range items {
+		vatAmount := IncludedVat(item.TotalPrice-item.DiscountAmount, item.VatPercent)
+		quote.VatAmount += vatAmount
+		quote.Items = append(quote.Items, entity.CheckoutQuoteItem{
+			ServiceID:      item.ServiceID,
+			ServiceName:    item.ServiceName,
+			Quantity:       item.Quantity,
+			PurchaseType:   item.PurchaseType,
+			ListPrice:      prices[item.ServiceID].ListPrice,
+			UnitPrice:      item.UnitPrice,
+			TotalPrice:     item.TotalPrice,
+			DiscountAmount: item.DiscountAmount,
+			VatPercent:     item.VatPercent,
+			VatAmount:      vatAmount,
+		})
+	}
+	quote.VatAmount = roundToOre(quote.VatAmount)
+
+	if err := s.quoteRepo.CreateQuote(ctx, quote); err != nil {
+		log.Error().Err(err).Msg("Failed to create quote")
+		return nil, fmt.Errorf("failed to create quote: %w", err)
+	}
+
+	return quote, nil
+}
+
+// InitiateCheckout starts the payment for a quote. The amounts charged are the
+// quoted amounts; nothing priced by the client is used.
+func (s *CheckoutService) InitiateCheckout(ctx context.Context, req *CheckoutRequest) (*CheckoutResult, error) {
+	quote, err := s.quoteRepo.GetQuoteByID(ctx, req.QuoteID)
+	if err != nil {
+		log.Error().Err(err).Str("quoteID", req.QuoteID).Msg("Failed to get quote")
+		return nil, fmt.Errorf("failed to get quote: %w", err)
+	}
+
+	if quote == nil {
+		return nil, ErrQuoteNotFound
+	}
+
+	if quote.UsedAt != nil || !time.Now().Before(quote.ExpiresAt) {
+		return nil, ErrQuoteNotValid
+	}
+
+	if err := s.consentService.CheckTerms(req.Consents.TermsVersion); err != nil {
+		return nil, err
+	}
+
+	var nationalID personnummer.Number
+	if req.NationalID != "" {
+		if nationalID, err = parseNationalID(req.NationalID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Claim the quote before anything is recorded, so it is paid only once. The
+	// claim is released when the checkout fails before the payment is created,
+	// so the customer can try again with the same quote.
+	claimed, err := s.quoteRepo.ClaimQuote(ctx, quote.ID)
+	if err != nil {
+		log.Error().Err(err).Str("quoteID", quote.ID).Msg("Failed to claim quote")
+		return nil, fmt.Errorf("failed to claim quote: %w", err)
+	}
+	if !claimed {
+		return nil, ErrQuoteNotValid
+	}
+
+	var payment *entity.Payment
+	defer func() {
+		if payment == nil {
+			s.releaseQuote(ctx, quote.ID)
+		}
+	}()
+
+	// Resolve the customer the order is placed for
+	customer, err := s.customerService.ResolveCheckoutCustomer(ctx, req.CustomerID, req.Customer, nationalID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to resolve checkout customer")
+		return nil, fmt.Errorf("failed to resolve customer: %w", err)
+	}
+	req.Customer.ID = customer.ID
+	req.Customer.Email = customer.Email
+
+	// A logged in customer may rely on the national ID stored on their account
+	if nationalID.IsZero() && req.CustomerID > 0 {
+		if nationalID, err = s.customerService.NationalID(customer); err != nil {
+			return nil, fmt.Errorf("failed to read national ID: %w", err)
+		}
+	}
+
+	// Record what the customer agreed to, including the reminder answer
+	req.Consents.CheckoutReminders = req.RecoveryConsent
+	if err := s.consentService.RecordCheckoutConsents(ctx, customer.ID, req.Consents, req.Origin); err != nil {
+		return nil, err
+	}
+
+	// Check the promo code limits now that the customer is known. The use
+	// itself is reserved with the payment.
+	if quote.PromoCode != nil {
+		if err := s.promoService.CheckUsage(ctx, *quote.PromoCode, req.Customer.Email); err != nil {
+			log.Debug().Err(err).Str("promoCode", *quote.PromoCode).Msg("Promo code rejected in checkout")
+			return nil, err
+		}
+	}
+
+	// Prepare payment items from the quoted lines
+	paymentItems := make([]entity.PaymentItem, 0, len(quote.Items))
+	for _, item := range quote.Items {
+		paymentItems = append(paymentItems, entity.PaymentItem{
+			ServiceID:      item.ServiceID,
+			ServiceName:    item.ServiceName,
+			Quantity:       item.Quantity,
+			UnitPrice:      item.UnitPrice,
+			TotalPrice:     item.TotalPrice,
+			DiscountAmount: item.DiscountAmount,
+			VatPercent:     item.VatPercent,
+			PurchaseType:   item.PurchaseType,
+		})
 	}
 
 	// Initiate payment
-	payment, err := s.paymentService.InitiatePayment(ctx, &req.Customer, paymentItems, &PaymentOptions{
-		TotalAmount:     req.TotalAmount,
-		TransactionType: transactionType,
+	payment, err = s.paymentService.InitiatePayment(ctx, &req.Customer, paymentItems, &PaymentOptions{
+		TotalAmount:     quote.TotalAmount,
+		TransactionType: quote.TransactionType,
+		PromoCodeID:     quote.PromoCodeID,
+		DiscountAmount:  quote.DiscountAmount,
+		GiftCardID:      quote.GiftCardID,
+		GiftCardAmount:  quote.GiftCardAmount,
+		RecoveryConsent: req.RecoveryConsent,
+		NationalID:      nationalID,
 	})
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to initiate payment")
 		return nil, fmt.Errorf("failed to initiate payment: %w", err)
 	}
 
+	if err := s.quoteRepo.UpdateQuotePayment(ctx, quote.ID, payment.ID); err != nil {
+		log.Error().Err(err).Str("quoteID", quote.ID).Int64("paymentID", payment.ID).Msg("Failed to link quote to payment")
+	}
+
+	// Close the cart so it cannot be checked out twice
+	if quote.CartToken != nil {
+		_ = s.cartService.MarkCheckedOut(ctx, *quote.CartToken, payment.ID)
+	}
+
+	// Complete the order without Svea when the gift card covers all of it
+	if quote.TotalAmount == 0 && quote.GiftCardAmount > 0 {
+		result, err := s.completeGiftCardPayment(ctx, payment.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		return &CheckoutResult{
+			PaymentID:      payment.ID,
+			Status:         entity.PaymentStatusSuccess,
+			Booking:        result,
+			Customer:       req.Customer,
+			Items:          paymentItems,
+			DiscountAmount: quote.DiscountAmount,
+			GiftCardAmount: quote.GiftCardAmount,
+			TotalAmount:    quote.TotalAmount,
+		}, nil
+	}
+
 	// Create Svea order
 	orderResponse, err := s.paymentService.CreateSveaOrder(ctx, payment.ID)
 	if err != nil {
@@ -164,26 +451,105 @@ func (s *CheckoutService) InitiateCheckout(ctx context.Context, req *CheckoutReq
 		Status:         payment.Status,
 		Customer:       req.Customer,
 		Items:          paymentItems,
+		DiscountAmount: quote.DiscountAmount,
+		GiftCardAmount: quote.GiftCardAmount,
+		TotalAmount:    quote.TotalAmount,
 	}, nil
 }
 
-// ProcessPayment processes a payment in Svea Ekonomi
-func (s *CheckoutService) ProcessPayment(ctx context.Context, paymentID int64, paymentMethod string) error {
-	// Process payment
-	err := s.paymentService.ProcessPayment(ctx, paymentID, paymentMethod)
+// releaseQuote makes a claimed quote usable again after its checkout failed
+func (s *CheckoutService) releaseQuote(ctx context.Context, id string) {
+	if err := s.quoteRepo.ReleaseQuote(ctx, id); err != nil {
+		log.Error().Err(err).Str("quoteID", id).Msg("Failed to release quote")
+	}
+}
+
+// Payment result statuses
+const (
+	PaymentResultSuccess        = "success"
+	PaymentResultBookingPending = "booking_pending"
+)
+
+// PaymentResult is the outcome of a processed payment. When the payment was taken
+// but it could not be settled, the status is PaymentResultBookingPending and
+// payment reconciliation settles it later.
+type PaymentResult struct {
+	Status    string
+	Booking   *entity.Booking
+	GiftCards []entity.GiftCard
+}
+
+// ProcessPayment processes a payment in Svea Ekonomi. The payment is marked
+// successful and its booking is created in one transaction.
+func (s *CheckoutService) ProcessPayment(ctx context.Context, paymentID int64, paymentMethod string) (*PaymentResult, error) {
+	paymentWithItems, err := s.paymentService.repo.GetPaymentWithItems(ctx, paymentID)
+	if err != nil {
+		log.Error().Err(err).Int64("paymentID", paymentID).Msg("Failed to get payment")
+		return nil, fmt.Errorf("failed to get payment: %w", err)
+	}
+
+	if paymentWithItems == nil {
+		return nil, ErrPaymentNotFound
+	}
+
+	// Process payment, settling it in the transaction that marks it successful
+	var result *PaymentResult
+	var created bool
+	err = s.paymentService.ProcessPayment(ctx, paymentID, paymentMethod, func(tx *sqlx.Tx) error {
+		var err error
+		result, created, err = s.fulfillPayment(ctx, tx, paymentWithItems)
+		return err
+	})
+	if errors.Is(err, ErrPaymentNotCompleted) {
+		log.Error().Err(err).Int64("paymentID", paymentID).Msg("Payment taken but settling it failed, leaving it to reconciliation")
+		return &PaymentResult{Status: PaymentResultBookingPending}, nil
+	}
 	if err != nil {
 		log.Error().Err(err).Int64("paymentID", paymentID).Str("method", paymentMethod).Msg("Failed to process payment")
-		return fmt.Errorf("failed to process payment: %w", err)
+		return nil, fmt.Errorf("failed to process payment: %w", err)
+	}
+
+	if created {
+		s.bookingService.recordCreated(ctx, result.Booking)
+	}
+
+	return result, nil
+}
+
+// completeGiftCardPayment marks a payment fully covered by a gift card as
+// successful and settles it in one transaction
+func (s *CheckoutService) completeGiftCardPayment(ctx context.Context, paymentID int64) (*PaymentResult, error) {
+	paymentWithItems, err := s.paymentService.repo.GetPaymentWithItems(ctx, paymentID)
+	if err != nil {
+		log.Error().Err(err).Int64("paymentID", paymentID).Msg("Failed to get payment")
+		return nil, fmt.Errorf("failed to get payment: %w", err)
+	}
+
+	if paymentWithItems == nil {
+		return nil, ErrPaymentNotFound
 	}
 
-	// Create booking for successful payment
-	_, err = s.bookingService.CreateBooking(ctx, paymentID, nil)
+	var result *PaymentResult
+	var created bool
+	_, err = s.paymentService.CompleteGiftCardPayment(ctx, paymentID, func(tx *sqlx.Tx) error {
+		var err error
+		result, created, err = s.fulfillPayment(ctx, tx, paymentWithItems)
+		return err
+	})
+	if errors.Is(err, ErrPaymentNotCompleted) {
+		log.Error().Err(err).Int64("paymentID", paymentID).Msg("Gift card payment taken but settling it failed, leaving it to reconciliation")
+		return &PaymentResult{Status: PaymentResultBookingPending}, nil
+	}
 	if err != nil {
-		log.Error().Err(err).Int64("paymentID", paymentID).Msg("Failed to create booking after successful payment")
-		return fmt.Errorf("payment successful but failed to create booking: %w", err)
+		log.Error().Err(err).Int64("paymentID", paymentID).Msg("Failed to complete gift card payment")
+		return nil, fmt.Errorf("failed to complete gift card payment: %w", err)
 	}
 
-	return nil
+	if created {
+		s.bookingService.recordCreated(ctx, result.Booking)
+	}
+
+	return result, nil
 }
 
 // VerifyPayment checks the status of a payment
@@ -197,31 +563,99 @@ func (s *CheckoutService) VerifyPayment(ctx context.Context, paymentID int64) (*
 
 	// If payment is successful, ensure a booking exists
 	if payment.Status == entity.PaymentStatusSuccess {
-		// Check if booking already exists
-		existingBooking, err := s.bookingService.bookingRepo.GetBookingByPaymentID(ctx, paymentID)
-		if err != nil {
-			log.Error().Err(err).Int64("paymentID", paymentID).Msg("Failed to check for existing booking")
-			return payment, nil // Return payment info despite booking check error
-		}
-
-		if existingBooking == nil {
-			// Create booking for successful payment
-			_, err = s.bookingService.CreateBooking(ctx, paymentID, nil)
-			if err != nil {
-				log.Error().Err(err).Int64("paymentID", paymentID).Msg("Failed to create booking during payment verification")
-				return payment, nil // Return payment info despite booking creation error
-			}
+		if _, err := s.settlePayment(ctx, payment); err != nil {
+			log.Error().Err(err).Int64("paymentID", paymentID).Msg("Failed to create booking during payment verification")
+			return payment, nil // Return payment info despite booking creation error
 		}
 	}
 
 	return payment, nil
 }
 
-// Helper function to compare floats with tolerance
-func almostEqual(a, b, tolerance float64) bool {
-	diff := a - b
-	if diff < 0 {
-		diff = -diff
+// loadCart replaces the items of a quote request with the contents of its cart
+func (s *CheckoutService) loadCart(ctx context.Context, req *QuoteRequest) error {
+	cart, err := s.cartService.GetCart(ctx, req.CartToken)
+	if err != nil {
+		return err
+	}
+
+	if cart.Cart.Status == entity.CartStatusCheckedOut {
+		return ErrCartCheckedOut
+	}
+
+	req.Items = make([]CheckoutItem, 0, len(cart.Items))
+	for _, item := range cart.Items {
+		req.Items = append(req.Items, CheckoutItem{
+			ServiceID:    item.ServiceID,
+			Quantity:     item.Quantity,
+			PurchaseType: item.PurchaseType,
+		})
+	}
+
+	return nil
+}
+
+// settlePayment records the promo code redemption, issues the gift cards and
+// creates the booking of a successful payment in one transaction. What is already
+// done is skipped, so a payment can be settled more than once, and when settling
+// fails nothing is kept for the next attempt to trip over.
+func (s *CheckoutService) settlePayment(ctx context.Context, payment *entity.Payment) (*entity.Booking, error) {
+	paymentWithItems, err := s.paymentService.repo.GetPaymentWithItems(ctx, payment.ID)
+	if err != nil {
+		log.Error().Err(err).Int64("paymentID", payment.ID).Msg("Failed to get payment data")
+		return nil, fmt.Errorf("failed to get payment data: %w", err)
+	}
+
+	if paymentWithItems == nil {
+		return nil, ErrPaymentNotFound
+	}
+
+	if paymentWithItems.Payment.Status != entity.PaymentStatusSuccess {
+		return nil, fmt.Errorf("%w (status: %s)", ErrPaymentNotSuccessful, paymentWithItems.Payment.Status)
+	}
+
+	var result *PaymentResult
+	var created bool
+	err = s.paymentService.repo.Transaction(func(tx *sqlx.Tx) error {
+		var err error
+		result, created, err = s.fulfillPayment(ctx, tx, paymentWithItems)
+		return err
+	})
+	if err != nil {
+		log.Error().Err(err).Int64("paymentID", payment.ID).Msg("Failed to settle payment")
+		return nil, fmt.Errorf("%w: %v", ErrBookingFailed, err)
+	}
+
+	if created {
+		s.bookingService.recordCreated(ctx, result.Booking)
+	}
+
+	return result.Booking, nil
+}
+
+// fulfillPayment creates the booking, records the promo code redemption and
+// issues the gift cards of a successful payment in tx. A failure fails tx, so
+// the payment is left unsettled for reconciliation to retry. It reports whether
+// the booking was created, for the caller to record once tx is committed.
+func (s *CheckoutService) fulfillPayment(ctx context.Context, tx *sqlx.Tx, paymentWithItems *entity.PaymentWithItems) (*PaymentResult, bool, error) {
+	booking, created, err := s.bookingService.CreateBookingTx(ctx, tx, paymentWithItems, nil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	payment := &paymentWithItems.Payment
+	if err := s.promoService.RecordRedemption(ctx, tx, payment); err != nil {
+		return nil, false, err
 	}
-	return diff <= tolerance
-}
\ No newline at end of file
+
+	giftCards, err := s.giftCardService.IssueGiftCards(ctx, tx, payment)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &PaymentResult{
+		Status:    PaymentResultSuccess,
+		Booking:   booking,
+		GiftCards: giftCards,
+	}, created, nil
+}
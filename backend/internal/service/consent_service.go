@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// Consent errors
+var (
+	ErrTermsNotAccepted       = errors.New("the current terms must be accepted")
+	ErrInvalidConsentType     = errors.New("invalid consent type")
+	ErrConsentNotWithdrawable = errors.New("consent cannot be withdrawn")
+)
+
+// withdrawableConsentTypes are the consents a customer can withdraw. Accepting
+// the terms is part of an order and is not withdrawn on its own.
+var withdrawableConsentTypes = map[string]bool{
+	entity.ConsentTypeMarketingEmail:    true,
+	entity.ConsentTypeSMSReminders:      true,
+	entity.ConsentTypeCheckoutReminders: true,
+}
+
+// ConsentRepository defines the interface for customer consent data operations
+type ConsentRepository interface {
+	CreateConsents(ctx context.Context, consents []entity.CustomerConsent) error
+	GetCurrentConsents(ctx context.Context, customerID int64) ([]entity.CustomerConsent, error)
+	GetLatestConsent(ctx context.Context, customerID int64, consentType string) (*entity.CustomerConsent, error)
+}
+
+// ConsentService records the consents customers give and withdraw, and answers
+// whether a customer may be contacted. Every marketing message must be checked
+// with Allows before it is sent.
+type ConsentService struct {
+	repo                 ConsentRepository
+	termsVersion         string
+	privacyPolicyVersion string
+}
+
+// NewConsentService creates a new ConsentService. termsVersion is the version
+// of the terms customers must accept at checkout, and privacyPolicyVersion the
+// version of the privacy policy other consents are given under.
+func NewConsentService(repo ConsentRepository, termsVersion string, privacyPolicyVersion string) *ConsentService {
+	return &ConsentService{
+		repo:                 repo,
+		termsVersion:         termsVersion,
+		privacyPolicyVersion: privacyPolicyVersion,
+	}
+}
+
+// CheckoutConsents holds the consents given at checkout. TermsVersion is the
+// version of the terms the customer accepted.
+type CheckoutConsents struct {
+	TermsVersion      string `json:"termsVersion"`
+	MarketingEmail    bool   `json:"marketingEmail"`
+	SMSReminders      bool   `json:"smsReminders"`
+	CheckoutReminders bool   `json:"checkoutReminders"`
+}
+
+// ConsentOrigin describes where and by whom a consent was given or withdrawn
+type ConsentOrigin struct {
+	Source    string
+	IPAddress string
+	UserAgent string
+}
+
+// TermsVersion returns the version of the terms customers must accept
+func (s *ConsentService) TermsVersion() string {
+	return s.termsVersion
+}
+
+// PrivacyPolicyVersion returns the version of the privacy policy consents are
+// given under
+func (s *ConsentService) PrivacyPolicyVersion() string {
+	return s.privacyPolicyVersion
+}
+
+// CheckTerms checks that the given terms version is the current one
+func (s *ConsentService) CheckTerms(version string) error {
+	if version != s.termsVersion {
+		return ErrTermsNotAccepted
+	}
+
+	return nil
+}
+
+// RecordCheckoutConsents records the consents given at checkout. Options the
+// customer left unticked are not recorded, so an earlier consent is only
+// withdrawn when the customer asks for it.
+func (s *ConsentService) RecordCheckoutConsents(ctx context.Context, customerID int64, consents CheckoutConsents, origin ConsentOrigin) error {
+	if err := s.CheckTerms(consents.TermsVersion); err != nil {
+		return err
+	}
+
+	records := []entity.CustomerConsent{
+		s.newConsent(customerID, entity.ConsentTypeTerms, true, consents.TermsVersion, origin),
+	}
+
+	granted := []struct {
+		consentType string
+		granted     bool
+	}{
+		{entity.ConsentTypeMarketingEmail, consents.MarketingEmail},
+		{entity.ConsentTypeSMSReminders, consents.SMSReminders},
+		{entity.ConsentTypeCheckoutReminders, consents.CheckoutReminders},
+	}
+	for _, consent := range granted {
+		if consent.granted {
+			records = append(records, s.newConsent(customerID, consent.consentType, true, s.privacyPolicyVersion, origin))
+		}
+	}
+
+	if err := s.repo.CreateConsents(ctx, records); err != nil {
+		log.Error().Err(err).Int64("customerID", customerID).Msg("Failed to record checkout consents")
+		return fmt.Errorf("failed to record consents: %w", err)
+	}
+
+	return nil
+}
+
+// GetConsents retrieves the current state of each consent of a customer
+func (s *ConsentService) GetConsents(ctx context.Context, customerID int64) ([]entity.CustomerConsent, error) {
+	consents, err := s.repo.GetCurrentConsents(ctx, customerID)
+	if err != nil {
+		log.Error().Err(err).Int64("customerID", customerID).Msg("Failed to get consents")
+		return nil, fmt.Errorf("failed to get consents: %w", err)
+	}
+
+	return consents, nil
+}
+
+// WithdrawConsent withdraws a consent of a customer. Withdrawing a consent that
+// is not given is not an error.
+func (s *ConsentService) WithdrawConsent(ctx context.Context, customerID int64, consentType string, origin ConsentOrigin) error {
+	if consentType == entity.ConsentTypeTerms {
+		return ErrConsentNotWithdrawable
+	}
+
+	if !withdrawableConsentTypes[consentType] {
+		return ErrInvalidConsentType
+	}
+
+	allowed, err := s.Allows(ctx, customerID, consentType)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return nil
+	}
+
+	withdrawal := s.newConsent(customerID, consentType, false, s.privacyPolicyVersion, origin)
+	if err := s.repo.CreateConsents(ctx, []entity.CustomerConsent{withdrawal}); err != nil {
+		log.Error().Err(err).Int64("customerID", customerID).Str("consentType", consentType).Msg("Failed to withdraw consent")
+		return fmt.Errorf("failed to withdraw consent: %w", err)
+	}
+
+	log.Info().Int64("customerID", customerID).Str("consentType", consentType).Msg("Consent withdrawn")
+
+	return nil
+}
+
+// Allows reports whether a customer currently consents to consentType
+func (s *ConsentService) Allows(ctx context.Context, customerID int64, consentType string) (bool, error) {
+	consent, err := s.repo.GetLatestConsent(ctx, customerID, consentType)
+	if err != nil {
+		log.Error().Err(err).Int64("customerID", customerID).Str("consentType", consentType).Msg("Failed to get consent")
+		return false, fmt.Errorf("failed to get consent: %w", err)
+	}
+
+	return consent != nil && consent.Granted, nil
+}
+
+// newConsent creates a consent record
+func (s *ConsentService) newConsent(customerID int64, consentType string, granted bool, policyVersion string, origin ConsentOrigin) entity.CustomerConsent {
+	return entity.CustomerConsent{
+		CustomerID:    customerID,
+		ConsentType:   consentType,
+		Granted:       granted,
+		PolicyVersion: policyVersion,
+		Source:        origin.Source,
+		IPAddress:     origin.IPAddress,
+		UserAgent:     origin.UserAgent,
+	}
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// Common contact form errors
+var (
+	ErrInvalidFormToken    = errors.New("invalid or expired form token")
+	ErrSubmittedTooQuickly = errors.New("form was submitted too quickly")
+	ErrContactRateLimited  = errors.New("too many messages, please try again later")
+)
+
+// formTokenMaxAge is how long a contact form token stays valid
+const formTokenMaxAge = 24 * time.Hour
+
+// ContactRepository defines the interface for contact message data operations
+type ContactRepository interface {
+	CreateContactMessage(ctx context.Context, message *entity.ContactMessage, email *entity.OutboxEmail) error
+	CountMessagesFromIP(ctx context.Context, ipAddress string, since time.Time) (int, error)
+}
+
+// ContactService provides business logic for the contact form
+type ContactService struct {
+	repo          ContactRepository
+	staffEmail    string
+	formSecret    []byte
+	minSubmitTime time.Duration
+	maxPerHour    int
+}
+
+// NewContactService creates a new ContactService. Messages are forwarded to
+// staffEmail. A form must be open for at least minSubmitTime before it is sent,
+// and each IP address may send at most maxPerHour messages an hour. Without a
+// formSecret, a random one is used and form tokens do not survive restarts.
+func NewContactService(repo ContactRepository, staffEmail string, formSecret string, minSubmitTime time.Duration, maxPerHour int) *ContactService {
+	secret := []byte(formSecret)
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			log.Fatal().Err(err).Msg("Failed to generate contact form secret")
+		}
+	}
+
+	return &ContactService{
+		repo:          repo,
+		staffEmail:    staffEmail,
+		formSecret:    secret,
+		minSubmitTime: minSubmitTime,
+		maxPerHour:    maxPerHour,
+	}
+}
+
+// ContactRequest represents a contact form submission. Website is a honeypot
+// field that people never fill in. FormToken is the token issued when the form
+// was opened.
+type ContactRequest struct {
+	Name      string
+	Email     string
+	Subject   string
+	Message   string
+	Website   string
+	FormToken string
+	IPAddress string
+	UserAgent string
+}
+
+// IssueFormToken returns a signed token recording when the contact form was opened
+func (s *ContactService) IssueFormToken() string {
+	issuedAt := strconv.FormatInt(time.Now().Unix(), 10)
+	return issuedAt + "." + s.signFormToken(issuedAt)
+}
+
+// SubmitMessage stores a contact message and queues it for forwarding to staff.
+// Submissions caught by the honeypot are dropped without an error so that bots
+// cannot tell they were filtered.
+func (s *ContactService) SubmitMessage(ctx context.Context, req *ContactRequest) error {
+	if req.Website != "" {
+		log.Info().Str("ip", req.IPAddress).Msg("Contact form honeypot triggered")
+		return nil
+	}
+
+	if err := s.checkFormToken(req.FormToken, time.Now()); err != nil {
+		log.Info().Err(err).Str("ip", req.IPAddress).Msg("Contact form rejected")
+		return err
+	}
+
+	count, err := s.repo.CountMessagesFromIP(ctx, req.IPAddress, time.Now().Add(-time.Hour))
+	if err != nil {
+		log.Error().Err(err).Str("ip", req.IPAddress).Msg("Failed to count contact messages")
+		return fmt.Errorf("failed to count contact messages: %w", err)
+	}
+	if count >= s.maxPerHour {
+		log.Info().Str("ip", req.IPAddress).Int("count", count).Msg("Contact form rate limit reached")
+		return ErrContactRateLimited
+	}
+
+	message := &entity.ContactMessage{
+		Name:      strings.TrimSpace(req.Name),
+		Email:     strings.TrimSpace(req.Email),
+		Subject:   strings.TrimSpace(req.Subject),
+		Message:   strings.TrimSpace(req.Message),
+		IPAddress: req.IPAddress,
+		UserAgent: req.UserAgent,
+	}
+
+	replyTo := message.Email
+	email := &entity.OutboxEmail{
+		Recipient: s.staffEmail,
+		ReplyTo:   &replyTo,
+		Subject:   "Kontaktformulär: " + message.Subject,
+		Body: fmt.Sprintf(
+			"Nytt meddelande via kontaktformuläret\n\nNamn: %s\nE-post: %s\nÄmne: %s\n\n%s\n",
+			message.Name, message.Email, message.Subject, message.Message,
+		),
+	}
+
+	if err := s.repo.CreateContactMessage(ctx, message, email); err != nil {
+		log.Error().Err(err).Msg("Failed to create contact message")
+		return fmt.Errorf("failed to create contact message: %w", err)
+	}
+
+	return nil
+}
+
+// checkFormToken verifies the signature of a form token and that the form was
+// open long enough, but not too long, before it was submitted
+func (s *ContactService) checkFormToken(token string, at time.Time) error {
+	issuedAt, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(s.signFormToken(issuedAt))) {
+		return ErrInvalidFormToken
+	}
+
+	seconds, err := strconv.ParseInt(issuedAt, 10, 64)
+	if err != nil {
+		return ErrInvalidFormToken
+	}
+
+	age := at.Sub(time.Unix(seconds, 0))
+	if age > formTokenMaxAge {
+		return ErrInvalidFormToken
+	}
+	if age < s.minSubmitTime {
+		return ErrSubmittedTooQuickly
+	}
+
+	return nil
+}
+
+// signFormToken returns the signature of a form token issue time
+func (s *ContactService) signFormToken(issuedAt string) string {
+	mac := hmac.New(sha256.New, s.formSecret)
+	mac.Write([]byte(issuedAt))
+	return hex.EncodeToString(mac.Sum(nil))
+}
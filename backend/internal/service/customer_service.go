@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/svenskhalsovard/api/internal/entity"
+	"github.com/svenskhalsovard/api/internal/personnummer"
+)
+
+// Common customer errors
+var (
+	ErrCustomerNotFound = errors.New("customer not found")
+)
+
+// CustomerService provides business logic for customer accounts
+type CustomerService struct {
+	repo        CustomerRepository
+	bookingRepo BookingRepository
+	cipher      FieldCipher
+}
+
+// NewCustomerService creates a new CustomerService. cipher protects the
+// national IDs stored with customers.
+func NewCustomerService(repo CustomerRepository, bookingRepo BookingRepository, cipher FieldCipher) *CustomerService {
+	return &CustomerService{
+		repo:        repo,
+		bookingRepo: bookingRepo,
+		cipher:      cipher,
+	}
+}
+
+// UpdateProfile updates the contact details of a logged in customer. The email
+// address identifies the account and cannot be changed here. The stored
+// national ID is replaced only when nationalID is not empty.
+func (s *CustomerService) UpdateProfile(ctx context.Context, customerID int64, profile entity.Customer, nationalID string) (*entity.Customer, error) {
+	var number personnummer.Number
+	if nationalID != "" {
+		var err error
+		if number, err = parseNationalID(nationalID); err != nil {
+			return nil, err
+		}
+	}
+
+	customer, err := s.repo.GetCustomerByID(ctx, customerID)
+	if err != nil {
+		log.Error().Err(err).Int64("customerID", customerID).Msg("Failed to get customer")
+		return nil, fmt.Errorf("failed to get customer: %w", err)
+	}
+
+	if customer == nil {
+		return nil, ErrCustomerNotFound
+	}
+
+	customer.FirstName = strings.TrimSpace(profile.FirstName)
+	customer.LastName = strings.TrimSpace(profile.LastName)
+	customer.Phone = strings.TrimSpace(profile.Phone)
+	customer.StreetAddress = strings.TrimSpace(profile.StreetAddress)
+	customer.PostalCode = strings.TrimSpace(profile.PostalCode)
+	customer.City = strings.TrimSpace(profile.City)
+	customer.AdditionalInfo = strings.TrimSpace(profile.AdditionalInfo)
+
+	if !number.IsZero() {
+		if customer.NationalIDEncrypted, err = encryptNationalID(s.cipher, number); err != nil {
+			log.Error().Err(err).Int64("customerID", customerID).Msg("Failed to encrypt national ID")
+			return nil, err
+		}
+	}
+
+	if err := s.repo.UpdateCustomerProfile(ctx, customer); err != nil {
+		log.Error().Err(err).Int64("customerID", customerID).Msg("Failed to update customer profile")
+		return nil, fmt.Errorf("failed to update customer profile: %w", err)
+	}
+
+	return customer, nil
+}
+
+// GetBookingHistory retrieves the bookings of a customer, newest first
+func (s *CustomerService) GetBookingHistory(ctx context.Context, customerID int64) ([]entity.BookingWithItems, error) {
+	bookings, err := s.bookingRepo.GetBookingsByCustomerID(ctx, customerID)
+	if err != nil {
+		log.Error().Err(err).Int64("customerID", customerID).Msg("Failed to get booking history")
+		return nil, fmt.Errorf("failed to get booking history: %w", err)
+	}
+
+	return bookings, nil
+}
+
+// NationalID returns the decrypted national ID of a customer, or the zero
+// Number when none is stored
+func (s *CustomerService) NationalID(customer *entity.Customer) (personnummer.Number, error) {
+	number, err := decryptNationalID(s.cipher, customer.NationalIDEncrypted)
+	if err != nil {
+		log.Error().Err(err).Int64("customerID", customer.ID).Msg("Failed to read customer national ID")
+		return personnummer.Number{}, err
+	}
+	return number, nil
+}
+
+// ResolveCheckoutCustomer returns the customer a checkout is made for. A logged
+// in customer (customerID > 0) is used as is. Otherwise the customer with the
+// email in details is used, or created from details and nationalID when there
+// is none. The details never overwrite an existing customer's profile.
+func (s *CustomerService) ResolveCheckoutCustomer(ctx context.Context, customerID int64, details entity.Customer, nationalID personnummer.Number) (*entity.Customer, error) {
+	if customerID > 0 {
+		customer, err := s.repo.GetCustomerByID(ctx, customerID)
+		if err != nil {
+			log.Error().Err(err).Int64("customerID", customerID).Msg("Failed to get customer")
+			return nil, fmt.Errorf("failed to get customer: %w", err)
+		}
+		if customer == nil {
+			return nil, ErrCustomerNotFound
+		}
+		return customer, nil
+	}
+
+	details.ID = 0
+	details.Email = normalizeEmail(details.Email)
+
+	encrypted, err := encryptNationalID(s.cipher, nationalID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to encrypt national ID")
+		return nil, err
+	}
+	details.NationalIDEncrypted = encrypted
+
+	customer, err := s.repo.CreateCustomer(ctx, &details)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to resolve checkout customer")
+		return nil, fmt.Errorf("failed to resolve customer: %w", err)
+	}
+
+	return customer, nil
+}
@@ -0,0 +1,381 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// Common data export errors
+var (
+	ErrDataExportNotFound = errors.New("data export not found")
+	ErrDataExportExpired  = errors.New("data export has expired")
+)
+
+// dataExportReadme describes the files of a data export archive
+const dataExportReadme = `Personal data held by Svensk Hälsovård
+
+This archive contains the personal data we hold about you, as requested under
+Article 15 of the General Data Protection Regulation (GDPR). Every file is in
+JSON format.
+
+customer.json           Your customer account
+payments.json           Your payments with their items and the details given at checkout
+bookings.json           Your bookings with their items
+contact_messages.json   Messages sent to us through the contact form
+notifications.json      Emails we have sent you
+consents.json           The consents you have given and withdrawn, and your
+                        choices about checkout reminder emails
+`
+
+// DataExportRepository defines the interface for data export operations
+type DataExportRepository interface {
+	GetCustomerData(ctx context.Context, customer *entity.Customer) (*entity.CustomerData, error)
+	CreateDataExport(ctx context.Context, export *entity.DataExport) error
+	GetDataExportByTokenHash(ctx context.Context, tokenHash string) (*entity.DataExport, error)
+	MarkDataExportDownloaded(ctx context.Context, id int64) error
+	DeleteExpiredArchives(ctx context.Context, before time.Time) (int, error)
+}
+
+// DataExportService answers requests for access to personal data under GDPR
+// Article 15. An export is a zip archive of JSON files with everything stored
+// about a customer, kept encrypted until its download link expires.
+type DataExportService struct {
+	repo         DataExportRepository
+	customerRepo CustomerRepository
+	cipher       FieldCipher
+	downloadURL  string
+	ttl          time.Duration
+}
+
+// NewDataExportService creates a new DataExportService. Download links are
+// downloadURL followed by the token, and are valid for ttl.
+func NewDataExportService(repo DataExportRepository, customerRepo CustomerRepository, cipher FieldCipher, downloadURL string, ttl time.Duration) *DataExportService {
+	return &DataExportService{
+		repo:         repo,
+		customerRepo: customerRepo,
+		cipher:       cipher,
+		downloadURL:  downloadURL,
+		ttl:          ttl,
+	}
+}
+
+// DataExportLink is the time-limited link to download a data export
+type DataExportLink struct {
+	ExportID  int64
+	URL       string
+	ExpiresAt time.Time
+}
+
+// DataExportArchive is a downloaded data export
+type DataExportArchive struct {
+	FileName string
+	Data     []byte
+}
+
+// CreateExport builds the data export of a customer and returns its download
+// link. requestedBy records whether the customer or staff asked for it.
+func (s *DataExportService) CreateExport(ctx context.Context, customerID int64, requestedBy string) (*DataExportLink, error) {
+	customer, err := s.customerRepo.GetCustomerByID(ctx, customerID)
+	if err != nil {
+		log.Error().Err(err).Int64("customerID", customerID).Msg("Failed to get customer")
+		return nil, fmt.Errorf("failed to get customer: %w", err)
+	}
+
+	if customer == nil {
+		return nil, ErrCustomerNotFound
+	}
+
+	return s.createExport(ctx, customer, requestedBy)
+}
+
+// CreateExportByEmail builds the data export of the customer with the given
+// email and returns its download link
+func (s *DataExportService) CreateExportByEmail(ctx context.Context, email string, requestedBy string) (*DataExportLink, error) {
+	customer, err := s.customerRepo.GetCustomerByEmail(ctx, normalizeEmail(email))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get customer by email")
+		return nil, fmt.Errorf("failed to get customer: %w", err)
+	}
+
+	if customer == nil {
+		return nil, ErrCustomerNotFound
+	}
+
+	return s.createExport(ctx, customer, requestedBy)
+}
+
+// DownloadExport returns the archive of the data export with the given token
+func (s *DataExportService) DownloadExport(ctx context.Context, token string) (*DataExportArchive, error) {
+	export, err := s.repo.GetDataExportByTokenHash(ctx, hashSecret(token))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get data export")
+		return nil, fmt.Errorf("failed to get data export: %w", err)
+	}
+
+	if export == nil {
+		return nil, ErrDataExportNotFound
+	}
+
+	if time.Now().After(export.ExpiresAt) || export.ArchiveEncrypted == nil {
+		return nil, ErrDataExportExpired
+	}
+
+	encoded, err := s.cipher.Decrypt(*export.ArchiveEncrypted)
+	if err != nil {
+		log.Error().Err(err).Int64("exportID", export.ID).Msg("Failed to decrypt data export")
+		return nil, fmt.Errorf("failed to decrypt data export: %w", err)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode data export: %w", err)
+	}
+
+	if err := s.repo.MarkDataExportDownloaded(ctx, export.ID); err != nil {
+		// The download itself succeeded
+		log.Error().Err(err).Int64("exportID", export.ID).Msg("Failed to mark data export downloaded")
+	}
+
+	log.Info().Int64("exportID", export.ID).Int64("customerID", export.CustomerID).Msg("Data export downloaded")
+
+	return &DataExportArchive{
+		FileName: exportFileName(export),
+		Data:     data,
+	}, nil
+}
+
+// DeleteExpiredArchives removes the archives of expired data exports
+func (s *DataExportService) DeleteExpiredArchives(ctx context.Context) (int, error) {
+	deleted, err := s.repo.DeleteExpiredArchives(ctx, time.Now())
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to delete expired data exports")
+		return 0, fmt.Errorf("failed to delete expired data exports: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// Run removes expired archives every interval until the context is cancelled
+func (s *DataExportService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := s.DeleteExpiredArchives(ctx)
+			if err != nil {
+				continue
+			}
+			if deleted > 0 {
+				log.Info().Int("deleted", deleted).Msg("Deleted expired data exports")
+			}
+		}
+	}
+}
+
+// createExport builds, encrypts and stores the data export of a customer
+func (s *DataExportService) createExport(ctx context.Context, customer *entity.Customer, requestedBy string) (*DataExportLink, error) {
+	data, err := s.repo.GetCustomerData(ctx, customer)
+	if err != nil {
+		log.Error().Err(err).Int64("customerID", customer.ID).Msg("Failed to get customer data")
+		return nil, fmt.Errorf("failed to get customer data: %w", err)
+	}
+
+	archive, err := s.buildArchive(customer, data)
+	if err != nil {
+		log.Error().Err(err).Int64("customerID", customer.ID).Msg("Failed to build data export")
+		return nil, fmt.Errorf("failed to build data export: %w", err)
+	}
+
+	encrypted, err := s.cipher.Encrypt(base64.StdEncoding.EncodeToString(archive))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt data export: %w", err)
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate download token: %w", err)
+	}
+
+	export := &entity.DataExport{
+		CustomerID:       customer.ID,
+		TokenHash:        hashSecret(token),
+		RequestedBy:      requestedBy,
+		ArchiveEncrypted: &encrypted,
+		ExpiresAt:        time.Now().Add(s.ttl),
+	}
+
+	if err := s.repo.CreateDataExport(ctx, export); err != nil {
+		log.Error().Err(err).Int64("customerID", customer.ID).Msg("Failed to create data export")
+		return nil, fmt.Errorf("failed to create data export: %w", err)
+	}
+
+	log.Info().
+		Int64("exportID", export.ID).
+		Int64("customerID", customer.ID).
+		Str("requestedBy", requestedBy).
+		Msg("Data export created")
+
+	return &DataExportLink{
+		ExportID:  export.ID,
+		URL:       s.downloadURL + "/" + token,
+		ExpiresAt: export.ExpiresAt,
+	}, nil
+}
+
+// customerExport is the customer record in a data export, with the national ID
+// decrypted
+type customerExport struct {
+	entity.Customer
+	NationalID string `json:"nationalId,omitempty"`
+}
+
+// paymentExport is a payment in a data export
+type paymentExport struct {
+	entity.Payment
+	CustomerNationalID string               `json:"customerNationalId,omitempty"`
+	Items              []entity.PaymentItem `json:"items"`
+}
+
+// bookingExport is a booking in a data export
+type bookingExport struct {
+	entity.Booking
+	CustomerNationalID string               `json:"customerNationalId,omitempty"`
+	Items              []entity.BookingItem `json:"items"`
+}
+
+// reminderConsentExport is the answer to the checkout reminder question given
+// for a payment
+type reminderConsentExport struct {
+	PaymentID      int64     `json:"paymentId"`
+	OrderReference string    `json:"orderReference"`
+	Consented      bool      `json:"consented"`
+	GivenAt        time.Time `json:"givenAt"`
+}
+
+// consentsExport holds the consent related data in a data export
+type consentsExport struct {
+	Consents          []entity.CustomerConsent  `json:"consents"`
+	CheckoutReminders []reminderConsentExport   `json:"checkoutReminders"`
+	RemindersSent     []entity.CheckoutRecovery `json:"remindersSent"`
+	EmailOptOuts      []entity.EmailOptOut      `json:"emailOptOuts"`
+}
+
+// buildArchive writes the data of a customer to a zip archive
+func (s *DataExportService) buildArchive(customer *entity.Customer, data *entity.CustomerData) ([]byte, error) {
+	nationalID, err := decryptNationalID(s.cipher, customer.NationalIDEncrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	payments := make([]paymentExport, 0, len(data.Payments))
+	consents := consentsExport{
+		Consents:          data.Consents,
+		CheckoutReminders: make([]reminderConsentExport, 0, len(data.Payments)),
+		RemindersSent:     data.CheckoutRecoveries,
+		EmailOptOuts:      data.EmailOptOuts,
+	}
+	for _, payment := range data.Payments {
+		number, err := decryptNationalID(s.cipher, payment.Payment.NationalIDEncrypted)
+		if err != nil {
+			return nil, err
+		}
+
+		items := payment.Items
+		if items == nil {
+			items = []entity.PaymentItem{}
+		}
+
+		payments = append(payments, paymentExport{
+			Payment:            payment.Payment,
+			CustomerNationalID: number.Value(),
+			Items:              items,
+		})
+		consents.CheckoutReminders = append(consents.CheckoutReminders, reminderConsentExport{
+			PaymentID:      payment.Payment.ID,
+			OrderReference: payment.Payment.OrderReference,
+			Consented:      payment.Payment.RecoveryConsent,
+			GivenAt:        payment.Payment.CreatedAt,
+		})
+	}
+
+	bookings := make([]bookingExport, 0, len(data.Bookings))
+	for _, booking := range data.Bookings {
+		number, err := decryptNationalID(s.cipher, booking.Booking.NationalIDEncrypted)
+		if err != nil {
+			return nil, err
+		}
+
+		items := booking.Items
+		if items == nil {
+			items = []entity.BookingItem{}
+		}
+
+		bookings = append(bookings, bookingExport{
+			Booking:            booking.Booking,
+			CustomerNationalID: number.Value(),
+			Items:              items,
+		})
+	}
+
+	files := []struct {
+		name    string
+		content interface{}
+	}{
+		{"customer.json", customerExport{Customer: *customer, NationalID: nationalID.Value()}},
+		{"payments.json", payments},
+		{"bookings.json", bookings},
+		{"contact_messages.json", data.ContactMessages},
+		{"notifications.json", data.Notifications},
+		{"consents.json", consents},
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	readme, err := archive.Create("README.txt")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := readme.Write([]byte(dataExportReadme)); err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		content, err := json.MarshalIndent(file.content, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", file.name, err)
+		}
+
+		w, err := archive.Create(file.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(content); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// exportFileName returns the file name of a data export archive
+func exportFileName(export *entity.DataExport) string {
+	return fmt.Sprintf("personal-data-%d-%s.zip", export.CustomerID, export.CreatedAt.Format("20060102"))
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// retainedRecordBatchSize is how many retained records are pseudonymized at a time
+const retainedRecordBatchSize = 100
+
+// ErasureRepository defines the interface for erasure data operations
+type ErasureRepository interface {
+	GetPersonalRecords(ctx context.Context, customer *entity.Customer) ([]entity.PersonalRecord, []string, error)
+	EraseCustomer(ctx context.Context, customerID int64, emails []string, erase []entity.PersonalRecord, retain []entity.RetainedRecord) (bool, error)
+	GetExpiredRetainedRecords(ctx context.Context, before time.Time, limit int) ([]entity.RetainedRecord, error)
+	PseudonymizeRetainedRecords(ctx context.Context, records []entity.RetainedRecord) error
+}
+
+// ErasureService erases customers on request under GDPR Article 17. The
+// customer record is pseudonymized at once, while rows that the law requires
+// us to keep, such as payments under bokföringslagen, are kept as they are
+// until their retention ends and pseudonymized then.
+type ErasureService struct {
+	repo           ErasureRepository
+	customerRepo   CustomerRepository
+	retentionYears map[string]int
+}
+
+// NewErasureService creates a new ErasureService. retentionYears maps a table
+// to how many years its rows are kept after the end of the calendar year they
+// were created; tables that are not listed are not retained.
+func NewErasureService(repo ErasureRepository, customerRepo CustomerRepository, retentionYears map[string]int) *ErasureService {
+	return &ErasureService{
+		repo:           repo,
+		customerRepo:   customerRepo,
+		retentionYears: retentionYears,
+	}
+}
+
+// ErasureResult summarizes the erasure of a customer. RetainedUntil is when the
+// last retained row will be pseudonymized, and is zero when nothing is retained.
+type ErasureResult struct {
+	CustomerID    int64
+	Erased        int
+	Retained      int
+	RetainedUntil time.Time
+}
+
+// EraseCustomer erases the customer with the given ID
+func (s *ErasureService) EraseCustomer(ctx context.Context, customerID int64) (*ErasureResult, error) {
+	customer, err := s.customerRepo.GetCustomerByID(ctx, customerID)
+	if err != nil {
+		log.Error().Err(err).Int64("customerID", customerID).Msg("Failed to get customer")
+		return nil, fmt.Errorf("failed to get customer: %w", err)
+	}
+
+	if customer == nil {
+		return nil, ErrCustomerNotFound
+	}
+
+	return s.erase(ctx, customer)
+}
+
+// EraseCustomerByEmail erases the customer with the given email
+func (s *ErasureService) EraseCustomerByEmail(ctx context.Context, email string) (*ErasureResult, error) {
+	customer, err := s.customerRepo.GetCustomerByEmail(ctx, normalizeEmail(email))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get customer by email")
+		return nil, fmt.Errorf("failed to get customer: %w", err)
+	}
+
+	if customer == nil {
+		return nil, ErrCustomerNotFound
+	}
+
+	return s.erase(ctx, customer)
+}
+
+// PseudonymizeExpired pseudonymizes the retained rows of erased customers whose
+// retention has ended and returns how many there were
+func (s *ErasureService) PseudonymizeExpired(ctx context.Context) (int, error) {
+	total := 0
+
+	for {
+		records, err := s.repo.GetExpiredRetainedRecords(ctx, time.Now(), retainedRecordBatchSize)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to get expired retained records")
+			return total, fmt.Errorf("failed to get expired retained records: %w", err)
+		}
+
+		if len(records) == 0 {
+			return total, nil
+		}
+
+		if err := s.repo.PseudonymizeRetainedRecords(ctx, records); err != nil {
+			log.Error().Err(err).Msg("Failed to pseudonymize retained records")
+			return total, fmt.Errorf("failed to pseudonymize retained records: %w", err)
+		}
+
+		total += len(records)
+	}
+}
+
+// Run pseudonymizes expired retained rows every interval until the context is
+// cancelled
+func (s *ErasureService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			pseudonymized, err := s.PseudonymizeExpired(ctx)
+			if err != nil {
+				continue
+			}
+			if pseudonymized > 0 {
+				log.Info().Int("pseudonymized", pseudonymized).Msg("Pseudonymized retained records")
+			}
+		}
+	}
+}
+
+// erase applies the retention rules to the personal data of a customer and
+// erases it
+func (s *ErasureService) erase(ctx context.Context, customer *entity.Customer) (*ErasureResult, error) {
+	records, emails, err := s.repo.GetPersonalRecords(ctx, customer)
+	if err != nil {
+		log.Error().Err(err).Int64("customerID", customer.ID).Msg("Failed to get personal records")
+		return nil, fmt.Errorf("failed to get personal records: %w", err)
+	}
+
+	now := time.Now()
+	result := &ErasureResult{
+		CustomerID: customer.ID,
+	}
+
+	var erase []entity.PersonalRecord
+	var retain []entity.RetainedRecord
+	for _, record := range records {
+		retainUntil := s.retainUntil(record)
+		if !retainUntil.After(now) {
+			erase = append(erase, record)
+			continue
+		}
+
+		retain = append(retain, entity.RetainedRecord{
+			CustomerID:  customer.ID,
+			TableName:   record.TableName,
+			RecordID:    record.RecordID,
+			RetainUntil: retainUntil,
+		})
+		if retainUntil.After(result.RetainedUntil) {
+			result.RetainedUntil = retainUntil
+		}
+	}
+
+	erased, err := s.repo.EraseCustomer(ctx, customer.ID, emails, erase, retain)
+	if err != nil {
+		log.Error().Err(err).Int64("customerID", customer.ID).Msg("Failed to erase customer")
+		return nil, fmt.Errorf("failed to erase customer: %w", err)
+	}
+
+	if !erased {
+		return nil, ErrCustomerNotFound
+	}
+
+	result.Erased = len(erase)
+	result.Retained = len(retain)
+
+	log.Info().
+		Int64("customerID", customer.ID).
+		Int("erased", result.Erased).
+		Int("retained", result.Retained).
+		Msg("Customer erased")
+
+	return result, nil
+}
+
+// retainUntil returns when the retention of a row ends. Retention is counted
+// from the end of the calendar year the row was created, as bokföringslagen
+// does.
+func (s *ErasureService) retainUntil(record entity.PersonalRecord) time.Time {
+	years := s.retentionYears[record.TableName]
+	if years <= 0 {
+		return time.Time{}
+	}
+
+	return time.Date(record.CreatedAt.Year()+years+1, time.January, 1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// Gift card errors
+var (
+	ErrGiftCardNotFound      = errors.New("gift card not found")
+	ErrGiftCardNotValid      = errors.New("gift card is expired or has no balance")
+	ErrGiftCardNotApplicable = errors.New("gift cards cannot be used to buy gift cards")
+)
+
+// giftCardValidityMonths is how long a gift card can be redeemed after it is issued
+const giftCardValidityMonths = 24
+
+// giftCardCodeAlphabet leaves out characters that are easily confused when read aloud or typed
+const giftCardCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// GiftCardRepository defines the interface for gift card data operations
+type GiftCardRepository interface {
+	GetGiftCardByCode(ctx context.Context, code string) (*entity.GiftCard, error)
+	GetGiftCardsByPurchasePaymentID(ctx context.Context, paymentID int64) ([]entity.GiftCard, error)
+	GetGiftCardPaymentItems(ctx context.Context, paymentID int64) ([]entity.PaymentItem, error)
+	CreateGiftCards(ctx context.Context, tx *sqlx.Tx, paymentID int64, giftCards []entity.GiftCard, email *entity.OutboxEmail) ([]entity.GiftCard, error)
+	RedeemGiftCard(ctx context.Context, tx *sqlx.Tx, giftCardID int64, paymentID int64, amount float64) error
+	ReleaseGiftCard(ctx context.Context, paymentID int64) error
+}
+
+// GiftCardService provides business logic for gift cards
+type GiftCardService struct {
+	repo GiftCardRepository
+}
+
+// NewGiftCardService creates a new GiftCardService
+func NewGiftCardService(repo GiftCardRepository) *GiftCardService {
+	return &GiftCardService{
+		repo: repo,
+	}
+}
+
+// GetGiftCard retrieves a gift card by its code
+func (s *GiftCardService) GetGiftCard(ctx context.Context, code string) (*entity.GiftCard, error) {
+	giftCard, err := s.repo.GetGiftCardByCode(ctx, strings.TrimSpace(code))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get gift card")
+		return nil, fmt.Errorf("failed to get gift card: %w", err)
+	}
+
+	if giftCard == nil {
+		return nil, ErrGiftCardNotFound
+	}
+
+	return giftCard, nil
+}
+
+// ResolveRedemption checks that a gift card can be redeemed at the given time and
+// returns it with the part of the amount its balance covers
+func (s *GiftCardService) ResolveRedemption(ctx context.Context, code string, amount float64, at time.Time) (*entity.GiftCard, float64, error) {
+	giftCard, err := s.GetGiftCard(ctx, code)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if !giftCard.IsActive || giftCard.Balance <= 0 || (giftCard.ExpiresAt != nil && !at.Before(*giftCard.ExpiresAt)) {
+		return nil, 0, ErrGiftCardNotValid
+	}
+
+	return giftCard, roundToOre(math.Min(giftCard.Balance, amount)), nil
+}
+
+// IssueGiftCards issues one gift card for each gift card item bought with a payment
+// in tx and queues an email with the codes to the purchaser. Issuing again for the
+// same payment returns the cards already issued.
+func (s *GiftCardService) IssueGiftCards(ctx context.Context, tx *sqlx.Tx, payment *entity.Payment) ([]entity.GiftCard, error) {
+	paymentID := payment.ID
+	items, err := s.repo.GetGiftCardPaymentItems(ctx, paymentID)
+	if err != nil {
+		log.Error().Err(err).Int64("paymentID", paymentID).Msg("Failed to get gift card payment items")
+		return nil, fmt.Errorf("failed to get gift card payment items: %w", err)
+	}
+
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	issued, err := s.repo.GetGiftCardsByPurchasePaymentID(ctx, paymentID)
+	if err != nil {
+		log.Error().Err(err).Int64("paymentID", paymentID).Msg("Failed to get issued gift cards")
+		return nil, fmt.Errorf("failed to get issued gift cards: %w", err)
+	}
+
+	if len(issued) > 0 {
+		return issued, nil
+	}
+
+	expiresAt := time.Now().UTC().AddDate(0, giftCardValidityMonths, 0)
+	giftCards := make([]entity.GiftCard, 0, len(items))
+	for _, item := range items {
+		for i := 0; i < item.Quantity; i++ {
+			code, err := generateGiftCardCode()
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate gift card code: %w", err)
+			}
+
+			giftCards = append(giftCards, entity.GiftCard{
+				Code:          code,
+				InitialAmount: item.UnitPrice,
+				Currency:      entity.CurrencySEK,
+				ExpiresAt:     &expiresAt,
+				IsActive:      true,
+			})
+		}
+	}
+
+	issued, err = s.repo.CreateGiftCards(ctx, tx, paymentID, giftCards, buildGiftCardEmail(payment, giftCards))
+	if err != nil {
+		log.Error().Err(err).Int64("paymentID", paymentID).Msg("Failed to issue gift cards")
+		return nil, fmt.Errorf("failed to issue gift cards: %w", err)
+	}
+
+	return issued, nil
+}
+
+// Helper functions
+
+// buildGiftCardEmail composes the email that delivers the codes of issued gift cards
+func buildGiftCardEmail(payment *entity.Payment, giftCards []entity.GiftCard) *entity.OutboxEmail {
+	var body strings.Builder
+	fmt.Fprintf(&body, "Hej %s,\n\n", payment.CustomerSnapshot.FirstName)
+	body.WriteString("Tack för ditt köp! Här är dina presentkort från Svensk Hälsovård:\n\n")
+	for _, giftCard := range giftCards {
+		fmt.Fprintf(&body, "  %s  %.2f %s", giftCard.Code, giftCard.InitialAmount, giftCard.Currency)
+		if giftCard.ExpiresAt != nil {
+			fmt.Fprintf(&body, ", giltigt till %s", giftCard.ExpiresAt.Format("2006-01-02"))
+		}
+		body.WriteString("\n")
+	}
+	body.WriteString("\nAnge koden i kassan för att betala med presentkortet.\n\n")
+	body.WriteString("Med vänliga hälsningar,\nSvensk Hälsovård\n")
+
+	return &entity.OutboxEmail{
+		Recipient: payment.CustomerSnapshot.Email,
+		Subject:   "Dina presentkort",
+		Body:      body.String(),
+	}
+}
+
+// generateGiftCardCode generates a random code formatted as XXXX-XXXX-XXXX-XXXX
+func generateGiftCardCode() (string, error) {
+	var code strings.Builder
+	max := big.NewInt(int64(len(giftCardCodeAlphabet)))
+	for i := 0; i < 16; i++ {
+		if i > 0 && i%4 == 0 {
+			code.WriteByte('-')
+		}
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code.WriteByte(giftCardCodeAlphabet[n.Int64()])
+	}
+	return code.String(), nil
+}
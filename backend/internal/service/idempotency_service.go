@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// Common idempotency errors
+var (
+	ErrIdempotencyKeyReused     = errors.New("idempotency key was already used for a different request")
+	ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is still being processed")
+)
+
+// IdempotencyRepository defines the interface for idempotency key data operations
+type IdempotencyRepository interface {
+	ClaimKey(ctx context.Context, record *entity.IdempotencyKey, staleBefore time.Time) (bool, error)
+	GetKey(ctx context.Context, scope string, key string) (*entity.IdempotencyKey, error)
+	CompleteKey(ctx context.Context, id int64, statusCode int, contentType string, body []byte) error
+	ReleaseKey(ctx context.Context, id int64) error
+	DeleteExpiredKeys(ctx context.Context, before time.Time) (int, error)
+}
+
+// IdempotencyService makes retried requests safe. The first request with an
+// idempotency key claims it and stores its response; retries with the same key
+// and request get the stored response until the key expires after ttl. A claim
+// whose request has not completed after claimTimeout is taken to have been
+// abandoned, and the key can be claimed again.
+type IdempotencyService struct {
+	repo         IdempotencyRepository
+	ttl          time.Duration
+	claimTimeout time.Duration
+}
+
+// NewIdempotencyService creates a new IdempotencyService
+func NewIdempotencyService(repo IdempotencyRepository, ttl time.Duration, claimTimeout time.Duration) *IdempotencyService {
+	return &IdempotencyService{
+		repo:         repo,
+		ttl:          ttl,
+		claimTimeout: claimTimeout,
+	}
+}
+
+// Begin claims an idempotency key for a request. It reports true when the key
+// was claimed and the request should be processed, and false with the stored
+// response when the request was already processed. It returns
+// ErrIdempotencyKeyReused when the key was used for a request with another
+// fingerprint, and ErrIdempotencyKeyInProgress while the first request with the
+// key is still being processed.
+func (s *IdempotencyService) Begin(ctx context.Context, scope string, key string, fingerprint string) (*entity.IdempotencyKey, bool, error) {
+	now := time.Now()
+	record := &entity.IdempotencyKey{
+		Scope:       scope,
+		Key:         key,
+		Fingerprint: fingerprint,
+		ExpiresAt:   now.Add(s.ttl),
+	}
+
+	claimed, err := s.repo.ClaimKey(ctx, record, now.Add(-s.claimTimeout))
+	if err != nil {
+		log.Error().Err(err).Str("scope", scope).Msg("Failed to claim idempotency key")
+		return nil, false, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+	if claimed {
+		return record, true, nil
+	}
+
+	existing, err := s.repo.GetKey(ctx, scope, key)
+	if err != nil {
+		log.Error().Err(err).Str("scope", scope).Msg("Failed to get idempotency key")
+		return nil, false, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+
+	// The first request released the key in between, so the retry may try again
+	if existing == nil {
+		return nil, false, ErrIdempotencyKeyInProgress
+	}
+
+	if existing.Fingerprint != fingerprint {
+		return nil, false, ErrIdempotencyKeyReused
+	}
+
+	if existing.CompletedAt == nil {
+		return nil, false, ErrIdempotencyKeyInProgress
+	}
+
+	return existing, false, nil
+}
+
+// Complete stores the response of a request with a claimed idempotency key
+func (s *IdempotencyService) Complete(ctx context.Context, id int64, statusCode int, contentType string, body []byte) error {
+	if err := s.repo.CompleteKey(ctx, id, statusCode, contentType, body); err != nil {
+		log.Error().Err(err).Int64("idempotencyKeyID", id).Msg("Failed to store idempotent response")
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+
+	return nil
+}
+
+// Release gives up a claimed idempotency key whose request failed, so that a
+// retry is processed again
+func (s *IdempotencyService) Release(ctx context.Context, id int64) error {
+	if err := s.repo.ReleaseKey(ctx, id); err != nil {
+		log.Error().Err(err).Int64("idempotencyKeyID", id).Msg("Failed to release idempotency key")
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpiredKeys removes the idempotency keys and stored responses that have
+// expired. It returns the number of keys removed.
+func (s *IdempotencyService) DeleteExpiredKeys(ctx context.Context) (int, error) {
+	deleted, err := s.repo.DeleteExpiredKeys(ctx, time.Now())
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to delete expired idempotency keys")
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// Run removes expired idempotency keys every interval until the context is
+// cancelled
+func (s *IdempotencyService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := s.DeleteExpiredKeys(ctx)
+			if err != nil {
+				continue
+			}
+			if deleted > 0 {
+				log.Info().Int("deleted", deleted).Msg("Deleted expired idempotency keys")
+			}
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/svenskhalsovard/api/internal/personnummer"
+)
+
+// Common national ID errors
+var (
+	ErrInvalidNationalID  = errors.New("invalid personnummer or samordningsnummer")
+	ErrNationalIDRequired = errors.New("a personnummer is required for this payment method")
+	ErrCustomerUnderage   = errors.New("the customer must be at least 18 years old for this payment method")
+)
+
+// minimumPaymentAge is the age a customer must have reached to pay by invoice
+// or direct debit
+const minimumPaymentAge = 18
+
+// FieldCipher encrypts and decrypts single database fields
+type FieldCipher interface {
+	Encrypt(plaintext string) (string, error)
+	Decrypt(ciphertext string) (string, error)
+}
+
+// parseNationalID validates a personnummer or samordningsnummer given by a customer
+func parseNationalID(input string) (personnummer.Number, error) {
+	number, err := personnummer.Parse(input, time.Now())
+	if err != nil {
+		return personnummer.Number{}, fmt.Errorf("%w: %v", ErrInvalidNationalID, err)
+	}
+	return number, nil
+}
+
+// encryptNationalID encrypts a national ID for storage. The zero Number is
+// stored as NULL.
+func encryptNationalID(cipher FieldCipher, number personnummer.Number) (*string, error) {
+	if number.IsZero() {
+		return nil, nil
+	}
+
+	encrypted, err := cipher.Encrypt(number.Value())
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt national ID: %w", err)
+	}
+	return &encrypted, nil
+}
+
+// decryptNationalID decrypts a stored national ID. NULL is returned as the
+// zero Number.
+func decryptNationalID(cipher FieldCipher, encrypted *string) (personnummer.Number, error) {
+	if encrypted == nil || *encrypted == "" {
+		return personnummer.Number{}, nil
+	}
+
+	value, err := cipher.Decrypt(*encrypted)
+	if err != nil {
+		return personnummer.Number{}, fmt.Errorf("failed to decrypt national ID: %w", err)
+	}
+
+	number, err := personnummer.Parse(value, time.Now())
+	if err != nil {
+		return personnummer.Number{}, fmt.Errorf("stored national ID is invalid: %w", err)
+	}
+	return number, nil
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/svenskhalsovard/api/internal/entity"
+	"github.com/svenskhalsovard/api/internal/mailer"
+)
+
+// maxOutboxAttempts is how many times delivery of an outbox email is tried
+const maxOutboxAttempts = 5
+
+// outboxBatchSize is how many outbox emails are delivered per run
+const outboxBatchSize = 50
+
+// OutboxRepository defines the interface for email outbox data operations
+type OutboxRepository interface {
+	EnqueueEmail(ctx context.Context, email *entity.OutboxEmail) error
+	GetPendingEmails(ctx context.Context, limit int) ([]entity.OutboxEmail, error)
+	MarkEmailSent(ctx context.Context, id int64) error
+	MarkEmailFailed(ctx context.Context, id int64, errorMessage string, retryAt *time.Time) error
+}
+
+// OutboxService delivers the emails queued in the outbox
+type OutboxService struct {
+	repo   OutboxRepository
+	mailer Mailer
+}
+
+// NewOutboxService creates a new OutboxService
+func NewOutboxService(repo OutboxRepository, mailer Mailer) *OutboxService {
+	return &OutboxService{
+		repo:   repo,
+		mailer: mailer,
+	}
+}
+
+// DeliverPending sends the emails that are due. Failed emails are retried with
+// a growing delay until maxOutboxAttempts is reached. It returns the number of
+// emails sent.
+func (s *OutboxService) DeliverPending(ctx context.Context) (int, error) {
+	emails, err := s.repo.GetPendingEmails(ctx, outboxBatchSize)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get pending outbox emails")
+		return 0, fmt.Errorf("failed to get pending emails: %w", err)
+	}
+
+	sent := 0
+	for _, email := range emails {
+		if err := ctx.Err(); err != nil {
+			return sent, err
+		}
+
+		message := mailer.Message{
+			To:      email.Recipient,
+			Subject: email.Subject,
+			Body:    email.Body,
+		}
+		if email.ReplyTo != nil {
+			message.ReplyTo = *email.ReplyTo
+		}
+
+		if err := s.mailer.Send(ctx, message); err != nil {
+			var retryAt *time.Time
+			if attempts := email.Attempts + 1; attempts < maxOutboxAttempts {
+				next := time.Now().Add(time.Duration(attempts*attempts) * time.Minute)
+				retryAt = &next
+			}
+
+			log.Error().Err(err).Int64("outboxEmailID", email.ID).Int("attempts", email.Attempts+1).Msg("Failed to deliver outbox email")
+			if err := s.repo.MarkEmailFailed(ctx, email.ID, err.Error(), retryAt); err != nil {
+				log.Error().Err(err).Int64("outboxEmailID", email.ID).Msg("Failed to record outbox email failure")
+			}
+			continue
+		}
+
+		if err := s.repo.MarkEmailSent(ctx, email.ID); err != nil {
+			log.Error().Err(err).Int64("outboxEmailID", email.ID).Msg("Failed to mark outbox email sent")
+			continue
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+// Run delivers pending emails every interval until the context is cancelled
+func (s *OutboxService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.DeliverPending(ctx); err != nil {
+				log.Error().Err(err).Msg("Failed to deliver outbox emails")
+			}
+		}
+	}
+}
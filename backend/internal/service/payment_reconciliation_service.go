@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// PaymentReconciliationService settles payments whose checkout did not complete.
+// A payment can be taken by Svea while its booking fails to be created, or while
+// the payment itself fails to be recorded as successful. Such payments are
+// verified with Svea and booked, so no customer pays without a booking. Payments
+// abandoned before being paid are cancelled, releasing the gift card balance
+// they hold.
+type PaymentReconciliationService struct {
+	checkoutService *CheckoutService
+	minAge          time.Duration
+	maxAge          time.Duration
+}
+
+// NewPaymentReconciliationService creates a new PaymentReconciliationService.
+// Payments are reconciled once they have not changed for minAge, unless they
+// last changed more than maxAge ago, in which case unpaid payments expire.
+func NewPaymentReconciliationService(
+	checkoutService *CheckoutService,
+	minAge time.Duration,
+	maxAge time.Duration,
+) *PaymentReconciliationService {
+	return &PaymentReconciliationService{
+		checkoutService: checkoutService,
+		minAge:          minAge,
+		maxAge:          maxAge,
+	}
+}
+
+// ReconcilePayments books the successful payments without a booking and
+// verifies the payments pending at Svea, booking those that were paid. It
+// returns the number of payments settled.
+func (s *PaymentReconciliationService) ReconcilePayments(ctx context.Context) (int, error) {
+	now := time.Now()
+	payments, err := s.checkoutService.paymentService.repo.FindUnsettledPayments(ctx, now.Add(-s.maxAge), now.Add(-s.minAge))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to find unsettled payments")
+		return 0, fmt.Errorf("failed to find unsettled payments: %w", err)
+	}
+
+	settled := 0
+
+	for i := range payments {
+		if err := ctx.Err(); err != nil {
+			return settled, err
+		}
+
+		ok, err := s.reconcilePayment(ctx, &payments[i])
+		if err != nil {
+			log.Error().Err(err).Int64("paymentID", payments[i].ID).Msg("Failed to reconcile payment")
+			continue
+		}
+		if ok {
+			settled++
+		}
+	}
+
+	return settled, nil
+}
+
+// ExpirePayments cancels the initiated and pending payments that have not
+// changed for maxAge. Payments with a Svea order are verified first, and those
+// that were paid are settled instead. It returns the number of payments expired.
+func (s *PaymentReconciliationService) ExpirePayments(ctx context.Context) (int, error) {
+	payments, err := s.checkoutService.paymentService.repo.FindExpiredPayments(ctx, time.Now().Add(-s.maxAge))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to find expired payments")
+		return 0, fmt.Errorf("failed to find expired payments: %w", err)
+	}
+
+	expired := 0
+
+	for i := range payments {
+		if err := ctx.Err(); err != nil {
+			return expired, err
+		}
+
+		ok, err := s.expirePayment(ctx, &payments[i])
+		if err != nil {
+			log.Error().Err(err).Int64("paymentID", payments[i].ID).Msg("Failed to expire payment")
+			continue
+		}
+		if ok {
+			expired++
+		}
+	}
+
+	return expired, nil
+}
+
+// Run reconciles and expires payments every interval until the context is cancelled
+func (s *PaymentReconciliationService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			settled, err := s.ReconcilePayments(ctx)
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to reconcile payments")
+			} else if settled > 0 {
+				log.Info().Int("settled", settled).Msg("Reconciled payments")
+			}
+
+			expired, err := s.ExpirePayments(ctx)
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to expire payments")
+			} else if expired > 0 {
+				log.Info().Int("expired", expired).Msg("Expired payments")
+			}
+		}
+	}
+}
+
+// reconcilePayment settles a payment, verifying it with Svea first when it is
+// pending. It returns false when the payment has not been paid.
+func (s *PaymentReconciliationService) reconcilePayment(ctx context.Context, payment *entity.Payment) (bool, error) {
+	if payment.Status == entity.PaymentStatusPending {
+		verified, err := s.checkoutService.paymentService.VerifyPayment(ctx, payment.ID)
+		if err != nil {
+			return false, err
+		}
+		if verified.Status != entity.PaymentStatusSuccess {
+			return false, nil
+		}
+		payment = verified
+	}
+
+	booking, err := s.checkoutService.settlePayment(ctx, payment)
+	if err != nil {
+		return false, err
+	}
+
+	log.Info().Int64("paymentID", payment.ID).Str("bookingNumber", booking.BookingNumber).Msg("Settled unsettled payment")
+	return true, nil
+}
+
+// expirePayment cancels an abandoned payment. It returns false when the payment
+// turned out to be paid, or was changed since it was found.
+func (s *PaymentReconciliationService) expirePayment(ctx context.Context, payment *entity.Payment) (bool, error) {
+	paymentService := s.checkoutService.paymentService
+
+	if payment.ExternalPaymentID != "" {
+		verified, err := paymentService.VerifyPayment(ctx, payment.ID)
+		if err != nil {
+			return false, err
+		}
+
+		switch verified.Status {
+		case entity.PaymentStatusSuccess:
+			if _, err := s.checkoutService.settlePayment(ctx, verified); err != nil {
+				return false, err
+			}
+			return false, nil
+		case entity.PaymentStatusFailed, entity.PaymentStatusCancelled:
+			// Closed by verification, which released the gift card amount
+			return true, nil
+		}
+
+		// Verification may have moved the payment from initiated to pending
+		payment, err = paymentService.getPayment(ctx, payment.ID)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if err := paymentService.CancelPayment(ctx, payment, "Payment expired"); err != nil {
+		if errors.Is(err, entity.ErrPaymentVersionConflict) || errors.Is(err, ErrInvalidPaymentStatus) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
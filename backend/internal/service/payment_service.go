@@ -2,15 +2,27 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"strconv"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/rs/zerolog/log"
 	"github.com/svenskhalsovard/api/internal/entity"
+	"github.com/svenskhalsovard/api/internal/personnummer"
 	"github.com/svenskhalsovard/api/internal/svea"
 )
 
+// Common payment errors
+var (
+	ErrPaymentNotFound      = errors.New("payment not found")
+	ErrInvalidPaymentStatus = errors.New("payment has the wrong status for this operation")
+	ErrPaymentFailed        = errors.New("payment failed")
+	ErrPaymentNotCompleted  = errors.New("payment was taken but could not be completed")
+)
+
 // PaymentRepository defines the interface for payment data operations
 type PaymentRepository interface {
 	CreatePayment(ctx context.Context, tx *sqlx.Tx, payment *entity.Payment, items []entity.PaymentItem) error
@@ -18,30 +30,49 @@ type PaymentRepository interface {
 	GetPaymentByExternalID(ctx context.Context, externalID string) (*entity.Payment, error)
 	GetPaymentByOrderReference(ctx context.Context, orderReference string) (*entity.Payment, error)
 	GetPaymentWithItems(ctx context.Context, id int64) (*entity.PaymentWithItems, error)
-	UpdatePaymentStatus(ctx context.Context, id int64, status string, errorMessage string) error
+	UpdatePaymentStatus(ctx context.Context, id int64, expectedVersion int, status string, errorMessage string) error
+	UpdatePaymentStatusTx(ctx context.Context, tx *sqlx.Tx, id int64, expectedVersion int, status string, errorMessage string) error
 	UpdatePaymentExternalID(ctx context.Context, id int64, externalID string) error
 	FindIncompletePayments(ctx context.Context, maxAge string) ([]entity.Payment, error)
+	FindUnsettledPayments(ctx context.Context, since time.Time, before time.Time) ([]entity.Payment, error)
+	FindExpiredPayments(ctx context.Context, before time.Time) ([]entity.Payment, error)
 	Transaction(fn func(*sqlx.Tx) error) error
 }
 
+// PaymentEventRepository defines the interface for recording payment events
+type PaymentEventRepository interface {
+	CreatePaymentEvent(ctx context.Context, paymentID int64, eventType string, details map[string]string) error
+}
+
 // SveaClient defines the interface for Svea Ekonomi API client
 type SveaClient interface {
 	CreateOrder(ctx context.Context, order *svea.OrderRequest) (*svea.OrderResponse, error)
 	GetOrder(ctx context.Context, orderID string) (*svea.Order, error)
 	FinalizePayment(ctx context.Context, orderID string, paymentMethod string) (*svea.PaymentResponse, error)
+	CancelOrder(ctx context.Context, orderID string) error
 }
 
 // PaymentService provides business logic for payments
 type PaymentService struct {
-	repo       PaymentRepository
-	sveaClient SveaClient
+	repo         PaymentRepository
+	giftCardRepo GiftCardRepository
+	promoRepo    PromoCodeRepository
+	eventRepo    PaymentEventRepository
+	sveaClient   SveaClient
+	cipher       FieldCipher
 }
 
-// NewPaymentService creates a new PaymentService
-func NewPaymentService(repo PaymentRepository, sveaClient SveaClient) *PaymentService {
+// NewPaymentService creates a new PaymentService. cipher protects the national
+// IDs stored with payments, and what happens to payments is recorded with
+// eventRepo.
+func NewPaymentService(repo PaymentRepository, giftCardRepo GiftCardRepository, promoRepo PromoCodeRepository, eventRepo PaymentEventRepository, sveaClient SveaClient, cipher FieldCipher) *PaymentService {
 	return &PaymentService{
-		repo:       repo,
-		sveaClient: sveaClient,
+		repo:         repo,
+		giftCardRepo: giftCardRepo,
+		promoRepo:    promoRepo,
+		eventRepo:    eventRepo,
+		sveaClient:   sveaClient,
+		cipher:       cipher,
 	}
 }
 
@@ -50,21 +81,55 @@ func (s *PaymentService) InitiatePayment(ctx context.Context, customer *entity.C
 	// Generate unique order reference
 	orderReference := fmt.Sprintf("ORD-%d", time.Now().UnixNano())
 
-	// Create payment record in database
+	// Create payment record in database, keeping the customer details given at
+	// checkout with the payment
 	payment := &entity.Payment{
-		CustomerID:      customer.ID,
-		Amount:          options.TotalAmount,
-		Currency:        entity.CurrencySEK,
-		Status:          entity.PaymentStatusInitiated,
-		OrderReference:  orderReference,
-		TransactionType: options.TransactionType,
+		CustomerID:       customer.ID,
+		CustomerSnapshot: customer.Snapshot(),
+		Amount:           options.TotalAmount,
+		Currency:         entity.CurrencySEK,
+		Status:           entity.PaymentStatusInitiated,
+		OrderReference:   orderReference,
+		TransactionType:  options.TransactionType,
+		PromoCodeID:      options.PromoCodeID,
+		DiscountAmount:   options.DiscountAmount,
+		GiftCardID:       options.GiftCardID,
+		GiftCardAmount:   options.GiftCardAmount,
+		RecoveryConsent:  options.RecoveryConsent,
 	}
 
-	// Start database transaction
-	err := s.repo.Transaction(func(tx *sqlx.Tx) error {
+	nationalID, err := encryptNationalID(s.cipher, options.NationalID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to encrypt national ID")
+		return nil, err
+	}
+	payment.NationalIDEncrypted = nationalID
+
+	// Start database transaction, reserving the promo code use and drawing the
+	// gift card amount with the payment
+	err = s.repo.Transaction(func(tx *sqlx.Tx) error {
 		if err := s.repo.CreatePayment(ctx, tx, payment, items); err != nil {
 			return err
 		}
+		if payment.PromoCodeID != nil {
+			reserved, err := s.promoRepo.ReservePromoCode(ctx, tx, &entity.PromoCodeRedemption{
+				PromoCodeID:    *payment.PromoCodeID,
+				PaymentID:      payment.ID,
+				CustomerID:     payment.CustomerID,
+				DiscountAmount: payment.DiscountAmount,
+			}, customer.Email)
+			if err != nil {
+				return err
+			}
+			if !reserved {
+				return ErrPromoCodeUsageLimit
+			}
+		}
+		if payment.GiftCardID != nil && payment.GiftCardAmount > 0 {
+			if err := s.giftCardRepo.RedeemGiftCard(ctx, tx, *payment.GiftCardID, payment.ID, payment.GiftCardAmount); err != nil {
+				return err
+			}
+		}
 		return nil
 	})
 
@@ -73,6 +138,12 @@ func (s *PaymentService) InitiatePayment(ctx context.Context, customer *entity.C
 		return nil, fmt.Errorf("failed to initiate payment: %w", err)
 	}
 
+	s.recordEvent(ctx, payment.ID, entity.PaymentEventCreated, map[string]string{
+		"orderReference": payment.OrderReference,
+		"amount":         strconv.FormatFloat(payment.Amount, 'f', 2, 64),
+		"currency":       payment.Currency,
+	})
+
 	return payment, nil
 }
 
@@ -86,7 +157,15 @@ func (s *PaymentService) CreateSveaOrder(ctx context.Context, paymentID int64) (
 	}
 
 	if paymentWithItems == nil {
-		return nil, fmt.Errorf("payment not found")
+		return nil, ErrPaymentNotFound
+	}
+
+	// Svea uses the personnummer for its credit check when a payment method
+	// that needs it is chosen
+	nationalID, err := decryptNationalID(s.cipher, paymentWithItems.Payment.NationalIDEncrypted)
+	if err != nil {
+		log.Error().Err(err).Int64("paymentID", paymentID).Msg("Failed to read national ID")
+		return nil, fmt.Errorf("failed to read national ID: %w", err)
 	}
 
 	// Prepare Svea order request
@@ -106,6 +185,7 @@ func (s *PaymentService) CreateSveaOrder(ctx context.Context, paymentID int64) (
 				City:          paymentWithItems.Customer.City,
 				CountryCode:   "SE",
 			},
+			NationalId: nationalID.Value(),
 		},
 		Items: make([]svea.OrderItem, 0, len(paymentWithItems.Items)),
 	}
@@ -116,8 +196,21 @@ func (s *PaymentService) CreateSveaOrder(ctx context.Context, paymentID int64) (
 			ArticleNumber: fmt.Sprintf("SRV-%d", item.ServiceID),
 			Name:          item.ServiceName,
 			Quantity:      item.Quantity,
-			UnitPrice:     int(item.UnitPrice * 100), // Convert to cents/öre
-			VatPercent:    25,                       // Assuming 25% VAT
+			UnitPrice:     int(math.Round(item.UnitPrice * 100)), // Convert to cents/öre
+			VatPercent:    item.VatPercent,
+			Unit:          "st",
+			Discount:      int(math.Round(item.DiscountAmount * 100)), // Convert to cents/öre
+		})
+	}
+
+	// Deduct the gift card amount so only the remainder is charged
+	if paymentWithItems.Payment.GiftCardAmount > 0 {
+		orderRequest.Items = append(orderRequest.Items, svea.OrderItem{
+			ArticleNumber: "GIFTCARD",
+			Name:          "Presentkort",
+			Quantity:      1,
+			UnitPrice:     -int(math.Round(paymentWithItems.Payment.GiftCardAmount * 100)),
+			VatPercent:    0, // Gift cards are multi-purpose vouchers without VAT
 			Unit:          "st",
 		})
 	}
@@ -125,10 +218,14 @@ func (s *PaymentService) CreateSveaOrder(ctx context.Context, paymentID int64) (
 	// Create order in Svea
 	orderResponse, err := s.sveaClient.CreateOrder(ctx, orderRequest)
 	if err != nil {
+		// Svea may echo the request, so mask the personnummer before the
+		// error is logged or stored
+		message := personnummer.Mask(err.Error())
+
 		// Update payment status to failed
-		_ = s.repo.UpdatePaymentStatus(ctx, paymentID, entity.PaymentStatusFailed, err.Error())
-		log.Error().Err(err).Int64("paymentID", paymentID).Msg("Failed to create Svea order")
-		return nil, fmt.Errorf("failed to create Svea order: %w", err)
+		s.failPayment(ctx, paymentID, paymentWithItems.Payment.Version, message)
+		log.Error().Str("error", message).Int64("paymentID", paymentID).Msg("Failed to create Svea order")
+		return nil, fmt.Errorf("failed to create Svea order: %s", message)
 	}
 
 	// Update payment with external ID
@@ -137,8 +234,12 @@ func (s *PaymentService) CreateSveaOrder(ctx context.Context, paymentID int64) (
 		log.Error().Err(err).Int64("paymentID", paymentID).Str("orderID", orderResponse.OrderID).Msg("Failed to update payment with external ID")
 	}
 
+	s.recordEvent(ctx, paymentID, entity.PaymentEventSveaOrderCreated, map[string]string{
+		"sveaOrderId": orderResponse.OrderID,
+	})
+
 	// Update payment status to pending
-	err = s.repo.UpdatePaymentStatus(ctx, paymentID, entity.PaymentStatusPending, "")
+	err = s.repo.UpdatePaymentStatus(ctx, paymentID, paymentWithItems.Payment.Version, entity.PaymentStatusPending, "")
 	if err != nil {
 		log.Error().Err(err).Int64("paymentID", paymentID).Msg("Failed to update payment status")
 	}
@@ -146,8 +247,65 @@ func (s *PaymentService) CreateSveaOrder(ctx context.Context, paymentID int64) (
 	return orderResponse, nil
 }
 
-// ProcessPayment processes a payment with Svea Ekonomi
-func (s *PaymentService) ProcessPayment(ctx context.Context, paymentID int64, paymentMethod string) error {
+// ResumeSveaOrder returns the Svea order of an unfinished payment so the customer
+// can complete it. The existing order is reused while Svea still accepts it.
+// A new order is only created from the payment and its items once Svea no
+// longer has the old one or it can no longer be paid, and the old order is
+// cancelled first. Other errors from Svea are returned, so the old order is
+// not replaced while it may still be paid.
+func (s *PaymentService) ResumeSveaOrder(ctx context.Context, paymentID int64) (*svea.OrderResponse, error) {
+	payment, err := s.repo.GetPaymentByID(ctx, paymentID)
+	if err != nil {
+		log.Error().Err(err).Int64("paymentID", paymentID).Msg("Failed to get payment")
+		return nil, fmt.Errorf("failed to get payment: %w", err)
+	}
+
+	if payment == nil {
+		return nil, ErrPaymentNotFound
+	}
+
+	if payment.Status != entity.PaymentStatusInitiated && payment.Status != entity.PaymentStatusPending {
+		return nil, fmt.Errorf("%w: cannot resume a %s payment", ErrInvalidPaymentStatus, payment.Status)
+	}
+
+	s.recordEvent(ctx, paymentID, entity.PaymentEventRetried, nil)
+
+	if payment.ExternalPaymentID != "" {
+		order, err := s.sveaClient.GetOrder(ctx, payment.ExternalPaymentID)
+		if err != nil && !errors.Is(err, svea.ErrOrderNotFound) {
+			log.Error().Err(err).Int64("paymentID", paymentID).Str("externalID", payment.ExternalPaymentID).Msg("Failed to get order from Svea")
+			return nil, fmt.Errorf("failed to get Svea order: %w", err)
+		}
+
+		if order != nil {
+			switch {
+			case order.Status == svea.OrderStatusCreated && order.CheckoutUI.Snippet != "":
+				return &svea.OrderResponse{
+					OrderID:    order.ID,
+					Status:     order.Status,
+					CheckoutUI: order.CheckoutUI,
+				}, nil
+			case order.Status == svea.OrderStatusPending || order.Status == svea.OrderStatusCompleted:
+				// The customer may already be paying, which reconciliation
+				// picks up, so a second order could charge them twice
+				return nil, fmt.Errorf("%w: the Svea order is %s", ErrInvalidPaymentStatus, order.Status)
+			case order.Status != svea.OrderStatusCancelled:
+				// The old order is cancelled before it is replaced, so it
+				// cannot be paid as well
+				if err := s.sveaClient.CancelOrder(ctx, payment.ExternalPaymentID); err != nil {
+					log.Error().Err(err).Int64("paymentID", paymentID).Str("externalID", payment.ExternalPaymentID).Msg("Failed to cancel Svea order")
+					return nil, fmt.Errorf("failed to cancel Svea order: %w", err)
+				}
+			}
+		}
+	}
+
+	return s.CreateSveaOrder(ctx, paymentID)
+}
+
+// ProcessPayment processes a payment with Svea Ekonomi. complete, if given, runs
+// in the transaction that marks the payment successful; see completePayment.
+func (s *PaymentService) ProcessPayment(ctx context.Context, paymentID int64, paymentMethod string, complete func(tx *sqlx.Tx) error) error {
 	// Get payment data
 	payment, err := s.repo.GetPaymentByID(ctx, paymentID)
 	if err != nil {
@@ -156,36 +314,51 @@ func (s *PaymentService) ProcessPayment(ctx context.Context, paymentID int64, pa
 	}
 
 	if payment == nil {
-		return fmt.Errorf("payment not found")
+		return ErrPaymentNotFound
 	}
 
 	if payment.Status != entity.PaymentStatusPending {
-		return fmt.Errorf("payment cannot be processed (status: %s)", payment.Status)
+		return fmt.Errorf("%w: cannot process a %s payment", ErrInvalidPaymentStatus, payment.Status)
 	}
 
 	if payment.ExternalPaymentID == "" {
 		return fmt.Errorf("payment has no external ID")
 	}
 
+	// The personnummer was sent with the order; payment methods that need it
+	// can only be chosen when it was given
+	if svea.RequiresNationalID(paymentMethod) {
+		number, err := decryptNationalID(s.cipher, payment.NationalIDEncrypted)
+		if err != nil {
+			log.Error().Err(err).Int64("paymentID", paymentID).Msg("Failed to read national ID")
+			return fmt.Errorf("failed to read national ID: %w", err)
+		}
+		if number.IsZero() {
+			return ErrNationalIDRequired
+		}
+		if number.Age(time.Now()) < minimumPaymentAge {
+			return ErrCustomerUnderage
+		}
+	}
+
+	s.recordEvent(ctx, paymentID, entity.PaymentEventMethodChosen, map[string]string{
+		"paymentMethod": paymentMethod,
+	})
+
 	// Finalize payment in Svea
 	_, err = s.sveaClient.FinalizePayment(ctx, payment.ExternalPaymentID, paymentMethod)
 	if err != nil {
-		// Update payment status to failed
-		_ = s.repo.UpdatePaymentStatus(ctx, paymentID, entity.PaymentStatusFailed, err.Error())
-		log.Error().Err(err).Int64("paymentID", paymentID).Msg("Failed to process payment")
-		return fmt.Errorf("failed to process payment: %w", err)
-	}
+		// Svea may echo the request, so mask the personnummer before the
+		// error is logged or stored
+		message := personnummer.Mask(err.Error())
 
-	// Update payment status and method
-	payment.Status = entity.PaymentStatusSuccess
-	payment.PaymentMethod = paymentMethod
-	err = s.repo.UpdatePaymentStatus(ctx, paymentID, payment.Status, "")
-	if err != nil {
-		log.Error().Err(err).Int64("paymentID", paymentID).Msg("Failed to update payment status")
-		return fmt.Errorf("payment processed but failed to update status: %w", err)
+		// Update payment status to failed
+		s.failPayment(ctx, paymentID, payment.Version, message)
+		log.Error().Str("error", message).Int64("paymentID", paymentID).Msg("Failed to process payment")
+		return fmt.Errorf("%w: %s", ErrPaymentFailed, message)
 	}
 
-	return nil
+	return s.completePayment(ctx, payment, paymentMethod, complete)
 }
 
 // VerifyPayment checks the status of a payment with Svea Ekonomi
@@ -198,11 +371,14 @@ func (s *PaymentService) VerifyPayment(ctx context.Context, paymentID int64) (*e
 	}
 
 	if payment == nil {
-		return nil, fmt.Errorf("payment not found")
+		return nil, ErrPaymentNotFound
 	}
 
 	// If payment is already in a final state, return it
-	if payment.Status == entity.PaymentStatusSuccess || payment.Status == entity.PaymentStatusFailed {
+	if payment.Status == entity.PaymentStatusSuccess ||
+		payment.Status == entity.PaymentStatusFailed ||
+		payment.Status == entity.PaymentStatusCancelled ||
+		payment.Status == entity.PaymentStatusRefunded {
 		return payment, nil
 	}
 
@@ -220,25 +396,236 @@ func (s *PaymentService) VerifyPayment(ctx context.Context, paymentID int64) (*e
 
 	// Map Svea order status to our payment status
 	newStatus := s.mapSveaOrderStatus(order.Status)
-	if newStatus != payment.Status {
+	if newStatus == entity.PaymentStatusFailed || newStatus == entity.PaymentStatusCancelled {
+		if err := s.closePayment(ctx, paymentID, payment.Version, newStatus, fmt.Sprintf("Svea order status %s", order.Status)); err != nil {
+			log.Error().Err(err).Int64("paymentID", paymentID).Str("status", newStatus).Msg("Failed to close payment")
+			return payment, nil
+		}
+		payment.Status = newStatus
+	} else if newStatus != payment.Status {
 		// Update payment status
-		err = s.repo.UpdatePaymentStatus(ctx, paymentID, newStatus, "")
+		err = s.repo.UpdatePaymentStatus(ctx, paymentID, payment.Version, newStatus, "")
+		if errors.Is(err, entity.ErrPaymentVersionConflict) || errors.Is(err, entity.ErrInvalidPaymentTransition) {
+			// Another request moved the payment first, so return its status
+			log.Warn().Err(err).Int64("paymentID", paymentID).Str("status", newStatus).Msg("Payment status changed during verification")
+			return s.getPayment(ctx, paymentID)
+		}
 		if err != nil {
 			log.Error().Err(err).Int64("paymentID", paymentID).Str("status", newStatus).Msg("Failed to update payment status")
 			return payment, nil // Return current payment info, don't fail on status update error
 		}
+
+		eventType := entity.PaymentEventStatusChanged
+		if newStatus == entity.PaymentStatusSuccess {
+			eventType = entity.PaymentEventSucceeded
+		}
+		s.recordEvent(ctx, paymentID, eventType, map[string]string{
+			"status":          newStatus,
+			"sveaOrderStatus": order.Status,
+		})
+
 		payment.Status = newStatus
 	}
 
 	return payment, nil
 }
 
+// CancelPayment cancels a payment that was abandoned before it was paid. Its
+// Svea order, if any, is cancelled first so it can no longer be paid, and the
+// gift card amount the payment held is released.
+func (s *PaymentService) CancelPayment(ctx context.Context, payment *entity.Payment, reason string) error {
+	if payment.Status != entity.PaymentStatusInitiated && payment.Status != entity.PaymentStatusPending {
+		return fmt.Errorf("%w: cannot cancel a %s payment", ErrInvalidPaymentStatus, payment.Status)
+	}
+
+	if payment.ExternalPaymentID != "" {
+		if err := s.sveaClient.CancelOrder(ctx, payment.ExternalPaymentID); err != nil {
+			log.Error().Err(err).Int64("paymentID", payment.ID).Str("externalID", payment.ExternalPaymentID).Msg("Failed to cancel Svea order")
+			return fmt.Errorf("failed to cancel Svea order: %w", err)
+		}
+	}
+
+	if err := s.closePayment(ctx, payment.ID, payment.Version, entity.PaymentStatusCancelled, reason); err != nil {
+		log.Error().Err(err).Int64("paymentID", payment.ID).Msg("Failed to cancel payment")
+		return fmt.Errorf("failed to cancel payment: %w", err)
+	}
+
+	return nil
+}
+
+// CompleteGiftCardPayment marks a payment fully covered by a gift card as successful
+// without sending it to Svea. complete, if given, runs in the transaction that
+// marks the payment successful; see completePayment. The payment is returned
+// together with ErrPaymentNotCompleted when only the payment was marked.
+func (s *PaymentService) CompleteGiftCardPayment(ctx context.Context, paymentID int64, complete func(tx *sqlx.Tx) error) (*entity.Payment, error) {
+	payment, err := s.repo.GetPaymentByID(ctx, paymentID)
+	if err != nil {
+		log.Error().Err(err).Int64("paymentID", paymentID).Msg("Failed to get payment")
+		return nil, fmt.Errorf("failed to get payment: %w", err)
+	}
+
+	if payment == nil {
+		return nil, ErrPaymentNotFound
+	}
+
+	if payment.Amount > 0 || payment.GiftCardAmount <= 0 {
+		return nil, fmt.Errorf("payment is not fully covered by a gift card")
+	}
+
+	err = s.completePayment(ctx, payment, entity.PaymentMethodGiftCard, complete)
+	if err != nil && !errors.Is(err, ErrPaymentNotCompleted) {
+		return nil, err
+	}
+
+	payment.Status = entity.PaymentStatusSuccess
+	payment.PaymentMethod = entity.PaymentMethodGiftCard
+	return payment, err
+}
+
 // Helper methods
 
-// PaymentOptions contains options for initiating a payment
+// PaymentOptions contains options for initiating a payment. TotalAmount is the
+// amount left to pay after the gift card amount. NationalID is the customer's
+// personnummer, if given, and is stored encrypted with the payment.
 type PaymentOptions struct {
 	TotalAmount     float64
 	TransactionType string
+	PromoCodeID     *int64
+	DiscountAmount  float64
+	GiftCardID      *int64
+	GiftCardAmount  float64
+	RecoveryConsent bool
+	NationalID      personnummer.Number
+}
+
+// completePayment marks a payment successful and runs complete, if given, in the
+// same transaction, so what it writes is committed together with the payment.
+// The payment has already been taken, so when that transaction fails the payment
+// is still marked successful on its own and ErrPaymentNotCompleted is returned,
+// for the caller to complete it later. When another request finalized the
+// payment first, the conflict is returned and the payment is recorded for staff
+// to reconcile or refund.
+func (s *PaymentService) completePayment(ctx context.Context, payment *entity.Payment, paymentMethod string, complete func(tx *sqlx.Tx) error) error {
+	err := s.repo.Transaction(func(tx *sqlx.Tx) error {
+		if err := s.repo.UpdatePaymentStatusTx(ctx, tx, payment.ID, payment.Version, entity.PaymentStatusSuccess, ""); err != nil {
+			return err
+		}
+		if complete != nil {
+			return complete(tx)
+		}
+		return nil
+	})
+	if err == nil {
+		s.recordEvent(ctx, payment.ID, entity.PaymentEventSucceeded, map[string]string{
+			"paymentMethod": paymentMethod,
+		})
+		return nil
+	}
+
+	log.Error().Err(err).Int64("paymentID", payment.ID).Msg("Failed to complete processed payment")
+
+	// The payment has been taken, so record it even though it could not be
+	// completed. The caller completes it later.
+	updateErr := s.repo.UpdatePaymentStatus(ctx, payment.ID, payment.Version, entity.PaymentStatusSuccess, "")
+	if updateErr == nil {
+		s.recordEvent(ctx, payment.ID, entity.PaymentEventSucceeded, map[string]string{
+			"paymentMethod": paymentMethod,
+		})
+		return fmt.Errorf("%w: %v", ErrPaymentNotCompleted, err)
+	}
+
+	if !errors.Is(updateErr, entity.ErrPaymentVersionConflict) && !errors.Is(updateErr, entity.ErrInvalidPaymentTransition) {
+		// Reconciliation finds a payment pending at Svea and verifies it
+		log.Error().Err(updateErr).Int64("paymentID", payment.ID).Msg("Failed to update payment status")
+		return fmt.Errorf("payment processed but failed to update status: %w", updateErr)
+	}
+
+	// Another request moved the payment first. It can only be completed later
+	// when it ended up successful.
+	current, getErr := s.getPayment(ctx, payment.ID)
+	if getErr == nil && current.Status == entity.PaymentStatusSuccess {
+		return fmt.Errorf("%w: %v", ErrPaymentNotCompleted, err)
+	}
+
+	// The payment is final here while the money was taken, so staff must
+	// reconcile or refund it
+	status := "unknown"
+	if getErr == nil {
+		status = current.Status
+	}
+	log.Error().Err(updateErr).Int64("paymentID", payment.ID).Str("status", status).Str("externalID", payment.ExternalPaymentID).Msg("Payment taken after it was finalized")
+	details := map[string]string{
+		"paymentMethod": paymentMethod,
+		"status":        status,
+	}
+	if payment.ExternalPaymentID != "" {
+		details["sveaOrderId"] = payment.ExternalPaymentID
+	}
+	s.recordEvent(ctx, payment.ID, entity.PaymentEventTakenAfterFinal, details)
+
+	return fmt.Errorf("payment was taken but is %s: %w", status, updateErr)
+}
+
+// recordEvent adds an event to the timeline of a payment. The timeline is for
+// support only, so a failure to record it does not fail the payment.
+func (s *PaymentService) recordEvent(ctx context.Context, paymentID int64, eventType string, details map[string]string) {
+	if err := s.eventRepo.CreatePaymentEvent(ctx, paymentID, eventType, details); err != nil {
+		log.Error().Err(err).Int64("paymentID", paymentID).Str("eventType", eventType).Msg("Failed to record payment event")
+	}
+}
+
+// getPayment retrieves a payment by ID
+func (s *PaymentService) getPayment(ctx context.Context, paymentID int64) (*entity.Payment, error) {
+	payment, err := s.repo.GetPaymentByID(ctx, paymentID)
+	if err != nil {
+		log.Error().Err(err).Int64("paymentID", paymentID).Msg("Failed to get payment")
+		return nil, fmt.Errorf("failed to get payment: %w", err)
+	}
+
+	if payment == nil {
+		return nil, ErrPaymentNotFound
+	}
+
+	return payment, nil
+}
+
+// failPayment marks a payment with the given version as failed and returns any
+// gift card amount it drew. The gift card amount is kept when the payment could
+// not be marked failed, since the payment may have succeeded in the meantime.
+func (s *PaymentService) failPayment(ctx context.Context, paymentID int64, version int, errorMessage string) {
+	if err := s.closePayment(ctx, paymentID, version, entity.PaymentStatusFailed, errorMessage); err != nil {
+		log.Error().Err(err).Int64("paymentID", paymentID).Msg("Failed to update payment status")
+	}
+}
+
+// closePayment moves a payment with the given version that was not paid to
+// failed or cancelled, returns any gift card amount it drew and releases the
+// promo code use it reserved
+func (s *PaymentService) closePayment(ctx context.Context, paymentID int64, version int, status string, reason string) error {
+	if err := s.repo.UpdatePaymentStatus(ctx, paymentID, version, status, reason); err != nil {
+		return err
+	}
+
+	var details map[string]string
+	if reason != "" {
+		details = map[string]string{"reason": reason}
+	}
+	eventType := entity.PaymentEventFailed
+	if status != entity.PaymentStatusFailed {
+		eventType = entity.PaymentEventStatusChanged
+		details = map[string]string{"status": status, "reason": reason}
+	}
+	s.recordEvent(ctx, paymentID, eventType, details)
+
+	if err := s.giftCardRepo.ReleaseGiftCard(ctx, paymentID); err != nil {
+		log.Error().Err(err).Int64("paymentID", paymentID).Str("status", status).Msg("Failed to release gift card amount of closed payment")
+	}
+
+	if err := s.promoRepo.ReleasePromoCode(ctx, paymentID); err != nil {
+		log.Error().Err(err).Int64("paymentID", paymentID).Str("status", status).Msg("Failed to release promo code use of closed payment")
+	}
+
+	return nil
 }
 
 // mapTransactionType maps our transaction type to Svea's payment type
@@ -258,8 +645,10 @@ func (s *PaymentService) mapSveaOrderStatus(sveaStatus string) string {
 		return entity.PaymentStatusPending
 	case "Completed", "Paid":
 		return entity.PaymentStatusSuccess
-	case "Failed", "Cancelled":
+	case "Failed":
 		return entity.PaymentStatusFailed
+	case "Cancelled":
+		return entity.PaymentStatusCancelled
 	default:
 		return entity.PaymentStatusPending
 	}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// standardVatPercent is the VAT rate included in service prices
+const standardVatPercent = 25
+
+// CampaignRepository defines the interface for price campaign data operations
+type CampaignRepository interface {
+	GetCurrentCampaigns(ctx context.Context, at time.Time) ([]entity.PriceCampaign, error)
+	CreateCampaign(ctx context.Context, campaign *entity.PriceCampaign) error
+	EndCampaign(ctx context.Context, id int64, endsAt time.Time) (bool, error)
+	GetLastCampaignChange(ctx context.Context, at time.Time) (*time.Time, error)
+}
+
+// PricingService resolves the effective prices of services from price campaigns
+type PricingService struct {
+	campaignRepo CampaignRepository
+}
+
+// NewPricingService creates a new PricingService
+func NewPricingService(campaignRepo CampaignRepository) *PricingService {
+	return &PricingService{
+		campaignRepo: campaignRepo,
+	}
+}
+
+// CurrentCampaigns retrieves the campaigns that have not ended at the given time
+func (s *PricingService) CurrentCampaigns(ctx context.Context, at time.Time) ([]entity.PriceCampaign, error) {
+	campaigns, err := s.campaignRepo.GetCurrentCampaigns(ctx, at)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get current campaigns")
+		return nil, fmt.Errorf("failed to get current campaigns: %w", err)
+	}
+
+	return campaigns, nil
+}
+
+// LastPriceChange returns the last time at or before the given time that a
+// campaign changed prices, or nil when no campaign ever did
+func (s *PricingService) LastPriceChange(ctx context.Context, at time.Time) (*time.Time, error) {
+	changedAt, err := s.campaignRepo.GetLastCampaignChange(ctx, at)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get last campaign change")
+		return nil, fmt.Errorf("failed to get last campaign change: %w", err)
+	}
+
+	return changedAt, nil
+}
+
+// ResolvePrices returns the effective price of each service at the given time
+func (s *PricingService) ResolvePrices(ctx context.Context, services []entity.Service, at time.Time) (map[int64]entity.ServicePrice, error) {
+	campaigns, err := s.CurrentCampaigns(ctx, at)
+	if err != nil {
+		return nil, err
+	}
+
+	return EffectivePrices(services, campaigns, at), nil
+}
+
+// EffectivePrices applies the campaigns running at the given time to each service.
+// When several campaigns target a service, the one giving the lowest price wins.
+// Gift cards are never discounted, since their price is the value they are
+// issued with.
+func EffectivePrices(services []entity.Service, campaigns []entity.PriceCampaign, at time.Time) map[int64]entity.ServicePrice {
+	prices := make(map[int64]entity.ServicePrice, len(services))
+	for _, service := range services {
+		price := entity.ServicePrice{
+			ServiceID: service.ID,
+			ListPrice: service.Price,
+			Price:     service.Price,
+		}
+
+		if service.IsGiftCard {
+			prices[service.ID] = price
+			continue
+		}
+
+		for i := range campaigns {
+			campaign := &campaigns[i]
+			if !campaignRunning(campaign, at) || !campaignTargets(campaign, service.ID) {
+				continue
+			}
+
+			discounted := applyCampaign(campaign, service.Price)
+			if discounted < price.Price {
+				price.Price = discounted
+				price.CampaignID = &campaign.ID
+				price.CampaignName = campaign.Name
+				price.ValidFrom = &campaign.StartsAt
+				price.ValidUntil = campaign.EndsAt
+			}
+		}
+
+		prices[service.ID] = price
+	}
+
+	return prices
+}
+
+// VatPercent returns the VAT rate included in the price of a service. Gift cards
+// are multi-purpose vouchers without VAT; it is charged when they are redeemed.
+func VatPercent(service entity.Service) int {
+	if service.IsGiftCard {
+		return 0
+	}
+	return standardVatPercent
+}
+
+// IncludedVat returns the VAT part of an amount that includes VAT at the given rate
+func IncludedVat(amount float64, vatPercent int) float64 {
+	return roundToOre(amount * float64(vatPercent) / float64(100+vatPercent))
+}
+
+// Helper functions
+
+// campaignRunning checks whether a campaign is active at the given time
+func campaignRunning(campaign *entity.PriceCampaign, at time.Time) bool {
+	if !campaign.IsActive || at.Before(campaign.StartsAt) {
+		return false
+	}
+	return campaign.EndsAt == nil || at.Before(*campaign.EndsAt)
+}
+
+// campaignTargets checks whether a campaign applies to a service
+func campaignTargets(campaign *entity.PriceCampaign, serviceID int64) bool {
+	if campaign.AppliesToAll {
+		return true
+	}
+	for _, id := range campaign.ServiceIDs {
+		if id == serviceID {
+			return true
+		}
+	}
+	return false
+}
+
+// applyCampaign calculates the discounted price, rounded to whole öre and never below zero
+func applyCampaign(campaign *entity.PriceCampaign, price float64) float64 {
+	var discounted float64
+	switch campaign.DiscountType {
+	case entity.DiscountTypePercentage:
+		discounted = price * (1 - campaign.DiscountValue/100)
+	case entity.DiscountTypeFixedAmount:
+		discounted = price - campaign.DiscountValue
+	default:
+		return price
+	}
+
+	return math.Max(0, roundToOre(discounted))
+}
+
+// roundToOre rounds an amount in kronor to two decimals
+func roundToOre(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
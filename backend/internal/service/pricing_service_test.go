@@ -0,0 +1,54 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+func TestEffectivePrices(t *testing.T) {
+	at := time.Date(2024, time.June, 15, 12, 0, 0, 0, time.UTC)
+	ended := at.Add(-time.Hour)
+
+	services := []entity.Service{
+		{ID: 1, Price: 1000},
+		{ID: 2, Price: 500, IsGiftCard: true},
+		{ID: 3, Price: 800},
+	}
+	campaigns := []entity.PriceCampaign{
+		{ID: 10, Name: "Sommar", DiscountType: entity.DiscountTypePercentage, DiscountValue: 20, StartsAt: at.AddDate(0, 0, -1), AppliesToAll: true, IsActive: true},
+		{ID: 11, Name: "Hundralappen", DiscountType: entity.DiscountTypeFixedAmount, DiscountValue: 300, StartsAt: at.AddDate(0, 0, -1), ServiceIDs: []int64{1}, IsActive: true},
+		{ID: 12, Name: "Avslutad", DiscountType: entity.DiscountTypeFixedAmount, DiscountValue: 700, StartsAt: at.AddDate(0, 0, -2), EndsAt: &ended, ServiceIDs: []int64{3}, IsActive: true},
+	}
+
+	prices := EffectivePrices(services, campaigns, at)
+
+	tests := []struct {
+		name       string
+		serviceID  int64
+		want       float64
+		campaignID int64
+	}{
+		{name: "lowest campaign price wins", serviceID: 1, want: 700, campaignID: 11},
+		{name: "gift card keeps its face value", serviceID: 2, want: 500},
+		{name: "ended campaign is ignored", serviceID: 3, want: 640, campaignID: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price := prices[tt.serviceID]
+			if price.Price != tt.want {
+				t.Errorf("Price = %v, want %v", price.Price, tt.want)
+			}
+
+			var campaignID int64
+			if price.CampaignID != nil {
+				campaignID = *price.CampaignID
+			}
+			if campaignID != tt.campaignID {
+				t.Errorf("CampaignID = %d, want %d", campaignID, tt.campaignID)
+			}
+		})
+	}
+}
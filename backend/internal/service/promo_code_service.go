@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// Promo code errors
+var (
+	ErrPromoCodeNotFound      = errors.New("promo code not found")
+	ErrPromoCodeNotValid      = errors.New("promo code is not valid at this time")
+	ErrPromoCodeUsageLimit    = errors.New("promo code usage limit reached")
+	ErrPromoCodeMinimumNotMet = errors.New("order amount is below the promo code minimum")
+	ErrPromoCodeNotApplicable = errors.New("promo code does not apply to any item in the order")
+)
+
+// PromoCodeRepository defines the interface for promo code data operations
+type PromoCodeRepository interface {
+	GetPromoCodeByCode(ctx context.Context, code string) (*entity.PromoCode, error)
+	CountRedemptions(ctx context.Context, promoCodeID int64) (int, error)
+	CountCustomerRedemptions(ctx context.Context, promoCodeID int64, email string) (int, error)
+	CreateRedemption(ctx context.Context, tx *sqlx.Tx, redemption *entity.PromoCodeRedemption) error
+	ReservePromoCode(ctx context.Context, tx *sqlx.Tx, redemption *entity.PromoCodeRedemption, email string) (bool, error)
+	ReleasePromoCode(ctx context.Context, paymentID int64) error
+}
+
+// PromoCodeService provides business logic for promo codes
+type PromoCodeService struct {
+	repo PromoCodeRepository
+}
+
+// NewPromoCodeService creates a new PromoCodeService
+func NewPromoCodeService(repo PromoCodeRepository) *PromoCodeService {
+	return &PromoCodeService{
+		repo: repo,
+	}
+}
+
+// ApplyPromoCode validates a promo code for an order at the given time and sets
+// the discount of each eligible item. It returns the promo code and the total
+// discount. The per-customer limit is checked by CheckUsage once the customer is known.
+func (s *PromoCodeService) ApplyPromoCode(ctx context.Context, code string, items []entity.PaymentItem, at time.Time) (*entity.PromoCode, float64, error) {
+	promoCode, err := s.getValidPromoCode(ctx, code, at)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	subtotal := 0.0
+	for _, item := range items {
+		subtotal += item.TotalPrice
+	}
+
+	if promoCode.MinOrderAmount != nil && subtotal < *promoCode.MinOrderAmount {
+		return nil, 0, fmt.Errorf("%w (minimum %.2f)", ErrPromoCodeMinimumNotMet, *promoCode.MinOrderAmount)
+	}
+
+	if err := s.checkUsage(ctx, promoCode, ""); err != nil {
+		return nil, 0, err
+	}
+
+	discount := allocatePromoDiscount(promoCode, items)
+	if discount <= 0 {
+		return nil, 0, ErrPromoCodeNotApplicable
+	}
+
+	return promoCode, discount, nil
+}
+
+// CheckUsage checks that a promo code is still valid and within its overall and
+// per-customer usage limits for the customer with the given email
+func (s *PromoCodeService) CheckUsage(ctx context.Context, code string, customerEmail string) error {
+	promoCode, err := s.getValidPromoCode(ctx, code, time.Now())
+	if err != nil {
+		return err
+	}
+
+	return s.checkUsage(ctx, promoCode, customerEmail)
+}
+
+// RecordRedemption records the promo code redemption of a successful payment in
+// tx, unless it was already reserved when the payment was initiated. Payments
+// without a promo code are ignored.
+func (s *PromoCodeService) RecordRedemption(ctx context.Context, tx *sqlx.Tx, payment *entity.Payment) error {
+	if payment.PromoCodeID == nil {
+		return nil
+	}
+
+	redemption := &entity.PromoCodeRedemption{
+		PromoCodeID:    *payment.PromoCodeID,
+		PaymentID:      payment.ID,
+		CustomerID:     payment.CustomerID,
+		DiscountAmount: payment.DiscountAmount,
+	}
+
+	if err := s.repo.CreateRedemption(ctx, tx, redemption); err != nil {
+		log.Error().Err(err).Int64("paymentID", payment.ID).Int64("promoCodeID", *payment.PromoCodeID).Msg("Failed to record promo code redemption")
+		return fmt.Errorf("failed to record promo code redemption: %w", err)
+	}
+
+	return nil
+}
+
+// Helper methods
+
+// getValidPromoCode retrieves a promo code and checks that it is active at the given time
+func (s *PromoCodeService) getValidPromoCode(ctx context.Context, code string, at time.Time) (*entity.PromoCode, error) {
+	promoCode, err := s.repo.GetPromoCodeByCode(ctx, strings.TrimSpace(code))
+	if err != nil {
+		log.Error().Err(err).Str("code", code).Msg("Failed to get promo code")
+		return nil, fmt.Errorf("failed to get promo code: %w", err)
+	}
+
+	if promoCode == nil {
+		return nil, ErrPromoCodeNotFound
+	}
+
+	if !promoCodeValid(promoCode, at) {
+		return nil, ErrPromoCodeNotValid
+	}
+
+	return promoCode, nil
+}
+
+// checkUsage checks the usage limits of a promo code. The per-customer limit is
+// skipped when no customer email is given.
+func (s *PromoCodeService) checkUsage(ctx context.Context, promoCode *entity.PromoCode, customerEmail string) error {
+	if promoCode.MaxUses != nil {
+		uses, err := s.repo.CountRedemptions(ctx, promoCode.ID)
+		if err != nil {
+			log.Error().Err(err).Int64("promoCodeID", promoCode.ID).Msg("Failed to count promo code redemptions")
+			return fmt.Errorf("failed to count promo code redemptions: %w", err)
+		}
+		if uses >= *promoCode.MaxUses {
+			return ErrPromoCodeUsageLimit
+		}
+	}
+
+	if promoCode.MaxUsesPerCustomer != nil && customerEmail != "" {
+		uses, err := s.repo.CountCustomerRedemptions(ctx, promoCode.ID, customerEmail)
+		if err != nil {
+			log.Error().Err(err).Int64("promoCodeID", promoCode.ID).Msg("Failed to count customer promo code redemptions")
+			return fmt.Errorf("failed to count customer promo code redemptions: %w", err)
+		}
+		if uses >= *promoCode.MaxUsesPerCustomer {
+			return ErrPromoCodeUsageLimit
+		}
+	}
+
+	return nil
+}
+
+// Helper functions
+
+// promoCodeValid checks whether a promo code is active at the given time
+func promoCodeValid(promoCode *entity.PromoCode, at time.Time) bool {
+	if !promoCode.IsActive || at.Before(promoCode.StartsAt) {
+		return false
+	}
+	return promoCode.ExpiresAt == nil || at.Before(*promoCode.ExpiresAt)
+}
+
+// promoCodeTargets checks whether a promo code applies to a service
+func promoCodeTargets(promoCode *entity.PromoCode, serviceID int64) bool {
+	if promoCode.AppliesToAll {
+		return true
+	}
+	for _, id := range promoCode.ServiceIDs {
+		if id == serviceID {
+			return true
+		}
+	}
+	return false
+}
+
+// allocatePromoDiscount sets the discount of each eligible item and returns the total.
+// Fixed amounts are capped at the eligible amount and spread over the eligible items
+// in proportion to their totals, with the rounding remainder on the last one.
+func allocatePromoDiscount(promoCode *entity.PromoCode, items []entity.PaymentItem) float64 {
+	eligible := make([]int, 0, len(items))
+	eligibleTotal := 0.0
+	for i := range items {
+		items[i].DiscountAmount = 0
+		if promoCodeTargets(promoCode, items[i].ServiceID) {
+			eligible = append(eligible, i)
+			eligibleTotal += items[i].TotalPrice
+		}
+	}
+
+	if len(eligible) == 0 || eligibleTotal <= 0 {
+		return 0
+	}
+
+	total := 0.0
+	switch promoCode.DiscountType {
+	case entity.DiscountTypePercentage:
+		percent := math.Min(promoCode.DiscountValue, 100)
+		for _, i := range eligible {
+			items[i].DiscountAmount = roundToOre(items[i].TotalPrice * percent / 100)
+			total += items[i].DiscountAmount
+		}
+	case entity.DiscountTypeFixedAmount:
+		amount := roundToOre(math.Min(promoCode.DiscountValue, eligibleTotal))
+		for n, i := range eligible {
+			if n == len(eligible)-1 {
+				items[i].DiscountAmount = roundToOre(amount - total)
+			} else {
+				items[i].DiscountAmount = roundToOre(amount * items[i].TotalPrice / eligibleTotal)
+			}
+			total += items[i].DiscountAmount
+		}
+	}
+
+	return roundToOre(total)
+}
@@ -2,30 +2,100 @@ package service
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/svenskhalsovard/api/internal/entity"
+	"github.com/svenskhalsovard/api/internal/slug"
 )
 
 // ServiceRepository defines the interface for service data operations
 type ServiceRepository interface {
 	GetServices(ctx context.Context) ([]entity.Service, error)
+	SearchServices(ctx context.Context, filter entity.ServiceFilter, after *entity.ServiceCursor) ([]entity.Service, error)
 	GetServiceByID(ctx context.Context, id int64) (*entity.Service, error)
+	GetServiceBySlug(ctx context.Context, slug string) (*entity.Service, error)
+	GetServiceSlugRedirect(ctx context.Context, slug string) (*entity.ServiceSlugRedirect, error)
 	GetServiceWithFeatures(ctx context.Context, id int64) (*entity.ServiceWithFeatures, error)
+	GetServiceFeatures(ctx context.Context, serviceID int64) ([]entity.ServiceFeature, error)
 	GetServicesByIDs(ctx context.Context, ids []int64) (map[int64]entity.Service, error)
+	IsSlugTaken(ctx context.Context, slug string, excludeServiceID int64) (bool, error)
+	CreateService(ctx context.Context, service *entity.Service) error
+	UpdateService(ctx context.Context, service *entity.Service) (bool, error)
+	GetCatalogVersion(ctx context.Context) (string, error)
+}
+
+// Pagination limits for service listings
+const (
+	DefaultServicePageSize = 50
+	MaxServicePageSize     = 100
+)
+
+// Service errors
+var (
+	ErrServiceNotFound      = errors.New("service not found")
+	ErrInvalidServiceFilter = errors.New("invalid service filter")
+)
+
+// ServiceMovedError is returned when a service is requested by a slug it had
+// before it was renamed. Slug holds the current slug of the service.
+type ServiceMovedError struct {
+	Slug string
+}
+
+func (e *ServiceMovedError) Error() string {
+	return fmt.Sprintf("service has moved to %s", e.Slug)
 }
 
 // ServiceService provides business logic for services
 type ServiceService struct {
-	repo ServiceRepository
+	repo         ServiceRepository
+	categoryRepo CategoryRepository
+	pricing      *PricingService
+	cache        *catalogCache
 }
 
-// NewServiceService creates a new ServiceService
-func NewServiceService(repo ServiceRepository) *ServiceService {
+// NewServiceService creates a new ServiceService. Catalog reads are cached in
+// process for cacheTTL; a non-positive TTL disables the cache.
+func NewServiceService(repo ServiceRepository, categoryRepo CategoryRepository, pricing *PricingService, cacheTTL time.Duration) *ServiceService {
 	return &ServiceService{
-		repo: repo,
+		repo:         repo,
+		categoryRepo: categoryRepo,
+		pricing:      pricing,
+		cache:        newCatalogCache(cacheTTL),
+	}
+}
+
+// InvalidateCatalog clears the catalog cache. It must be called after any write
+// that changes what the catalog endpoints return. Changes made outside this
+// process are picked up by checkCatalogVersion instead.
+func (s *ServiceService) InvalidateCatalog() {
+	s.cache.invalidate()
+}
+
+// checkCatalogVersion clears the catalog cache when the catalog has changed in
+// the database since the cached entries were read, such as by another API
+// instance or by maintenance in the database
+func (s *ServiceService) checkCatalogVersion(ctx context.Context) error {
+	if !s.cache.enabled() {
+		return nil
 	}
+
+	version, err := s.repo.GetCatalogVersion(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get catalog version")
+		return fmt.Errorf("failed to get catalog version: %w", err)
+	}
+
+	s.cache.syncVersion(version)
+	return nil
 }
 
 // GetServices retrieves all active services
@@ -39,6 +109,167 @@ func (s *ServiceService) GetServices(ctx context.Context) ([]entity.Service, err
 	return services, nil
 }
 
+// SearchServices retrieves one page of active services matching the filter
+func (s *ServiceService) SearchServices(ctx context.Context, filter entity.ServiceFilter) (*entity.ServicePage, error) {
+	if err := normalizeServiceFilter(&filter); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkCatalogVersion(ctx); err != nil {
+		return nil, err
+	}
+
+	cacheKey := serviceFilterCacheKey(filter)
+	if cached, ok := s.cache.get(cacheKey); ok {
+		return s.withPagePrices(ctx, cached.(*entity.ServicePage))
+	}
+
+	if filter.Category != "" {
+		categories, err := s.categoryRepo.GetCategories(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to get categories")
+			return nil, fmt.Errorf("failed to get categories: %w", err)
+		}
+
+		categoryIDs, ok := categoryWithDescendants(categories, filter.Category)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrCategoryNotFound, filter.Category)
+		}
+		filter.CategoryIDs = categoryIDs
+	}
+
+	var after *entity.ServiceCursor
+	if filter.Cursor != "" {
+		cursor, err := decodeServiceCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		after = cursor
+	}
+
+	// Campaign prices are not known to the database, so price filters and
+	// sorting are applied to the effective prices here
+	if filter.MinPrice != nil || filter.MaxPrice != nil || filter.SortBy == entity.ServiceSortPrice {
+		return s.searchServicesByPrice(ctx, filter, after)
+	}
+
+	// Fetch one extra row to find out whether another page follows
+	pageFilter := filter
+	pageFilter.Limit = filter.Limit + 1
+
+	services, err := s.repo.SearchServices(ctx, pageFilter, after)
+	if err != nil {
+		log.Error().Err(err).Interface("filter", filter).Msg("Failed to search services")
+		return nil, fmt.Errorf("failed to search services: %w", err)
+	}
+
+	page := &entity.ServicePage{
+		Services: services,
+		Limit:    filter.Limit,
+	}
+
+	if len(services) > filter.Limit {
+		page.Services = services[:filter.Limit]
+		page.HasMore = true
+		page.NextCursor = encodeServiceCursor(filter.SortBy, page.Services[len(page.Services)-1], nil)
+	}
+
+	s.cache.set(cacheKey, page)
+	return s.withPagePrices(ctx, page)
+}
+
+// searchServicesByPrice retrieves one page of active services matching a filter
+// on or sorted by price. The services matching the other conditions are read in
+// full, which the size of the catalog allows, and filtered, sorted and paged by
+// their effective price.
+func (s *ServiceService) searchServicesByPrice(ctx context.Context, filter entity.ServiceFilter, after *entity.ServiceCursor) (*entity.ServicePage, error) {
+	sortByPrice := filter.SortBy == entity.ServiceSortPrice
+
+	// Other sort fields keep the database order and the cursor is applied there
+	candidateFilter := filter
+	candidateFilter.MinPrice = nil
+	candidateFilter.MaxPrice = nil
+	candidateFilter.Limit = 0
+	candidateAfter := after
+	if sortByPrice {
+		candidateFilter.SortBy = entity.ServiceSortID
+		candidateFilter.SortOrder = entity.SortOrderAsc
+		candidateFilter.Cursor = ""
+		candidateAfter = nil
+	}
+
+	var candidates []entity.Service
+	cacheKey := "candidates:" + serviceFilterCacheKey(candidateFilter)
+	if cached, ok := s.cache.get(cacheKey); ok {
+		candidates = cached.([]entity.Service)
+	} else {
+		var err error
+		candidates, err = s.repo.SearchServices(ctx, candidateFilter, candidateAfter)
+		if err != nil {
+			log.Error().Err(err).Interface("filter", filter).Msg("Failed to search services")
+			return nil, fmt.Errorf("failed to search services: %w", err)
+		}
+		s.cache.set(cacheKey, candidates)
+	}
+
+	prices, err := s.currentPrices(ctx, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	services := make([]entity.Service, 0, len(candidates))
+	for _, service := range candidates {
+		price := prices[service.ID].Price
+		if filter.MinPrice != nil && price < *filter.MinPrice {
+			continue
+		}
+		if filter.MaxPrice != nil && price > *filter.MaxPrice {
+			continue
+		}
+		services = append(services, service)
+	}
+
+	if sortByPrice {
+		descending := filter.SortOrder == entity.SortOrderDesc
+		before := func(priceA float64, idA int64, priceB float64, idB int64) bool {
+			if priceA != priceB {
+				return (priceA < priceB) != descending
+			}
+			return (idA < idB) != descending
+		}
+
+		sort.Slice(services, func(i, j int) bool {
+			return before(prices[services[i].ID].Price, services[i].ID, prices[services[j].ID].Price, services[j].ID)
+		})
+
+		if after != nil {
+			afterPrice, err := strconv.ParseFloat(after.SortValue, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidServiceFilter)
+			}
+
+			start := sort.Search(len(services), func(i int) bool {
+				return before(afterPrice, after.ID, prices[services[i].ID].Price, services[i].ID)
+			})
+			services = services[start:]
+		}
+	}
+
+	page := &entity.ServicePage{
+		Services: services,
+		Prices:   prices,
+		Limit:    filter.Limit,
+	}
+
+	if len(services) > filter.Limit {
+		page.Services = services[:filter.Limit]
+		page.HasMore = true
+		page.NextCursor = encodeServiceCursor(filter.SortBy, page.Services[len(page.Services)-1], prices)
+	}
+
+	return page, nil
+}
+
 // GetServiceByID retrieves a service by ID
 func (s *ServiceService) GetServiceByID(ctx context.Context, id int64) (*entity.Service, error) {
 	service, err := s.repo.GetServiceByID(ctx, id)
@@ -48,7 +279,7 @@ func (s *ServiceService) GetServiceByID(ctx context.Context, id int64) (*entity.
 	}
 
 	if service == nil {
-		return nil, fmt.Errorf("service not found")
+		return nil, ErrServiceNotFound
 	}
 
 	return service, nil
@@ -56,6 +287,15 @@ func (s *ServiceService) GetServiceByID(ctx context.Context, id int64) (*entity.
 
 // GetServiceWithFeatures retrieves a service with its features by ID
 func (s *ServiceService) GetServiceWithFeatures(ctx context.Context, id int64) (*entity.ServiceWithFeatures, error) {
+	if err := s.checkCatalogVersion(ctx); err != nil {
+		return nil, err
+	}
+
+	cacheKey := fmt.Sprintf("service:id:%d", id)
+	if cached, ok := s.cache.get(cacheKey); ok {
+		return s.withServicePrice(ctx, cached.(*entity.ServiceWithFeatures))
+	}
+
 	serviceWithFeatures, err := s.repo.GetServiceWithFeatures(ctx, id)
 	if err != nil {
 		log.Error().Err(err).Int64("serviceID", id).Msg("Failed to get service with features")
@@ -63,10 +303,98 @@ func (s *ServiceService) GetServiceWithFeatures(ctx context.Context, id int64) (
 	}
 
 	if serviceWithFeatures == nil {
-		return nil, fmt.Errorf("service not found")
+		return nil, ErrServiceNotFound
 	}
 
-	return serviceWithFeatures, nil
+	s.cache.set(cacheKey, serviceWithFeatures)
+	return s.withServicePrice(ctx, serviceWithFeatures)
+}
+
+// GetServiceWithFeaturesBySlug retrieves a service with its features by slug.
+// A slug the service had before being renamed yields a *ServiceMovedError.
+func (s *ServiceService) GetServiceWithFeaturesBySlug(ctx context.Context, slug string) (*entity.ServiceWithFeatures, error) {
+	if err := s.checkCatalogVersion(ctx); err != nil {
+		return nil, err
+	}
+
+	cacheKey := "service:slug:" + slug
+	if cached, ok := s.cache.get(cacheKey); ok {
+		return s.withServicePrice(ctx, cached.(*entity.ServiceWithFeatures))
+	}
+
+	service, err := s.repo.GetServiceBySlug(ctx, slug)
+	if err != nil {
+		log.Error().Err(err).Str("slug", slug).Msg("Failed to get service by slug")
+		return nil, fmt.Errorf("failed to get service by slug: %w", err)
+	}
+
+	if service == nil {
+		return nil, s.resolveSlugRedirect(ctx, slug)
+	}
+
+	features, err := s.repo.GetServiceFeatures(ctx, service.ID)
+	if err != nil {
+		log.Error().Err(err).Int64("serviceID", service.ID).Msg("Failed to get service features")
+		return nil, fmt.Errorf("failed to get service features: %w", err)
+	}
+
+	serviceWithFeatures := &entity.ServiceWithFeatures{
+		Service:  *service,
+		Features: features,
+	}
+
+	s.cache.set(cacheKey, serviceWithFeatures)
+	return s.withServicePrice(ctx, serviceWithFeatures)
+}
+
+// CreateService creates a new service with a unique slug generated from its name
+func (s *ServiceService) CreateService(ctx context.Context, service *entity.Service) error {
+	if err := s.validateServiceCategory(ctx, service.CategoryID); err != nil {
+		return err
+	}
+
+	serviceSlug, err := s.uniqueSlug(ctx, service.Name, 0)
+	if err != nil {
+		return err
+	}
+	service.Slug = serviceSlug
+
+	if err := s.repo.CreateService(ctx, service); err != nil {
+		log.Error().Err(err).Str("name", service.Name).Msg("Failed to create service")
+		return fmt.Errorf("failed to create service: %w", err)
+	}
+
+	s.InvalidateCatalog()
+
+	return nil
+}
+
+// UpdateService updates a service and regenerates its slug from the name.
+// The repository keeps the previous slug as a redirect when it changes.
+func (s *ServiceService) UpdateService(ctx context.Context, service *entity.Service) error {
+	if err := s.validateServiceCategory(ctx, service.CategoryID); err != nil {
+		return err
+	}
+
+	serviceSlug, err := s.uniqueSlug(ctx, service.Name, service.ID)
+	if err != nil {
+		return err
+	}
+	service.Slug = serviceSlug
+
+	found, err := s.repo.UpdateService(ctx, service)
+	if err != nil {
+		log.Error().Err(err).Int64("serviceID", service.ID).Msg("Failed to update service")
+		return fmt.Errorf("failed to update service: %w", err)
+	}
+
+	if !found {
+		return ErrServiceNotFound
+	}
+
+	s.InvalidateCatalog()
+
+	return nil
 }
 
 // GetServicesByIDs retrieves multiple services by their IDs
@@ -103,4 +431,218 @@ func (s *ServiceService) ValidateServices(ctx context.Context, serviceIDs []int6
 	}
 
 	return services, nil
-}
\ No newline at end of file
+}
+
+// Helper functions
+
+// currentPrices resolves the effective prices of services now, with the last
+// time campaigns changed them. The campaign list is cached with the catalog;
+// campaign windows are evaluated on every call.
+func (s *ServiceService) currentPrices(ctx context.Context, services []entity.Service) (map[int64]entity.ServicePrice, error) {
+	var campaigns []entity.PriceCampaign
+	if cached, ok := s.cache.get("campaigns"); ok {
+		campaigns = cached.([]entity.PriceCampaign)
+	} else {
+		var err error
+		campaigns, err = s.pricing.CurrentCampaigns(ctx, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		s.cache.set("campaigns", campaigns)
+	}
+
+	// Not cached, since campaigns start and end without the catalog changing
+	now := time.Now()
+	changedAt, err := s.pricing.LastPriceChange(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	prices := EffectivePrices(services, campaigns, now)
+	for id, price := range prices {
+		price.ChangedAt = changedAt
+		prices[id] = price
+	}
+
+	return prices, nil
+}
+
+// withPagePrices returns a copy of a (possibly cached) page with current prices
+func (s *ServiceService) withPagePrices(ctx context.Context, page *entity.ServicePage) (*entity.ServicePage, error) {
+	prices, err := s.currentPrices(ctx, page.Services)
+	if err != nil {
+		return nil, err
+	}
+
+	priced := *page
+	priced.Prices = prices
+	return &priced, nil
+}
+
+// withServicePrice returns a copy of a (possibly cached) service with its current price
+func (s *ServiceService) withServicePrice(ctx context.Context, serviceWithFeatures *entity.ServiceWithFeatures) (*entity.ServiceWithFeatures, error) {
+	prices, err := s.currentPrices(ctx, []entity.Service{serviceWithFeatures.Service})
+	if err != nil {
+		return nil, err
+	}
+
+	price := prices[serviceWithFeatures.Service.ID]
+	priced := *serviceWithFeatures
+	priced.Price = &price
+	return &priced, nil
+}
+
+// resolveSlugRedirect returns a *ServiceMovedError when slug is a previous slug
+// of an active service, and ErrServiceNotFound otherwise
+func (s *ServiceService) resolveSlugRedirect(ctx context.Context, oldSlug string) error {
+	redirect, err := s.repo.GetServiceSlugRedirect(ctx, oldSlug)
+	if err != nil {
+		log.Error().Err(err).Str("slug", oldSlug).Msg("Failed to get service slug redirect")
+		return fmt.Errorf("failed to get service slug redirect: %w", err)
+	}
+
+	if redirect == nil {
+		return ErrServiceNotFound
+	}
+
+	service, err := s.repo.GetServiceByID(ctx, redirect.ServiceID)
+	if err != nil {
+		log.Error().Err(err).Int64("serviceID", redirect.ServiceID).Msg("Failed to get redirected service")
+		return fmt.Errorf("failed to get service by ID: %w", err)
+	}
+
+	if service == nil {
+		return ErrServiceNotFound
+	}
+
+	return &ServiceMovedError{Slug: service.Slug}
+}
+
+// validateServiceCategory returns ErrCategoryNotFound when categoryID is set
+// and does not match an active category
+func (s *ServiceService) validateServiceCategory(ctx context.Context, categoryID *int64) error {
+	if categoryID == nil {
+		return nil
+	}
+
+	categories, err := s.categoryRepo.GetCategories(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get categories")
+		return fmt.Errorf("failed to get categories: %w", err)
+	}
+
+	for _, category := range categories {
+		if category.ID == *categoryID {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %d", ErrCategoryNotFound, *categoryID)
+}
+
+// uniqueSlug generates a slug from name, adding a numeric suffix when the slug
+// is already used by another service
+func (s *ServiceService) uniqueSlug(ctx context.Context, name string, serviceID int64) (string, error) {
+	base := slug.Make(name)
+	if base == "" || slug.IsNumeric(base) {
+		// Numeric slugs would be indistinguishable from service IDs in URLs
+		base = strings.TrimSuffix("tjanst-"+base, "-")
+	}
+
+	candidate := base
+	for suffix := 2; ; suffix++ {
+		taken, err := s.repo.IsSlugTaken(ctx, candidate, serviceID)
+		if err != nil {
+			log.Error().Err(err).Str("slug", candidate).Msg("Failed to check service slug")
+			return "", fmt.Errorf("failed to check service slug: %w", err)
+		}
+		if !taken {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s-%d", base, suffix)
+	}
+}
+
+// normalizeServiceFilter applies defaults to a service filter and validates its values
+func normalizeServiceFilter(filter *entity.ServiceFilter) error {
+	switch filter.SortBy {
+	case "":
+		filter.SortBy = entity.ServiceSortID
+	case entity.ServiceSortID, entity.ServiceSortName, entity.ServiceSortPrice, entity.ServiceSortCreatedAt:
+	default:
+		return fmt.Errorf("%w: unsupported sort field %q", ErrInvalidServiceFilter, filter.SortBy)
+	}
+
+	switch filter.SortOrder {
+	case "":
+		filter.SortOrder = entity.SortOrderAsc
+	case entity.SortOrderAsc, entity.SortOrderDesc:
+	default:
+		return fmt.Errorf("%w: unsupported sort order %q", ErrInvalidServiceFilter, filter.SortOrder)
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultServicePageSize
+	}
+	if filter.Limit > MaxServicePageSize {
+		filter.Limit = MaxServicePageSize
+	}
+
+	if filter.MinPrice != nil && *filter.MinPrice < 0 {
+		return fmt.Errorf("%w: minimum price cannot be negative", ErrInvalidServiceFilter)
+	}
+
+	if filter.MinPrice != nil && filter.MaxPrice != nil && *filter.MinPrice > *filter.MaxPrice {
+		return fmt.Errorf("%w: minimum price is greater than maximum price", ErrInvalidServiceFilter)
+	}
+
+	return nil
+}
+
+// serviceFilterCacheKey builds the catalog cache key for a normalized filter
+func serviceFilterCacheKey(filter entity.ServiceFilter) string {
+	formatPrice := func(price *float64) string {
+		if price == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*price, 'f', -1, 64)
+	}
+
+	return fmt.Sprintf("search:%q:%q:%t:%s:%s:%s:%s:%q:%d",
+		filter.Search, filter.Category, filter.SubscriptionOnly,
+		formatPrice(filter.MinPrice), formatPrice(filter.MaxPrice),
+		filter.SortBy, filter.SortOrder, filter.Cursor, filter.Limit)
+}
+
+// encodeServiceCursor builds the opaque cursor pointing after the given service.
+// Services sorted by price are sorted by their effective price in prices.
+func encodeServiceCursor(sortBy string, service entity.Service, prices map[int64]entity.ServicePrice) string {
+	cursor := entity.ServiceCursor{ID: service.ID}
+
+	switch sortBy {
+	case entity.ServiceSortName:
+		cursor.SortValue = service.Name
+	case entity.ServiceSortPrice:
+		cursor.SortValue = strconv.FormatFloat(prices[service.ID].Price, 'f', -1, 64)
+	case entity.ServiceSortCreatedAt:
+		cursor.SortValue = service.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeServiceCursor parses an opaque cursor produced by encodeServiceCursor
+func decodeServiceCursor(value string) (*entity.ServiceCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidServiceFilter)
+	}
+
+	var cursor entity.ServiceCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID <= 0 {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidServiceFilter)
+	}
+
+	return &cursor, nil
+}
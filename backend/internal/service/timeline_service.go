@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/rs/zerolog/log"
+	"github.com/svenskhalsovard/api/internal/entity"
+)
+
+// TimelineRepository defines the interface for reading payment and booking events
+type TimelineRepository interface {
+	GetPaymentEvents(ctx context.Context, paymentID int64) ([]entity.PaymentEvent, error)
+	GetBookingEvents(ctx context.Context, bookingID int64) ([]entity.BookingEvent, error)
+}
+
+// TimelineService builds the timeline of an order from the events of its payment
+// and booking, so support can follow what happened
+type TimelineService struct {
+	repo        TimelineRepository
+	paymentRepo PaymentRepository
+	bookingRepo BookingRepository
+}
+
+// NewTimelineService creates a new TimelineService
+func NewTimelineService(repo TimelineRepository, paymentRepo PaymentRepository, bookingRepo BookingRepository) *TimelineService {
+	return &TimelineService{
+		repo:        repo,
+		paymentRepo: paymentRepo,
+		bookingRepo: bookingRepo,
+	}
+}
+
+// GetPaymentTimeline retrieves the events of a payment and of the booking made
+// for it, oldest first
+func (s *TimelineService) GetPaymentTimeline(ctx context.Context, paymentID int64) ([]entity.TimelineEvent, error) {
+	payment, err := s.paymentRepo.GetPaymentByID(ctx, paymentID)
+	if err != nil {
+		log.Error().Err(err).Int64("paymentID", paymentID).Msg("Failed to get payment")
+		return nil, fmt.Errorf("failed to get payment: %w", err)
+	}
+
+	if payment == nil {
+		return nil, ErrPaymentNotFound
+	}
+
+	booking, err := s.bookingRepo.GetBookingByPaymentID(ctx, paymentID)
+	if err != nil {
+		log.Error().Err(err).Int64("paymentID", paymentID).Msg("Failed to get booking of payment")
+		return nil, fmt.Errorf("failed to get booking: %w", err)
+	}
+
+	return s.buildTimeline(ctx, paymentID, booking)
+}
+
+// GetBookingTimeline retrieves the events of a booking and of the payment it was
+// made for, oldest first
+func (s *TimelineService) GetBookingTimeline(ctx context.Context, bookingID int64) ([]entity.TimelineEvent, error) {
+	booking, err := s.bookingRepo.GetBookingByID(ctx, bookingID)
+	if err != nil {
+		log.Error().Err(err).Int64("bookingID", bookingID).Msg("Failed to get booking")
+		return nil, fmt.Errorf("failed to get booking: %w", err)
+	}
+
+	if booking == nil {
+		return nil, ErrBookingNotFound
+	}
+
+	return s.buildTimeline(ctx, booking.PaymentID, booking)
+}
+
+// buildTimeline merges the events of a payment and its booking, if any, in the
+// order they happened. Events recorded in the same second keep the payment
+// events first, since a booking is only made once its payment succeeded.
+func (s *TimelineService) buildTimeline(ctx context.Context, paymentID int64, booking *entity.Booking) ([]entity.TimelineEvent, error) {
+	paymentEvents, err := s.repo.GetPaymentEvents(ctx, paymentID)
+	if err != nil {
+		log.Error().Err(err).Int64("paymentID", paymentID).Msg("Failed to get payment events")
+		return nil, fmt.Errorf("failed to get payment events: %w", err)
+	}
+
+	timeline := make([]entity.TimelineEvent, 0, len(paymentEvents))
+	for _, event := range paymentEvents {
+		timeline = append(timeline, entity.TimelineEvent{
+			Source:    entity.TimelineSourcePayment,
+			SourceID:  event.PaymentID,
+			EventType: event.EventType,
+			Details:   event.Details,
+			CreatedAt: event.CreatedAt,
+		})
+	}
+
+	if booking != nil {
+		bookingEvents, err := s.repo.GetBookingEvents(ctx, booking.ID)
+		if err != nil {
+			log.Error().Err(err).Int64("bookingID", booking.ID).Msg("Failed to get booking events")
+			return nil, fmt.Errorf("failed to get booking events: %w", err)
+		}
+
+		for _, event := range bookingEvents {
+			timeline = append(timeline, entity.TimelineEvent{
+				Source:    entity.TimelineSourceBooking,
+				SourceID:  event.BookingID,
+				EventType: event.EventType,
+				Details:   event.Details,
+				CreatedAt: event.CreatedAt,
+			})
+		}
+	}
+
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].CreatedAt.Before(timeline[j].CreatedAt)
+	})
+
+	return timeline, nil
+}
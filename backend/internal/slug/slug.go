@@ -0,0 +1,60 @@
+package slug
+
+import (
+	"strings"
+	"unicode"
+)
+
+// transliterations maps letters without an ASCII equivalent to their closest ASCII spelling
+var transliterations = map[rune]string{
+	'å': "a", 'ä': "a", 'ö': "o",
+	'æ': "ae", 'ø': "o", 'ü': "u",
+	'é': "e", 'è': "e", 'ê': "e", 'ë': "e",
+	'á': "a", 'à': "a", 'â': "a",
+	'í': "i", 'ì': "i", 'î': "i", 'ï': "i",
+	'ó': "o", 'ò': "o", 'ô': "o",
+	'ú': "u", 'ù': "u", 'û': "u",
+	'ç': "c", 'ñ': "n", 'ß': "ss",
+}
+
+// Make builds a URL-safe slug from a name, e.g. "Hälsokontroll - Kvinna" becomes
+// "halsokontroll-kvinna". Swedish and other accented letters are transliterated,
+// and every run of other characters becomes a single hyphen.
+func Make(name string) string {
+	var b strings.Builder
+	pendingHyphen := false
+
+	for _, r := range strings.ToLower(name) {
+		var part string
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			part = string(r)
+		case transliterations[r] != "":
+			part = transliterations[r]
+		default:
+			pendingHyphen = true
+			continue
+		}
+
+		if pendingHyphen && b.Len() > 0 {
+			b.WriteByte('-')
+		}
+		pendingHyphen = false
+		b.WriteString(part)
+	}
+
+	return b.String()
+}
+
+// IsNumeric reports whether a slug consists only of digits and could be mistaken for an ID
+func IsNumeric(slug string) bool {
+	if slug == "" {
+		return false
+	}
+	for _, r := range slug {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -13,6 +14,10 @@ import (
 	"github.com/svenskhalsovard/api/internal/config"
 )
 
+// ErrOrderNotFound is returned when Svea has no order with the given ID, such as
+// after an unpaid order expired
+var ErrOrderNotFound = errors.New("order not found")
+
 // Client is a client for the Svea Ekonomi API
 type Client struct {
 	httpClient *http.Client
@@ -108,6 +113,9 @@ func (c *Client) GetOrder(ctx context.Context, orderID string) (*Order, error) {
 	}
 
 	// Check response status
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrOrderNotFound
+	}
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("unexpected response status: %d, body: %s", resp.StatusCode, string(respBody))
 	}
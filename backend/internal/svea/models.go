@@ -42,7 +42,7 @@ type OrderItem struct {
 	UnitPrice     int    `json:"unitPrice"` // In cents/öre
 	VatPercent    int    `json:"vatPercent"`
 	Unit          string `json:"unit"`
-	Discount      int    `json:"discount,omitempty"`
+	Discount      int    `json:"discount,omitempty"` // Row discount in cents/öre
 }
 
 // OrderResponse represents a response from creating an order in Svea Ekonomi
@@ -75,6 +75,7 @@ type Order struct {
 	CreatedAt        time.Time   `json:"createdAt"`
 	UpdatedAt        time.Time   `json:"updatedAt"`
 	Payments         []Payment   `json:"payments"`
+	CheckoutUI       CheckoutUIData `json:"checkoutUI"`
 }
 
 // Payment represents a payment in Svea Ekonomi
@@ -144,6 +145,12 @@ const (
 	PaymentMethodSwish     = "swish"
 )
 
+// RequiresNationalID reports whether a payment method needs the customer's
+// personnummer, which Svea uses for its credit check
+func RequiresNationalID(paymentMethod string) bool {
+	return paymentMethod == PaymentMethodInvoice || paymentMethod == PaymentMethodDirectDebit
+}
+
 // PaymentType constants
 const (
 	PaymentTypeOneTime    = "onetime"
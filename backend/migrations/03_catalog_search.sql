@@ -0,0 +1,13 @@
+-- Add a category to services for catalog filtering
+ALTER TABLE services ADD COLUMN category VARCHAR(100) NOT NULL DEFAULT '' AFTER image;
+
+-- Backfill categories for the seeded services
+UPDATE services SET category = 'halsokontroll' WHERE name LIKE 'Hälsokontroll%';
+UPDATE services SET category = 'blodprov' WHERE name LIKE 'Blodprov%';
+
+-- Create indexes for catalog search, filtering and keyset pagination
+CREATE INDEX idx_services_category ON services(category);
+CREATE INDEX idx_services_is_subscription ON services(is_subscription);
+CREATE INDEX idx_services_active_name ON services(is_active, name, id);
+CREATE INDEX idx_services_active_price ON services(is_active, price, id);
+CREATE INDEX idx_services_active_created_at ON services(is_active, created_at, id);
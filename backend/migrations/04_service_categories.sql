@@ -0,0 +1,36 @@
+-- Create service_categories table
+CREATE TABLE IF NOT EXISTS service_categories (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    parent_id BIGINT NULL,
+    slug VARCHAR(100) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description VARCHAR(1000) NOT NULL DEFAULT '',
+    sort_order INT NOT NULL DEFAULT 0,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP NULL,
+    FOREIGN KEY (parent_id) REFERENCES service_categories(id),
+    UNIQUE KEY (slug)
+);
+
+-- Insert categories
+INSERT INTO service_categories (slug, name, description, sort_order) VALUES
+('halsokontroll', 'Hälsokontroller', 'Omfattande hälsokontroller med läkarkonsultation', 1),
+('blodprov', 'Blodprov', 'Blodprover och labbpaket för att följa din hälsa', 2);
+
+-- Assign services to categories
+ALTER TABLE services ADD COLUMN category_id BIGINT NULL AFTER image;
+ALTER TABLE services ADD FOREIGN KEY (category_id) REFERENCES service_categories(id);
+
+UPDATE services
+JOIN service_categories ON service_categories.slug = services.category
+SET services.category_id = service_categories.id;
+
+-- Replace the free-text category column
+DROP INDEX idx_services_category ON services;
+ALTER TABLE services DROP COLUMN category;
+
+-- Create indexes
+CREATE INDEX idx_services_category_id ON services(category_id);
+CREATE INDEX idx_service_categories_parent_id ON service_categories(parent_id, sort_order);
@@ -0,0 +1,23 @@
+-- Add slugs to services
+ALTER TABLE services ADD COLUMN slug VARCHAR(255) NULL AFTER name;
+
+-- Backfill slugs for the seeded services
+UPDATE services SET slug = 'halsokontroll-kvinna' WHERE name = 'Hälsokontroll - Kvinna';
+UPDATE services SET slug = 'halsokontroll-man' WHERE name = 'Hälsokontroll - Man';
+UPDATE services SET slug = 'blodprov-bas' WHERE name = 'Blodprov - Bas';
+UPDATE services SET slug = 'blodprov-premium' WHERE name = 'Blodprov - Premium';
+UPDATE services SET slug = 'blodprov-prenumeration' WHERE name = 'Blodprov - Prenumeration';
+UPDATE services SET slug = CONCAT('tjanst-', id) WHERE slug IS NULL;
+
+ALTER TABLE services MODIFY slug VARCHAR(255) NOT NULL;
+ALTER TABLE services ADD UNIQUE KEY (slug);
+
+-- Create service_slug_redirects table for previous slugs of renamed services
+CREATE TABLE IF NOT EXISTS service_slug_redirects (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    service_id BIGINT NOT NULL,
+    slug VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (service_id) REFERENCES services(id),
+    UNIQUE KEY (slug)
+);
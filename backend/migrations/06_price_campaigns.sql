@@ -0,0 +1,46 @@
+-- Create price_campaigns table
+CREATE TABLE IF NOT EXISTS price_campaigns (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    name VARCHAR(255) NOT NULL,
+    discount_type VARCHAR(50) NOT NULL,
+    discount_value DECIMAL(10, 2) NOT NULL,
+    starts_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ends_at TIMESTAMP NULL,
+    applies_to_all BOOLEAN NOT NULL DEFAULT FALSE,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP NULL,
+    CHECK (discount_type IN ('percentage', 'fixed_amount')),
+    CHECK (discount_value >= 0)
+);
+
+-- Create price_campaign_services table for per-service targeting
+CREATE TABLE IF NOT EXISTS price_campaign_services (
+    campaign_id BIGINT NOT NULL,
+    service_id BIGINT NOT NULL,
+    PRIMARY KEY (campaign_id, service_id),
+    FOREIGN KEY (campaign_id) REFERENCES price_campaigns(id),
+    FOREIGN KEY (service_id) REFERENCES services(id)
+);
+
+-- Move existing static discounts into open-ended fixed-amount campaigns
+INSERT INTO price_campaigns (name, discount_type, discount_value, applies_to_all)
+SELECT CONCAT('Rabatt - ', name), 'fixed_amount', price - discounted_price, FALSE
+FROM services
+WHERE discounted_price IS NOT NULL
+AND discounted_price < price
+ORDER BY id;
+
+INSERT INTO price_campaign_services (campaign_id, service_id)
+SELECT price_campaigns.id, services.id
+FROM services
+JOIN price_campaigns ON price_campaigns.name = CONCAT('Rabatt - ', services.name)
+WHERE services.discounted_price IS NOT NULL
+AND services.discounted_price < services.price;
+
+ALTER TABLE services DROP COLUMN discounted_price;
+
+-- Create indexes
+CREATE INDEX idx_price_campaigns_window ON price_campaigns(is_active, ends_at, starts_at);
+CREATE INDEX idx_price_campaign_services_service_id ON price_campaign_services(service_id);
@@ -0,0 +1,53 @@
+-- Create promo_codes table
+CREATE TABLE IF NOT EXISTS promo_codes (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    code VARCHAR(50) NOT NULL,
+    description VARCHAR(255) NULL,
+    discount_type VARCHAR(50) NOT NULL,
+    discount_value DECIMAL(10, 2) NOT NULL,
+    max_uses INT NULL,
+    max_uses_per_customer INT NULL,
+    min_order_amount DECIMAL(10, 2) NULL,
+    starts_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NULL,
+    applies_to_all BOOLEAN NOT NULL DEFAULT TRUE,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP NULL,
+    UNIQUE KEY (code),
+    CHECK (discount_type IN ('percentage', 'fixed_amount')),
+    CHECK (discount_value >= 0)
+);
+
+-- Create promo_code_services table for service restrictions
+CREATE TABLE IF NOT EXISTS promo_code_services (
+    promo_code_id BIGINT NOT NULL,
+    service_id BIGINT NOT NULL,
+    PRIMARY KEY (promo_code_id, service_id),
+    FOREIGN KEY (promo_code_id) REFERENCES promo_codes(id),
+    FOREIGN KEY (service_id) REFERENCES services(id)
+);
+
+-- Create promo_code_redemptions table
+CREATE TABLE IF NOT EXISTS promo_code_redemptions (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    promo_code_id BIGINT NOT NULL,
+    payment_id BIGINT NOT NULL,
+    customer_id BIGINT NOT NULL,
+    discount_amount DECIMAL(10, 2) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (promo_code_id) REFERENCES promo_codes(id),
+    FOREIGN KEY (payment_id) REFERENCES payments(id),
+    FOREIGN KEY (customer_id) REFERENCES customers(id),
+    UNIQUE KEY (payment_id)
+);
+
+-- Record the applied promo code and discount on payments
+ALTER TABLE payments ADD COLUMN promo_code_id BIGINT NULL AFTER transaction_type;
+ALTER TABLE payments ADD COLUMN discount_amount DECIMAL(10, 2) NOT NULL DEFAULT 0 AFTER promo_code_id;
+ALTER TABLE payments ADD FOREIGN KEY (promo_code_id) REFERENCES promo_codes(id);
+ALTER TABLE payment_items ADD COLUMN discount_amount DECIMAL(10, 2) NOT NULL DEFAULT 0 AFTER total_price;
+
+-- Create indexes
+CREATE INDEX idx_promo_code_redemptions_promo_code_id ON promo_code_redemptions(promo_code_id, customer_id);
@@ -0,0 +1,80 @@
+-- Mark services sold as gift cards
+ALTER TABLE services ADD COLUMN is_gift_card BOOLEAN NOT NULL DEFAULT FALSE AFTER subscription_interval;
+
+-- Create gift_cards table
+CREATE TABLE IF NOT EXISTS gift_cards (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    code VARCHAR(50) NOT NULL,
+    initial_amount DECIMAL(10, 2) NOT NULL,
+    balance DECIMAL(10, 2) NOT NULL,
+    currency VARCHAR(10) NOT NULL DEFAULT 'SEK',
+    purchase_payment_id BIGINT NULL,
+    expires_at TIMESTAMP NULL,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP NULL,
+    FOREIGN KEY (purchase_payment_id) REFERENCES payments(id),
+    UNIQUE KEY (code),
+    CHECK (balance >= 0 AND balance <= initial_amount)
+);
+
+-- Create gift_card_transactions table as the balance ledger
+CREATE TABLE IF NOT EXISTS gift_card_transactions (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    gift_card_id BIGINT NOT NULL,
+    payment_id BIGINT NULL,
+    type VARCHAR(50) NOT NULL,
+    amount DECIMAL(10, 2) NOT NULL,
+    balance_after DECIMAL(10, 2) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (gift_card_id) REFERENCES gift_cards(id),
+    FOREIGN KEY (payment_id) REFERENCES payments(id),
+    CHECK (type IN ('issue', 'redeem', 'release'))
+);
+
+-- Record the gift card redeemed by a payment
+ALTER TABLE payments ADD COLUMN gift_card_id BIGINT NULL AFTER discount_amount;
+ALTER TABLE payments ADD COLUMN gift_card_amount DECIMAL(10, 2) NOT NULL DEFAULT 0 AFTER gift_card_id;
+ALTER TABLE payments ADD FOREIGN KEY (gift_card_id) REFERENCES gift_cards(id);
+
+-- Insert gift card products
+INSERT INTO services (
+    name,
+    slug,
+    short_description,
+    description,
+    price,
+    is_subscription,
+    subscription_interval,
+    is_gift_card,
+    image,
+    is_active
+) VALUES (
+    'Presentkort 1000 kr',
+    'presentkort-1000',
+    'Ge bort hälsa med ett presentkort på 1000 kr',
+    'Presentkortet kan användas för alla våra hälsokontroller och blodprover. Det kan delas upp på flera köp och är giltigt i 24 månader.',
+    1000.00,
+    FALSE,
+    NULL,
+    TRUE,
+    '/assets/images/gift-card.jpg',
+    TRUE
+), (
+    'Presentkort 3000 kr',
+    'presentkort-3000',
+    'Ge bort hälsa med ett presentkort på 3000 kr',
+    'Presentkortet kan användas för alla våra hälsokontroller och blodprover. Det kan delas upp på flera köp och är giltigt i 24 månader.',
+    3000.00,
+    FALSE,
+    NULL,
+    TRUE,
+    '/assets/images/gift-card.jpg',
+    TRUE
+);
+
+-- Create indexes
+CREATE INDEX idx_gift_cards_purchase_payment_id ON gift_cards(purchase_payment_id);
+CREATE INDEX idx_gift_card_transactions_gift_card_id ON gift_card_transactions(gift_card_id);
+CREATE INDEX idx_gift_card_transactions_payment_id ON gift_card_transactions(payment_id);
@@ -0,0 +1,31 @@
+-- Create carts table
+CREATE TABLE IF NOT EXISTS carts (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    token VARCHAR(64) NOT NULL,
+    status VARCHAR(50) NOT NULL DEFAULT 'open',
+    payment_id BIGINT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP NULL,
+    FOREIGN KEY (payment_id) REFERENCES payments(id),
+    UNIQUE KEY (token),
+    CHECK (status IN ('open', 'checked_out'))
+);
+
+-- Create cart_items table
+CREATE TABLE IF NOT EXISTS cart_items (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    cart_id BIGINT NOT NULL,
+    service_id BIGINT NOT NULL,
+    quantity INT NOT NULL,
+    purchase_type VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (cart_id) REFERENCES carts(id),
+    FOREIGN KEY (service_id) REFERENCES services(id),
+    UNIQUE KEY (cart_id, service_id, purchase_type)
+);
+
+-- Create indexes
+CREATE INDEX idx_carts_status_updated_at ON carts(status, updated_at);
@@ -0,0 +1,48 @@
+-- Create checkout_quotes table for server-priced orders awaiting confirmation
+CREATE TABLE IF NOT EXISTS checkout_quotes (
+    id VARCHAR(36) PRIMARY KEY,
+    cart_token VARCHAR(64) NULL,
+    transaction_type VARCHAR(50) NOT NULL,
+    promo_code_id BIGINT NULL,
+    promo_code VARCHAR(50) NULL,
+    gift_card_id BIGINT NULL,
+    gift_card_code VARCHAR(50) NULL,
+    subtotal DECIMAL(10, 2) NOT NULL,
+    discount_amount DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    gift_card_amount DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    vat_amount DECIMAL(10, 2) NOT NULL,
+    total_amount DECIMAL(10, 2) NOT NULL,
+    currency VARCHAR(10) NOT NULL DEFAULT 'SEK',
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP NULL,
+    payment_id BIGINT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (promo_code_id) REFERENCES promo_codes(id),
+    FOREIGN KEY (gift_card_id) REFERENCES gift_cards(id),
+    FOREIGN KEY (payment_id) REFERENCES payments(id)
+);
+
+-- Create checkout_quote_items table
+CREATE TABLE IF NOT EXISTS checkout_quote_items (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    quote_id VARCHAR(36) NOT NULL,
+    service_id BIGINT NOT NULL,
+    service_name VARCHAR(255) NOT NULL,
+    quantity INT NOT NULL,
+    purchase_type VARCHAR(50) NOT NULL,
+    list_price DECIMAL(10, 2) NOT NULL,
+    unit_price DECIMAL(10, 2) NOT NULL,
+    total_price DECIMAL(10, 2) NOT NULL,
+    discount_amount DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    vat_percent INT NOT NULL,
+    vat_amount DECIMAL(10, 2) NOT NULL,
+    FOREIGN KEY (quote_id) REFERENCES checkout_quotes(id),
+    FOREIGN KEY (service_id) REFERENCES services(id)
+);
+
+-- Store the VAT rate of each payment item
+ALTER TABLE payment_items ADD COLUMN vat_percent INT NOT NULL DEFAULT 25 AFTER discount_amount;
+
+-- Create indexes
+CREATE INDEX idx_checkout_quote_items_quote_id ON checkout_quote_items(quote_id);
+CREATE INDEX idx_checkout_quotes_expires_at ON checkout_quotes(expires_at);
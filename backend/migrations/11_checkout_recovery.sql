@@ -0,0 +1,27 @@
+-- Record whether the customer agreed to a reminder about an unfinished checkout
+ALTER TABLE payments ADD COLUMN recovery_consent BOOLEAN NOT NULL DEFAULT FALSE AFTER gift_card_amount;
+
+-- Create checkout_recoveries table, one row per payment that was emailed
+CREATE TABLE IF NOT EXISTS checkout_recoveries (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    payment_id BIGINT NOT NULL,
+    token VARCHAR(64) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    sent_at TIMESTAMP NULL,
+    resumed_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (payment_id) REFERENCES payments(id),
+    UNIQUE KEY (payment_id),
+    UNIQUE KEY (token)
+);
+
+-- Create email_opt_outs table for addresses that no longer want checkout reminders
+CREATE TABLE IF NOT EXISTS email_opt_outs (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    email VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY (email)
+);
+
+-- Create indexes
+CREATE INDEX idx_payments_status_created_at ON payments(status, created_at);
@@ -0,0 +1,20 @@
+-- Create email_outbox table. Emails are written here in the same transaction as
+-- the change that causes them and delivered by a background job.
+CREATE TABLE IF NOT EXISTS email_outbox (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    recipient VARCHAR(255) NOT NULL,
+    reply_to VARCHAR(255) NULL,
+    subject VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL,
+    status VARCHAR(50) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT NULL,
+    available_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    sent_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    CHECK (status IN ('pending', 'sent', 'failed'))
+);
+
+-- Create indexes
+CREATE INDEX idx_email_outbox_status_available_at ON email_outbox(status, available_at);
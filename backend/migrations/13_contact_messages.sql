@@ -0,0 +1,16 @@
+-- Create contact_messages table for contact form submissions
+CREATE TABLE IF NOT EXISTS contact_messages (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    message TEXT NOT NULL,
+    ip_address VARCHAR(64) NOT NULL,
+    user_agent VARCHAR(512) NOT NULL DEFAULT '',
+    outbox_email_id BIGINT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (outbox_email_id) REFERENCES email_outbox(id)
+);
+
+-- Create indexes
+CREATE INDEX idx_contact_messages_ip_created_at ON contact_messages(ip_address, created_at);
@@ -0,0 +1,29 @@
+-- Record when a customer proved ownership of their email address
+ALTER TABLE customers ADD COLUMN email_verified_at TIMESTAMP NULL AFTER additional_info;
+
+-- Create login_codes table for one-time email login codes. Only a hash of the
+-- code is stored.
+CREATE TABLE IF NOT EXISTS login_codes (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    email VARCHAR(255) NOT NULL,
+    code_hash CHAR(64) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create customer_sessions table. Only a hash of the session token is stored.
+CREATE TABLE IF NOT EXISTS customer_sessions (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    customer_id BIGINT NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (customer_id) REFERENCES customers(id),
+    UNIQUE KEY (token_hash)
+);
+
+-- Create indexes
+CREATE INDEX idx_login_codes_email_created_at ON login_codes(email, created_at);
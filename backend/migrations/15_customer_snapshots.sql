@@ -0,0 +1,47 @@
+-- Snapshot the customer details given at checkout on each payment and booking,
+-- so later changes to the customer record do not rewrite past orders
+ALTER TABLE payments
+    ADD COLUMN customer_first_name VARCHAR(255) NOT NULL DEFAULT '' AFTER customer_id,
+    ADD COLUMN customer_last_name VARCHAR(255) NOT NULL DEFAULT '' AFTER customer_first_name,
+    ADD COLUMN customer_email VARCHAR(255) NOT NULL DEFAULT '' AFTER customer_last_name,
+    ADD COLUMN customer_phone VARCHAR(50) NOT NULL DEFAULT '' AFTER customer_email,
+    ADD COLUMN customer_street_address VARCHAR(255) NOT NULL DEFAULT '' AFTER customer_phone,
+    ADD COLUMN customer_postal_code VARCHAR(20) NOT NULL DEFAULT '' AFTER customer_street_address,
+    ADD COLUMN customer_city VARCHAR(100) NOT NULL DEFAULT '' AFTER customer_postal_code,
+    ADD COLUMN customer_additional_info VARCHAR(1000) NOT NULL DEFAULT '' AFTER customer_city;
+
+ALTER TABLE bookings
+    ADD COLUMN customer_first_name VARCHAR(255) NOT NULL DEFAULT '' AFTER customer_id,
+    ADD COLUMN customer_last_name VARCHAR(255) NOT NULL DEFAULT '' AFTER customer_first_name,
+    ADD COLUMN customer_email VARCHAR(255) NOT NULL DEFAULT '' AFTER customer_last_name,
+    ADD COLUMN customer_phone VARCHAR(50) NOT NULL DEFAULT '' AFTER customer_email,
+    ADD COLUMN customer_street_address VARCHAR(255) NOT NULL DEFAULT '' AFTER customer_phone,
+    ADD COLUMN customer_postal_code VARCHAR(20) NOT NULL DEFAULT '' AFTER customer_street_address,
+    ADD COLUMN customer_city VARCHAR(100) NOT NULL DEFAULT '' AFTER customer_postal_code,
+    ADD COLUMN customer_additional_info VARCHAR(1000) NOT NULL DEFAULT '' AFTER customer_city;
+
+-- Backfill existing payments and bookings from the current customer records,
+-- which are the best record left of the details used at the time
+UPDATE payments p
+JOIN customers c ON c.id = p.customer_id
+SET p.customer_first_name = c.first_name,
+    p.customer_last_name = c.last_name,
+    p.customer_email = c.email,
+    p.customer_phone = c.phone,
+    p.customer_street_address = c.street_address,
+    p.customer_postal_code = c.postal_code,
+    p.customer_city = c.city,
+    p.customer_additional_info = LEFT(COALESCE(c.additional_info, ''), 1000)
+WHERE p.customer_email = '';
+
+UPDATE bookings b
+JOIN customers c ON c.id = b.customer_id
+SET b.customer_first_name = c.first_name,
+    b.customer_last_name = c.last_name,
+    b.customer_email = c.email,
+    b.customer_phone = c.phone,
+    b.customer_street_address = c.street_address,
+    b.customer_postal_code = c.postal_code,
+    b.customer_city = c.city,
+    b.customer_additional_info = LEFT(COALESCE(c.additional_info, ''), 1000)
+WHERE b.customer_email = '';
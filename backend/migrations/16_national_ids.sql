@@ -0,0 +1,5 @@
+-- Store the personnummer or samordningsnummer of customers, encrypted by the
+-- application. Payments and bookings keep the number given for the order.
+ALTER TABLE customers ADD COLUMN national_id_encrypted TEXT NULL AFTER additional_info;
+ALTER TABLE payments ADD COLUMN customer_national_id_encrypted TEXT NULL AFTER customer_additional_info;
+ALTER TABLE bookings ADD COLUMN customer_national_id_encrypted TEXT NULL AFTER customer_additional_info;
@@ -0,0 +1,17 @@
+-- Customer personal data is encrypted by the application, which makes the
+-- columns longer and the email unusable for lookups. Emails are looked up by
+-- email_hash instead, a keyed hash of the normalized address. The indexes on
+-- email are dropped, since TEXT columns cannot be indexed in full.
+-- Run the reencrypt command after this migration to encrypt existing rows.
+ALTER TABLE customers
+    MODIFY first_name TEXT NOT NULL,
+    MODIFY last_name TEXT NOT NULL,
+    MODIFY email TEXT NOT NULL,
+    MODIFY phone TEXT NOT NULL,
+    MODIFY street_address TEXT NOT NULL,
+    MODIFY postal_code TEXT NOT NULL,
+    MODIFY city TEXT NOT NULL,
+    ADD COLUMN email_hash CHAR(64) NULL AFTER email,
+    DROP INDEX email,
+    DROP INDEX idx_customers_email,
+    ADD UNIQUE KEY (email_hash);
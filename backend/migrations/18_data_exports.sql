@@ -0,0 +1,20 @@
+-- Create data_exports table for archives of the personal data of a customer,
+-- requested under GDPR Article 15. The archive is encrypted by the application
+-- and removed when the download link expires; the row is kept as a record of
+-- the request. Only a hash of the download token is stored.
+CREATE TABLE IF NOT EXISTS data_exports (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    customer_id BIGINT NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    requested_by VARCHAR(50) NOT NULL,
+    archive_encrypted LONGTEXT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    downloaded_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (customer_id) REFERENCES customers(id),
+    UNIQUE KEY (token_hash),
+    CHECK (requested_by IN ('customer', 'staff'))
+);
+
+-- Create indexes
+CREATE INDEX idx_data_exports_expires_at ON data_exports(expires_at);
@@ -0,0 +1,20 @@
+-- Record when a customer was erased on request under GDPR Article 17. The
+-- customer row is kept, pseudonymized, since payments and bookings refer to it.
+ALTER TABLE customers ADD COLUMN erased_at TIMESTAMP NULL AFTER email_verified_at;
+
+-- Create retained_records table for rows of erased customers that must be
+-- kept for a while, such as payments under bokföringslagen. They are
+-- pseudonymized once retain_until has passed.
+CREATE TABLE IF NOT EXISTS retained_records (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    customer_id BIGINT NOT NULL,
+    table_name VARCHAR(64) NOT NULL,
+    record_id BIGINT NOT NULL,
+    retain_until TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (customer_id) REFERENCES customers(id),
+    UNIQUE KEY (table_name, record_id)
+);
+
+-- Create indexes
+CREATE INDEX idx_retained_records_retain_until ON retained_records(retain_until);
@@ -0,0 +1,28 @@
+-- Create customer_consents table recording the consents customers give and
+-- withdraw. The table is append-only: a change adds a row and the current state
+-- of a consent is its latest row. Only the IP address and user agent are ever
+-- cleared, when the customer is erased.
+CREATE TABLE IF NOT EXISTS customer_consents (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    customer_id BIGINT NOT NULL,
+    consent_type VARCHAR(50) NOT NULL,
+    granted BOOLEAN NOT NULL,
+    policy_version VARCHAR(50) NOT NULL,
+    source VARCHAR(50) NOT NULL,
+    ip_address VARCHAR(64) NOT NULL DEFAULT '',
+    user_agent VARCHAR(512) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (customer_id) REFERENCES customers(id),
+    CHECK (consent_type IN ('terms', 'marketing_email', 'sms_reminders', 'checkout_reminders')),
+    CHECK (source IN ('checkout', 'account', 'email_link', 'erasure', 'migration'))
+);
+
+-- Create indexes
+CREATE INDEX idx_customer_consents_customer_type ON customer_consents(customer_id, consent_type, id);
+
+-- Carry over the checkout reminder answers given before consents were recorded
+INSERT INTO customer_consents (customer_id, consent_type, granted, policy_version, source, created_at)
+SELECT customer_id, 'checkout_reminders', TRUE, 'unversioned', 'migration', MAX(created_at)
+FROM payments
+WHERE recovery_consent = TRUE
+GROUP BY customer_id;
@@ -0,0 +1,26 @@
+-- Create audit_log table recording who or what changed payments, bookings and
+-- customers. Values hold no personal data, so entries can be kept after a
+-- customer is erased.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    entity_type VARCHAR(50) NOT NULL,
+    entity_id BIGINT NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    old_values JSON NULL,
+    new_values JSON NULL,
+    actor_type VARCHAR(20) NOT NULL,
+    actor_id VARCHAR(255) NOT NULL DEFAULT '',
+    request_id VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (actor_type IN ('system', 'webhook', 'admin', 'customer'))
+);
+
+-- Create indexes
+CREATE INDEX idx_audit_log_entity ON audit_log(entity_type, entity_id, id);
+
+-- Make the audit log append-only
+CREATE TRIGGER audit_log_no_update BEFORE UPDATE ON audit_log
+FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_log is append-only';
+
+CREATE TRIGGER audit_log_no_delete BEFORE DELETE ON audit_log
+FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_log is append-only';
@@ -0,0 +1,4 @@
+-- Add version column to payments. Every status update increments it and only
+-- applies when the payment still has the version it was read with, so two
+-- concurrent updates cannot both move a payment.
+ALTER TABLE payments ADD COLUMN version INT NOT NULL DEFAULT 0 AFTER status;
@@ -0,0 +1,18 @@
+-- Add the time a booking entered each status of its lifecycle
+ALTER TABLE bookings
+    ADD COLUMN confirmed_at TIMESTAMP NULL AFTER notes,
+    ADD COLUMN checked_in_at TIMESTAMP NULL AFTER confirmed_at,
+    ADD COLUMN completed_at TIMESTAMP NULL AFTER checked_in_at,
+    ADD COLUMN cancelled_at TIMESTAMP NULL AFTER completed_at,
+    ADD COLUMN no_show_at TIMESTAMP NULL AFTER cancelled_at;
+
+-- Bookings have been confirmed when they were created, and the last update of
+-- a cancelled or completed booking is the best known time of that status
+UPDATE bookings SET confirmed_at = created_at WHERE status IN ('confirmed', 'completed', 'cancelled');
+UPDATE bookings SET completed_at = updated_at WHERE status = 'completed';
+UPDATE bookings SET cancelled_at = updated_at WHERE status = 'cancelled';
+
+-- Only allow the statuses of the booking lifecycle
+ALTER TABLE bookings
+    ADD CONSTRAINT chk_bookings_status
+    CHECK (status IN ('pending', 'confirmed', 'checked_in', 'completed', 'cancelled', 'no_show'));
@@ -0,0 +1,25 @@
+-- Create payment_events and booking_events tables recording what happened to
+-- payments and bookings, for the timeline support follows an order by. Details
+-- hold no customer details, only values such as the payment method or the
+-- reason a payment failed.
+CREATE TABLE IF NOT EXISTS payment_events (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    payment_id BIGINT NOT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    details JSON NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (payment_id) REFERENCES payments(id)
+);
+
+CREATE TABLE IF NOT EXISTS booking_events (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    booking_id BIGINT NOT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    details JSON NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (booking_id) REFERENCES bookings(id)
+);
+
+-- Create indexes
+CREATE INDEX idx_payment_events_payment ON payment_events(payment_id, id);
+CREATE INDEX idx_booking_events_booking ON booking_events(booking_id, id);
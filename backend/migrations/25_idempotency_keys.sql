@@ -0,0 +1,20 @@
+-- Create idempotency_keys table storing the responses of requests made with an
+-- Idempotency-Key header, so retries are answered without being processed
+-- again. Responses may hold customer details, so rows are deleted once they
+-- expire.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    scope VARCHAR(255) NOT NULL,
+    idempotency_key VARCHAR(255) NOT NULL,
+    fingerprint CHAR(64) NOT NULL,
+    status_code INT NOT NULL DEFAULT 0,
+    content_type VARCHAR(255) NOT NULL DEFAULT '',
+    response_body MEDIUMBLOB NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP NULL,
+    expires_at TIMESTAMP NOT NULL,
+    UNIQUE KEY (scope, idempotency_key)
+);
+
+-- Create indexes
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
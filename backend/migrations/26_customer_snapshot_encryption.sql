@@ -0,0 +1,22 @@
+-- The customer details copied onto payments and bookings are encrypted by the
+-- application like the customers table, which makes the columns longer.
+-- Run the reencrypt command after this migration to encrypt existing rows.
+ALTER TABLE payments
+    MODIFY customer_first_name TEXT NOT NULL,
+    MODIFY customer_last_name TEXT NOT NULL,
+    MODIFY customer_email TEXT NOT NULL,
+    MODIFY customer_phone TEXT NOT NULL,
+    MODIFY customer_street_address TEXT NOT NULL,
+    MODIFY customer_postal_code TEXT NOT NULL,
+    MODIFY customer_city TEXT NOT NULL,
+    MODIFY customer_additional_info TEXT NOT NULL;
+
+ALTER TABLE bookings
+    MODIFY customer_first_name TEXT NOT NULL,
+    MODIFY customer_last_name TEXT NOT NULL,
+    MODIFY customer_email TEXT NOT NULL,
+    MODIFY customer_phone TEXT NOT NULL,
+    MODIFY customer_street_address TEXT NOT NULL,
+    MODIFY customer_postal_code TEXT NOT NULL,
+    MODIFY customer_city TEXT NOT NULL,
+    MODIFY customer_additional_info TEXT NOT NULL;
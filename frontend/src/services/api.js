@@ -1,6 +1,9 @@
 import axios from 'axios';
 import store from '@/store';
 
+// localStorage key of the customer session token
+export const SESSION_TOKEN_KEY = 'sessionToken';
+
 // Create axios instance with default config
 const api = axios.create({
   baseURL: process.env.VUE_APP_API_URL || '/api',
@@ -18,6 +21,11 @@ api.interceptors.request.use(
     store.dispatch('setLoading', true);
     // Clear previous errors
     store.dispatch('clearError');
+    // Send the session token of a logged in customer
+    const token = localStorage.getItem(SESSION_TOKEN_KEY);
+    if (token) {
+      config.headers.Authorization = `Bearer ${token}`;
+    }
     return config;
   },
   error => {
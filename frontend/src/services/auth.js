@@ -0,0 +1,124 @@
+import api, { SESSION_TOKEN_KEY } from './api';
+
+const authService = {
+  /**
+   * Emails a one-time login code to the customer
+   * @param {string} email - Customer email address
+   * @returns {Promise} - Promise resolved when the code has been sent
+   */
+  async requestLoginCode(email) {
+    try {
+      const response = await api.post('/api/auth/login-code', { email });
+      return response.data;
+    } catch (error) {
+      console.error('Login code request error:', error);
+      throw error;
+    }
+  },
+
+  /**
+   * Logs in with a one-time code and stores the session token
+   * @param {string} email - Customer email address
+   * @param {string} code - Six-digit code from the email
+   * @returns {Promise} - Promise with the logged in customer
+   */
+  async verifyLoginCode(email, code) {
+    try {
+      const response = await api.post('/api/auth/verify', { email, code });
+      localStorage.setItem(SESSION_TOKEN_KEY, response.data.data.token);
+      return response.data.data.customer;
+    } catch (error) {
+      console.error('Login code verification error:', error);
+      throw error;
+    }
+  },
+
+  /**
+   * Ends the current session
+   * @returns {Promise} - Promise resolved when logged out
+   */
+  async logout() {
+    try {
+      await api.post('/api/auth/logout');
+    } finally {
+      localStorage.removeItem(SESSION_TOKEN_KEY);
+    }
+  },
+
+  /**
+   * Whether a session token is stored
+   * @returns {boolean}
+   */
+  isLoggedIn() {
+    return !!localStorage.getItem(SESSION_TOKEN_KEY);
+  },
+
+  /**
+   * Gets the logged in customer's profile
+   * @returns {Promise} - Promise with the profile
+   */
+  async getProfile() {
+    const response = await api.get('/api/me');
+    return response.data.data;
+  },
+
+  /**
+   * Updates the logged in customer's profile
+   * @param {Object} profile - Name, phone and address
+   * @returns {Promise} - Promise with the updated profile
+   */
+  async updateProfile(profile) {
+    const response = await api.put('/api/me', profile);
+    return response.data.data;
+  },
+
+  /**
+   * Gets the logged in customer's booking history
+   * @returns {Promise} - Promise with the bookings, newest first
+   */
+  async getBookings() {
+    const response = await api.get('/api/me/bookings');
+    return response.data.data;
+  },
+
+  /**
+   * Requests an export of all personal data held about the logged in customer
+   * @returns {Promise} - Promise with the download URL and its expiry time
+   */
+  async requestDataExport() {
+    const response = await api.post('/api/me/data-export');
+    return response.data.data;
+  },
+
+  /**
+   * Gets the current consents of the logged in customer
+   * @returns {Promise} - Promise with the consents
+   */
+  async getConsents() {
+    const response = await api.get('/api/me/consents');
+    return response.data.data;
+  },
+
+  /**
+   * Withdraws a consent of the logged in customer
+   * @param {string} type - marketing_email, sms_reminders or checkout_reminders
+   * @returns {Promise} - Promise with the updated consents
+   */
+  async withdrawConsent(type) {
+    const response = await api.delete(`/api/me/consents/${encodeURIComponent(type)}`);
+    return response.data.data;
+  },
+
+  /**
+   * Erases the logged in customer's account and personal data. Payments and
+   * bookings that must be kept for bookkeeping are pseudonymized later.
+   * @returns {Promise} - Promise with the number of erased and retained records
+   */
+  async eraseAccount() {
+    const response = await api.delete('/api/me');
+    localStorage.removeItem(SESSION_TOKEN_KEY);
+    return response.data.data;
+  }
+};
+
+export default authService;
@@ -1,14 +1,54 @@
 import api from './api';
 
+/**
+ * Builds the request config sending an idempotency key, so a retried request is
+ * not processed twice
+ * @param {string} [idempotencyKey] - Key shared by a request and its retries
+ * @returns {Object} - Axios request config
+ */
+function idempotent(idempotencyKey) {
+  return idempotencyKey ? { headers: { 'Idempotency-Key': idempotencyKey } } : {};
+}
+
 const checkoutService = {
   /**
-   * Initiates the checkout process by sending customer and cart data to the backend
-   * @param {Object} checkoutData - Customer and cart data
+   * Asks the backend to price the cart
+   * @param {Object} quoteData - Cart items, without prices
+   * @returns {Promise} - Promise with the priced quote
+   */
+  async createQuote(quoteData) {
+    try {
+      const response = await api.post('/api/checkout/quote', quoteData);
+      return response.data;
+    } catch (error) {
+      console.error('Checkout quote error:', error);
+      throw error;
+    }
+  },
+
+  /**
+   * Gets the versions of the terms and privacy policy accepted at checkout
+   * @returns {Promise} - Promise with the current policy versions
+   */
+  async getConsentPolicy() {
+    try {
+      const response = await api.get('/api/consents/policy');
+      return response.data.data;
+    } catch (error) {
+      console.error('Consent policy error:', error);
+      throw error;
+    }
+  },
+
+  /**
+   * Initiates the checkout process by confirming a quote for the customer
+   * @param {Object} checkoutData - Customer data and quote ID
+   * @param {string} [idempotencyKey] - Key shared by the request and its retries
    * @returns {Promise} - Promise with payment initiation data
    */
-  async initiateCheckout(checkoutData) {
+  async initiateCheckout(checkoutData, idempotencyKey) {
     try {
-      const response = await api.post('/api/checkout/initiate', checkoutData);
+      const response = await api.post('/api/checkout/initiate', checkoutData, idempotent(idempotencyKey));
       return response.data;
     } catch (error) {
       console.error('Checkout initiation error:', error);
@@ -19,11 +59,12 @@ const checkoutService = {
   /**
    * Processes the payment with Svea Ekonomi via our backend
    * @param {Object} paymentData - Payment data including paymentId and payment details
+   * @param {string} [idempotencyKey] - Key shared by the request and its retries
    * @returns {Promise} - Promise with payment result
    */
-  async processPayment(paymentData) {
+  async processPayment(paymentData, idempotencyKey) {
     try {
-      const response = await api.post('/api/checkout/payment', paymentData);
+      const response = await api.post('/api/checkout/payment', paymentData, idempotent(idempotencyKey));
       return response.data;
     } catch (error) {
       console.error('Payment processing error:', error);
@@ -31,6 +72,22 @@ const checkoutService = {
     }
   },
 
+  /**
+   * Creates the booking of a successful payment
+   * @param {Object} bookingData - Payment ID and customer data
+   * @param {string} [idempotencyKey] - Key shared by the request and its retries
+   * @returns {Promise} - Promise with the booking
+   */
+  async createBooking(bookingData, idempotencyKey) {
+    try {
+      const response = await api.post('/api/bookings', bookingData, idempotent(idempotencyKey));
+      return response.data;
+    } catch (error) {
+      console.error('Booking creation error:', error);
+      throw error;
+    }
+  },
+
   /**
    * Verifies the payment status with the backend
    * @param {string} paymentId - The payment ID to verify
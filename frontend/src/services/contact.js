@@ -0,0 +1,34 @@
+import api from './api';
+
+const contactService = {
+  /**
+   * Gets the token to send with the contact form, issued when the form is opened
+   * @returns {Promise} - Promise with the form token
+   */
+  async getFormToken() {
+    try {
+      const response = await api.get('/api/contact/token');
+      return response.data.data.formToken;
+    } catch (error) {
+      console.error('Contact form token error:', error);
+      throw error;
+    }
+  },
+
+  /**
+   * Sends a contact form message to the backend
+   * @param {Object} messageData - Form fields and form token
+   * @returns {Promise} - Promise with the submission result
+   */
+  async submitMessage(messageData) {
+    try {
+      const response = await api.post('/api/contact', messageData);
+      return response.data;
+    } catch (error) {
+      console.error('Contact form submission error:', error);
+      throw error;
+    }
+  }
+};
+
+export default contactService;
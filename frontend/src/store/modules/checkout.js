@@ -1,6 +1,44 @@
-import api from '@/services/api';
 import checkoutService from '@/services/checkout';
 
+/**
+ * Returns the idempotency key of a checkout step, creating it on the first
+ * attempt. The key is kept until the backend answers, so a request retried
+ * after a network error is not processed twice.
+ */
+function idempotencyKey(commit, state, step) {
+  if (!state.idempotencyKeys[step]) {
+    commit('SET_IDEMPOTENCY_KEY', { step, key: crypto.randomUUID() });
+  }
+  return state.idempotencyKeys[step];
+}
+
+/**
+ * Forgets the idempotency key of a checkout step once the backend has answered,
+ * so the next attempt is a new request
+ */
+function settleIdempotencyKey(commit, step, error) {
+  if (!error || error.response) {
+    commit('CLEAR_IDEMPOTENCY_KEY', step);
+  }
+}
+
+/**
+ * Records the booking of a completed payment. The backend books the payment
+ * together with it. When the booking could not be created yet, the payment is
+ * taken and the backend creates the booking later.
+ */
+function completeBooking(commit, dispatch, result) {
+  if (result.status === 'booking_pending') {
+    commit('SET_BOOKING_STATUS', 'paid');
+  } else {
+    commit('SET_BOOKING_STATUS', 'confirmed');
+    commit('SET_BOOKING_ID', result.bookingId);
+  }
+
+  // Clear cart after successful payment
+  dispatch('cart/clearCart', null, { root: true });
+}
+
 export default {
   namespaced: true,
   state: {
@@ -11,7 +49,8 @@ export default {
       phone: '',
       streetAddress: '',
       postalCode: '',
-      city: ''
+      city: '',
+      nationalId: ''
     },
     payment: {
       status: null, // null, 'pending', 'success', 'failed'
@@ -19,10 +58,11 @@ export default {
       errorMessage: null
     },
     booking: {
-      status: null, // null, 'pending', 'confirmed', 'failed'
+      status: null, // null, 'pending', 'confirmed', 'paid', 'failed'
       bookingId: null,
       errorMessage: null
-    }
+    },
+    idempotencyKeys: {}
   },
   mutations: {
     UPDATE_CUSTOMER(state, customerData) {
@@ -46,6 +86,14 @@ export default {
     SET_BOOKING_ERROR(state, errorMessage) {
       state.booking.errorMessage = errorMessage;
     },
+    SET_IDEMPOTENCY_KEY(state, { step, key }) {
+      state.idempotencyKeys = { ...state.idempotencyKeys, [step]: key };
+    },
+    CLEAR_IDEMPOTENCY_KEY(state, step) {
+      const keys = { ...state.idempotencyKeys };
+      delete keys[step];
+      state.idempotencyKeys = keys;
+    },
     RESET_CHECKOUT(state) {
       state.payment = {
         status: null,
@@ -57,37 +105,58 @@ export default {
         bookingId: null,
         errorMessage: null
       };
+      state.idempotencyKeys = {};
     }
   },
   actions: {
     updateCustomer({ commit }, customerData) {
       commit('UPDATE_CUSTOMER', customerData);
     },
-    async initiateCheckout({ commit, state, rootGetters }) {
+    async initiateCheckout({ commit, state, rootGetters, dispatch }) {
       try {
         // Set payment status to pending
         commit('SET_PAYMENT_STATUS', 'pending');
         
-        // Prepare checkout data
-        const checkoutData = {
-          customer: state.customer,
+        // Have the backend price the cart
+        const quote = await checkoutService.createQuote({
           items: rootGetters['cart/cartItems'].map(item => ({
             serviceId: item.service.id,
             quantity: item.quantity,
-            purchaseType: item.purchaseType,
-            price: item.service.discountedPrice || item.service.price
-          })),
-          totalAmount: rootGetters['cart/cartTotal']
+            purchaseType: item.purchaseType
+          }))
+        });
+
+        // Prepare checkout data
+        const checkoutData = {
+          customer: state.customer,
+          quoteId: quote.data.quoteId,
+          recoveryConsent: state.customer.recoveryConsent === true,
+          consents: {
+            termsVersion: state.customer.termsAccepted ? state.customer.termsVersion : '',
+            marketingEmail: state.customer.marketingEmail === true,
+            smsReminders: state.customer.smsReminders === true
+          }
         };
         
         // Send checkout request to the backend
-        const response = await checkoutService.initiateCheckout(checkoutData);
+        const response = await checkoutService.initiateCheckout(
+          checkoutData,
+          idempotencyKey(commit, state, 'initiate')
+        );
+        settleIdempotencyKey(commit, 'initiate');
         
         // Set payment ID from response
         commit('SET_PAYMENT_ID', response.paymentId);
         
+        // Orders paid in full by gift card are completed at once
+        if (response.booking) {
+          commit('SET_PAYMENT_STATUS', 'success');
+          completeBooking(commit, dispatch, response.booking);
+        }
+        
         return response;
       } catch (error) {
+        settleIdempotencyKey(commit, 'initiate', error);
         commit('SET_PAYMENT_STATUS', 'failed');
         commit('SET_PAYMENT_ERROR', error.message || 'Ett fel uppstod vid betalningsförsöket');
         throw error;
@@ -99,16 +168,17 @@ export default {
         const response = await checkoutService.processPayment({
           paymentId: state.payment.paymentId,
           ...paymentDetails
-        });
+        }, idempotencyKey(commit, state, 'payment'));
+        settleIdempotencyKey(commit, 'payment');
         
         // Update payment status
         commit('SET_PAYMENT_STATUS', 'success');
         
-        // Create booking
-        await dispatch('createBooking');
+        completeBooking(commit, dispatch, response);
         
         return response;
       } catch (error) {
+        settleIdempotencyKey(commit, 'payment', error);
         commit('SET_PAYMENT_STATUS', 'failed');
         commit('SET_PAYMENT_ERROR', error.message || 'Ett fel uppstod vid betalningen');
         throw error;
@@ -131,17 +201,22 @@ export default {
         };
         
         // Send booking request to backend
-        const response = await api.post('/api/bookings', bookingData);
+        const response = await checkoutService.createBooking(
+          bookingData,
+          idempotencyKey(commit, state, 'booking')
+        );
+        settleIdempotencyKey(commit, 'booking');
         
         // Update booking status and ID
         commit('SET_BOOKING_STATUS', 'confirmed');
-        commit('SET_BOOKING_ID', response.data.bookingId);
+        commit('SET_BOOKING_ID', response.bookingId);
         
         // Clear cart after successful booking
         dispatch('cart/clearCart', null, { root: true });
         
-        return response.data;
+        return response;
       } catch (error) {
+        settleIdempotencyKey(commit, 'booking', error);
         commit('SET_BOOKING_STATUS', 'failed');
         commit('SET_BOOKING_ERROR', error.message || 'Ett fel uppstod vid bokningen');
         throw error;
@@ -159,6 +234,6 @@ export default {
     bookingStatus: state => state.booking.status,
     bookingId: state => state.booking.bookingId,
     bookingError: state => state.booking.errorMessage,
-    isCheckoutComplete: state => state.booking.status === 'confirmed'
+    isCheckoutComplete: state => state.booking.status === 'confirmed' || state.booking.status === 'paid'
   }
 };
\ No newline at end of file